@@ -1,14 +1,35 @@
 package main
 
 import (
+	"context"
+	"flowboard-backend-go/internal/activity"
+	"flowboard-backend-go/internal/audit"
+	"flowboard-backend-go/internal/boards"
+	"flowboard-backend-go/internal/collab"
+	"flowboard-backend-go/internal/comments"
 	"flowboard-backend-go/internal/database"
+	"flowboard-backend-go/internal/events"
+	"flowboard-backend-go/internal/favorites"
 	"flowboard-backend-go/internal/middleware"
+	"flowboard-backend-go/internal/notifications"
+	"flowboard-backend-go/internal/outbox"
 	"flowboard-backend-go/internal/pages"
+	"flowboard-backend-go/internal/realtime"
+	"flowboard-backend-go/internal/reminders"
+	"flowboard-backend-go/internal/tasks"
+	"flowboard-backend-go/internal/templates"
 	_users "flowboard-backend-go/internal/users"
+	"flowboard-backend-go/internal/webhooks"
+	"flowboard-backend-go/internal/workspaces"
 	"flowboard-backend-go/pkg/config"
 	"flowboard-backend-go/pkg/logger"
+	"flowboard-backend-go/pkg/mailer"
+	"flowboard-backend-go/pkg/pubsub"
 	"fmt"
 	"log"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -23,23 +44,174 @@ func main() {
 	logger.Log.Infow("Starting FlowBoard API")
 
 	db := database.Connect(cfg)
-	db.AutoMigrate(&_users.User{}, &pages.Page{})
+	db.AutoMigrate(
+		&_users.User{},
+		&pages.Page{}, &pages.Block{}, &pages.Tag{}, &pages.PageTag{}, &pages.PageLink{},
+		&workspaces.Workspace{}, &workspaces.Member{}, &workspaces.Invitation{},
+		&favorites.Favorite{}, &favorites.Pin{}, &favorites.RecentView{},
+		&templates.Template{},
+		&boards.Board{}, &boards.Column{}, &boards.Card{},
+		&tasks.Task{},
+		&reminders.Reminder{},
+		&notifications.Notification{}, &notifications.Preference{},
+		&comments.Comment{},
+		&events.Event{},
+		&webhooks.Webhook{}, &webhooks.Delivery{},
+		&outbox.Message{}, &outbox.Receipt{},
+		&audit.Entry{},
+		&activity.Activity{}, &activity.Source{},
+		&realtime.Ticket{},
+	)
 
-	// Users
+	mail, err := mailer.New(cfg.Mail)
+	if err != nil {
+		logger.Log.Fatalw("Cannot configure mailer", "error", err)
+	}
+
+	// Pub/sub fans real-time events out across replicas
+	ps, err := pubsub.New(context.Background(), cfg.PubSub, cfg.DatabaseDSN())
+	if err != nil {
+		logger.Log.Fatalw("Cannot configure pub/sub", "error", err)
+	}
+	defer ps.Close()
+
+	// Users; tokens stop working once their user revokes them
 	userRepo := _users.NewRepository(db)
 	userService := _users.NewService(userRepo)
-	jwtMgr := middleware.NewJWTManager(cfg.JWTSecret)
-	userHandler := _users.NewHandler(userService, jwtMgr)
+	jwtMgr := middleware.NewJWTManager(cfg.JWTSecret, middleware.WithRevocationCheck(userService.TokensRevoked))
+	// Streams authenticate with a bearer token or a single-use ticket
+	streamAuth := realtime.NewAuthenticator(jwtMgr, realtime.NewTicketRepository(db))
+
+	// Audit trail
+	auditService := audit.NewService(audit.NewRepository(db))
+	auditHandler := audit.NewHandler(auditService)
+
+	// Event log behind the SSE stream
+	eventRepo := events.NewRepository(db)
+	eventService := events.NewService(eventRepo, ps)
+	go eventService.Run(context.Background())
+	go events.RunPruner(context.Background(), eventService, time.Hour)
+	eventHandler := events.NewHandler(eventService, streamAuth)
+
+	// Notifications
+	notificationRepo := notifications.NewRepository(db)
+	notificationService := notifications.NewService(notificationRepo, userService, mail, cfg.AppURL,
+		notifications.WithEventPublisher(eventService),
+	)
+	notificationHandler := notifications.NewHandler(notificationService)
+
+	// Workspaces
+	workspaceRepo := workspaces.NewRepository(db)
+	workspaceService := workspaces.NewService(workspaceRepo, userService, mail, cfg.AppURL,
+		workspaces.WithNotifier(notificationService),
+		workspaces.WithEventPublisher(eventService),
+	)
+	workspaceHandler := workspaces.NewHandler(workspaceService, auditService)
+
+	userHandler := _users.NewHandler(userService, jwtMgr, auditService)
+	isAdmin := func(userID uint) (bool, error) {
+		u, err := userService.GetByID(userID)
+		if err != nil || u == nil {
+			return false, err
+		}
+		return slices.Contains(cfg.AdminEmails, strings.ToLower(u.Email)), nil
+	}
+
+	// Webhooks
+	webhookRepo := webhooks.NewRepository(db)
+	var webhookOpts []webhooks.Option
+	if cfg.WebhooksAllowLocal {
+		webhookOpts = append(webhookOpts, webhooks.WithLocalTargets())
+	}
+	webhookService := webhooks.NewService(webhookRepo, workspaceService, webhookOpts...)
+	webhookHandler := webhooks.NewHandler(webhookService, auditService)
+	go webhooks.RunWorker(context.Background(), webhookService, 5*time.Second)
 
 	// Pages
 	pageRepo := pages.NewRepository(db)
-	pageService := pages.NewService(pageRepo)
-	pageHandler := pages.NewHandler(pageService)
+	pageService := pages.NewService(pageRepo,
+		pages.WithWorkspaceAccess(workspaceService),
+		pages.WithNotifier(notificationService),
+		pages.WithUserDirectory(userService),
+	)
+	hub := realtime.NewHub(ps, pageService, workspaceService)
+	go hub.Run(context.Background())
+	realtimeHandler := realtime.NewHandler(hub, streamAuth, middleware.OriginChecker(cfg.CORS))
+
+	// Collaborative editing
+	collabManager := collab.NewManager(ps, pageService, workspaceService, collab.WithAuditLog(auditService))
+	go collabManager.Run(context.Background(), 5*time.Second)
+	collabHandler := collab.NewHandler(collabManager, streamAuth, middleware.OriginChecker(cfg.CORS))
+
+	// Favorites, pins and recently viewed
+	favoriteRepo := favorites.NewRepository(db)
+	favoriteService := favorites.NewService(favoriteRepo, pageService)
+	favoriteHandler := favorites.NewHandler(favoriteService)
+
+	// Activity feeds are built from page, comment and task events
+	activityService := activity.NewService(activity.NewRepository(db), pageService, workspaceService)
+	activityHandler := activity.NewHandler(activityService)
+	go activity.RunPruner(context.Background(), activityService, time.Hour)
+
+	pageHandler := pages.NewHandler(pageService, favoriteService, auditService)
+	go pages.RunRankRebalancer(context.Background(), pageService, 10*time.Minute)
+
+	// Comments
+	commentRepo := comments.NewRepository(db)
+	commentService := comments.NewService(commentRepo, pageService, comments.WithNotifier(notificationService))
+	commentHandler := comments.NewHandler(commentService)
+
+	// Templates
+	templateRepo := templates.NewRepository(db)
+	templateService := templates.NewService(templateRepo, pageService, userService, workspaceService)
+	templateHandler := templates.NewHandler(templateService, auditService)
+
+	// Boards
+	boardRepo := boards.NewRepository(db)
+	boardService := boards.NewService(boardRepo, pageService, workspaceService)
+	boardHandler := boards.NewHandler(boardService)
+
+	// Tasks
+	taskRepo := tasks.NewRepository(db)
+	taskService := tasks.NewService(taskRepo, pageService, workspaceService)
+	taskHandler := tasks.NewHandler(taskService)
+
+	// Reminders
+	reminderRepo := reminders.NewRepository(db)
+	reminderService := reminders.NewService(reminderRepo, pageService, taskService,
+		reminders.NewNotificationChannel(notificationService),
+	)
+	reminderHandler := reminders.NewHandler(reminderService)
+	go reminders.RunScheduler(context.Background(), reminderService, 30*time.Second)
+
+	// Outbox relay: domain events recorded with their changes reach these
+	// consumers at least once. Consumer names are stored in receipts; keep
+	// them stable.
+	relay := outbox.NewRelay(outbox.NewRepository(db))
+	relay.Subscribe("realtime", pages.Consume(realtime.NewPublisher(ps)), pages.EventTypes...)
+	relay.Subscribe("events", pages.Consume(eventService), pages.EventTypes...)
+	relay.Subscribe("webhooks", pages.Consume(webhookService), pages.EventTypes...)
+	relay.Subscribe("link-invitations", _users.Consume(workspaceService), string(_users.UserRegistered))
+	relay.Subscribe("activity-pages", pages.Consume(activityService), pages.EventTypes...)
+	relay.Subscribe("activity-comments", comments.Consume(activityService), comments.EventTypes...)
+	relay.Subscribe("activity-tasks", tasks.Consume(activityService), tasks.EventTypes...)
+	relay.Subscribe("favorites", pages.Consume(favoriteService), string(pages.PageDeleted))
+	relay.Subscribe("comments", pages.Consume(commentService), string(pages.PageDeleted))
+	relay.Subscribe("notifications-pages", pages.Consume(pageService), string(pages.PageCreated), string(pages.PageUpdated))
+	relay.Subscribe("notifications-comments", comments.Consume(commentService), string(comments.CommentCreated))
+	relay.Subscribe("reminders", reminders.Consume(reminderService), reminders.EventTypes...)
+	go outbox.RunRelay(context.Background(), relay, time.Second)
 
 	// Gin
 	gin.SetMode(cfg.Mode)
 	r := gin.Default()
-	r.Use(middleware.CORSMiddleware())
+	// Client IPs end up in the audit trail, so X-Forwarded-For is only
+	// believed from the configured proxies.
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		logger.Log.Fatalw("Invalid TRUSTED_PROXIES", "error", err)
+	}
+	r.Use(middleware.RequestIDMiddleware())
+	r.Use(middleware.CORSMiddleware(cfg.CORS))
 
 	api := r.Group("/api")
 	{
@@ -47,18 +219,148 @@ func main() {
 		auth.POST("/signup", userHandler.Register)
 		auth.POST("/login", userHandler.Login)
 
+		// Authenticates itself: browsers cannot send headers on upgrade,
+		// so they fetch a single-use ticket first.
+		api.GET("/ws", realtimeHandler.ServeWS)
+		api.GET("/pages/:id/collab", collabHandler.ServeWS)
+		// EventSource cannot send headers either.
+		api.GET("/events", eventHandler.Stream)
+		api.POST("/stream-tickets", middleware.AuthMiddleware(jwtMgr), realtimeHandler.IssueTicket)
+
 		usersGroup := api.Group("/users")
 		usersGroup.Use(middleware.AuthMiddleware(jwtMgr))
 		usersGroup.GET("/me", userHandler.Profile)
+		usersGroup.POST("/me/revoke-tokens", userHandler.RevokeTokens)
+		usersGroup.GET("/me/favorites", favoriteHandler.GetFavorites)
+		usersGroup.POST("/me/favorites", favoriteHandler.AddFavorite)
+		usersGroup.PUT("/me/favorites/order", favoriteHandler.ReorderFavorites)
+		usersGroup.DELETE("/me/favorites/:pageId", favoriteHandler.RemoveFavorite)
+		usersGroup.GET("/me/pins", favoriteHandler.GetPins)
+		usersGroup.POST("/me/pins", favoriteHandler.AddPin)
+		usersGroup.DELETE("/me/pins/:pageId", favoriteHandler.RemovePin)
+		usersGroup.GET("/me/recent", favoriteHandler.GetRecent)
 	}
 
 	pagesGroup := api.Group("/pages")
 	pagesGroup.Use(middleware.AuthMiddleware(jwtMgr))
 	pagesGroup.GET("", pageHandler.GetAllPages)
 	pagesGroup.POST("", pageHandler.CreatePage)
+	pagesGroup.POST("/import", pageHandler.ImportPages)
+	pagesGroup.POST("/from-template/:templateId", templateHandler.Instantiate)
 	pagesGroup.GET("/:id", pageHandler.GetPageByID)
 	pagesGroup.PUT("/:id", pageHandler.UpdatePage)
 	pagesGroup.DELETE("/:id", pageHandler.DeletePage)
+	pagesGroup.GET("/:id/blocks", pageHandler.GetBlocks)
+	pagesGroup.POST("/:id/blocks", pageHandler.InsertBlock)
+	pagesGroup.PUT("/:id/blocks/:blockId", pageHandler.UpdateBlock)
+	pagesGroup.POST("/:id/blocks/:blockId/move", pageHandler.MoveBlock)
+	pagesGroup.DELETE("/:id/blocks/:blockId", pageHandler.DeleteBlock)
+	pagesGroup.GET("/:id/export", pageHandler.ExportPage)
+	pagesGroup.GET("/:id/render", pageHandler.RenderPage)
+	pagesGroup.GET("/:id/backlinks", pageHandler.GetBacklinks)
+	pagesGroup.GET("/:id/presence", realtimeHandler.GetPresence)
+	pagesGroup.GET("/:id/activity", activityHandler.GetPageActivity)
+	pagesGroup.POST("/:id/duplicate", pageHandler.DuplicatePage)
+	pagesGroup.POST("/:id/move", pageHandler.MovePage)
+	pagesGroup.POST("/:id/reorder", pageHandler.ReorderPage)
+	pagesGroup.POST("/:id/tags", pageHandler.AttachTags)
+	pagesGroup.DELETE("/:id/tags/:tagId", pageHandler.DetachTag)
+	pagesGroup.GET("/:id/tasks", taskHandler.GetPageTasks)
+	pagesGroup.POST("/:id/tasks", taskHandler.CreateTask)
+	pagesGroup.GET("/:id/comments", commentHandler.GetComments)
+	pagesGroup.POST("/:id/comments", commentHandler.CreateComment)
+	pagesGroup.PUT("/:id/comments/:commentId", commentHandler.UpdateComment)
+	pagesGroup.DELETE("/:id/comments/:commentId", commentHandler.DeleteComment)
+	pagesGroup.POST("/:id/comments/:commentId/resolve", commentHandler.ResolveThread)
+	pagesGroup.POST("/:id/comments/:commentId/unresolve", commentHandler.UnresolveThread)
+
+	tagsGroup := api.Group("/tags")
+	tagsGroup.Use(middleware.AuthMiddleware(jwtMgr))
+	tagsGroup.GET("", pageHandler.GetTags)
+	tagsGroup.POST("", pageHandler.CreateTag)
+	tagsGroup.PUT("/:id", pageHandler.UpdateTag)
+	tagsGroup.POST("/:id/merge", pageHandler.MergeTag)
+	tagsGroup.DELETE("/:id", pageHandler.DeleteTag)
+
+	templatesGroup := api.Group("/templates")
+	templatesGroup.Use(middleware.AuthMiddleware(jwtMgr))
+	templatesGroup.GET("", templateHandler.GetTemplates)
+	templatesGroup.POST("", templateHandler.CreateTemplate)
+	templatesGroup.GET("/:id", templateHandler.GetTemplate)
+	templatesGroup.PUT("/:id", templateHandler.UpdateTemplate)
+	templatesGroup.DELETE("/:id", templateHandler.DeleteTemplate)
+
+	tasksGroup := api.Group("/tasks")
+	tasksGroup.Use(middleware.AuthMiddleware(jwtMgr))
+	tasksGroup.GET("", taskHandler.FindTasks)
+	tasksGroup.GET("/mine", taskHandler.MyTasks)
+	tasksGroup.PUT("/:id", taskHandler.UpdateTask)
+	tasksGroup.DELETE("/:id", taskHandler.DeleteTask)
+
+	remindersGroup := api.Group("/reminders")
+	remindersGroup.Use(middleware.AuthMiddleware(jwtMgr))
+	remindersGroup.GET("", reminderHandler.GetReminders)
+	remindersGroup.POST("", reminderHandler.CreateReminder)
+	remindersGroup.DELETE("/:id", reminderHandler.DeleteReminder)
+
+	notificationsGroup := api.Group("/notifications")
+	notificationsGroup.Use(middleware.AuthMiddleware(jwtMgr))
+	notificationsGroup.GET("", notificationHandler.GetNotifications)
+	notificationsGroup.POST("/read-all", notificationHandler.MarkAllRead)
+	notificationsGroup.GET("/preferences", notificationHandler.GetPreferences)
+	notificationsGroup.PUT("/preferences", notificationHandler.UpdatePreferences)
+	notificationsGroup.POST("/:id/read", notificationHandler.MarkRead)
+
+	boardsGroup := api.Group("/boards")
+	boardsGroup.Use(middleware.AuthMiddleware(jwtMgr))
+	boardsGroup.GET("", boardHandler.GetBoards)
+	boardsGroup.POST("", boardHandler.CreateBoard)
+	boardsGroup.GET("/:id", boardHandler.GetBoard)
+	boardsGroup.PUT("/:id", boardHandler.UpdateBoard)
+	boardsGroup.DELETE("/:id", boardHandler.DeleteBoard)
+	boardsGroup.POST("/:id/columns", boardHandler.CreateColumn)
+	boardsGroup.PUT("/:id/columns/:columnId", boardHandler.UpdateColumn)
+	boardsGroup.POST("/:id/columns/:columnId/move", boardHandler.MoveColumn)
+	boardsGroup.DELETE("/:id/columns/:columnId", boardHandler.DeleteColumn)
+	boardsGroup.POST("/:id/cards", boardHandler.CreateCard)
+	boardsGroup.PUT("/:id/cards/:cardId", boardHandler.UpdateCard)
+	boardsGroup.POST("/:id/cards/:cardId/move", boardHandler.MoveCard)
+	boardsGroup.DELETE("/:id/cards/:cardId", boardHandler.DeleteCard)
+
+	workspacesGroup := api.Group("/workspaces")
+	workspacesGroup.Use(middleware.AuthMiddleware(jwtMgr))
+	workspacesGroup.GET("", workspaceHandler.GetWorkspaces)
+	workspacesGroup.POST("", workspaceHandler.CreateWorkspace)
+	workspacesGroup.GET("/:id/invitations", workspaceHandler.GetInvitations)
+	workspacesGroup.POST("/:id/invitations", workspaceHandler.CreateInvitation)
+	workspacesGroup.DELETE("/:id/invitations/:invitationId", workspaceHandler.RevokeInvitation)
+	workspacesGroup.PUT("/:id/members/:userId", workspaceHandler.UpdateMemberRole)
+	workspacesGroup.DELETE("/:id/members/:userId", workspaceHandler.RemoveMember)
+
+	webhooksGroup := api.Group("/webhooks")
+	webhooksGroup.Use(middleware.AuthMiddleware(jwtMgr))
+	webhooksGroup.GET("", webhookHandler.GetWebhooks)
+	webhooksGroup.POST("", webhookHandler.CreateWebhook)
+	webhooksGroup.PUT("/:id", webhookHandler.UpdateWebhook)
+	webhooksGroup.DELETE("/:id", webhookHandler.DeleteWebhook)
+	webhooksGroup.POST("/:id/test", webhookHandler.SendTest)
+	webhooksGroup.GET("/:id/deliveries", webhookHandler.GetDeliveries)
+	webhooksGroup.POST("/:id/deliveries/:deliveryId/redeliver", webhookHandler.Redeliver)
+
+	activityGroup := api.Group("/activity")
+	activityGroup.Use(middleware.AuthMiddleware(jwtMgr))
+	activityGroup.GET("", activityHandler.GetActivity)
+
+	adminGroup := api.Group("/admin")
+	adminGroup.Use(middleware.AuthMiddleware(jwtMgr), middleware.AdminMiddleware(isAdmin))
+	adminGroup.GET("/audit", auditHandler.GetEntries)
+	adminGroup.GET("/audit/export", auditHandler.Export)
+
+	invitationsGroup := api.Group("/invitations")
+	invitationsGroup.Use(middleware.AuthMiddleware(jwtMgr))
+	invitationsGroup.GET("", workspaceHandler.GetMyInvitations)
+	invitationsGroup.POST("/accept", workspaceHandler.AcceptInvitation)
+	invitationsGroup.POST("/decline", workspaceHandler.DeclineInvitation)
 
 	addr := fmt.Sprintf(":%s", cfg.Port)
 	logger.Log.Infow("Listening", "port", cfg.Port)
@@ -0,0 +1,140 @@
+package activity
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"flowboard-backend-go/internal/middleware"
+	"flowboard-backend-go/internal/pages"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// getUserID safely retrieves user ID from context
+func getUserID(c *gin.Context) (uint, error) {
+	uidVal, exists := c.Get(middleware.ContextUserIDKey)
+	if !exists {
+		return 0, fmt.Errorf("unauthorized")
+	}
+
+	uid, ok := uidVal.(uint)
+	if !ok {
+		return 0, fmt.Errorf("invalid user ID type")
+	}
+
+	return uid, nil
+}
+
+// respondError maps service errors to HTTP statuses
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, pages.ErrPageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidCursor):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, pages.ErrForbidden), errors.Is(err, ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// parseFilter reads ?workspaceId=&limit=&before=, where before is the
+// nextCursor of the previous page.
+func parseFilter(c *gin.Context) (Filter, error) {
+	var filter Filter
+	if v := c.Query("workspaceId"); v != "" {
+		id64, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return filter, errors.New("invalid workspaceId")
+		}
+		id := uint(id64)
+		filter.WorkspaceID = &id
+	}
+
+	var err error
+	if v := c.Query("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			return filter, errors.New("invalid limit")
+		}
+	}
+	if v := c.Query("before"); v != "" {
+		if filter.Before, err = ParseCursor(v); err != nil {
+			return filter, err
+		}
+	}
+	return filter, nil
+}
+
+// respondPage sends a page of entries with the cursor of the next one,
+// which is null after the last page.
+func respondPage(c *gin.Context, list []Activity, total int64, filter Filter) {
+	var next *string
+	if cursor := NextCursor(list, filter); cursor != nil {
+		s := cursor.String()
+		next = &s
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": list, "total": total, "nextCursor": next})
+}
+
+// GetPageActivity pages through a page's activity, newest first.
+func (h *Handler) GetPageActivity(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	filter, err := parseFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, total, err := h.service.GetPageActivity(uint(id64), userID, filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	respondPage(c, list, total, filter)
+}
+
+// GetActivity pages through the activity across the user's personal
+// pages and workspaces; ?workspaceId= narrows it to one workspace.
+func (h *Handler) GetActivity(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	filter, err := parseFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, total, err := h.service.GetActivity(userID, filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	respondPage(c, list, total, filter)
+}
@@ -0,0 +1,83 @@
+package activity
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Activity is one line of a feed: something a user did to a page, or to a
+// comment or task on it. Rapid successive edits by the same user fold into
+// one entry; Count says how many it covers, CreatedAt is the first and
+// LastAt the latest.
+type Activity struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	Type        string `gorm:"size:64;not null" json:"type"`
+	PageID      uint   `gorm:"not null;index" json:"pageId"`
+	PageTitle   string `gorm:"size:255" json:"pageTitle"`
+	WorkspaceID *uint  `gorm:"index" json:"workspaceId,omitempty"`
+	OwnerID     uint   `gorm:"not null;index" json:"ownerId"`
+	ActorID     uint   `gorm:"not null" json:"actorId"`
+	ActorName   string `gorm:"-" json:"actorName"`
+	// SubjectID and Subject name the task or comment, if any: a task's
+	// title or the start of a comment.
+	SubjectID uint      `json:"subjectId,omitempty"`
+	Subject   string    `gorm:"size:255" json:"subject,omitempty"`
+	Count     int       `gorm:"not null" json:"count"`
+	Summary   string    `gorm:"-" json:"summary"`
+	CreatedAt time.Time `json:"createdAt"`
+	LastAt    time.Time `gorm:"not null;index" json:"lastAt"`
+}
+
+// Source records that an outbox message was applied to the feed, so a
+// redelivered one is not counted twice.
+type Source struct {
+	EventID   uint64    `gorm:"primaryKey;autoIncrement:false"`
+	CreatedAt time.Time `gorm:"not null;index"`
+}
+
+func (Source) TableName() string {
+	return "activity_sources"
+}
+
+// Filter pages through a feed, newest first. WorkspaceID narrows the
+// user-wide feed to one workspace; Before continues after the last entry
+// of the previous page.
+type Filter struct {
+	WorkspaceID *uint
+	Limit       int
+	Before      *Cursor
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a position in a feed, which is ordered by LastAt and then ID,
+// both descending. Clients receive it as an opaque string.
+type Cursor struct {
+	LastAt time.Time
+	ID     uint
+}
+
+// CursorAfter returns the cursor continuing after a.
+func CursorAfter(a *Activity) *Cursor {
+	return &Cursor{LastAt: a.LastAt, ID: a.ID}
+}
+
+func (c *Cursor) String() string {
+	return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d:%d", c.LastAt.UnixMicro(), c.ID))
+}
+
+// ParseCursor reads a cursor produced by String.
+func ParseCursor(v string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var micros int64
+	var id uint
+	if _, err := fmt.Sscanf(string(raw), "%d:%d", &micros, &id); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &Cursor{LastAt: time.UnixMicro(micros).UTC(), ID: id}, nil
+}
@@ -0,0 +1,146 @@
+package activity
+
+import (
+	"time"
+
+	"flowboard-backend-go/internal/users"
+	"flowboard-backend-go/internal/workspaces"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	// Transaction runs fn with a Repository bound to a single database
+	// transaction; any error rolls everything back.
+	Transaction(fn func(repo Repository) error) error
+	// MarkApplied records the outbox message as applied and reports false
+	// if it already was.
+	MarkApplied(eventID uint64, at time.Time) (bool, error)
+	// Latest locks and returns the page's most recent entry, or nil.
+	Latest(pageID uint) (*Activity, error)
+	Create(a *Activity) error
+	Save(a *Activity) error
+	// GetByPage returns up to limit of the page's entries after before,
+	// or from the start when it is nil, and how many there are in all.
+	GetByPage(pageID uint, limit int, before *Cursor) ([]Activity, int64, error)
+	// GetFeed pages through entries like GetByPage, taking those on pages
+	// now in one of workspaceIDs and, if personal is set, on the user's
+	// personal pages. Entries of deleted pages keep the location recorded
+	// with them.
+	GetFeed(userID uint, workspaceIDs []uint, personal bool, limit int, before *Cursor) ([]Activity, int64, error)
+	WorkspaceIDs(userID uint) ([]uint, error)
+	UserNames(ids []uint) (map[uint]string, error)
+	PruneSources(before time.Time) (int64, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// feedOrder puts the most recently active entries first.
+const feedOrder = "activities.last_at DESC, activities.id DESC"
+
+// Where an entry's page is now, or was when it was deleted.
+const (
+	pageWorkspace = "CASE WHEN pages.id IS NULL THEN activities.workspace_id ELSE pages.workspace_id END"
+	pageOwner     = "CASE WHEN pages.id IS NULL THEN activities.owner_id ELSE pages.user_id END"
+)
+
+func (r *repository) Transaction(fn func(repo Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&repository{db: tx})
+	})
+}
+
+func (r *repository) MarkApplied(eventID uint64, at time.Time) (bool, error) {
+	res := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&Source{EventID: eventID, CreatedAt: at})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *repository) Latest(pageID uint) (*Activity, error) {
+	var list []Activity
+	err := r.db.Where("page_id = ?", pageID).
+		Order(feedOrder).
+		Limit(1).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Find(&list).Error
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return &list[0], nil
+}
+
+func (r *repository) Create(a *Activity) error {
+	return r.db.Create(a).Error
+}
+
+func (r *repository) Save(a *Activity) error {
+	return r.db.Save(a).Error
+}
+
+func (r *repository) GetByPage(pageID uint, limit int, before *Cursor) ([]Activity, int64, error) {
+	return r.page(func(db *gorm.DB) *gorm.DB {
+		return db.Where("activities.page_id = ?", pageID)
+	}, limit, before)
+}
+
+func (r *repository) GetFeed(userID uint, workspaceIDs []uint, personal bool, limit int, before *Cursor) ([]Activity, int64, error) {
+	return r.page(func(db *gorm.DB) *gorm.DB {
+		audience := r.db.Where("1 = 0")
+		if len(workspaceIDs) > 0 {
+			audience = audience.Or(pageWorkspace+" IN ?", workspaceIDs)
+		}
+		if personal {
+			audience = audience.Or(pageWorkspace+" IS NULL AND "+pageOwner+" = ?", userID)
+		}
+		return db.Joins("LEFT JOIN pages ON pages.id = activities.page_id").Where(audience)
+	}, limit, before)
+}
+
+func (r *repository) page(scope func(db *gorm.DB) *gorm.DB, limit int, before *Cursor) ([]Activity, int64, error) {
+	var total int64
+	if err := r.db.Model(&Activity{}).Scopes(scope).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	query := r.db.Scopes(scope)
+	if before != nil {
+		query = query.Where("(activities.last_at, activities.id) < (?, ?)", before.LastAt, before.ID)
+	}
+	var list []Activity
+	err := query.Order(feedOrder).Limit(limit).Find(&list).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+func (r *repository) WorkspaceIDs(userID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&workspaces.Member{}).Where("user_id = ?", userID).Pluck("workspace_id", &ids).Error
+	return ids, err
+}
+
+func (r *repository) UserNames(ids []uint) (map[uint]string, error) {
+	names := map[uint]string{}
+	if len(ids) == 0 {
+		return names, nil
+	}
+	var list []users.User
+	if err := r.db.Select("id", "name").Where("id IN ?", ids).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	for _, u := range list {
+		names[u.ID] = u.Name
+	}
+	return names, nil
+}
+
+func (r *repository) PruneSources(before time.Time) (int64, error) {
+	res := r.db.Where("created_at < ?", before).Delete(&Source{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,265 @@
+package activity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"flowboard-backend-go/internal/comments"
+	"flowboard-backend-go/internal/pages"
+	"flowboard-backend-go/internal/tasks"
+	"flowboard-backend-go/internal/workspaces"
+	"flowboard-backend-go/pkg/logger"
+)
+
+var ErrForbidden = errors.New("not a member of this workspace")
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+	// groupWindow is how long after an edit the same user's next edit of
+	// the same thing still folds into its entry.
+	groupWindow = 10 * time.Minute
+	// sourceRetention bounds how long applied messages are remembered. The
+	// relay only redelivers a message it handled when it stopped before
+	// recording that, so a day is ample.
+	sourceRetention = 24 * time.Hour
+)
+
+// PageLookup resolves pages with access checks; satisfied by pages.Service.
+type PageLookup interface {
+	GetPageByID(id, userID uint) (*pages.Page, error)
+}
+
+// WorkspaceAccess reports workspace membership; satisfied by
+// workspaces.Service.
+type WorkspaceAccess interface {
+	MemberRole(workspaceID, userID uint) (workspaces.Role, error)
+}
+
+// Service builds the activity feeds from page, comment and task events
+// relayed by the outbox.
+type Service interface {
+	pages.EventHandler
+	comments.EventHandler
+	tasks.EventHandler
+
+	// GetPageActivity pages through a page's feed, newest first.
+	GetPageActivity(pageID, userID uint, filter Filter) ([]Activity, int64, error)
+	// GetActivity pages through everything the user can see: their
+	// personal pages and their workspaces, or just filter.WorkspaceID.
+	GetActivity(userID uint, filter Filter) ([]Activity, int64, error)
+	Prune(before time.Time) (int64, error)
+}
+
+type service struct {
+	repo       Repository
+	pages      PageLookup
+	workspaces WorkspaceAccess
+	now        func() time.Time
+}
+
+func NewService(repo Repository, pages PageLookup, workspaces WorkspaceAccess) Service {
+	return &service{repo: repo, pages: pages, workspaces: workspaces, now: time.Now}
+}
+
+func (s *service) HandlePageEvent(_ context.Context, eventID uint64, e pages.PageEvent) error {
+	return s.apply(eventID, &Activity{
+		Type:        string(e.Type),
+		PageID:      e.PageID,
+		PageTitle:   e.Title,
+		WorkspaceID: e.WorkspaceID,
+		OwnerID:     e.OwnerID,
+		ActorID:     e.ActorID,
+		CreatedAt:   e.At,
+	}, e.Type == pages.PageUpdated)
+}
+
+func (s *service) HandleCommentEvent(_ context.Context, eventID uint64, e comments.CommentEvent) error {
+	return s.apply(eventID, &Activity{
+		Type:        string(e.Type),
+		PageID:      e.PageID,
+		PageTitle:   e.PageTitle,
+		WorkspaceID: e.WorkspaceID,
+		OwnerID:     e.OwnerID,
+		ActorID:     e.ActorID,
+		SubjectID:   e.CommentID,
+		Subject:     e.Excerpt,
+		CreatedAt:   e.At,
+	}, false)
+}
+
+func (s *service) HandleTaskEvent(_ context.Context, eventID uint64, e tasks.TaskEvent) error {
+	return s.apply(eventID, &Activity{
+		Type:        string(e.Type),
+		PageID:      e.PageID,
+		PageTitle:   e.PageTitle,
+		WorkspaceID: e.WorkspaceID,
+		OwnerID:     e.OwnerID,
+		ActorID:     e.ActorID,
+		SubjectID:   e.TaskID,
+		Subject:     e.Title,
+		CreatedAt:   e.At,
+	}, e.Type == tasks.TaskUpdated)
+}
+
+// apply adds a to the feed once per event. A groupable entry folds into
+// the page's latest entry instead when that is the same kind of change to
+// the same thing by the same user, made within groupWindow.
+func (s *service) apply(eventID uint64, a *Activity, groupable bool) error {
+	a.Count = 1
+	a.LastAt = a.CreatedAt
+	return s.repo.Transaction(func(repo Repository) error {
+		fresh, err := repo.MarkApplied(eventID, s.now())
+		if err != nil || !fresh {
+			return err
+		}
+		if groupable {
+			last, err := repo.Latest(a.PageID)
+			if err != nil {
+				return err
+			}
+			if last != nil && last.Type == a.Type && last.ActorID == a.ActorID &&
+				last.SubjectID == a.SubjectID && a.CreatedAt.Sub(last.LastAt) <= groupWindow {
+				last.Count++
+				if a.LastAt.After(last.LastAt) {
+					last.LastAt = a.LastAt
+					last.PageTitle, last.Subject = a.PageTitle, a.Subject
+				}
+				return repo.Save(last)
+			}
+		}
+		return repo.Create(a)
+	})
+}
+
+func (s *service) GetPageActivity(pageID, userID uint, filter Filter) ([]Activity, int64, error) {
+	if _, err := s.pages.GetPageByID(pageID, userID); err != nil {
+		return nil, 0, err
+	}
+	list, total, err := s.repo.GetByPage(pageID, limit(filter), filter.Before)
+	if err != nil {
+		return nil, 0, err
+	}
+	return list, total, s.describe(list)
+}
+
+func (s *service) GetActivity(userID uint, filter Filter) ([]Activity, int64, error) {
+	var workspaceIDs []uint
+	personal := filter.WorkspaceID == nil
+	if filter.WorkspaceID != nil {
+		role, err := s.workspaces.MemberRole(*filter.WorkspaceID, userID)
+		if err != nil {
+			return nil, 0, err
+		}
+		if role == "" {
+			return nil, 0, ErrForbidden
+		}
+		workspaceIDs = []uint{*filter.WorkspaceID}
+	} else {
+		var err error
+		if workspaceIDs, err = s.repo.WorkspaceIDs(userID); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	list, total, err := s.repo.GetFeed(userID, workspaceIDs, personal, limit(filter), filter.Before)
+	if err != nil {
+		return nil, 0, err
+	}
+	return list, total, s.describe(list)
+}
+
+func (s *service) Prune(before time.Time) (int64, error) {
+	return s.repo.PruneSources(before)
+}
+
+// limit is the page size filter asks for, within bounds.
+func limit(filter Filter) int {
+	switch {
+	case filter.Limit <= 0:
+		return defaultLimit
+	case filter.Limit > maxLimit:
+		return maxLimit
+	}
+	return filter.Limit
+}
+
+// NextCursor returns the cursor of the page after list, or nil when list
+// was the last one.
+func NextCursor(list []Activity, filter Filter) *Cursor {
+	if len(list) == 0 || len(list) < limit(filter) {
+		return nil
+	}
+	return CursorAfter(&list[len(list)-1])
+}
+
+// describe fills in actor names and summaries.
+func (s *service) describe(list []Activity) error {
+	ids := make([]uint, 0, len(list))
+	for _, a := range list {
+		ids = append(ids, a.ActorID)
+	}
+	names, err := s.repo.UserNames(ids)
+	if err != nil {
+		return err
+	}
+	for i := range list {
+		list[i].ActorName = names[list[i].ActorID]
+		list[i].Summary = summarize(&list[i])
+	}
+	return nil
+}
+
+// summaries phrase each entry type; %[1]s is the actor, %[2]s the page and
+// %[3]s the subject.
+var summaries = map[string]string{
+	string(pages.PageCreated):       "%[1]s created %[2]s",
+	string(pages.PageUpdated):       "%[1]s edited %[2]s",
+	string(pages.PageDeleted):       "%[1]s deleted %[2]s",
+	string(comments.CommentCreated): "%[1]s commented on %[2]s",
+	string(comments.ThreadResolved): "%[1]s resolved a comment thread on %[2]s",
+	string(tasks.TaskCreated):       "%[1]s added the task “%[3]s” to %[2]s",
+	string(tasks.TaskUpdated):       "%[1]s updated the task “%[3]s” on %[2]s",
+	string(tasks.TaskCompleted):     "%[1]s completed the task “%[3]s” on %[2]s",
+	string(tasks.TaskDeleted):       "%[1]s deleted the task “%[3]s” from %[2]s",
+}
+
+// summarize phrases an entry for people, e.g. "Alex edited Roadmap (3
+// edits)". The time is left to clients, which know the viewer's clock.
+func summarize(a *Activity) string {
+	actor, page := a.ActorName, a.PageTitle
+	if actor == "" {
+		actor = "Someone"
+	}
+	if page == "" {
+		page = "Untitled"
+	}
+	format, ok := summaries[a.Type]
+	if !ok {
+		return fmt.Sprintf("%s changed %s", actor, page)
+	}
+	text := fmt.Sprintf(format, actor, page, a.Subject)
+	if a.Count > 1 {
+		text += fmt.Sprintf(" (%d edits)", a.Count)
+	}
+	return text
+}
+
+// RunPruner forgets applied messages every interval until ctx is
+// cancelled.
+func RunPruner(ctx context.Context, s Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Prune(time.Now().Add(-sourceRetention)); err != nil {
+				logger.Log.Errorw("Pruning activity sources failed", "error", err)
+			}
+		}
+	}
+}
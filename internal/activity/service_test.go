@@ -0,0 +1,279 @@
+package activity
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"flowboard-backend-go/internal/comments"
+	"flowboard-backend-go/internal/pages"
+	"flowboard-backend-go/internal/tasks"
+	"flowboard-backend-go/internal/workspaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memRepo is an in-memory Repository for service tests.
+// pages holds where pages are now; entries of pages missing from it
+// count as deleted.
+type memRepo struct {
+	entries []*Activity
+	sources map[uint64]bool
+	members map[uint][]uint
+	names   map[uint]string
+	pages   map[uint]pages.Page
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{sources: map[uint64]bool{}, members: map[uint][]uint{}, names: map[uint]string{}, pages: map[uint]pages.Page{}}
+}
+
+func (r *memRepo) Transaction(fn func(repo Repository) error) error {
+	return fn(r)
+}
+
+func (r *memRepo) MarkApplied(eventID uint64, _ time.Time) (bool, error) {
+	if r.sources[eventID] {
+		return false, nil
+	}
+	r.sources[eventID] = true
+	return true, nil
+}
+
+func (r *memRepo) Latest(pageID uint) (*Activity, error) {
+	list := r.filter(func(a *Activity) bool { return a.PageID == pageID })
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return &list[0], nil
+}
+
+func (r *memRepo) Create(a *Activity) error {
+	a.ID = uint(len(r.entries) + 1)
+	cp := *a
+	r.entries = append(r.entries, &cp)
+	return nil
+}
+
+func (r *memRepo) Save(a *Activity) error {
+	cp := *a
+	r.entries[a.ID-1] = &cp
+	return nil
+}
+
+// filter returns copies of the matching entries, most recently active
+// first.
+func (r *memRepo) filter(keep func(a *Activity) bool) []Activity {
+	var out []Activity
+	for _, a := range r.entries {
+		if keep(a) {
+			out = append(out, *a)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].LastAt.Equal(out[j].LastAt) {
+			return out[i].LastAt.After(out[j].LastAt)
+		}
+		return out[i].ID > out[j].ID
+	})
+	return out
+}
+
+func paged(list []Activity, limit int, before *Cursor) ([]Activity, int64, error) {
+	total := int64(len(list))
+	if before != nil {
+		for len(list) > 0 && !(list[0].LastAt.Before(before.LastAt) ||
+			(list[0].LastAt.Equal(before.LastAt) && list[0].ID < before.ID)) {
+			list = list[1:]
+		}
+	}
+	if len(list) > limit {
+		list = list[:limit]
+	}
+	return list, total, nil
+}
+
+func (r *memRepo) GetByPage(pageID uint, limit int, before *Cursor) ([]Activity, int64, error) {
+	return paged(r.filter(func(a *Activity) bool { return a.PageID == pageID }), limit, before)
+}
+
+func (r *memRepo) GetFeed(userID uint, workspaceIDs []uint, personal bool, limit int, before *Cursor) ([]Activity, int64, error) {
+	return paged(r.filter(func(a *Activity) bool {
+		workspaceID, ownerID := a.WorkspaceID, a.OwnerID
+		if p, ok := r.pages[a.PageID]; ok {
+			workspaceID, ownerID = p.WorkspaceID, p.UserID
+		}
+		if workspaceID == nil {
+			return personal && ownerID == userID
+		}
+		for _, id := range workspaceIDs {
+			if *workspaceID == id {
+				return true
+			}
+		}
+		return false
+	}), limit, before)
+}
+
+func (r *memRepo) WorkspaceIDs(userID uint) ([]uint, error) {
+	return r.members[userID], nil
+}
+
+func (r *memRepo) UserNames(ids []uint) (map[uint]string, error) {
+	return r.names, nil
+}
+
+func (r *memRepo) PruneSources(before time.Time) (int64, error) {
+	return 0, nil
+}
+
+// stubPages serves page 100 in workspace 7 to users 1 and 2.
+type stubPages struct{}
+
+func (stubPages) GetPageByID(id, userID uint) (*pages.Page, error) {
+	if id == 100 && (userID == 1 || userID == 2) {
+		ws := uint(7)
+		return &pages.Page{ID: id, WorkspaceID: &ws, UserID: 1}, nil
+	}
+	return nil, pages.ErrPageNotFound
+}
+
+type stubWorkspaces map[[2]uint]workspaces.Role
+
+func (w stubWorkspaces) MemberRole(workspaceID, userID uint) (workspaces.Role, error) {
+	return w[[2]uint{workspaceID, userID}], nil
+}
+
+func newTestService() (*service, *memRepo) {
+	repo := newMemRepo()
+	repo.names = map[uint]string{1: "Alex", 2: "Sam"}
+	repo.members = map[uint][]uint{1: {7}, 2: {7}}
+	access := stubWorkspaces{{7, 1}: workspaces.RoleAdmin, {7, 2}: workspaces.RoleEditor}
+	return NewService(repo, stubPages{}, access).(*service), repo
+}
+
+func TestRapidEditsByOneUserAreGrouped(t *testing.T) {
+	s, _ := newTestService()
+	ctx := context.Background()
+	ws := uint(7)
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	edit := func(id uint64, actor uint, at time.Duration, title string) {
+		e := pages.PageEvent{Type: pages.PageUpdated, PageID: 100, WorkspaceID: &ws, OwnerID: 1, ActorID: actor, Title: title, At: start.Add(at)}
+		require.NoError(t, s.HandlePageEvent(ctx, id, e))
+	}
+
+	require.NoError(t, s.HandlePageEvent(ctx, 1, pages.PageEvent{Type: pages.PageCreated, PageID: 100, WorkspaceID: &ws, OwnerID: 1, ActorID: 1, Title: "Roadmap", At: start}))
+	edit(2, 1, time.Minute, "Roadmap")
+	edit(3, 1, 5*time.Minute, "Roadmap")
+	edit(3, 1, 5*time.Minute, "Roadmap") // redelivered
+	edit(4, 1, 14*time.Minute, "Roadmap 2024")
+	edit(5, 2, 15*time.Minute, "Roadmap 2024") // someone else
+	edit(6, 1, 16*time.Minute, "Roadmap 2024") // after someone else
+	edit(7, 1, 27*time.Minute, "Roadmap (Q3)") // too long after
+	require.NoError(t, s.HandleCommentEvent(ctx, 8, comments.CommentEvent{
+		Type: comments.CommentCreated, CommentID: 4, PageID: 100, PageTitle: "Roadmap (Q3)", WorkspaceID: &ws, OwnerID: 1, ActorID: 2, Excerpt: "Nice", At: start.Add(28 * time.Minute),
+	}))
+	require.NoError(t, s.HandleTaskEvent(ctx, 9, tasks.TaskEvent{
+		Type: tasks.TaskCompleted, TaskID: 3, Title: "Ship beta", PageID: 100, PageTitle: "Roadmap (Q3)", WorkspaceID: &ws, OwnerID: 1, ActorID: 1, At: start.Add(29 * time.Minute),
+	}))
+
+	list, total, err := s.GetPageActivity(100, 2, Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), total)
+	var summaries []string
+	for _, a := range list {
+		summaries = append(summaries, a.Summary)
+	}
+	assert.Equal(t, []string{
+		"Alex completed the task “Ship beta” on Roadmap (Q3)",
+		"Sam commented on Roadmap (Q3)",
+		"Alex edited Roadmap (Q3)",
+		"Alex edited Roadmap 2024",
+		"Sam edited Roadmap 2024",
+		"Alex edited Roadmap 2024 (3 edits)",
+		"Alex created Roadmap",
+	}, summaries)
+
+	grouped := list[5]
+	assert.Equal(t, 3, grouped.Count)
+	assert.Equal(t, start.Add(time.Minute), grouped.CreatedAt)
+	assert.Equal(t, start.Add(14*time.Minute), grouped.LastAt)
+
+	// Paging with cursors walks the same list.
+	var walked []Activity
+	filter := Filter{Limit: 3}
+	for page := 1; ; page++ {
+		part, total, err := s.GetPageActivity(100, 1, filter)
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), total)
+		walked = append(walked, part...)
+		next := NextCursor(part, filter)
+		if next == nil {
+			assert.Equal(t, 3, page)
+			break
+		}
+		filter.Before, err = ParseCursor(next.String())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, list, walked)
+
+	_, _, err = s.GetPageActivity(100, 3, Filter{})
+	assert.ErrorIs(t, err, pages.ErrPageNotFound)
+}
+
+func TestFeedCoversPersonalPagesAndWorkspaces(t *testing.T) {
+	s, repo := newTestService()
+	ctx := context.Background()
+	ws, other := uint(7), uint(8)
+	now := time.Now()
+	events := []pages.PageEvent{
+		{Type: pages.PageCreated, PageID: 100, WorkspaceID: &ws, OwnerID: 1, ActorID: 1, Title: "Team", At: now},
+		{Type: pages.PageCreated, PageID: 200, OwnerID: 2, ActorID: 2, Title: "Diary", At: now},
+		{Type: pages.PageCreated, PageID: 300, WorkspaceID: &other, OwnerID: 3, ActorID: 3, Title: "Elsewhere", At: now},
+		{Type: pages.PageDeleted, PageID: 201, OwnerID: 1, ActorID: 1, Title: "Old notes", At: now},
+	}
+	for i, e := range events {
+		require.NoError(t, s.HandlePageEvent(ctx, uint64(i+1), e))
+	}
+	require.Len(t, repo.entries, 4)
+
+	list, total, err := s.GetActivity(1, Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Equal(t, "Alex deleted Old notes", list[0].Summary)
+	assert.Equal(t, "Team", list[1].PageTitle)
+
+	list, _, err = s.GetActivity(2, Filter{WorkspaceID: &ws})
+	require.NoError(t, err)
+	require.Len(t, list, 1, "narrowing to a workspace drops personal pages")
+	assert.Equal(t, uint(100), list[0].PageID)
+
+	_, _, err = s.GetActivity(1, Filter{WorkspaceID: &other})
+	assert.ErrorIs(t, err, ErrForbidden)
+}
+
+func TestFeedFollowsPagesOutOfWorkspaces(t *testing.T) {
+	s, repo := newTestService()
+	ctx := context.Background()
+	ws := uint(7)
+	require.NoError(t, s.HandlePageEvent(ctx, 1, pages.PageEvent{Type: pages.PageCreated, PageID: 100, WorkspaceID: &ws, OwnerID: 1, ActorID: 1, Title: "Team", At: time.Now()}))
+	repo.pages[100] = pages.Page{ID: 100, WorkspaceID: &ws, UserID: 1}
+
+	list, _, err := s.GetActivity(2, Filter{})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+
+	// The owner moves the page to their personal pages.
+	repo.pages[100] = pages.Page{ID: 100, UserID: 1}
+	list, _, err = s.GetActivity(2, Filter{})
+	require.NoError(t, err)
+	assert.Empty(t, list, "former workspace members no longer see it")
+	list, _, err = s.GetActivity(2, Filter{WorkspaceID: &ws})
+	require.NoError(t, err)
+	assert.Empty(t, list)
+	list, _, err = s.GetActivity(1, Filter{})
+	require.NoError(t, err)
+	assert.Len(t, list, 1, "the owner still does")
+}
@@ -0,0 +1,135 @@
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"flowboard-backend-go/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves the audit trail to administrators; mount it behind
+// middleware.AdminMiddleware.
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// GetEntries pages through entries, newest first. See parseFilter for
+// the query parameters.
+func (h *Handler) GetEntries(c *gin.Context) {
+	filter, err := parseFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, total, err := h.service.Query(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": list, "total": total})
+}
+
+// Export downloads every entry matching the filter as CSV, oldest first.
+// The download itself is audited.
+func (h *Handler) Export(c *gin.Context) {
+	filter, err := parseFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.service.Record(FromRequest(c), AuditExported, Target{}, map[string]any{"query": c.Request.URL.RawQuery})
+
+	name := fmt.Sprintf("audit-%s.csv", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	c.Status(http.StatusOK)
+	if err := h.service.Export(c.Writer, filter); err != nil {
+		// The status is already sent; a truncated file is all we can do.
+		logger.Log.Errorw("Exporting audit trail failed", "error", err)
+	}
+}
+
+// parseFilter reads ?actorId=&action=&targetType=&targetId=&from=&to=
+// &limit=&offset=. Dates are RFC 3339 timestamps or plain YYYY-MM-DD
+// days; a plain to day is included in the range.
+func parseFilter(c *gin.Context) (Filter, error) {
+	filter := Filter{
+		Action:     Action(c.Query("action")),
+		TargetType: c.Query("targetType"),
+	}
+
+	for _, name := range []string{"actorId", "targetId"} {
+		v := c.Query(name)
+		if v == "" {
+			continue
+		}
+		id64, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return filter, fmt.Errorf("invalid %s", name)
+		}
+		id := uint(id64)
+		if name == "actorId" {
+			filter.ActorID = &id
+		} else {
+			filter.TargetID = id
+		}
+	}
+	if filter.TargetID != 0 && filter.TargetType == "" {
+		return filter, errors.New("targetId requires targetType")
+	}
+
+	if v := c.Query("from"); v != "" {
+		t, _, err := parseDate(v)
+		if err != nil {
+			return filter, errors.New("invalid from")
+		}
+		filter.From = &t
+	}
+	if v := c.Query("to"); v != "" {
+		t, day, err := parseDate(v)
+		if err != nil {
+			return filter, errors.New("invalid to")
+		}
+		if day {
+			t = t.AddDate(0, 0, 1)
+		}
+		filter.To = &t
+	}
+
+	var err error
+	if v := c.Query("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			return filter, errors.New("invalid limit")
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if filter.Offset, err = strconv.Atoi(v); err != nil {
+			return filter, errors.New("invalid offset")
+		}
+	}
+	return filter, nil
+}
+
+// parseDate accepts an RFC 3339 timestamp or a YYYY-MM-DD day (UTC) and
+// reports which one it got.
+func parseDate(v string) (time.Time, bool, error) {
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	return t, false, err
+}
@@ -0,0 +1,85 @@
+package audit
+
+import "time"
+
+// Action names what an entry records.
+type Action string
+
+const (
+	LoginSucceeded Action = "auth.login"
+	LoginFailed    Action = "auth.login_failed"
+	// TokensRevoked records a user signing out every session at once.
+	TokensRevoked Action = "auth.tokens_revoked"
+
+	PageCreated Action = "page.created"
+	PageUpdated Action = "page.updated"
+	PageDeleted Action = "page.deleted"
+
+	// Pages are shared by inviting people to their workspace.
+	ShareInvited Action = "share.invited"
+	ShareRevoked Action = "share.revoked"
+	// MemberAdded grants a workspace role, through an accepted invitation.
+	MemberAdded       Action = "permission.member_added"
+	MemberRoleChanged Action = "permission.role_changed"
+	MemberRemoved     Action = "permission.member_removed"
+
+	WebhookCreated Action = "webhook.created"
+	WebhookUpdated Action = "webhook.updated"
+	WebhookDeleted Action = "webhook.deleted"
+
+	// AuditExported records an administrator downloading the trail.
+	AuditExported Action = "admin.audit_exported"
+)
+
+// Target types.
+const (
+	TargetUser       = "user"
+	TargetPage       = "page"
+	TargetWorkspace  = "workspace"
+	TargetInvitation = "invitation"
+	TargetWebhook    = "webhook"
+)
+
+// Target is what an action was done to; the zero Target means none.
+type Target struct {
+	Type string
+	ID   uint
+}
+
+// Request describes who made a request and from where. ActorID is nil for
+// anonymous requests, such as failed logins.
+type Request struct {
+	ActorID   *uint  `gorm:"index" json:"actorId,omitempty"`
+	IP        string `gorm:"size:45" json:"ip"`
+	UserAgent string `gorm:"size:512" json:"userAgent"`
+	RequestID string `gorm:"size:64;index" json:"requestId"`
+}
+
+// Entry is one line of the append-only audit trail. Nothing updates or
+// deletes entries.
+type Entry struct {
+	ID uint64 `gorm:"primaryKey" json:"id"`
+	Request
+	Action     Action         `gorm:"size:64;not null;index" json:"action"`
+	TargetType string         `gorm:"size:32;index:idx_audit_target" json:"targetType,omitempty"`
+	TargetID   uint           `gorm:"index:idx_audit_target" json:"targetId,omitempty"`
+	Details    map[string]any `gorm:"serializer:json;type:jsonb" json:"details,omitempty"`
+	CreatedAt  time.Time      `gorm:"not null;index" json:"createdAt"`
+}
+
+func (Entry) TableName() string {
+	return "audit_entries"
+}
+
+// Filter narrows audit queries. From is inclusive and To exclusive; a
+// TargetID only counts together with TargetType.
+type Filter struct {
+	ActorID    *uint
+	Action     Action
+	TargetType string
+	TargetID   uint
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
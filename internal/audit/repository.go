@@ -0,0 +1,78 @@
+package audit
+
+import "gorm.io/gorm"
+
+// Repository only ever appends: the trail is not editable through the API.
+type Repository interface {
+	Append(e *Entry) error
+	// Find returns a page of entries matching the filter, newest first,
+	// and how many match in total.
+	Find(filter Filter) ([]Entry, int64, error)
+	// Scan returns up to limit entries matching the filter after afterID,
+	// oldest first, ignoring the filter's Limit and Offset.
+	Scan(filter Filter, afterID uint64, limit int) ([]Entry, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Append(e *Entry) error {
+	return r.db.Create(e).Error
+}
+
+func (r *repository) Find(filter Filter) ([]Entry, int64, error) {
+	var total int64
+	if err := r.db.Model(&Entry{}).Scopes(matching(filter)).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var list []Entry
+	err := r.db.Scopes(matching(filter)).
+		Order("id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&list).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+func (r *repository) Scan(filter Filter, afterID uint64, limit int) ([]Entry, error) {
+	var list []Entry
+	err := r.db.Scopes(matching(filter)).
+		Where("id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&list).Error
+	return list, err
+}
+
+func matching(filter Filter) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.ActorID != nil {
+			db = db.Where("actor_id = ?", *filter.ActorID)
+		}
+		if filter.Action != "" {
+			db = db.Where("action = ?", filter.Action)
+		}
+		if filter.TargetType != "" {
+			db = db.Where("target_type = ?", filter.TargetType)
+			if filter.TargetID != 0 {
+				db = db.Where("target_id = ?", filter.TargetID)
+			}
+		}
+		if filter.From != nil {
+			db = db.Where("created_at >= ?", *filter.From)
+		}
+		if filter.To != nil {
+			db = db.Where("created_at < ?", *filter.To)
+		}
+		return db
+	}
+}
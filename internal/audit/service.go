@@ -0,0 +1,168 @@
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"flowboard-backend-go/internal/middleware"
+	"flowboard-backend-go/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+	// exportBatch bounds how many entries Export loads at a time.
+	exportBatch  = 500
+	maxUserAgent = 512
+)
+
+// Recorder appends to the audit trail; handlers take it so the service
+// stays unaware of HTTP.
+type Recorder interface {
+	// Record appends an entry. Failures are logged, never returned: the
+	// audited action has already happened.
+	Record(req Request, action Action, target Target, details map[string]any)
+}
+
+type Service interface {
+	Recorder
+	Query(filter Filter) ([]Entry, int64, error)
+	// Export writes every entry matching the filter to w as CSV, oldest
+	// first, ignoring the filter's Limit and Offset.
+	Export(w io.Writer, filter Filter) error
+}
+
+type service struct {
+	repo Repository
+	now  func() time.Time
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo, now: time.Now}
+}
+
+// FromRequest describes the request behind c: the authenticated user, if
+// any, the client IP, user agent and the ID from RequestIDMiddleware.
+func FromRequest(c *gin.Context) Request {
+	req := Request{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		RequestID: middleware.GetRequestID(c),
+	}
+	if len(req.UserAgent) > maxUserAgent {
+		req.UserAgent = req.UserAgent[:maxUserAgent]
+	}
+	if uid, ok := c.Get(middleware.ContextUserIDKey); ok {
+		if id, ok := uid.(uint); ok {
+			req.ActorID = &id
+		}
+	}
+	return req
+}
+
+func (s *service) Record(req Request, action Action, target Target, details map[string]any) {
+	e := &Entry{
+		Request:    req,
+		Action:     action,
+		TargetType: target.Type,
+		TargetID:   target.ID,
+		Details:    details,
+		CreatedAt:  s.now(),
+	}
+	if err := s.repo.Append(e); err != nil {
+		logger.Log.Errorw("Recording audit entry failed", "action", action, "requestID", req.RequestID, "error", err)
+	}
+}
+
+func (s *service) Query(filter Filter) ([]Entry, int64, error) {
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = defaultLimit
+	case filter.Limit > maxLimit:
+		filter.Limit = maxLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.repo.Find(filter)
+}
+
+var csvHeader = []string{
+	"id", "created_at", "actor_id", "action", "target_type", "target_id",
+	"ip", "user_agent", "request_id", "details",
+}
+
+func (s *service) Export(w io.Writer, filter Filter) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	var after uint64
+	for {
+		list, err := s.repo.Scan(filter, after, exportBatch)
+		if err != nil {
+			return err
+		}
+		for i := range list {
+			row, err := csvRow(&list[i])
+			if err != nil {
+				return err
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+			after = list[i].ID
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return err
+		}
+		if len(list) < exportBatch {
+			return nil
+		}
+	}
+}
+
+func csvRow(e *Entry) ([]string, error) {
+	var actor, target, details string
+	if e.ActorID != nil {
+		actor = strconv.FormatUint(uint64(*e.ActorID), 10)
+	}
+	if e.TargetID != 0 {
+		target = strconv.FormatUint(uint64(e.TargetID), 10)
+	}
+	if len(e.Details) > 0 {
+		b, err := json.Marshal(e.Details)
+		if err != nil {
+			return nil, err
+		}
+		details = string(b)
+	}
+	return []string{
+		strconv.FormatUint(e.ID, 10),
+		e.CreatedAt.UTC().Format(time.RFC3339),
+		actor,
+		string(e.Action),
+		e.TargetType,
+		target,
+		csvText(e.IP),
+		csvText(e.UserAgent),
+		csvText(e.RequestID),
+		csvText(details),
+	}, nil
+}
+
+// csvText defuses client-supplied text that a spreadsheet would run as a
+// formula.
+func csvText(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
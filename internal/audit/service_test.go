@@ -0,0 +1,172 @@
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"flowboard-backend-go/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memRepo struct {
+	entries []Entry
+}
+
+func (r *memRepo) Append(e *Entry) error {
+	e.ID = uint64(len(r.entries) + 1)
+	r.entries = append(r.entries, *e)
+	return nil
+}
+
+func (r *memRepo) matching(filter Filter) []Entry {
+	var out []Entry
+	for _, e := range r.entries {
+		switch {
+		case filter.ActorID != nil && (e.ActorID == nil || *e.ActorID != *filter.ActorID):
+		case filter.Action != "" && e.Action != filter.Action:
+		case filter.TargetType != "" && e.TargetType != filter.TargetType:
+		case filter.TargetType != "" && filter.TargetID != 0 && e.TargetID != filter.TargetID:
+		case filter.From != nil && e.CreatedAt.Before(*filter.From):
+		case filter.To != nil && !e.CreatedAt.Before(*filter.To):
+		default:
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func (r *memRepo) Find(filter Filter) ([]Entry, int64, error) {
+	all := r.matching(filter)
+	var list []Entry
+	for i := len(all) - 1 - filter.Offset; i >= 0 && len(list) < filter.Limit; i-- {
+		list = append(list, all[i])
+	}
+	return list, int64(len(all)), nil
+}
+
+func (r *memRepo) Scan(filter Filter, afterID uint64, limit int) ([]Entry, error) {
+	var list []Entry
+	for _, e := range r.matching(filter) {
+		if e.ID > afterID && len(list) < limit {
+			list = append(list, e)
+		}
+	}
+	return list, nil
+}
+
+func newTestRouter(s Service, adminID uint) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.RequestIDMiddleware())
+	r.Use(func(c *gin.Context) {
+		c.Set(middleware.ContextUserIDKey, adminID)
+	})
+	h := NewHandler(s)
+	r.GET("/audit", h.GetEntries)
+	r.GET("/audit/export", h.Export)
+	return r
+}
+
+func uintPtr(v uint) *uint { return &v }
+
+func TestFromRequestDescribesTheCaller(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var got Request
+	r := gin.New()
+	r.Use(middleware.RequestIDMiddleware())
+	r.GET("/", func(c *gin.Context) {
+		c.Set(middleware.ContextUserIDKey, uint(4))
+		got = FromRequest(c)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "203.0.113.9:5123"
+	req.Header.Set("User-Agent", "curl/8.0")
+	req.Header.Set(middleware.RequestIDHeader, "req-1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, Request{ActorID: uintPtr(4), IP: "203.0.113.9", UserAgent: "curl/8.0", RequestID: "req-1"}, got)
+}
+
+func TestGetEntriesFilters(t *testing.T) {
+	repo := &memRepo{}
+	s := NewService(repo).(*service)
+	day := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return day }
+	s.Record(Request{ActorID: uintPtr(1)}, PageDeleted, Target{Type: TargetPage, ID: 3}, nil)
+	s.Record(Request{ActorID: uintPtr(2)}, PageDeleted, Target{Type: TargetPage, ID: 3}, nil)
+	s.Record(Request{ActorID: uintPtr(1)}, PageCreated, Target{Type: TargetPage, ID: 4}, nil)
+	day = day.AddDate(0, 0, 1)
+	s.Record(Request{ActorID: uintPtr(1)}, PageDeleted, Target{Type: TargetPage, ID: 4}, nil)
+	s.Record(Request{}, LoginFailed, Target{}, map[string]any{"email": "x@example.com"})
+
+	r := newTestRouter(s, 9)
+	get := func(query string) (int, []Entry, int64) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/audit?"+query, nil))
+		var body struct {
+			Data  []Entry `json:"data"`
+			Total int64   `json:"total"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body.Data, body.Total
+	}
+
+	code, list, total := get("action=page.deleted&targetType=page&targetId=3")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, int64(2), total)
+	assert.Equal(t, uint64(2), list[0].ID, "newest first")
+
+	_, list, _ = get("actorId=1&from=2024-05-01&to=2024-05-01")
+	require.Len(t, list, 2)
+	assert.Equal(t, PageCreated, list[0].Action)
+
+	_, list, total = get("limit=1&offset=1")
+	assert.Equal(t, int64(5), total)
+	require.Len(t, list, 1)
+	assert.Equal(t, uint64(4), list[0].ID)
+
+	for _, bad := range []string{"actorId=me", "targetId=3", "from=yesterday", "limit=x"} {
+		code, _, _ := get(bad)
+		assert.Equal(t, http.StatusBadRequest, code, bad)
+	}
+}
+
+func TestExportWritesCSVAndIsAudited(t *testing.T) {
+	repo := &memRepo{}
+	s := NewService(repo)
+	for i := 0; i < exportBatch; i++ {
+		s.Record(Request{ActorID: uintPtr(1), IP: "10.0.0.1"}, PageUpdated, Target{Type: TargetPage, ID: 3}, nil)
+	}
+	s.Record(Request{UserAgent: "=HYPERLINK(\"http://evil\")"}, LoginFailed, Target{}, map[string]any{"email": "a@b.c"})
+
+	w := httptest.NewRecorder()
+	newTestRouter(s, 9).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/audit/export", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+
+	rows, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, csvHeader, rows[0])
+	require.Len(t, rows, 1+exportBatch+2, "header, every entry, and the export itself")
+	assert.Equal(t, []string{"1", rows[1][1], "1", "page.updated", "page", "3", "10.0.0.1", "", "", ""}, rows[1])
+
+	failed := rows[exportBatch+1]
+	assert.Equal(t, "auth.login_failed", failed[3])
+	assert.Equal(t, `'=HYPERLINK("http://evil")`, failed[7], "formulas are defused")
+	assert.Equal(t, `{"email":"a@b.c"}`, failed[9])
+
+	exported := rows[exportBatch+2]
+	assert.Equal(t, "9", exported[2])
+	assert.Equal(t, string(AuditExported), exported[3])
+	assert.Equal(t, w.Header().Get(middleware.RequestIDHeader), exported[8])
+}
@@ -0,0 +1,409 @@
+package boards
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"flowboard-backend-go/internal/middleware"
+	"flowboard-backend-go/internal/pages"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// getUserID safely retrieves user ID from context
+func getUserID(c *gin.Context) (uint, error) {
+	uidVal, exists := c.Get(middleware.ContextUserIDKey)
+	if !exists {
+		return 0, fmt.Errorf("unauthorized")
+	}
+
+	uid, ok := uidVal.(uint)
+	if !ok {
+		return 0, fmt.Errorf("invalid user ID type")
+	}
+
+	return uid, nil
+}
+
+// parseID reads a numeric path parameter
+func parseID(c *gin.Context, name string) (uint, bool) {
+	id64, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		return 0, false
+	}
+	return uint(id64), true
+}
+
+// respondError maps service errors to HTTP statuses
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrBoardNotFound), errors.Is(err, ErrColumnNotFound), errors.Is(err, ErrCardNotFound),
+		errors.Is(err, pages.ErrPageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrForbidden), errors.Is(err, pages.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidAssignee):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrColumnNotEmpty), errors.Is(err, ErrWIPLimitReached):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (h *Handler) GetBoards(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var workspaceID *uint
+	if v := c.Query("workspaceId"); v != "" {
+		id64, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workspaceId"})
+			return
+		}
+		id := uint(id64)
+		workspaceID = &id
+	}
+
+	list, err := h.service.GetBoards(userID, workspaceID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": list})
+}
+
+func (h *Handler) GetBoard(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	board, err := h.service.GetBoard(id, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": board})
+}
+
+func (h *Handler) CreateBoard(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input BoardInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	board, err := h.service.CreateBoard(input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": board})
+}
+
+func (h *Handler) UpdateBoard(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input BoardUpdateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	board, err := h.service.UpdateBoard(id, input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": board})
+}
+
+func (h *Handler) DeleteBoard(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteBoard(id, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+func (h *Handler) CreateColumn(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	boardID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input ColumnInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	column, err := h.service.CreateColumn(boardID, input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": column})
+}
+
+func (h *Handler) UpdateColumn(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	boardID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	columnID, ok := parseID(c, "columnId")
+	if !ok {
+		return
+	}
+
+	var input ColumnInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	column, err := h.service.UpdateColumn(boardID, columnID, input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": column})
+}
+
+func (h *Handler) MoveColumn(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	boardID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	columnID, ok := parseID(c, "columnId")
+	if !ok {
+		return
+	}
+
+	var input MoveInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	columns, err := h.service.MoveColumn(boardID, columnID, input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": columns})
+}
+
+func (h *Handler) DeleteColumn(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	boardID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	columnID, ok := parseID(c, "columnId")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteColumn(boardID, columnID, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+func (h *Handler) CreateCard(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	boardID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input CardInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := h.service.CreateCard(boardID, input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	resp := gin.H{"success": true, "data": created.Card}
+	if created.Warning != "" {
+		resp["warning"] = created.Warning
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+func (h *Handler) UpdateCard(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	boardID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	cardID, ok := parseID(c, "cardId")
+	if !ok {
+		return
+	}
+
+	var input CardInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	card, err := h.service.UpdateCard(boardID, cardID, input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": card})
+}
+
+// MoveCard moves a card within or across columns: {"columnId": 3,
+// "afterId": 12} places it after card 12 in column 3. Moves past a WIP
+// limit fail with 409, or succeed with a "warning" when the column only
+// warns.
+func (h *Handler) MoveCard(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	boardID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	cardID, ok := parseID(c, "cardId")
+	if !ok {
+		return
+	}
+
+	var input MoveInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	moved, err := h.service.MoveCard(boardID, cardID, input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	resp := gin.H{"success": true, "data": moved.Card}
+	if moved.Warning != "" {
+		resp["warning"] = moved.Warning
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *Handler) DeleteCard(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	boardID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	cardID, ok := parseID(c, "cardId")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteCard(boardID, cardID, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
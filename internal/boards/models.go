@@ -0,0 +1,124 @@
+package boards
+
+import "time"
+
+// Board is personal to UserID unless WorkspaceID is set, in which case
+// every member of that workspace can reach it.
+type Board struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"not null;index" json:"userId"`
+	WorkspaceID *uint     `gorm:"index" json:"workspaceId,omitempty"`
+	Name        string    `gorm:"size:255;not null" json:"name"`
+	Description string    `gorm:"type:text" json:"description"`
+	Columns     []Column  `gorm:"foreignKey:BoardID" json:"columns,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// WIPPolicy decides what happens when a move would push a column past its
+// WIP limit.
+type WIPPolicy string
+
+const (
+	WIPReject WIPPolicy = "reject"
+	WIPWarn   WIPPolicy = "warn"
+)
+
+// ColumnAction is an automation applied to a card when it enters a column.
+type ColumnAction string
+
+const (
+	ActionSetCompleted   ColumnAction = "set_completed"
+	ActionClearCompleted ColumnAction = "clear_completed"
+	ActionUnassign       ColumnAction = "unassign"
+)
+
+// Column is an ordered lane of a board. Rank orders columns (see pkg/rank).
+// A WIPLimit of zero means unlimited.
+type Column struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	BoardID   uint           `gorm:"not null;index" json:"boardId"`
+	Name      string         `gorm:"size:255;not null" json:"name"`
+	Rank      string         `gorm:"not null;default:''" json:"rank"`
+	WIPLimit  int            `gorm:"not null;default:0" json:"wipLimit"`
+	WIPPolicy WIPPolicy      `gorm:"size:16;not null;default:'reject'" json:"wipPolicy"`
+	OnEnter   []ColumnAction `gorm:"serializer:json;type:jsonb" json:"onEnter"`
+	Cards     []Card         `gorm:"foreignKey:ColumnID" json:"cards,omitempty"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
+
+// Card is a unit of work in a column. PageID optionally links a page that
+// holds its long-form content.
+type Card struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	BoardID     uint       `gorm:"not null;index" json:"boardId"`
+	ColumnID    uint       `gorm:"not null;index" json:"columnId"`
+	Title       string     `gorm:"size:255;not null" json:"title"`
+	Description string     `gorm:"type:text" json:"description"`
+	AssigneeID  *uint      `gorm:"index" json:"assigneeId"`
+	DueDate     *time.Time `json:"dueDate"`
+	Labels      []string   `gorm:"serializer:json;type:jsonb" json:"labels"`
+	PageID      *uint      `gorm:"index" json:"pageId"`
+	Rank        string     `gorm:"not null;default:''" json:"rank"`
+	CompletedAt *time.Time `json:"completedAt"`
+	CreatedBy   uint       `gorm:"not null" json:"createdBy"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// BoardInput for creating a board. Columns names the initial columns;
+// when empty the board starts with To do, In progress and Done, the last
+// of which marks cards completed.
+type BoardInput struct {
+	Name        string   `json:"name" binding:"required,max=255"`
+	Description string   `json:"description"`
+	WorkspaceID *uint    `json:"workspaceId"`
+	Columns     []string `json:"columns" binding:"omitempty,dive,required,max=255"`
+}
+
+// BoardUpdateInput for renaming a board or changing its description
+type BoardUpdateInput struct {
+	Name        *string `json:"name" binding:"omitempty,min=1,max=255"`
+	Description *string `json:"description"`
+}
+
+// ColumnInput for creating or replacing a column
+type ColumnInput struct {
+	Name      string         `json:"name" binding:"required,max=255"`
+	WIPLimit  int            `json:"wipLimit" binding:"min=0"`
+	WIPPolicy WIPPolicy      `json:"wipPolicy" binding:"omitempty,oneof=reject warn"`
+	OnEnter   []ColumnAction `json:"onEnter" binding:"omitempty,dive,oneof=set_completed clear_completed unassign"`
+}
+
+// CardMove is the outcome of creating or moving a card. Warning is set when
+// the card took a column with the "warn" policy past its WIP limit.
+type CardMove struct {
+	Card    *Card  `json:"card"`
+	Warning string `json:"warning,omitempty"`
+}
+
+// MoveInput places a column or card right after AfterID; nil moves it
+// first. For cards, ColumnID selects the destination column.
+type MoveInput struct {
+	ColumnID uint  `json:"columnId"`
+	AfterID  *uint `json:"afterId"`
+}
+
+// CardInput for creating or replacing a card. New cards are appended to
+// ColumnID; on update ColumnID is ignored (use the move endpoint).
+type CardInput struct {
+	ColumnID    uint       `json:"columnId"`
+	Title       string     `json:"title" binding:"required,max=255"`
+	Description string     `json:"description"`
+	AssigneeID  *uint      `json:"assigneeId"`
+	DueDate     *time.Time `json:"dueDate"`
+	Labels      []string   `json:"labels" binding:"omitempty,dive,required,max=64"`
+	PageID      *uint      `json:"pageId"`
+}
+
+var defaultColumns = []ColumnInput{
+	{Name: "To do"},
+	{Name: "In progress"},
+	{Name: "Done", OnEnter: []ColumnAction{ActionSetCompleted}},
+}
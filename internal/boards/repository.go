@@ -0,0 +1,219 @@
+package boards
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	// Transaction runs fn with a Repository bound to a single database
+	// transaction; any error rolls everything back.
+	Transaction(fn func(repo Repository) error) error
+
+	CreateBoard(board *Board) error
+	GetBoardByID(id uint) (*Board, error)
+	GetBoardsByUser(userID uint) ([]Board, error)
+	GetBoardsByWorkspace(workspaceID uint) ([]Board, error)
+	UpdateBoard(board *Board) error
+	DeleteBoard(id uint) error
+
+	GetColumns(boardID uint) ([]Column, error)
+	// GetColumnsWithCards loads a board's columns and their cards in order.
+	GetColumnsWithCards(boardID uint) ([]Column, error)
+	GetColumnByID(id uint) (*Column, error)
+	// LockColumn is GetColumnByID taking a FOR UPDATE lock on the row.
+	// Call it inside Transaction.
+	LockColumn(id uint) (*Column, error)
+	CreateColumn(column *Column) error
+	UpdateColumn(column *Column) error
+	DeleteColumn(id uint) error
+	SetColumnRanks(ranks map[uint]string) error
+
+	GetCards(columnID uint) ([]Card, error)
+	CountCards(columnID uint) (int64, error)
+	GetCardByID(id uint) (*Card, error)
+	CreateCard(card *Card) error
+	UpdateCard(card *Card) error
+	DeleteCard(id uint) error
+	SetCardRanks(ranks map[uint]string) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// rankOrder sorts by rank bytewise; rank keys rely on ASCII ordering, which
+// locale collations do not preserve.
+const rankOrder = `rank COLLATE "C", id`
+
+func (r *repository) Transaction(fn func(repo Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&repository{db: tx})
+	})
+}
+
+// CreateBoard stores the board together with any columns set on it.
+func (r *repository) CreateBoard(board *Board) error {
+	return r.db.Create(board).Error
+}
+
+func (r *repository) GetBoardByID(id uint) (*Board, error) {
+	var board Board
+	if err := r.db.First(&board, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &board, nil
+}
+
+func (r *repository) GetBoardsByUser(userID uint) ([]Board, error) {
+	var list []Board
+	if err := r.db.Where("user_id = ? AND workspace_id IS NULL", userID).Order("name").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *repository) GetBoardsByWorkspace(workspaceID uint) ([]Board, error) {
+	var list []Board
+	if err := r.db.Where("workspace_id = ?", workspaceID).Order("name").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *repository) UpdateBoard(board *Board) error {
+	return r.db.Omit("Columns").Save(board).Error
+}
+
+func (r *repository) DeleteBoard(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("board_id = ?", id).Delete(&Card{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("board_id = ?", id).Delete(&Column{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Board{}, id).Error
+	})
+}
+
+func (r *repository) GetColumns(boardID uint) ([]Column, error) {
+	var list []Column
+	if err := r.db.Where("board_id = ?", boardID).Order(rankOrder).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *repository) GetColumnsWithCards(boardID uint) ([]Column, error) {
+	var list []Column
+	err := r.db.
+		Preload("Cards", func(db *gorm.DB) *gorm.DB { return db.Order(rankOrder) }).
+		Where("board_id = ?", boardID).
+		Order(rankOrder).
+		Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *repository) GetColumnByID(id uint) (*Column, error) {
+	var column Column
+	if err := r.db.First(&column, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &column, nil
+}
+
+func (r *repository) LockColumn(id uint) (*Column, error) {
+	var column Column
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&column, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &column, nil
+}
+
+func (r *repository) CreateColumn(column *Column) error {
+	return r.db.Create(column).Error
+}
+
+func (r *repository) UpdateColumn(column *Column) error {
+	return r.db.Omit("Cards").Save(column).Error
+}
+
+func (r *repository) DeleteColumn(id uint) error {
+	return r.db.Delete(&Column{}, id).Error
+}
+
+func (r *repository) SetColumnRanks(ranks map[uint]string) error {
+	for id, key := range ranks {
+		if err := r.db.Model(&Column{}).Where("id = ?", id).UpdateColumn("rank", key).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *repository) GetCards(columnID uint) ([]Card, error) {
+	var list []Card
+	if err := r.db.Where("column_id = ?", columnID).Order(rankOrder).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *repository) CountCards(columnID uint) (int64, error) {
+	var n int64
+	if err := r.db.Model(&Card{}).Where("column_id = ?", columnID).Count(&n).Error; err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (r *repository) GetCardByID(id uint) (*Card, error) {
+	var card Card
+	if err := r.db.First(&card, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &card, nil
+}
+
+func (r *repository) CreateCard(card *Card) error {
+	return r.db.Create(card).Error
+}
+
+func (r *repository) UpdateCard(card *Card) error {
+	return r.db.Save(card).Error
+}
+
+func (r *repository) DeleteCard(id uint) error {
+	return r.db.Delete(&Card{}, id).Error
+}
+
+func (r *repository) SetCardRanks(ranks map[uint]string) error {
+	for id, key := range ranks {
+		if err := r.db.Model(&Card{}).Where("id = ?", id).UpdateColumn("rank", key).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
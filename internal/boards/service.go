@@ -0,0 +1,612 @@
+package boards
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"flowboard-backend-go/internal/pages"
+	"flowboard-backend-go/internal/workspaces"
+	"flowboard-backend-go/pkg/rank"
+)
+
+var (
+	ErrBoardNotFound   = errors.New("board not found")
+	ErrColumnNotFound  = errors.New("column not found")
+	ErrCardNotFound    = errors.New("card not found")
+	ErrColumnNotEmpty  = errors.New("column still has cards")
+	ErrWIPLimitReached = errors.New("column is at its WIP limit")
+	ErrInvalidAssignee = errors.New("assignee has no access to this board")
+	ErrForbidden       = errors.New("insufficient permissions")
+)
+
+// WorkspaceAccess reports a user's role in a workspace ("" for
+// non-members); satisfied by workspaces.Service.
+type WorkspaceAccess interface {
+	MemberRole(workspaceID, userID uint) (workspaces.Role, error)
+}
+
+// PageLookup resolves pages with access checks; satisfied by pages.Service.
+type PageLookup interface {
+	GetPageByID(id, userID uint) (*pages.Page, error)
+}
+
+type Service interface {
+	GetBoards(userID uint, workspaceID *uint) ([]Board, error)
+	GetBoard(id, userID uint) (*Board, error)
+	CreateBoard(input BoardInput, userID uint) (*Board, error)
+	UpdateBoard(id uint, input BoardUpdateInput, userID uint) (*Board, error)
+	DeleteBoard(id, userID uint) error
+
+	CreateColumn(boardID uint, input ColumnInput, userID uint) (*Column, error)
+	UpdateColumn(boardID, columnID uint, input ColumnInput, userID uint) (*Column, error)
+	MoveColumn(boardID, columnID uint, input MoveInput, userID uint) ([]Column, error)
+	DeleteColumn(boardID, columnID, userID uint) error
+
+	CreateCard(boardID uint, input CardInput, userID uint) (*CardMove, error)
+	UpdateCard(boardID, cardID uint, input CardInput, userID uint) (*Card, error)
+	MoveCard(boardID, cardID uint, input MoveInput, userID uint) (*CardMove, error)
+	DeleteCard(boardID, cardID, userID uint) error
+}
+
+type service struct {
+	repo       Repository
+	pages      PageLookup
+	workspaces WorkspaceAccess
+	now        func() time.Time
+}
+
+func NewService(repo Repository, pages PageLookup, workspaces WorkspaceAccess) Service {
+	return &service{repo: repo, pages: pages, workspaces: workspaces, now: time.Now}
+}
+
+// inTx runs fn against a copy of the service whose repository is bound to a
+// single transaction.
+func (s *service) inTx(fn func(tx *service) error) error {
+	return s.repo.Transaction(func(repo Repository) error {
+		tx := *s
+		tx.repo = repo
+		return fn(&tx)
+	})
+}
+
+func (s *service) GetBoards(userID uint, workspaceID *uint) ([]Board, error) {
+	if workspaceID != nil {
+		if err := s.requireWorkspaceRole(*workspaceID, userID, workspaces.RoleViewer); err != nil {
+			return nil, err
+		}
+		return s.repo.GetBoardsByWorkspace(*workspaceID)
+	}
+	return s.repo.GetBoardsByUser(userID)
+}
+
+// GetBoard returns the board with its columns and cards in order.
+func (s *service) GetBoard(id, userID uint) (*Board, error) {
+	board, err := s.loadBoard(id, userID, workspaces.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	if board.Columns, err = s.repo.GetColumnsWithCards(id); err != nil {
+		return nil, err
+	}
+	return board, nil
+}
+
+func (s *service) CreateBoard(input BoardInput, userID uint) (*Board, error) {
+	if input.WorkspaceID != nil {
+		if err := s.requireWorkspaceRole(*input.WorkspaceID, userID, workspaces.RoleEditor); err != nil {
+			return nil, err
+		}
+	}
+
+	columns := defaultColumns
+	if len(input.Columns) > 0 {
+		columns = make([]ColumnInput, len(input.Columns))
+		for i, name := range input.Columns {
+			columns[i] = ColumnInput{Name: name}
+		}
+	}
+	keys := rank.Spread(len(columns))
+	board := &Board{
+		UserID:      userID,
+		WorkspaceID: input.WorkspaceID,
+		Name:        strings.TrimSpace(input.Name),
+		Description: input.Description,
+	}
+	for i, in := range columns {
+		column := Column{Rank: keys[i]}
+		applyColumnInput(&column, in)
+		board.Columns = append(board.Columns, column)
+	}
+
+	if err := s.repo.CreateBoard(board); err != nil {
+		return nil, err
+	}
+	return board, nil
+}
+
+func (s *service) UpdateBoard(id uint, input BoardUpdateInput, userID uint) (*Board, error) {
+	board, err := s.loadBoard(id, userID, workspaces.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	if input.Name != nil {
+		board.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Description != nil {
+		board.Description = *input.Description
+	}
+	if err := s.repo.UpdateBoard(board); err != nil {
+		return nil, err
+	}
+	return board, nil
+}
+
+// DeleteBoard removes a board with all its columns and cards. On a
+// workspace board only its creator or a workspace admin may do this.
+func (s *service) DeleteBoard(id, userID uint) error {
+	board, err := s.loadBoard(id, userID, workspaces.RoleEditor)
+	if err != nil {
+		return err
+	}
+	if board.WorkspaceID != nil && board.UserID != userID {
+		if err := s.requireWorkspaceRole(*board.WorkspaceID, userID, workspaces.RoleAdmin); err != nil {
+			return err
+		}
+	}
+	return s.repo.DeleteBoard(id)
+}
+
+func (s *service) CreateColumn(boardID uint, input ColumnInput, userID uint) (*Column, error) {
+	var column *Column
+	err := s.inTx(func(tx *service) error {
+		if _, err := tx.loadBoard(boardID, userID, workspaces.RoleEditor); err != nil {
+			return err
+		}
+		columns, err := tx.repo.GetColumns(boardID)
+		if err != nil {
+			return err
+		}
+		key, err := tx.placeColumn(columns, len(columns))
+		if err != nil {
+			return err
+		}
+		column = &Column{BoardID: boardID, Rank: key}
+		applyColumnInput(column, input)
+		return tx.repo.CreateColumn(column)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return column, nil
+}
+
+func (s *service) UpdateColumn(boardID, columnID uint, input ColumnInput, userID uint) (*Column, error) {
+	if _, err := s.loadBoard(boardID, userID, workspaces.RoleEditor); err != nil {
+		return nil, err
+	}
+	column, err := s.loadColumn(boardID, columnID)
+	if err != nil {
+		return nil, err
+	}
+	applyColumnInput(column, input)
+	if err := s.repo.UpdateColumn(column); err != nil {
+		return nil, err
+	}
+	return column, nil
+}
+
+// MoveColumn places a column right after input.AfterID and returns the
+// board's columns in their new order.
+func (s *service) MoveColumn(boardID, columnID uint, input MoveInput, userID uint) ([]Column, error) {
+	var columns []Column
+	err := s.inTx(func(tx *service) error {
+		if _, err := tx.loadBoard(boardID, userID, workspaces.RoleEditor); err != nil {
+			return err
+		}
+		column, err := tx.loadColumn(boardID, columnID)
+		if err != nil {
+			return err
+		}
+		all, err := tx.repo.GetColumns(boardID)
+		if err != nil {
+			return err
+		}
+
+		others := make([]Column, 0, len(all))
+		for _, c := range all {
+			if c.ID != column.ID {
+				others = append(others, c)
+			}
+		}
+		ids := make([]uint, len(others))
+		for i, c := range others {
+			ids[i] = c.ID
+		}
+		at, ok := indexAfter(ids, input.AfterID)
+		if !ok {
+			return ErrColumnNotFound
+		}
+		if column.Rank, err = tx.placeColumn(others, at); err != nil {
+			return err
+		}
+		if err := tx.repo.SetColumnRanks(map[uint]string{column.ID: column.Rank}); err != nil {
+			return err
+		}
+		columns, err = tx.repo.GetColumns(boardID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+// DeleteColumn removes an empty column. The column is locked while it is
+// counted, so no card can enter it before it is gone.
+func (s *service) DeleteColumn(boardID, columnID, userID uint) error {
+	return s.inTx(func(tx *service) error {
+		if _, err := tx.loadBoard(boardID, userID, workspaces.RoleEditor); err != nil {
+			return err
+		}
+		if _, err := tx.lockColumn(boardID, columnID); err != nil {
+			return err
+		}
+		n, err := tx.repo.CountCards(columnID)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrColumnNotEmpty
+		}
+		return tx.repo.DeleteColumn(columnID)
+	})
+}
+
+// CreateCard appends a card to input.ColumnID. Like a move, this enters
+// the column: its WIP limit is checked and its OnEnter actions run.
+func (s *service) CreateCard(boardID uint, input CardInput, userID uint) (*CardMove, error) {
+	var result *CardMove
+	err := s.inTx(func(tx *service) error {
+		board, err := tx.loadBoard(boardID, userID, workspaces.RoleEditor)
+		if err != nil {
+			return err
+		}
+		column, err := tx.lockColumn(boardID, input.ColumnID)
+		if err != nil {
+			return err
+		}
+		card := &Card{BoardID: boardID, ColumnID: input.ColumnID, CreatedBy: userID}
+		if err := tx.applyCardInput(board, card, input, userID); err != nil {
+			return err
+		}
+
+		cards, err := tx.repo.GetCards(input.ColumnID)
+		if err != nil {
+			return err
+		}
+		result = &CardMove{Card: card}
+		if result.Warning, err = tx.enterColumn(column, card, len(cards)); err != nil {
+			return err
+		}
+		if card.Rank, err = tx.placeCard(cards, len(cards)); err != nil {
+			return err
+		}
+		return tx.repo.CreateCard(card)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *service) UpdateCard(boardID, cardID uint, input CardInput, userID uint) (*Card, error) {
+	board, err := s.loadBoard(boardID, userID, workspaces.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	card, err := s.loadCard(boardID, cardID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyCardInput(board, card, input, userID); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateCard(card); err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+// MoveCard places a card right after input.AfterID in input.ColumnID
+// (its current column when zero). Only the moved card's row changes
+// unless the destination's ranks need respreading. Entering another column
+// checks its WIP limit and runs its OnEnter actions.
+func (s *service) MoveCard(boardID, cardID uint, input MoveInput, userID uint) (*CardMove, error) {
+	var result *CardMove
+	err := s.inTx(func(tx *service) error {
+		if _, err := tx.loadBoard(boardID, userID, workspaces.RoleEditor); err != nil {
+			return err
+		}
+		c, err := tx.loadCard(boardID, cardID)
+		if err != nil {
+			return err
+		}
+		dest := input.ColumnID
+		if dest == 0 {
+			dest = c.ColumnID
+		}
+		column, err := tx.lockColumn(boardID, dest)
+		if err != nil {
+			return err
+		}
+
+		all, err := tx.repo.GetCards(dest)
+		if err != nil {
+			return err
+		}
+		others := make([]Card, 0, len(all))
+		for _, other := range all {
+			if other.ID != c.ID {
+				others = append(others, other)
+			}
+		}
+		ids := make([]uint, len(others))
+		for i, other := range others {
+			ids[i] = other.ID
+		}
+		at, ok := indexAfter(ids, input.AfterID)
+		if !ok {
+			return ErrCardNotFound
+		}
+
+		result = &CardMove{Card: c}
+		if dest != c.ColumnID {
+			if result.Warning, err = tx.enterColumn(column, c, len(others)); err != nil {
+				return err
+			}
+		}
+
+		if c.Rank, err = tx.placeCard(others, at); err != nil {
+			return err
+		}
+		c.ColumnID = dest
+		return tx.repo.UpdateCard(c)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// enterColumn admits a card into column, which already holds count cards:
+// it enforces the WIP limit, returning a warning under the "warn" policy,
+// and runs the column's OnEnter actions.
+func (s *service) enterColumn(column *Column, card *Card, count int) (string, error) {
+	warning := ""
+	if column.WIPLimit > 0 && count >= column.WIPLimit {
+		if column.WIPPolicy != WIPWarn {
+			return "", ErrWIPLimitReached
+		}
+		warning = fmt.Sprintf("%s is over its WIP limit (%d/%d)", column.Name, count+1, column.WIPLimit)
+	}
+	s.runActions(card, column.OnEnter)
+	return warning, nil
+}
+
+// runActions applies a column's OnEnter automations to a card entering it.
+func (s *service) runActions(card *Card, actions []ColumnAction) {
+	for _, action := range actions {
+		switch action {
+		case ActionSetCompleted:
+			if card.CompletedAt == nil {
+				now := s.now()
+				card.CompletedAt = &now
+			}
+		case ActionClearCompleted:
+			card.CompletedAt = nil
+		case ActionUnassign:
+			card.AssigneeID = nil
+		}
+	}
+}
+
+func (s *service) DeleteCard(boardID, cardID, userID uint) error {
+	if _, err := s.loadBoard(boardID, userID, workspaces.RoleEditor); err != nil {
+		return err
+	}
+	if _, err := s.loadCard(boardID, cardID); err != nil {
+		return err
+	}
+	return s.repo.DeleteCard(cardID)
+}
+
+func applyColumnInput(column *Column, input ColumnInput) {
+	column.Name = strings.TrimSpace(input.Name)
+	column.WIPLimit = input.WIPLimit
+	column.WIPPolicy = input.WIPPolicy
+	if column.WIPPolicy == "" {
+		column.WIPPolicy = WIPReject
+	}
+	column.OnEnter = input.OnEnter
+}
+
+// applyCardInput validates input and copies it onto card. Assignees must
+// be able to see the board and linked pages must be readable by the user.
+func (s *service) applyCardInput(board *Board, card *Card, input CardInput, userID uint) error {
+	if input.AssigneeID != nil {
+		if err := s.checkAssignee(board, *input.AssigneeID); err != nil {
+			return err
+		}
+	}
+	if input.PageID != nil {
+		if _, err := s.pages.GetPageByID(*input.PageID, userID); err != nil {
+			return err
+		}
+	}
+
+	card.Title = strings.TrimSpace(input.Title)
+	card.Description = input.Description
+	card.AssigneeID = input.AssigneeID
+	card.DueDate = input.DueDate
+	card.Labels = normalizeLabels(input.Labels)
+	card.PageID = input.PageID
+	return nil
+}
+
+func (s *service) checkAssignee(board *Board, assigneeID uint) error {
+	if board.WorkspaceID == nil {
+		if assigneeID != board.UserID {
+			return ErrInvalidAssignee
+		}
+		return nil
+	}
+	role, err := s.workspaces.MemberRole(*board.WorkspaceID, assigneeID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return ErrInvalidAssignee
+	}
+	return nil
+}
+
+// loadBoard loads a board and checks the user's access to it. Personal
+// boards are reachable by their owner only; workspace boards by members
+// holding at least min.
+func (s *service) loadBoard(id, userID uint, min workspaces.Role) (*Board, error) {
+	board, err := s.repo.GetBoardByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if board == nil {
+		return nil, ErrBoardNotFound
+	}
+	if board.WorkspaceID == nil {
+		if board.UserID != userID {
+			return nil, ErrBoardNotFound
+		}
+		return board, nil
+	}
+	if err := s.requireWorkspaceRole(*board.WorkspaceID, userID, min); err != nil {
+		return nil, err
+	}
+	return board, nil
+}
+
+func (s *service) loadColumn(boardID, columnID uint) (*Column, error) {
+	column, err := s.repo.GetColumnByID(columnID)
+	if err != nil {
+		return nil, err
+	}
+	if column == nil || column.BoardID != boardID {
+		return nil, ErrColumnNotFound
+	}
+	return column, nil
+}
+
+// lockColumn is loadColumn for a card entering the column: the row stays
+// locked until the transaction ends, so concurrent entries see each
+// other's cards when counting against the WIP limit.
+func (s *service) lockColumn(boardID, columnID uint) (*Column, error) {
+	column, err := s.repo.LockColumn(columnID)
+	if err != nil {
+		return nil, err
+	}
+	if column == nil || column.BoardID != boardID {
+		return nil, ErrColumnNotFound
+	}
+	return column, nil
+}
+
+func (s *service) loadCard(boardID, cardID uint) (*Card, error) {
+	card, err := s.repo.GetCardByID(cardID)
+	if err != nil {
+		return nil, err
+	}
+	if card == nil || card.BoardID != boardID {
+		return nil, ErrCardNotFound
+	}
+	return card, nil
+}
+
+// requireWorkspaceRole checks the user's workspace role. Non-members get
+// ErrBoardNotFound so the workspace's existence is not revealed.
+func (s *service) requireWorkspaceRole(workspaceID, userID uint, min workspaces.Role) error {
+	role, err := s.workspaces.MemberRole(workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return ErrBoardNotFound
+	}
+	if !role.AtLeast(min) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// placeColumn returns a rank for index at of columns, persisting a
+// respread of the others when their keys leave no room.
+func (s *service) placeColumn(columns []Column, at int) (string, error) {
+	keys := make([]string, len(columns))
+	for i, c := range columns {
+		keys[i] = c.Rank
+	}
+	key, respread := rank.Insert(keys, at)
+	if respread == nil {
+		return key, nil
+	}
+	ranks := make(map[uint]string, len(columns))
+	for i, c := range columns {
+		ranks[c.ID] = respread[i]
+	}
+	return key, s.repo.SetColumnRanks(ranks)
+}
+
+// placeCard is placeColumn for the cards of one column.
+func (s *service) placeCard(cards []Card, at int) (string, error) {
+	keys := make([]string, len(cards))
+	for i, c := range cards {
+		keys[i] = c.Rank
+	}
+	key, respread := rank.Insert(keys, at)
+	if respread == nil {
+		return key, nil
+	}
+	ranks := make(map[uint]string, len(cards))
+	for i, c := range cards {
+		ranks[c.ID] = respread[i]
+	}
+	return key, s.repo.SetCardRanks(ranks)
+}
+
+// indexAfter returns the index just after afterID in ids, or 0 when
+// afterID is nil.
+func indexAfter(ids []uint, afterID *uint) (int, bool) {
+	if afterID == nil {
+		return 0, true
+	}
+	for i, id := range ids {
+		if id == *afterID {
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
+
+func normalizeLabels(labels []string) []string {
+	out := make([]string, 0, len(labels))
+	seen := map[string]bool{}
+	for _, l := range labels {
+		l = strings.TrimSpace(l)
+		key := strings.ToLower(l)
+		if l == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, l)
+	}
+	return out
+}
@@ -0,0 +1,381 @@
+package boards
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"flowboard-backend-go/internal/pages"
+	"flowboard-backend-go/internal/workspaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memRepo is an in-memory Repository for service tests.
+type memRepo struct {
+	boards  map[uint]*Board
+	columns map[uint]*Column
+	cards   map[uint]*Card
+	nextID  uint
+	inTx    bool
+	locked  []uint // columns locked inside a transaction
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{boards: map[uint]*Board{}, columns: map[uint]*Column{}, cards: map[uint]*Card{}}
+}
+
+func (r *memRepo) id() uint {
+	r.nextID++
+	return r.nextID
+}
+
+func (r *memRepo) Transaction(fn func(repo Repository) error) error {
+	r.inTx = true
+	defer func() { r.inTx = false }()
+	return fn(r)
+}
+
+func (r *memRepo) CreateBoard(board *Board) error {
+	board.ID = r.id()
+	for i := range board.Columns {
+		board.Columns[i].ID = r.id()
+		board.Columns[i].BoardID = board.ID
+		cp := board.Columns[i]
+		r.columns[cp.ID] = &cp
+	}
+	cp := *board
+	cp.Columns = nil
+	r.boards[board.ID] = &cp
+	return nil
+}
+
+func (r *memRepo) GetBoardByID(id uint) (*Board, error) {
+	if b, ok := r.boards[id]; ok {
+		cp := *b
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (r *memRepo) GetBoardsByUser(userID uint) ([]Board, error) {
+	var out []Board
+	for _, b := range r.boards {
+		if b.UserID == userID && b.WorkspaceID == nil {
+			out = append(out, *b)
+		}
+	}
+	return out, nil
+}
+
+func (r *memRepo) GetBoardsByWorkspace(workspaceID uint) ([]Board, error) {
+	var out []Board
+	for _, b := range r.boards {
+		if b.WorkspaceID != nil && *b.WorkspaceID == workspaceID {
+			out = append(out, *b)
+		}
+	}
+	return out, nil
+}
+
+func (r *memRepo) UpdateBoard(board *Board) error {
+	cp := *board
+	r.boards[board.ID] = &cp
+	return nil
+}
+
+func (r *memRepo) DeleteBoard(id uint) error {
+	delete(r.boards, id)
+	return nil
+}
+
+func (r *memRepo) GetColumns(boardID uint) ([]Column, error) {
+	var out []Column
+	for _, c := range r.columns {
+		if c.BoardID == boardID {
+			out = append(out, *c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Rank < out[j].Rank })
+	return out, nil
+}
+
+func (r *memRepo) GetColumnsWithCards(boardID uint) ([]Column, error) {
+	out, _ := r.GetColumns(boardID)
+	for i := range out {
+		out[i].Cards, _ = r.GetCards(out[i].ID)
+	}
+	return out, nil
+}
+
+func (r *memRepo) GetColumnByID(id uint) (*Column, error) {
+	if c, ok := r.columns[id]; ok {
+		cp := *c
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (r *memRepo) LockColumn(id uint) (*Column, error) {
+	if r.inTx {
+		r.locked = append(r.locked, id)
+	}
+	return r.GetColumnByID(id)
+}
+
+func (r *memRepo) CreateColumn(column *Column) error {
+	column.ID = r.id()
+	cp := *column
+	r.columns[column.ID] = &cp
+	return nil
+}
+
+func (r *memRepo) UpdateColumn(column *Column) error {
+	cp := *column
+	r.columns[column.ID] = &cp
+	return nil
+}
+
+func (r *memRepo) DeleteColumn(id uint) error {
+	delete(r.columns, id)
+	return nil
+}
+
+func (r *memRepo) SetColumnRanks(ranks map[uint]string) error {
+	for id, key := range ranks {
+		r.columns[id].Rank = key
+	}
+	return nil
+}
+
+func (r *memRepo) GetCards(columnID uint) ([]Card, error) {
+	var out []Card
+	for _, c := range r.cards {
+		if c.ColumnID == columnID {
+			out = append(out, *c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Rank < out[j].Rank })
+	return out, nil
+}
+
+func (r *memRepo) CountCards(columnID uint) (int64, error) {
+	cards, _ := r.GetCards(columnID)
+	return int64(len(cards)), nil
+}
+
+func (r *memRepo) GetCardByID(id uint) (*Card, error) {
+	if c, ok := r.cards[id]; ok {
+		cp := *c
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (r *memRepo) CreateCard(card *Card) error {
+	card.ID = r.id()
+	cp := *card
+	r.cards[card.ID] = &cp
+	return nil
+}
+
+func (r *memRepo) UpdateCard(card *Card) error {
+	cp := *card
+	r.cards[card.ID] = &cp
+	return nil
+}
+
+func (r *memRepo) DeleteCard(id uint) error {
+	delete(r.cards, id)
+	return nil
+}
+
+func (r *memRepo) SetCardRanks(ranks map[uint]string) error {
+	for id, key := range ranks {
+		r.cards[id].Rank = key
+	}
+	return nil
+}
+
+// stubWorkspaces grants roles from a fixed table keyed by workspace, user.
+type stubWorkspaces map[[2]uint]workspaces.Role
+
+func (w stubWorkspaces) MemberRole(workspaceID, userID uint) (workspaces.Role, error) {
+	return w[[2]uint{workspaceID, userID}], nil
+}
+
+// stubPages lets user 1 read page 100 only.
+type stubPages struct{}
+
+func (stubPages) GetPageByID(id, userID uint) (*pages.Page, error) {
+	if id == 100 && userID == 1 {
+		return &pages.Page{ID: id, UserID: userID}, nil
+	}
+	return nil, pages.ErrPageNotFound
+}
+
+func cardTitles(cards []Card) []string {
+	out := make([]string, len(cards))
+	for i, c := range cards {
+		out[i] = c.Title
+	}
+	return out
+}
+
+func TestBoards_MoveCardsAcrossColumns(t *testing.T) {
+	repo := newMemRepo()
+	s := NewService(repo, stubPages{}, stubWorkspaces{})
+
+	board, err := s.CreateBoard(BoardInput{Name: "Sprint"}, 1)
+	require.NoError(t, err)
+	require.Len(t, board.Columns, 3)
+	todo, doing := board.Columns[0].ID, board.Columns[1].ID
+
+	a := createCard(t, s, board.ID, CardInput{ColumnID: todo, Title: "A"})
+	b := createCard(t, s, board.ID, CardInput{ColumnID: todo, Title: "B"})
+	c := createCard(t, s, board.ID, CardInput{ColumnID: todo, Title: "C"})
+
+	_, err = s.MoveCard(board.ID, c.ID, MoveInput{AfterID: nil}, 1)
+	require.NoError(t, err)
+	_, err = s.MoveCard(board.ID, a.ID, MoveInput{ColumnID: doing}, 1)
+	require.NoError(t, err)
+	_, err = s.MoveCard(board.ID, b.ID, MoveInput{ColumnID: doing, AfterID: &a.ID}, 1)
+	require.NoError(t, err)
+
+	got, err := s.GetBoard(board.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"To do", "In progress", "Done"}, []string{got.Columns[0].Name, got.Columns[1].Name, got.Columns[2].Name})
+	assert.Equal(t, []string{"C"}, cardTitles(got.Columns[0].Cards))
+	assert.Equal(t, []string{"A", "B"}, cardTitles(got.Columns[1].Cards))
+
+	_, err = s.MoveCard(board.ID, c.ID, MoveInput{ColumnID: doing, AfterID: &c.ID}, 1)
+	assert.Equal(t, ErrCardNotFound, err)
+	_, err = s.MoveCard(board.ID, c.ID, MoveInput{ColumnID: 999}, 1)
+	assert.Equal(t, ErrColumnNotFound, err)
+	_, err = s.GetBoard(board.ID, 2)
+	assert.Equal(t, ErrBoardNotFound, err)
+
+	assert.Equal(t, ErrColumnNotEmpty, s.DeleteColumn(board.ID, doing, 1))
+	columns, err := s.MoveColumn(board.ID, board.Columns[2].ID, MoveInput{}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Done", columns[0].Name)
+
+	// The column is locked against incoming cards while it is deleted.
+	done := columns[0].ID
+	repo.locked = nil
+	require.NoError(t, s.DeleteColumn(board.ID, done, 1))
+	assert.Equal(t, []uint{done}, repo.locked)
+}
+
+func TestBoards_CardValidation(t *testing.T) {
+	ws := uint(7)
+	access := stubWorkspaces{{ws, 1}: workspaces.RoleEditor, {ws, 2}: workspaces.RoleViewer}
+	s := NewService(newMemRepo(), stubPages{}, access)
+
+	board, err := s.CreateBoard(BoardInput{Name: "Team", WorkspaceID: &ws, Columns: []string{"Backlog"}}, 1)
+	require.NoError(t, err)
+	col := board.Columns[0].ID
+
+	viewer, outsider, page := uint(2), uint(3), uint(100)
+	card := createCard(t, s, board.ID, CardInput{
+		ColumnID:   col,
+		Title:      " Write spec ",
+		AssigneeID: &viewer,
+		PageID:     &page,
+		Labels:     []string{"docs", " Docs ", "", "q3"},
+	})
+	assert.Equal(t, "Write spec", card.Title)
+	assert.Equal(t, []string{"docs", "q3"}, card.Labels)
+
+	_, err = s.CreateCard(board.ID, CardInput{ColumnID: col, Title: "x", AssigneeID: &outsider}, 1)
+	assert.Equal(t, ErrInvalidAssignee, err)
+	other := uint(101)
+	_, err = s.CreateCard(board.ID, CardInput{ColumnID: col, Title: "x", PageID: &other}, 1)
+	assert.Equal(t, pages.ErrPageNotFound, err)
+	_, err = s.CreateCard(board.ID, CardInput{ColumnID: col, Title: "x"}, 2)
+	assert.Equal(t, ErrForbidden, err)
+	assert.Equal(t, ErrForbidden, s.DeleteBoard(board.ID, 2))
+}
+
+func TestBoards_WIPLimitsAndColumnRules(t *testing.T) {
+	repo := newMemRepo()
+	s := NewService(repo, stubPages{}, stubWorkspaces{}).(*service)
+	fixed := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return fixed }
+
+	board, err := s.CreateBoard(BoardInput{Name: "Flow"}, 1)
+	require.NoError(t, err)
+	todo, doing, done := board.Columns[0], board.Columns[1], board.Columns[2]
+	assert.Equal(t, []ColumnAction{ActionSetCompleted}, done.OnEnter)
+
+	owner := uint(1)
+	a := createCard(t, s, board.ID, CardInput{ColumnID: todo.ID, Title: "A", AssigneeID: &owner})
+	b := createCard(t, s, board.ID, CardInput{ColumnID: todo.ID, Title: "B"})
+
+	_, err = s.UpdateColumn(board.ID, doing.ID, ColumnInput{Name: "In progress", WIPLimit: 1}, 1)
+	require.NoError(t, err)
+	_, err = s.UpdateColumn(board.ID, todo.ID, ColumnInput{Name: "Backlog", OnEnter: []ColumnAction{ActionUnassign, ActionClearCompleted}}, 1)
+	require.NoError(t, err)
+
+	_, err = s.MoveCard(board.ID, a.ID, MoveInput{ColumnID: doing.ID}, 1)
+	require.NoError(t, err)
+	_, err = s.MoveCard(board.ID, b.ID, MoveInput{ColumnID: doing.ID}, 1)
+	assert.Equal(t, ErrWIPLimitReached, err)
+	// Reordering inside a full column is not an entry.
+	_, err = s.MoveCard(board.ID, a.ID, MoveInput{}, 1)
+	require.NoError(t, err)
+
+	_, err = s.UpdateColumn(board.ID, doing.ID, ColumnInput{Name: "In progress", WIPLimit: 1, WIPPolicy: WIPWarn}, 1)
+	require.NoError(t, err)
+	moved, err := s.MoveCard(board.ID, b.ID, MoveInput{ColumnID: doing.ID}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "In progress is over its WIP limit (2/1)", moved.Warning)
+
+	moved, err = s.MoveCard(board.ID, a.ID, MoveInput{ColumnID: done.ID}, 1)
+	require.NoError(t, err)
+	require.NotNil(t, moved.Card.CompletedAt)
+	assert.Equal(t, fixed, *moved.Card.CompletedAt)
+	assert.Empty(t, moved.Warning)
+
+	moved, err = s.MoveCard(board.ID, a.ID, MoveInput{ColumnID: todo.ID}, 1)
+	require.NoError(t, err)
+	assert.Nil(t, moved.Card.CompletedAt)
+	assert.Nil(t, moved.Card.AssigneeID)
+}
+
+func TestBoards_CreateCardEntersTheColumn(t *testing.T) {
+	repo := newMemRepo()
+	s := NewService(repo, stubPages{}, stubWorkspaces{}).(*service)
+	fixed := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return fixed }
+
+	board, err := s.CreateBoard(BoardInput{Name: "Flow"}, 1)
+	require.NoError(t, err)
+	doing, done := board.Columns[1], board.Columns[2]
+	_, err = s.UpdateColumn(board.ID, doing.ID, ColumnInput{Name: "In progress", WIPLimit: 1}, 1)
+	require.NoError(t, err)
+
+	createCard(t, s, board.ID, CardInput{ColumnID: doing.ID, Title: "A"})
+	_, err = s.CreateCard(board.ID, CardInput{ColumnID: doing.ID, Title: "B"}, 1)
+	assert.Equal(t, ErrWIPLimitReached, err)
+	cards, _ := repo.GetCards(doing.ID)
+	assert.Len(t, cards, 1)
+
+	_, err = s.UpdateColumn(board.ID, doing.ID, ColumnInput{Name: "In progress", WIPLimit: 1, WIPPolicy: WIPWarn}, 1)
+	require.NoError(t, err)
+	created, err := s.CreateCard(board.ID, CardInput{ColumnID: doing.ID, Title: "B"}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "In progress is over its WIP limit (2/1)", created.Warning)
+
+	finished := createCard(t, s, board.ID, CardInput{ColumnID: done.ID, Title: "C"})
+	require.NotNil(t, finished.CompletedAt)
+	assert.Equal(t, fixed, *finished.CompletedAt)
+}
+
+func createCard(t *testing.T, s Service, boardID uint, input CardInput) *Card {
+	t.Helper()
+	created, err := s.CreateCard(boardID, input, 1)
+	require.NoError(t, err)
+	return created.Card
+}
@@ -0,0 +1,356 @@
+// Package collab hosts collaborative editing sessions. Each open page is
+// held as a replicated text (an RGA sequence CRDT) that clients edit
+// concurrently over a WebSocket; the server merges their operations,
+// relays them to other replicas and saves the converged text back to the
+// page at intervals.
+package collab
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidOp      = errors.New("invalid operation")
+	ErrTooManyPending = errors.New("too many operations waiting for their dependencies")
+)
+
+const (
+	// maxPending bounds operations held back because something they
+	// depend on has not arrived yet.
+	maxPending = 10000
+	// seedClient authors the initial text of a document. Seeding is
+	// deterministic, so replicas seeding from the same page version agree.
+	seedClient = 0
+)
+
+// ID identifies an operation. Seq counts the operations of one client
+// from 1; an insert of n runes takes n consecutive Seqs, one per rune.
+type ID struct {
+	Client uint64 `json:"client"`
+	Seq    uint64 `json:"seq"`
+}
+
+// Op is an operation on a document. An insert places Text after the rune
+// Origin, or at the start when Origin is nil; its runes take the IDs
+// Seq, Seq+1, … and the Lamport clocks Lamport, Lamport+1, … A delete
+// removes the rune Target.
+type Op struct {
+	ID      ID     `json:"id"`
+	Origin  *ID    `json:"origin,omitempty"`
+	Lamport uint64 `json:"lamport,omitempty"`
+	Text    string `json:"text,omitempty"`
+	Target  *ID    `json:"target,omitempty"`
+}
+
+// span is how many Seqs the operation takes.
+func (o Op) span() uint64 {
+	if o.Target != nil {
+		return 1
+	}
+	return uint64(utf8.RuneCountInString(o.Text))
+}
+
+func (o Op) valid() bool {
+	if o.ID.Seq == 0 {
+		return false
+	}
+	if o.Target != nil {
+		return o.Text == "" && o.Origin == nil
+	}
+	return o.Text != "" && o.Lamport > 0 && utf8.ValidString(o.Text)
+}
+
+// split breaks an insert into pieces of at most n runes. The pieces are
+// equivalent to the original: each continues after the last rune of the
+// previous one.
+func (o Op) split(n int) []Op {
+	runes := []rune(o.Text)
+	if o.Target != nil || len(runes) <= n {
+		return []Op{o}
+	}
+	var parts []Op
+	for i := 0; i < len(runes); i += n {
+		part := o
+		part.ID.Seq = o.ID.Seq + uint64(i)
+		part.Lamport = o.Lamport + uint64(i)
+		part.Text = string(runes[i:min(i+n, len(runes))])
+		if i > 0 {
+			part.Origin = &ID{Client: o.ID.Client, Seq: part.ID.Seq - 1}
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// StateVector maps each client to the highest Seq integrated from it.
+type StateVector map[uint64]uint64
+
+func (sv StateVector) covers(id ID) bool {
+	return id.Seq <= sv[id.Client]
+}
+
+func (sv StateVector) clone() StateVector {
+	c := make(StateVector, len(sv))
+	for k, v := range sv {
+		c[k] = v
+	}
+	return c
+}
+
+type item struct {
+	id      ID
+	lamport uint64
+	r       rune
+	deleted bool
+}
+
+// outranks reports whether it stays ahead of a concurrent insert with the
+// given ID and clock when both follow the same rune. Later inserts come
+// first; ties break on the client.
+func (it *item) outranks(id ID, lamport uint64) bool {
+	if it.lamport != lamport {
+		return it.lamport > lamport
+	}
+	return it.id.Client > id.Client
+}
+
+// Doc is a replicated text. Operations may arrive in any order and more
+// than once: those whose dependencies are missing wait until they
+// arrive, and duplicates are ignored, so every replica that has seen the
+// same operations holds the same text. Doc is not safe for concurrent use.
+type Doc struct {
+	items   []*item // document order, including deleted runes
+	byID    map[ID]*item
+	sv      StateVector
+	clocks  map[uint64]uint64 // highest Lamport clock per client
+	lamport uint64
+	log     []Op
+	pending []Op
+}
+
+func NewDoc() *Doc {
+	return &Doc{byID: map[ID]*item{}, sv: StateVector{}, clocks: map[uint64]uint64{}}
+}
+
+// SeedDoc returns a document holding text, authored by seedClient.
+func SeedDoc(text string) *Doc {
+	d := NewDoc()
+	if text != "" {
+		d.Apply([]Op{{ID: ID{Client: seedClient, Seq: 1}, Lamport: 1, Text: text}})
+	}
+	return d
+}
+
+type opStatus int
+
+const (
+	opReady opStatus = iota
+	opWait
+	opDuplicate
+	opInvalid
+)
+
+// Apply integrates ops and returns those that took effect, including
+// earlier ones that were waiting on them. Invalid operations are dropped
+// and reported with ErrInvalidOp after the rest are applied.
+func (d *Doc) Apply(ops []Op) ([]Op, error) {
+	var err error
+	for _, op := range ops {
+		if !op.valid() {
+			err = ErrInvalidOp
+			continue
+		}
+		d.pending = append(d.pending, op)
+	}
+
+	var applied []Op
+	for progress := true; progress; {
+		progress = false
+		rest := d.pending[:0]
+		for _, op := range d.pending {
+			switch d.check(op) {
+			case opReady:
+				d.integrate(op)
+				applied = append(applied, op)
+				progress = true
+			case opWait:
+				rest = append(rest, op)
+			case opInvalid:
+				err = ErrInvalidOp
+			}
+		}
+		clear(d.pending[len(rest):])
+		d.pending = rest
+	}
+
+	if len(d.pending) > maxPending {
+		d.pending = nil
+		return applied, ErrTooManyPending
+	}
+	return applied, err
+}
+
+func (d *Doc) check(op Op) opStatus {
+	have := d.sv[op.ID.Client]
+	if op.ID.Seq <= have {
+		if op.ID.Seq+op.span()-1 <= have {
+			return opDuplicate
+		}
+		return opInvalid
+	}
+	if op.ID.Seq > have+1 {
+		return opWait
+	}
+
+	if op.Target != nil {
+		if d.byID[*op.Target] == nil {
+			return opWait
+		}
+		return opReady
+	}
+	if op.Lamport <= d.clocks[op.ID.Client] {
+		return opInvalid
+	}
+	if op.Origin != nil {
+		origin := d.byID[*op.Origin]
+		if origin == nil {
+			return opWait
+		}
+		// Clocks must grow along the document or replicas could order
+		// concurrent inserts differently.
+		if op.Lamport <= origin.lamport {
+			return opInvalid
+		}
+	}
+	return opReady
+}
+
+func (d *Doc) integrate(op Op) {
+	client := op.ID.Client
+	if op.Target != nil {
+		d.byID[*op.Target].deleted = true
+	} else {
+		pos := 0
+		if op.Origin != nil {
+			pos = d.indexOf(*op.Origin) + 1
+		}
+		for pos < len(d.items) && d.items[pos].outranks(op.ID, op.Lamport) {
+			pos++
+		}
+
+		runes := []rune(op.Text)
+		added := make([]*item, len(runes))
+		for i, r := range runes {
+			it := &item{id: ID{Client: client, Seq: op.ID.Seq + uint64(i)}, lamport: op.Lamport + uint64(i), r: r}
+			added[i] = it
+			d.byID[it.id] = it
+		}
+		d.items = slices.Insert(d.items, pos, added...)
+
+		last := op.Lamport + uint64(len(runes)) - 1
+		d.clocks[client] = last
+		d.lamport = max(d.lamport, last)
+	}
+	d.sv[client] = op.ID.Seq + op.span() - 1
+	d.log = append(d.log, op)
+}
+
+func (d *Doc) indexOf(id ID) int {
+	for i, it := range d.items {
+		if it.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Text returns the visible text.
+func (d *Doc) Text() string {
+	var b strings.Builder
+	for _, it := range d.items {
+		if !it.deleted {
+			b.WriteRune(it.r)
+		}
+	}
+	return b.String()
+}
+
+// StateVector returns what the document has integrated so far.
+func (d *Doc) StateVector() StateVector {
+	return d.sv.clone()
+}
+
+// Missing returns the operations a replica at sv has not seen, in an
+// order it can apply them.
+func (d *Doc) Missing(sv StateVector) []Op {
+	var ops []Op
+	for _, op := range d.log {
+		if !sv.covers(op.ID) {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// Ops returns every operation the document has integrated.
+func (d *Doc) Ops() []Op {
+	return slices.Clone(d.log)
+}
+
+// Insert inserts text before the rune at visible offset pos on behalf of
+// client and returns the operation, already applied.
+func (d *Doc) Insert(client uint64, pos int, text string) (Op, error) {
+	var origin *ID
+	if pos > 0 {
+		id, ok := d.visibleID(pos - 1)
+		if !ok {
+			return Op{}, ErrInvalidOp
+		}
+		origin = &id
+	}
+	op := Op{ID: ID{Client: client, Seq: d.sv[client] + 1}, Origin: origin, Lamport: d.lamport + 1, Text: text}
+	if _, err := d.Apply([]Op{op}); err != nil {
+		return Op{}, err
+	}
+	return op, nil
+}
+
+// Delete removes n runes from visible offset pos on behalf of client and
+// returns the operations, already applied.
+func (d *Doc) Delete(client uint64, pos, n int) ([]Op, error) {
+	targets := make([]ID, 0, n)
+	for i := 0; i < n; i++ {
+		id, ok := d.visibleID(pos + i)
+		if !ok {
+			return nil, ErrInvalidOp
+		}
+		targets = append(targets, id)
+	}
+	ops := make([]Op, len(targets))
+	for i := range targets {
+		ops[i] = Op{ID: ID{Client: client, Seq: d.sv[client] + 1 + uint64(i)}, Target: &targets[i]}
+	}
+	if _, err := d.Apply(ops); err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+func (d *Doc) visibleID(pos int) (ID, bool) {
+	if pos < 0 {
+		return ID{}, false
+	}
+	for _, it := range d.items {
+		if it.deleted {
+			continue
+		}
+		if pos == 0 {
+			return it.id, true
+		}
+		pos--
+	}
+	return ID{}, false
+}
@@ -0,0 +1,156 @@
+package collab
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrentInsertsAtTheSamePlaceConverge(t *testing.T) {
+	a, b := SeedDoc("ac"), SeedDoc("ac")
+
+	opA, err := a.Insert(1<<32+1, 1, "X")
+	require.NoError(t, err)
+	opB, err := b.Insert(1<<32+2, 1, "YY")
+	require.NoError(t, err)
+
+	_, err = a.Apply([]Op{opB})
+	require.NoError(t, err)
+	_, err = b.Apply([]Op{opA})
+	require.NoError(t, err)
+
+	assert.Equal(t, a.Text(), b.Text())
+	assert.Equal(t, "aYYXc", a.Text(), "ties on the clock break on the client")
+}
+
+func TestDeleteAndInsertAroundTheSameRune(t *testing.T) {
+	a, b := SeedDoc("abc"), SeedDoc("abc")
+
+	del, err := a.Delete(1<<32+1, 1, 1)
+	require.NoError(t, err)
+	ins, err := b.Insert(1<<32+2, 2, "!")
+	require.NoError(t, err)
+
+	_, err = a.Apply([]Op{ins})
+	require.NoError(t, err)
+	_, err = b.Apply(del)
+	require.NoError(t, err)
+
+	assert.Equal(t, "a!c", a.Text())
+	assert.Equal(t, "a!c", b.Text())
+}
+
+func TestApplyWaitsForDependenciesAndIgnoresDuplicates(t *testing.T) {
+	src := SeedDoc("")
+	first, err := src.Insert(1<<32, 0, "hello")
+	require.NoError(t, err)
+	second, err := src.Insert(1<<32, 5, " world")
+	require.NoError(t, err)
+	del, err := src.Delete(1<<32, 0, 1)
+	require.NoError(t, err)
+
+	dst := SeedDoc("")
+	applied, err := dst.Apply(append(del, second))
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+	assert.Equal(t, "", dst.Text())
+
+	applied, err = dst.Apply([]Op{first, first})
+	require.NoError(t, err)
+	assert.Len(t, applied, 3)
+	assert.Equal(t, "ello world", dst.Text())
+	assert.Equal(t, src.StateVector(), dst.StateVector())
+}
+
+func TestApplyRejectsInvalidOps(t *testing.T) {
+	d := SeedDoc("abc")
+	_, err := d.Apply([]Op{
+		{ID: ID{Client: 7, Seq: 1}},
+		{ID: ID{Client: 8, Seq: 1}, Lamport: 1, Origin: &ID{Client: seedClient, Seq: 2}, Text: "x"},
+	})
+	assert.ErrorIs(t, err, ErrInvalidOp)
+	assert.Equal(t, "abc", d.Text())
+}
+
+func TestSplitOpsAreEquivalent(t *testing.T) {
+	src := SeedDoc("ab")
+	op, err := src.Insert(1<<32, 1, "0123456789")
+	require.NoError(t, err)
+
+	dst := SeedDoc("ab")
+	parts := op.split(3)
+	require.Len(t, parts, 4)
+	rand.New(rand.NewSource(1)).Shuffle(len(parts), func(i, j int) { parts[i], parts[j] = parts[j], parts[i] })
+	_, err = dst.Apply(parts)
+	require.NoError(t, err)
+	assert.Equal(t, src.Text(), dst.Text())
+	assert.Equal(t, src.StateVector(), dst.StateVector())
+}
+
+// TestRandomConcurrentEditorsConverge simulates editors that type and
+// delete at random while their operations reach each other late, out of
+// order and more than once.
+func TestRandomConcurrentEditorsConverge(t *testing.T) {
+	for seed := int64(1); seed <= 50; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		const editors = 4
+		docs := make([]*Doc, editors)
+		inbox := make([][]Op, editors)
+		for i := range docs {
+			docs[i] = SeedDoc("The quick brown fox")
+		}
+
+		for step := 0; step < 200; step++ {
+			i := rng.Intn(editors)
+			d := docs[i]
+			client := uint64(1<<32 + i)
+
+			var ops []Op
+			if n := len([]rune(d.Text())); n > 0 && rng.Intn(3) == 0 {
+				pos := rng.Intn(n)
+				del, err := d.Delete(client, pos, 1+rng.Intn(min(3, n-pos)))
+				require.NoError(t, err)
+				ops = del
+			} else {
+				op, err := d.Insert(client, rng.Intn(len([]rune(d.Text()))+1), string(rune('a'+rng.Intn(26))))
+				require.NoError(t, err)
+				ops = []Op{op}
+			}
+			for j := range inbox {
+				if j != i {
+					inbox[j] = append(inbox[j], ops...)
+				}
+			}
+
+			// Deliver a random, shuffled share of someone's inbox, and
+			// sometimes deliver it twice.
+			j := rng.Intn(editors)
+			k := rng.Intn(len(inbox[j]) + 1)
+			batch := append([]Op(nil), inbox[j][:k]...)
+			rng.Shuffle(len(batch), func(a, b int) { batch[a], batch[b] = batch[b], batch[a] })
+			if rng.Intn(4) == 0 {
+				batch = append(batch, batch...)
+			}
+			_, err := docs[j].Apply(batch)
+			require.NoError(t, err)
+			inbox[j] = inbox[j][k:]
+		}
+
+		// Catch everyone up through state-vector sync, as a reconnecting
+		// client would.
+		for i := range docs {
+			for j := range docs {
+				if i != j {
+					_, err := docs[i].Apply(docs[j].Missing(docs[i].StateVector()))
+					require.NoError(t, err)
+				}
+			}
+		}
+		for i := 1; i < editors; i++ {
+			require.Equal(t, docs[0].Text(), docs[i].Text(), "seed %d", seed)
+			require.Equal(t, docs[0].StateVector(), docs[i].StateVector(), "seed %d", seed)
+		}
+	}
+}
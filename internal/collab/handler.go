@@ -0,0 +1,65 @@
+package collab
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"flowboard-backend-go/internal/audit"
+	"flowboard-backend-go/internal/pages"
+	"flowboard-backend-go/internal/realtime"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+type Handler struct {
+	manager  *Manager
+	auth     *realtime.Authenticator
+	upgrader websocket.Upgrader
+}
+
+func NewHandler(manager *Manager, auth *realtime.Authenticator, allowOrigin func(origin string) bool) *Handler {
+	return &Handler{manager: manager, auth: auth, upgrader: realtime.NewUpgrader(allowOrigin)}
+}
+
+// ServeWS joins the editing session of a page. Viewers follow along
+// read-only.
+func (h *Handler) ServeWS(c *gin.Context) {
+	userID, ok := h.auth.Authenticate(c)
+	if !ok {
+		return
+	}
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	page, err := h.manager.pages.GetPageByID(uint(id64), userID)
+	if err != nil {
+		if errors.Is(err, pages.ErrPageNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	canEdit, err := h.manager.canEdit(page, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already written the error response.
+		return
+	}
+	p := newPeer(h.manager, conn, userID, canEdit)
+	p.req = audit.FromRequest(c)
+	p.req.ActorID = &userID
+	h.manager.join(page, p)
+	go p.writePump()
+	go p.readPump()
+}
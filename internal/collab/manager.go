@@ -0,0 +1,529 @@
+package collab
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"flowboard-backend-go/internal/audit"
+	"flowboard-backend-go/internal/pages"
+	"flowboard-backend-go/internal/workspaces"
+	"flowboard-backend-go/pkg/logger"
+	"flowboard-backend-go/pkg/pubsub"
+)
+
+// Topic carries operations and sync messages between replicas.
+const Topic = "collab"
+
+const (
+	// relayBudget bounds the encoded size of one relay message, well
+	// below the Postgres NOTIFY limit.
+	relayBudget = 6000
+	// relaySplit is how many runes of an insert travel in one piece.
+	relaySplit = 512
+	// incomingBuffer is how many relay messages may wait for the manager.
+	incomingBuffer = 1024
+	publishTimeout = 5 * time.Second
+)
+
+// Relay message kinds.
+const (
+	relayOps   = "ops"   // operations made by a replica's clients
+	relaySync  = "sync"  // a replica opened a session and asks for state
+	relayState = "state" // the answer to sync, or a replica's new epoch
+	relaySaved = "saved" // a replica saved the text as page Version
+	relayCheck = "check" // a replica's periodic state vector
+)
+
+// relayMessage travels between replicas on Topic. To, when set, names
+// the only replica meant to act on it.
+type relayMessage struct {
+	Kind    string      `json:"kind"`
+	PageID  uint        `json:"pageId"`
+	Epoch   int         `json:"epoch"`
+	From    string      `json:"from"`
+	To      string      `json:"to,omitempty"`
+	Ops     []Op        `json:"ops,omitempty"`
+	SV      StateVector `json:"sv,omitempty"`
+	Version int         `json:"version,omitempty"`
+}
+
+// PageStore loads and saves pages with access checks; satisfied by
+// pages.Service.
+type PageStore interface {
+	GetPageByID(id, userID uint) (*pages.Page, error)
+	UpdatePage(id uint, input pages.PageInput, userID uint) (*pages.Page, error)
+}
+
+// WorkspaceAccess reports workspace membership; satisfied by
+// workspaces.Service.
+type WorkspaceAccess interface {
+	MemberRole(workspaceID, userID uint) (workspaces.Role, error)
+}
+
+// Manager hosts the editing sessions of this replica and keeps them in
+// step with sessions for the same pages on other replicas.
+type Manager struct {
+	ps          pubsub.PubSub
+	pages       PageStore
+	workspaces  WorkspaceAccess
+	audit       audit.Recorder
+	replica     string
+	incoming    chan relayMessage
+	unsubscribe func()
+
+	mu       sync.Mutex
+	sessions map[uint]*session
+}
+
+// Option configures optional collaborators of the manager.
+type Option func(*Manager)
+
+// WithAuditLog records every save of a collaborative document as a page
+// update by the user whose changes it holds.
+func WithAuditLog(r audit.Recorder) Option {
+	return func(m *Manager) { m.audit = r }
+}
+
+// NewManager subscribes to relay messages on ps. Call Run to process them
+// and save documents.
+func NewManager(ps pubsub.PubSub, pages PageStore, workspaces WorkspaceAccess, opts ...Option) *Manager {
+	id := make([]byte, 8)
+	rand.Read(id)
+	m := &Manager{
+		ps:         ps,
+		pages:      pages,
+		workspaces: workspaces,
+		replica:    hex.EncodeToString(id),
+		incoming:   make(chan relayMessage, incomingBuffer),
+		sessions:   map[uint]*session{},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.unsubscribe = ps.Subscribe(Topic, m.receive)
+	return m
+}
+
+// Run processes relay messages and saves changed documents every
+// interval until ctx is done, then saves everything and disconnects
+// all clients.
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer m.shutdown()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-m.incoming:
+			m.handleRelay(msg)
+		case <-ticker.C:
+			for _, s := range m.openSessions() {
+				m.flush(s)
+				m.check(s)
+			}
+		}
+	}
+}
+
+// receive is the pub/sub handler; it must not block.
+func (m *Manager) receive(payload []byte) {
+	var msg relayMessage
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		logger.Log.Warnw("Ignoring malformed collab message", "error", err)
+		return
+	}
+	if msg.From == m.replica || (msg.To != "" && msg.To != m.replica) {
+		return
+	}
+	select {
+	case m.incoming <- msg:
+	default:
+		logger.Log.Warnw("Collab message dropped, manager is behind", "pageID", msg.PageID, "kind", msg.Kind)
+	}
+}
+
+func (m *Manager) handleRelay(msg relayMessage) {
+	m.mu.Lock()
+	s := m.sessions[msg.PageID]
+	m.mu.Unlock()
+	if s == nil {
+		return
+	}
+
+	switch msg.Kind {
+	case relayOps, relayState:
+		m.receiveOps(s, msg)
+	case relaySync:
+		m.answerSync(s, msg)
+	case relayCheck:
+		m.answerCheck(s, msg)
+	case relaySaved:
+		s.mu.Lock()
+		s.noteSaved(msg.Version)
+		s.mu.Unlock()
+	}
+}
+
+// receiveOps merges operations from another replica. Replicas that
+// disagree on the epoch settle on the newer one; a session that holds
+// nothing but its seed adopts whatever epoch it hears first, which is how
+// a replica joins a session already running elsewhere.
+func (m *Manager) receiveOps(s *session, msg relayMessage) {
+	s.mu.Lock()
+	if msg.Version > 0 {
+		s.noteSaved(msg.Version)
+	}
+
+	switch {
+	case msg.Epoch == s.epoch:
+		applied, err := s.doc.Apply(msg.Ops)
+		if err != nil {
+			logger.Log.Warnw("Merging relayed operations failed", "pageID", s.pageID, "error", err)
+		}
+		s.fresh = false
+		s.broadcast(nil, message{Type: msgUpdate, Epoch: s.epoch, Ops: applied})
+		s.mu.Unlock()
+
+	case msg.Epoch > s.epoch || s.fresh:
+		s.adopt(msg.Epoch, NewDoc())
+		if _, err := s.doc.Apply(msg.Ops); err != nil {
+			logger.Log.Warnw("Merging relayed operations failed", "pageID", s.pageID, "error", err)
+		}
+		s.resetPeers()
+		sv := s.doc.StateVector()
+		s.mu.Unlock()
+		// Live operations are only a fragment of the new epoch.
+		if msg.Kind != relayState {
+			m.publish(relayMessage{Kind: relaySync, PageID: s.pageID, Epoch: msg.Epoch, SV: sv})
+		}
+
+	default:
+		// The sender is behind; tell it about our epoch once.
+		if s.told[msg.From] == s.epoch {
+			s.mu.Unlock()
+			return
+		}
+		s.told[msg.From] = s.epoch
+		ops, epoch, version := s.doc.Ops(), s.epoch, s.version
+		s.mu.Unlock()
+		m.publishOps(relayState, s.pageID, epoch, ops, msg.From, version)
+	}
+}
+
+// answerSync sends a replica what it is missing, or the whole document
+// when it is on another epoch.
+func (m *Manager) answerSync(s *session, msg relayMessage) {
+	s.mu.Lock()
+	ops := s.doc.Ops()
+	if msg.Epoch == s.epoch {
+		ops = s.doc.Missing(msg.SV)
+	}
+	epoch, version := s.epoch, s.version
+	s.mu.Unlock()
+	m.publishOps(relayState, s.pageID, epoch, ops, msg.From, version)
+}
+
+// check publishes the session's state vector. Relay messages are not
+// guaranteed to arrive; other replicas answer with whatever this one
+// turns out to be missing.
+func (m *Manager) check(s *session) {
+	s.mu.Lock()
+	msg := relayMessage{Kind: relayCheck, PageID: s.pageID, Epoch: s.epoch, SV: s.doc.StateVector()}
+	s.mu.Unlock()
+	m.publish(msg)
+}
+
+// answerCheck sends a replica the operations its state vector lacks, if
+// any. A replica on another epoch is treated like one that sent
+// operations, so the two settle on the newer epoch.
+func (m *Manager) answerCheck(s *session, msg relayMessage) {
+	s.mu.Lock()
+	if msg.Epoch != s.epoch {
+		s.mu.Unlock()
+		msg.Ops = nil
+		m.receiveOps(s, msg)
+		return
+	}
+	ops := s.doc.Missing(msg.SV)
+	epoch, version := s.epoch, s.version
+	s.mu.Unlock()
+	if len(ops) > 0 {
+		m.publishOps(relayState, s.pageID, epoch, ops, msg.From, version)
+	}
+}
+
+// join adds p to the session for page, opening one if needed.
+func (m *Manager) join(page *pages.Page, p *peer) {
+	m.mu.Lock()
+	s := m.sessions[page.ID]
+	opened := s == nil
+	if opened {
+		s = newSession(page)
+		m.sessions[page.ID] = s
+	}
+	s.mu.Lock()
+	s.peers[p] = struct{}{}
+	p.session = s
+	hello := relayMessage{Kind: relaySync, PageID: s.pageID, Epoch: s.epoch, SV: s.doc.StateVector()}
+	s.mu.Unlock()
+	m.mu.Unlock()
+
+	if opened {
+		m.publish(hello)
+	}
+}
+
+// leave removes p from its session. The last one out saves the document
+// and closes the session.
+func (m *Manager) leave(p *peer) {
+	s := p.session
+	s.mu.Lock()
+	delete(s.peers, p)
+	empty := len(s.peers) == 0
+	s.mu.Unlock()
+	if !empty {
+		return
+	}
+
+	m.flush(s)
+	m.mu.Lock()
+	s.mu.Lock()
+	if len(s.peers) == 0 && m.sessions[s.pageID] == s {
+		delete(m.sessions, s.pageID)
+	}
+	s.mu.Unlock()
+	m.mu.Unlock()
+}
+
+// update merges operations from a client, passes them on to the other
+// clients and replicas and reports what the client should hear back.
+func (m *Manager) update(p *peer, epoch int, ops []Op) error {
+	s := p.session
+	s.mu.Lock()
+	if !p.synced || epoch != s.epoch {
+		// The client has been or is about to be reset; it rebases its
+		// changes on the new epoch.
+		s.mu.Unlock()
+		return nil
+	}
+	applied, err := s.applyFrom(p, ops)
+	relay := s.ownOps(applied)
+	s.mu.Unlock()
+
+	m.publishOps(relayOps, s.pageID, epoch, relay, "", 0)
+	return err
+}
+
+// sync answers a client's sync message: with the operations it is missing
+// when it is on the current epoch, or with the whole document otherwise.
+func (m *Manager) sync(p *peer, msg message) error {
+	s := p.session
+	s.mu.Lock()
+	p.synced = true
+	if msg.Epoch != s.epoch {
+		s.send(p, message{Type: msgReset, Epoch: s.epoch, Ops: s.doc.Ops(), ReadOnly: !p.canEdit})
+		s.mu.Unlock()
+		return nil
+	}
+
+	var err error
+	var relay []Op
+	if len(msg.Ops) > 0 {
+		var applied []Op
+		applied, err = s.applyFrom(p, msg.Ops)
+		relay = s.ownOps(applied)
+	}
+	s.send(p, message{
+		Type:     msgSync,
+		Epoch:    s.epoch,
+		SV:       s.doc.StateVector(),
+		Ops:      s.doc.Missing(msg.SV),
+		ReadOnly: !p.canEdit,
+	})
+	epoch := s.epoch
+	s.mu.Unlock()
+
+	m.publishOps(relayOps, s.pageID, epoch, relay, "", 0)
+	return err
+}
+
+// flush re-checks every connected user's access, notices edits made to
+// the page outside the session and saves the document if clients changed
+// it. Edits from outside start a new epoch from the page as it is now;
+// clients rebase their unsent changes onto it.
+func (m *Manager) flush(s *session) {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	users := s.users()
+	dirty, editor, edited, text := s.dirty, s.editor, s.edited, s.doc.Text()
+	s.dirty = false
+	s.mu.Unlock()
+
+	restore := func() {
+		if dirty {
+			s.mu.Lock()
+			s.dirty = true
+			s.mu.Unlock()
+		}
+	}
+
+	var page *pages.Page
+	for _, userID := range users {
+		p, err := m.pages.GetPageByID(s.pageID, userID)
+		if errors.Is(err, pages.ErrPageNotFound) {
+			s.mu.Lock()
+			s.disconnect(userID, "page is no longer available")
+			s.mu.Unlock()
+			continue
+		}
+		if err != nil {
+			logger.Log.Errorw("Loading collaborative page failed", "pageID", s.pageID, "error", err)
+			restore()
+			return
+		}
+		page = p
+	}
+	if page == nil && dirty {
+		// Everyone left before the last changes were saved; the editor
+		// may still save them if they can see the page.
+		p, err := m.pages.GetPageByID(s.pageID, editor)
+		if err != nil {
+			return
+		}
+		page = p
+	}
+	if page == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.external(page.Version) {
+		// Give a save announced by another replica a tick to arrive
+		// before treating the change as foreign.
+		if s.suspect != page.Version {
+			s.suspect = page.Version
+			s.mu.Unlock()
+			restore()
+			return
+		}
+		s.adopt(page.Version, SeedDoc(page.Content))
+		s.noteSaved(page.Version)
+		s.resetPeers()
+		ops := s.doc.Ops()
+		s.mu.Unlock()
+		m.publishOps(relayState, s.pageID, page.Version, ops, "", page.Version)
+		return
+	}
+	s.mu.Unlock()
+
+	if !dirty || text == page.Content {
+		return
+	}
+	// Save over the version checked above only. If the page moved on in
+	// between, the next flush sees the new version: another replica's
+	// save is announced to us, anything else is an outside edit.
+	input := pages.PageInput{Title: page.Title, Content: text, Version: &page.Version}
+	updated, err := m.pages.UpdatePage(s.pageID, input, editor)
+	if errors.Is(err, pages.ErrVersionConflict) {
+		restore()
+		return
+	}
+	if err != nil {
+		logger.Log.Errorw("Saving collaborative page failed", "pageID", s.pageID, "userID", editor, "error", err)
+		restore()
+		return
+	}
+
+	s.mu.Lock()
+	s.noteSaved(updated.Version)
+	epoch := s.epoch
+	s.mu.Unlock()
+	m.publish(relayMessage{Kind: relaySaved, PageID: s.pageID, Epoch: epoch, Version: updated.Version})
+	if m.audit != nil {
+		m.audit.Record(edited, audit.PageUpdated, audit.Target{Type: audit.TargetPage, ID: s.pageID},
+			map[string]any{"title": updated.Title, "version": updated.Version, "collab": true})
+	}
+}
+
+// canEdit reports whether userID may change page: its owner for personal
+// pages, an editor or above for workspace pages.
+func (m *Manager) canEdit(page *pages.Page, userID uint) (bool, error) {
+	if page.WorkspaceID == nil {
+		return page.UserID == userID, nil
+	}
+	role, err := m.workspaces.MemberRole(*page.WorkspaceID, userID)
+	if err != nil {
+		return false, err
+	}
+	return role.AtLeast(workspaces.RoleEditor), nil
+}
+
+func (m *Manager) openSessions() []*session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]*session, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		list = append(list, s)
+	}
+	return list
+}
+
+func (m *Manager) shutdown() {
+	m.unsubscribe()
+	for _, s := range m.openSessions() {
+		m.flush(s)
+		s.mu.Lock()
+		for p := range s.peers {
+			p.close()
+		}
+		s.mu.Unlock()
+	}
+}
+
+// publishOps relays ops in messages that fit the relay budget. Inserts
+// are split as needed; at least one message is sent so the receiver
+// learns the epoch even of an empty document.
+func (m *Manager) publishOps(kind string, pageID uint, epoch int, ops []Op, to string, version int) {
+	if kind == relayOps && len(ops) == 0 {
+		return
+	}
+	msg := relayMessage{Kind: kind, PageID: pageID, Epoch: epoch, To: to, Version: version}
+	size := 0
+	for _, op := range ops {
+		for _, part := range op.split(relaySplit) {
+			// A rune may take six bytes once JSON-escaped.
+			cost := 96 + 6*utf8.RuneCountInString(part.Text)
+			if size+cost > relayBudget && len(msg.Ops) > 0 {
+				m.publish(msg)
+				msg.Ops, size = nil, 0
+			}
+			msg.Ops = append(msg.Ops, part)
+			size += cost
+		}
+	}
+	m.publish(msg)
+}
+
+func (m *Manager) publish(msg relayMessage) {
+	msg.From = m.replica
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		logger.Log.Errorw("Encoding collab message failed", "pageID", msg.PageID, "error", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+	if err := m.ps.Publish(ctx, Topic, payload); err != nil {
+		logger.Log.Errorw("Publishing collab message failed", "pageID", msg.PageID, "kind", msg.Kind, "error", err)
+	}
+}
@@ -0,0 +1,424 @@
+package collab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"flowboard-backend-go/internal/audit"
+	"flowboard-backend-go/internal/pages"
+	"flowboard-backend-go/internal/realtime"
+	"flowboard-backend-go/internal/workspaces"
+	"flowboard-backend-go/pkg/pubsub"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubPages is a page store shared by every replica in a test.
+// beforeSave, when set, runs at the start of the next UpdatePage.
+type stubPages struct {
+	mu         sync.Mutex
+	pages      map[uint]*pages.Page
+	saves      int
+	beforeSave func()
+}
+
+func (s *stubPages) GetPageByID(id, userID uint) (*pages.Page, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.pages[id]
+	if p == nil || (p.WorkspaceID == nil && p.UserID != userID) {
+		return nil, pages.ErrPageNotFound
+	}
+	cp := *p
+	return &cp, nil
+}
+
+func (s *stubPages) UpdatePage(id uint, input pages.PageInput, userID uint) (*pages.Page, error) {
+	if hook := s.beforeSave; hook != nil {
+		s.beforeSave = nil
+		hook()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.pages[id]
+	if input.Version != nil && *input.Version != p.Version {
+		return nil, pages.ErrVersionConflict
+	}
+	p.Title, p.Content = input.Title, input.Content
+	p.Version++
+	s.saves++
+	cp := *p
+	return &cp, nil
+}
+
+func (s *stubPages) content(id uint) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pages[id].Content
+}
+
+// lossyPubSub drops every message published while drop is set.
+type lossyPubSub struct {
+	pubsub.PubSub
+	drop atomic.Bool
+}
+
+func (l *lossyPubSub) Publish(ctx context.Context, topic string, payload []byte) error {
+	if l.drop.Load() {
+		return nil
+	}
+	return l.PubSub.Publish(ctx, topic, payload)
+}
+
+type stubWorkspaces map[[2]uint]workspaces.Role
+
+func (s stubWorkspaces) MemberRole(workspaceID, userID uint) (workspaces.Role, error) {
+	return s[[2]uint{workspaceID, userID}], nil
+}
+
+type stubTokens struct{}
+
+func (stubTokens) Verify(token string) (*jwt.RegisteredClaims, error) {
+	id, ok := strings.CutPrefix(token, "user-")
+	if !ok {
+		return nil, errors.New("invalid token")
+	}
+	return &jwt.RegisteredClaims{Subject: id}, nil
+}
+
+// auditLog keeps recorded audit entries.
+type auditLog struct {
+	mu      sync.Mutex
+	entries []audit.Entry
+}
+
+func (l *auditLog) Record(req audit.Request, action audit.Action, target audit.Target, details map[string]any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, audit.Entry{Request: req, Action: action, TargetType: target.Type, TargetID: target.ID, Details: details})
+}
+
+const ws = uint(7)
+
+func newStore(content string) *stubPages {
+	wsID := ws
+	return &stubPages{pages: map[uint]*pages.Page{
+		1: {ID: 1, Title: "Notes", Content: content, UserID: 1, WorkspaceID: &wsID, Version: 1},
+	}}
+}
+
+var roles = stubWorkspaces{{ws, 1}: workspaces.RoleEditor, {ws, 2}: workspaces.RoleEditor, {ws, 3}: workspaces.RoleViewer}
+
+// newReplica starts a manager and serves it, as one replica would.
+func newReplica(t *testing.T, ps pubsub.PubSub, store *stubPages) (*Manager, *httptest.Server) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	m := NewManager(ps, store, roles)
+	ctx, cancel := context.WithCancel(context.Background())
+	go m.Run(ctx, time.Hour)
+
+	r := gin.New()
+	r.GET("/pages/:id/collab", NewHandler(m, realtime.NewAuthenticator(stubTokens{}, nil), func(string) bool { return true }).ServeWS)
+	srv := httptest.NewServer(r)
+	t.Cleanup(func() {
+		srv.Close()
+		cancel()
+	})
+	return m, srv
+}
+
+// testClient is a minimal editor: it keeps its own replica of the
+// document, applies what the server sends and remembers its own
+// unacknowledged operations.
+type testClient struct {
+	t      *testing.T
+	conn   *websocket.Conn
+	client uint64
+
+	mu       sync.Mutex
+	doc      *Doc
+	epoch    int
+	readOnly bool
+	errors   []string
+	closed   bool
+}
+
+func connect(t *testing.T, srv *httptest.Server, userID uint, client uint64) *testClient {
+	t.Helper()
+	tc := &testClient{t: t, client: client, doc: NewDoc()}
+	tc.dial(srv, userID)
+	tc.syncUp(nil)
+	return tc
+}
+
+func (c *testClient) dial(srv *httptest.Server, userID uint) {
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/pages/1/collab"
+	header := http.Header{"Authorization": {"Bearer user-" + strconv.Itoa(int(userID))}}
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	require.NoError(c.t, err)
+	c.conn = conn
+	c.t.Cleanup(func() { conn.Close() })
+	go c.listen(conn)
+}
+
+func (c *testClient) syncUp(ops []Op) {
+	c.mu.Lock()
+	m := message{Type: msgSync, Epoch: c.epoch, SV: c.doc.StateVector(), Ops: ops}
+	c.mu.Unlock()
+	require.NoError(c.t, c.conn.WriteJSON(m))
+}
+
+func (c *testClient) listen(conn *websocket.Conn) {
+	for {
+		var m message
+		if err := conn.ReadJSON(&m); err != nil {
+			return
+		}
+		c.mu.Lock()
+		switch m.Type {
+		case msgSync, msgUpdate:
+			if m.Epoch == c.epoch {
+				c.doc.Apply(m.Ops)
+			}
+			if m.Type == msgSync {
+				c.readOnly = m.ReadOnly
+			}
+		case msgReset:
+			c.doc = NewDoc()
+			c.doc.Apply(m.Ops)
+			c.epoch = m.Epoch
+			c.readOnly = m.ReadOnly
+		case msgError:
+			c.errors = append(c.errors, m.Error)
+		case msgClosed:
+			c.closed = true
+		}
+		c.mu.Unlock()
+	}
+}
+
+func (c *testClient) insert(pos int, text string) {
+	c.mu.Lock()
+	op, err := c.doc.Insert(c.client, pos, text)
+	epoch := c.epoch
+	c.mu.Unlock()
+	require.NoError(c.t, err)
+	require.NoError(c.t, c.conn.WriteJSON(message{Type: msgUpdate, Epoch: epoch, Ops: []Op{op}}))
+}
+
+func (c *testClient) text() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.doc.Text()
+}
+
+func (c *testClient) synced() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.epoch != 0
+}
+
+func (m *Manager) session(pageID uint) *session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sessions[pageID]
+}
+
+// serverText is the session's text on replica m.
+func serverText(m *Manager) string {
+	s := m.session(1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.doc.Text()
+}
+
+func TestEditorsConvergeAndTheTextIsSaved(t *testing.T) {
+	store := newStore("Hello")
+	m, srv := newReplica(t, pubsub.NewLocal(), store)
+	log := &auditLog{}
+	m.audit = log
+
+	alice := connect(t, srv, 1, 1<<32+1)
+	bob := connect(t, srv, 2, 1<<32+2)
+	viewer := connect(t, srv, 3, 1<<32+3)
+	require.Eventually(t, func() bool { return alice.synced() && bob.synced() && viewer.synced() }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "Hello", bob.text())
+
+	// Both type at the end at the same time.
+	alice.insert(5, " world")
+	bob.insert(5, "!")
+	require.Eventually(t, func() bool {
+		return alice.text() == bob.text() && bob.text() == viewer.text() && len(alice.text()) == 12
+	}, 2*time.Second, 10*time.Millisecond)
+
+	require.Eventually(t, func() bool { return serverText(m) == alice.text() }, 2*time.Second, 10*time.Millisecond)
+	viewer.insert(0, "x")
+	require.Eventually(t, func() bool {
+		viewer.mu.Lock()
+		defer viewer.mu.Unlock()
+		return len(viewer.errors) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, ErrReadOnly.Error(), viewer.errors[0])
+	assert.True(t, viewer.readOnly)
+
+	m.flush(m.session(1))
+	assert.Equal(t, alice.text(), store.content(1))
+	assert.Equal(t, 1, store.saves)
+	require.Len(t, log.entries, 1)
+	entry := log.entries[0]
+	assert.Equal(t, audit.PageUpdated, entry.Action)
+	assert.Equal(t, uint(1), entry.TargetID)
+	require.NotNil(t, entry.ActorID)
+	assert.Contains(t, []uint{1, 2}, *entry.ActorID, "saved as one of the editors")
+	assert.NotEmpty(t, entry.IP)
+
+	// Nothing changed since, so nothing is saved.
+	m.flush(m.session(1))
+	assert.Equal(t, 1, store.saves)
+	assert.Len(t, log.entries, 1)
+}
+
+func TestReconnectingClientSyncsByStateVector(t *testing.T) {
+	store := newStore("abc")
+	_, srv := newReplica(t, pubsub.NewLocal(), store)
+
+	alice := connect(t, srv, 1, 1<<32+1)
+	bob := connect(t, srv, 2, 1<<32+2)
+	require.Eventually(t, func() bool { return alice.synced() && bob.synced() }, 2*time.Second, 10*time.Millisecond)
+
+	// Bob drops off and keeps typing while Alice edits.
+	bob.conn.Close()
+	bob.mu.Lock()
+	offline, err := bob.doc.Insert(bob.client, 3, "-bob")
+	bob.mu.Unlock()
+	require.NoError(t, err)
+	alice.insert(0, "alice-")
+	require.Eventually(t, func() bool { return alice.text() == "alice-abc" }, 2*time.Second, 10*time.Millisecond)
+
+	bob.dial(srv, 2)
+	bob.syncUp([]Op{offline})
+	require.Eventually(t, func() bool {
+		return alice.text() == "alice-abc-bob" && bob.text() == "alice-abc-bob"
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestReplicasShareSessions(t *testing.T) {
+	store := newStore("shared")
+	ps := pubsub.NewLocal()
+	a, srvA := newReplica(t, ps, store)
+	_, srvB := newReplica(t, ps, store)
+
+	alice := connect(t, srvA, 1, 1<<32+1)
+	require.Eventually(t, alice.synced, 2*time.Second, 10*time.Millisecond)
+	alice.insert(6, " notes")
+	require.Eventually(t, func() bool { return serverText(a) == "shared notes" }, 2*time.Second, 10*time.Millisecond)
+
+	// A save moves the page on; a replica opening the page later still
+	// joins the running session instead of starting over.
+	a.flush(a.session(1))
+	require.Equal(t, "shared notes", store.content(1))
+
+	bob := connect(t, srvB, 2, 1<<32+2)
+	require.Eventually(t, func() bool { return bob.text() == "shared notes" }, 2*time.Second, 10*time.Millisecond)
+
+	bob.insert(0, "our ")
+	alice.insert(12, "!")
+	require.Eventually(t, func() bool {
+		return alice.text() == "our shared notes!" && bob.text() == alice.text()
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestOutsideEditsResetTheSession(t *testing.T) {
+	store := newStore("draft")
+	m, srv := newReplica(t, pubsub.NewLocal(), store)
+
+	alice := connect(t, srv, 1, 1<<32+1)
+	require.Eventually(t, alice.synced, 2*time.Second, 10*time.Millisecond)
+
+	// Someone saves the page through the REST API.
+	_, err := store.UpdatePage(1, pages.PageInput{Title: "Notes", Content: "final"}, 2)
+	require.NoError(t, err)
+
+	s := m.session(1)
+	m.flush(s)
+	assert.Equal(t, "draft", alice.text(), "one tick of grace for saves announced late")
+	m.flush(s)
+	require.Eventually(t, func() bool { return alice.text() == "final" }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "final", store.content(1))
+}
+
+func TestSaveLosingARaceIsTreatedAsAnOutsideEdit(t *testing.T) {
+	store := newStore("draft")
+	m, srv := newReplica(t, pubsub.NewLocal(), store)
+
+	alice := connect(t, srv, 1, 1<<32+1)
+	require.Eventually(t, alice.synced, 2*time.Second, 10*time.Millisecond)
+	alice.insert(5, " v2")
+	require.Eventually(t, func() bool { return serverText(m) == "draft v2" }, 2*time.Second, 10*time.Millisecond)
+
+	// Someone saves through the REST API between the check and the save.
+	store.beforeSave = func() {
+		_, err := store.UpdatePage(1, pages.PageInput{Title: "Notes", Content: "final"}, 2)
+		require.NoError(t, err)
+	}
+	s := m.session(1)
+	m.flush(s)
+	assert.Equal(t, "final", store.content(1), "the outside edit is not overwritten")
+
+	m.flush(s)
+	m.flush(s)
+	require.Eventually(t, func() bool { return alice.text() == "final" }, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "final", store.content(1))
+}
+
+func TestStateChecksRecoverDroppedRelayMessages(t *testing.T) {
+	store := newStore("shared")
+	ps := &lossyPubSub{PubSub: pubsub.NewLocal()}
+	a, srvA := newReplica(t, ps, store)
+	b, srvB := newReplica(t, ps, store)
+
+	alice := connect(t, srvA, 1, 1<<32+1)
+	bob := connect(t, srvB, 2, 1<<32+2)
+	require.Eventually(t, func() bool { return alice.synced() && bob.synced() }, 2*time.Second, 10*time.Millisecond)
+
+	ps.drop.Store(true)
+	alice.insert(6, " notes")
+	require.Eventually(t, func() bool { return serverText(a) == "shared notes" }, 2*time.Second, 10*time.Millisecond)
+	ps.drop.Store(false)
+	assert.Equal(t, "shared", serverText(b))
+
+	b.check(b.session(1))
+	require.Eventually(t, func() bool { return bob.text() == "shared notes" }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestLosingAccessEndsTheSession(t *testing.T) {
+	store := newStore("secret")
+	m, srv := newReplica(t, pubsub.NewLocal(), store)
+
+	alice := connect(t, srv, 1, 1<<32+1)
+	require.Eventually(t, alice.synced, 2*time.Second, 10*time.Millisecond)
+
+	store.mu.Lock()
+	delete(store.pages, 1)
+	store.mu.Unlock()
+	m.flush(m.session(1))
+
+	require.Eventually(t, func() bool {
+		alice.mu.Lock()
+		defer alice.mu.Unlock()
+		return alice.closed
+	}, 2*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return m.session(1) == nil }, 2*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,159 @@
+package collab
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"flowboard-backend-go/internal/audit"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait  = 10 * time.Second
+	pongWait   = 60 * time.Second
+	pingPeriod = pongWait * 9 / 10
+	// sendBuffer is how many messages may queue for a client before it is
+	// considered too slow and disconnected; it resyncs on reconnect.
+	sendBuffer = 256
+	// maxMessageSize bounds what a client may send, enough for a large
+	// paste.
+	maxMessageSize = 1 << 20
+)
+
+// peer is one client connection to a session. clients and synced are
+// guarded by the session's lock.
+type peer struct {
+	manager *Manager
+	session *session
+	conn    *websocket.Conn
+	userID  uint
+	canEdit bool
+	req     audit.Request   // the handshake, for the audit trail
+	clients map[uint64]bool // client IDs this connection has used
+	synced  bool
+	send    chan []byte
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newPeer(m *Manager, conn *websocket.Conn, userID uint, canEdit bool) *peer {
+	return &peer{
+		manager: m,
+		conn:    conn,
+		userID:  userID,
+		canEdit: canEdit,
+		clients: map[uint64]bool{},
+		send:    make(chan []byte, sendBuffer),
+		done:    make(chan struct{}),
+	}
+}
+
+// push queues msg without blocking; a peer whose queue is full is
+// disconnected.
+func (p *peer) push(msg []byte) {
+	select {
+	case <-p.done:
+	case p.send <- msg:
+	default:
+		p.close()
+	}
+}
+
+func (p *peer) reply(m message) {
+	msg, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+	p.push(msg)
+}
+
+func (p *peer) close() {
+	p.once.Do(func() { close(p.done) })
+}
+
+func (p *peer) readPump() {
+	defer func() {
+		p.close()
+		p.manager.leave(p)
+	}()
+	p.conn.SetReadLimit(maxMessageSize)
+	p.conn.SetReadDeadline(time.Now().Add(pongWait))
+	p.conn.SetPongHandler(func(string) error {
+		return p.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		var m message
+		if err := p.conn.ReadJSON(&m); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				p.reply(message{Type: msgError, Error: "invalid message"})
+				continue
+			}
+			return
+		}
+
+		var err error
+		switch m.Type {
+		case msgSync:
+			err = p.manager.sync(p, m)
+		case msgUpdate:
+			err = p.manager.update(p, m.Epoch, m.Ops)
+		default:
+			err = errors.New("unknown message type")
+		}
+		if errors.Is(err, ErrTooManyPending) {
+			p.reply(message{Type: msgClosed, Error: "out of sync, reconnect"})
+			return
+		}
+		if err != nil {
+			p.reply(message{Type: msgError, Error: err.Error()})
+		}
+	}
+}
+
+// writePump owns all writes to the connection and closes it on the way
+// out, after sending whatever is still queued.
+func (p *peer) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		p.conn.Close()
+	}()
+
+	write := func(kind int, msg []byte) bool {
+		p.conn.SetWriteDeadline(time.Now().Add(writeWait))
+		if err := p.conn.WriteMessage(kind, msg); err != nil {
+			p.close()
+			return false
+		}
+		return true
+	}
+
+	for {
+		select {
+		case msg := <-p.send:
+			if !write(websocket.TextMessage, msg) {
+				return
+			}
+		case <-ticker.C:
+			if !write(websocket.PingMessage, nil) {
+				return
+			}
+		case <-p.done:
+			for len(p.send) > 0 {
+				if !write(websocket.TextMessage, <-p.send) {
+					return
+				}
+			}
+			p.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+				time.Now().Add(writeWait))
+			return
+		}
+	}
+}
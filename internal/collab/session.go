@@ -0,0 +1,218 @@
+package collab
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"flowboard-backend-go/internal/audit"
+	"flowboard-backend-go/internal/pages"
+)
+
+// Client IDs at or above minClientID belong to clients, which pick them
+// at random; lower IDs are reserved for the server. maxClientID keeps
+// IDs exact in JavaScript numbers.
+const (
+	minClientID = 1 << 32
+	maxClientID = 1<<53 - 1
+)
+
+var (
+	ErrReadOnly      = errors.New("you can only view this page")
+	ErrForeignClient = errors.New("client ID belongs to someone else")
+)
+
+// Client message types.
+const (
+	msgSync   = "sync"   // client → server on (re)connect; server's answer
+	msgUpdate = "update" // operations, either way
+	msgReset  = "reset"  // server → client: a new epoch replaces the document
+	msgClosed = "closed" // server → client: the session is over for you
+	msgError  = "error"
+)
+
+// message is the client protocol. A client connects and sends sync with
+// the epoch and state vector it has (zero for a fresh client) plus any
+// operations the server has not acknowledged. On the current epoch the
+// server answers sync with what the client is missing; otherwise it
+// answers reset with the whole document, onto which the client reapplies
+// its unacknowledged changes as new operations.
+type message struct {
+	Type     string      `json:"type"`
+	Epoch    int         `json:"epoch,omitempty"`
+	SV       StateVector `json:"sv,omitempty"`
+	Ops      []Op        `json:"ops,omitempty"`
+	ReadOnly bool        `json:"readOnly,omitempty"`
+	Error    string      `json:"error,omitempty"`
+}
+
+// session is one page's document on this replica and the clients editing
+// it. Its epoch is the page version the document was seeded from; all
+// replicas holding the page converge on one epoch.
+type session struct {
+	pageID  uint
+	flushMu sync.Mutex
+
+	mu      sync.Mutex
+	doc     *Doc
+	epoch   int
+	fresh   bool           // nothing but the seed has been integrated
+	version int            // newest page version known to hold the document
+	written map[int]bool   // page versions saved by some replica's session
+	suspect int            // page version that may have been edited elsewhere
+	told    map[string]int // epoch last sent to each lagging replica
+	peers   map[*peer]struct{}
+	owners  map[uint64]uint // client ID to user
+	dirty   bool
+	editor  uint // user whose changes are saved next
+	edited  audit.Request
+}
+
+func newSession(page *pages.Page) *session {
+	return &session{
+		pageID:  page.ID,
+		doc:     SeedDoc(page.Content),
+		epoch:   page.Version,
+		fresh:   true,
+		version: page.Version,
+		written: map[int]bool{},
+		told:    map[string]int{},
+		peers:   map[*peer]struct{}{},
+		owners:  map[uint64]uint{},
+	}
+}
+
+// The methods below expect s.mu to be held.
+
+func (s *session) noteSaved(version int) {
+	s.written[version] = true
+	s.version = max(s.version, version)
+}
+
+// external reports whether the page reached version through a change
+// no session made.
+func (s *session) external(version int) bool {
+	return version > s.version && !s.written[version]
+}
+
+// adopt replaces the document with doc as epoch. Changes not yet saved
+// are dropped; their authors reapply them after the reset.
+func (s *session) adopt(epoch int, doc *Doc) {
+	s.doc = doc
+	s.epoch = epoch
+	s.fresh = false
+	s.dirty = false
+	s.owners = map[uint64]uint{}
+	for p := range s.peers {
+		p.clients = map[uint64]bool{}
+	}
+}
+
+func (s *session) resetPeers() {
+	ops := s.doc.Ops()
+	for p := range s.peers {
+		if p.synced {
+			s.send(p, message{Type: msgReset, Epoch: s.epoch, Ops: ops, ReadOnly: !p.canEdit})
+		}
+	}
+}
+
+// applyFrom merges operations sent by p. Each client ID is bound to the
+// first user that uses it.
+func (s *session) applyFrom(p *peer, ops []Op) ([]Op, error) {
+	if !p.canEdit {
+		return nil, ErrReadOnly
+	}
+	for _, op := range ops {
+		c := op.ID.Client
+		if c < minClientID || c > maxClientID {
+			return nil, ErrInvalidOp
+		}
+		if owner, ok := s.owners[c]; ok && owner != p.userID {
+			return nil, ErrForeignClient
+		}
+	}
+	for _, op := range ops {
+		s.owners[op.ID.Client] = p.userID
+		p.clients[op.ID.Client] = true
+	}
+
+	applied, err := s.doc.Apply(ops)
+	if len(applied) > 0 {
+		s.fresh = false
+		s.dirty = true
+		s.editor = p.userID
+		s.edited = p.req
+		s.broadcast(p, message{Type: msgUpdate, Epoch: s.epoch, Ops: applied})
+	}
+	return applied, err
+}
+
+// ownOps filters ops down to those made by this replica's clients, which
+// this replica relays.
+func (s *session) ownOps(ops []Op) []Op {
+	var own []Op
+	for _, op := range ops {
+		if _, ok := s.owners[op.ID.Client]; ok {
+			own = append(own, op)
+		}
+	}
+	return own
+}
+
+// broadcast sends m to every synced peer. from, when set, made the
+// operations and only hears about those by other clients.
+func (s *session) broadcast(from *peer, m message) {
+	if len(m.Ops) == 0 {
+		return
+	}
+	for p := range s.peers {
+		if !p.synced {
+			continue
+		}
+		out := m
+		if p == from {
+			out.Ops = nil
+			for _, op := range m.Ops {
+				if !p.clients[op.ID.Client] {
+					out.Ops = append(out.Ops, op)
+				}
+			}
+			if len(out.Ops) == 0 {
+				continue
+			}
+		}
+		s.send(p, out)
+	}
+}
+
+func (s *session) send(p *peer, m message) {
+	msg, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+	p.push(msg)
+}
+
+// users lists the users connected to the session.
+func (s *session) users() []uint {
+	seen := map[uint]bool{}
+	var list []uint
+	for p := range s.peers {
+		if !seen[p.userID] {
+			seen[p.userID] = true
+			list = append(list, p.userID)
+		}
+	}
+	return list
+}
+
+// disconnect ends the session for every connection of userID.
+func (s *session) disconnect(userID uint, reason string) {
+	for p := range s.peers {
+		if p.userID == userID {
+			s.send(p, message{Type: msgClosed, Error: reason})
+			p.close()
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package comments
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"flowboard-backend-go/internal/outbox"
+	"flowboard-backend-go/internal/pages"
+)
+
+type EventType string
+
+const (
+	CommentCreated EventType = "comment.created"
+	// ThreadResolved is recorded when an open thread is resolved; reopening
+	// records nothing.
+	ThreadResolved EventType = "comment.resolved"
+)
+
+// EventTypes lists every comment event type, for subscribing to all of
+// them.
+var EventTypes = []string{string(CommentCreated), string(ThreadResolved)}
+
+// maxExcerpt bounds the comment text carried in events, in runes.
+const maxExcerpt = 140
+
+// CommentEvent describes a change to a comment. It names the page and its
+// audience so consumers need not look them up.
+type CommentEvent struct {
+	Type        EventType `json:"type"`
+	CommentID   uint      `json:"commentId"`
+	ThreadID    uint      `json:"threadId"`
+	PageID      uint      `json:"pageId"`
+	PageTitle   string    `json:"pageTitle"`
+	WorkspaceID *uint     `json:"workspaceId,omitempty"`
+	OwnerID     uint      `json:"ownerId"`
+	ActorID     uint      `json:"actorId"`
+	Excerpt     string    `json:"excerpt"`
+	At          time.Time `json:"at"`
+}
+
+// EventHandler consumes comment events relayed from the outbox. An event
+// may arrive more than once, always with the same eventID.
+type EventHandler interface {
+	HandleCommentEvent(ctx context.Context, eventID uint64, e CommentEvent) error
+}
+
+// Consume adapts h to an outbox handler; subscribe it to EventTypes.
+func Consume(h EventHandler) outbox.Handler {
+	return func(ctx context.Context, m *outbox.Message) error {
+		var e CommentEvent
+		if err := json.Unmarshal([]byte(m.Payload), &e); err != nil {
+			return err
+		}
+		return h.HandleCommentEvent(ctx, m.ID, e)
+	}
+}
+
+// event describes a change to comment, made by actorID on page.
+func (s *service) event(t EventType, page *pages.Page, comment *Comment, actorID uint) CommentEvent {
+	thread := comment.ID
+	if comment.ParentID != nil {
+		thread = *comment.ParentID
+	}
+	excerpt := []rune(comment.Body)
+	if len(excerpt) > maxExcerpt {
+		excerpt = append(excerpt[:maxExcerpt-1], '…')
+	}
+	return CommentEvent{
+		Type:        t,
+		CommentID:   comment.ID,
+		ThreadID:    thread,
+		PageID:      page.ID,
+		PageTitle:   page.Title,
+		WorkspaceID: page.WorkspaceID,
+		OwnerID:     page.UserID,
+		ActorID:     actorID,
+		Excerpt:     string(excerpt),
+		At:          s.now(),
+	}
+}
@@ -0,0 +1,205 @@
+package comments
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"flowboard-backend-go/internal/middleware"
+	"flowboard-backend-go/internal/pages"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// getUserID safely retrieves user ID from context
+func getUserID(c *gin.Context) (uint, error) {
+	uidVal, exists := c.Get(middleware.ContextUserIDKey)
+	if !exists {
+		return 0, fmt.Errorf("unauthorized")
+	}
+
+	uid, ok := uidVal.(uint)
+	if !ok {
+		return 0, fmt.Errorf("invalid user ID type")
+	}
+
+	return uid, nil
+}
+
+// parseID reads a numeric path parameter
+func parseID(c *gin.Context, name string) (uint, bool) {
+	id64, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		return 0, false
+	}
+	return uint(id64), true
+}
+
+// respondError maps service errors to HTTP statuses
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrCommentNotFound), errors.Is(err, pages.ErrPageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNotAuthor), errors.Is(err, pages.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidAnchor), errors.Is(err, ErrNotThread), errors.Is(err, ErrEmptyBody):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GetComments lists a page's threads with their replies. Pass
+// ?resolved=true or ?resolved=false to filter by state.
+func (h *Handler) GetComments(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	pageID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var resolved *bool
+	if v := c.Query("resolved"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid resolved"})
+			return
+		}
+		resolved = &b
+	}
+
+	list, err := h.service.GetComments(pageID, userID, resolved)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": list})
+}
+
+func (h *Handler) CreateComment(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	pageID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input CommentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := h.service.CreateComment(pageID, input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": comment})
+}
+
+func (h *Handler) UpdateComment(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	pageID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	commentID, ok := parseID(c, "commentId")
+	if !ok {
+		return
+	}
+
+	var input CommentUpdateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := h.service.UpdateComment(pageID, commentID, input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": comment})
+}
+
+func (h *Handler) DeleteComment(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	pageID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	commentID, ok := parseID(c, "commentId")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteComment(pageID, commentID, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+func (h *Handler) ResolveThread(c *gin.Context) {
+	h.setResolved(c, true)
+}
+
+func (h *Handler) UnresolveThread(c *gin.Context) {
+	h.setResolved(c, false)
+}
+
+func (h *Handler) setResolved(c *gin.Context, resolved bool) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	pageID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	commentID, ok := parseID(c, "commentId")
+	if !ok {
+		return
+	}
+
+	comment, err := h.service.SetResolved(pageID, commentID, resolved, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": comment})
+}
@@ -0,0 +1,51 @@
+package comments
+
+import "time"
+
+// Comment is a message on a page. A comment without ParentID starts a
+// thread; replies point at the thread's root, so threads are one level deep.
+// Only roots carry an anchor and a resolved state.
+type Comment struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	PageID     uint       `gorm:"not null;index" json:"pageId"`
+	ParentID   *uint      `gorm:"index" json:"parentId,omitempty"`
+	UserID     uint       `gorm:"not null" json:"userId"`
+	Body       string     `gorm:"type:text;not null" json:"body"`
+	Anchor     *Anchor    `gorm:"serializer:json;type:jsonb" json:"anchor,omitempty"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+	ResolvedBy *uint      `json:"resolvedBy,omitempty"`
+	EditedAt   *time.Time `json:"editedAt,omitempty"`
+	Replies    []Comment  `gorm:"foreignKey:ParentID" json:"replies,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+// Anchor ties a thread to a block, optionally to the rune range
+// [Start, End) of its text. Quote keeps the text the range covered when
+// the comment was made, so the thread still reads sensibly after edits.
+type Anchor struct {
+	BlockID string `json:"blockId"`
+	Start   *int   `json:"start,omitempty"`
+	End     *int   `json:"end,omitempty"`
+	Quote   string `json:"quote,omitempty"`
+}
+
+// CommentInput for starting a thread or replying to one. Anchor is only
+// allowed on new threads.
+type CommentInput struct {
+	Body     string       `json:"body" binding:"required,max=10000"`
+	ParentID *uint        `json:"parentId"`
+	Anchor   *AnchorInput `json:"anchor"`
+}
+
+// AnchorInput selects a block and, optionally, a range of its text
+type AnchorInput struct {
+	BlockID string `json:"blockId" binding:"required"`
+	Start   *int   `json:"start" binding:"omitempty,min=0"`
+	End     *int   `json:"end" binding:"omitempty,min=0"`
+}
+
+// CommentUpdateInput for editing a comment's body
+type CommentUpdateInput struct {
+	Body string `json:"body" binding:"required,max=10000"`
+}
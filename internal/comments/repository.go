@@ -0,0 +1,107 @@
+package comments
+
+import (
+	"errors"
+
+	"flowboard-backend-go/internal/outbox"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	// Transaction runs fn with a Repository bound to a single database
+	// transaction; any error rolls everything back.
+	Transaction(fn func(repo Repository) error) error
+	// AddEvent records a comment event in the outbox. Call it inside
+	// Transaction so the event commits together with the change.
+	AddEvent(e CommentEvent) error
+	CreateComment(comment *Comment) error
+	GetCommentByID(id uint) (*Comment, error)
+	// GetThreads lists a page's threads, oldest first, with their replies.
+	// A nil resolved returns every thread.
+	GetThreads(pageID uint, resolved *bool) ([]Comment, error)
+	// GetParticipants returns the distinct authors of a thread's comments
+	// older than beforeID.
+	GetParticipants(rootID, beforeID uint) ([]uint, error)
+	UpdateComment(comment *Comment) error
+	// DeleteComment removes a comment and, for a thread root, its replies.
+	DeleteComment(id uint) error
+	// DeleteByPage removes every comment on a page.
+	DeleteByPage(pageID uint) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Transaction(fn func(repo Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&repository{db: tx})
+	})
+}
+
+func (r *repository) AddEvent(e CommentEvent) error {
+	return outbox.Append(r.db, string(e.Type), e)
+}
+
+func (r *repository) CreateComment(comment *Comment) error {
+	return r.db.Create(comment).Error
+}
+
+func (r *repository) GetCommentByID(id uint) (*Comment, error) {
+	var comment Comment
+	if err := r.db.First(&comment, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &comment, nil
+}
+
+func (r *repository) GetThreads(pageID uint, resolved *bool) ([]Comment, error) {
+	q := r.db.
+		Preload("Replies", func(db *gorm.DB) *gorm.DB { return db.Order("created_at, id") }).
+		Where("page_id = ? AND parent_id IS NULL", pageID)
+	if resolved != nil {
+		if *resolved {
+			q = q.Where("resolved_at IS NOT NULL")
+		} else {
+			q = q.Where("resolved_at IS NULL")
+		}
+	}
+
+	var list []Comment
+	if err := q.Order("created_at, id").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *repository) GetParticipants(rootID, beforeID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&Comment{}).
+		Where("(id = ? OR parent_id = ?) AND id < ?", rootID, rootID, beforeID).
+		Distinct().
+		Pluck("user_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *repository) UpdateComment(comment *Comment) error {
+	return r.db.Omit("Replies").Save(comment).Error
+}
+
+func (r *repository) DeleteComment(id uint) error {
+	return r.db.Where("id = ? OR parent_id = ?", id, id).Delete(&Comment{}).Error
+}
+
+func (r *repository) DeleteByPage(pageID uint) error {
+	return r.db.Where("page_id = ?", pageID).Delete(&Comment{}).Error
+}
@@ -0,0 +1,316 @@
+package comments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"flowboard-backend-go/internal/notifications"
+	"flowboard-backend-go/internal/pages"
+)
+
+var (
+	ErrCommentNotFound = errors.New("comment not found")
+	ErrNotAuthor       = errors.New("only the author can change this comment")
+	ErrInvalidAnchor   = errors.New("invalid comment anchor")
+	ErrNotThread       = errors.New("only a thread's first comment can be resolved")
+	ErrEmptyBody       = errors.New("comment body cannot be empty")
+)
+
+// PageLookup resolves pages with access checks; satisfied by pages.Service.
+type PageLookup interface {
+	GetPageByID(id, userID uint) (*pages.Page, error)
+	GetBlocks(pageID, userID uint) ([]pages.Block, error)
+}
+
+type Service interface {
+	// HandleCommentEvent notifies the page owner and thread participants
+	// about new comments.
+	EventHandler
+	// HandlePageEvent removes the comments of deleted pages.
+	pages.EventHandler
+
+	// GetComments lists the page's threads; resolved filters by state when
+	// set.
+	GetComments(pageID, userID uint, resolved *bool) ([]Comment, error)
+	CreateComment(pageID uint, input CommentInput, userID uint) (*Comment, error)
+	UpdateComment(pageID, id uint, input CommentUpdateInput, userID uint) (*Comment, error)
+	DeleteComment(pageID, id, userID uint) error
+	SetResolved(pageID, id uint, resolved bool, userID uint) (*Comment, error)
+}
+
+type service struct {
+	repo     Repository
+	pages    PageLookup
+	notifier notifications.Notifier
+	now      func() time.Time
+}
+
+// Option configures optional collaborators of the comment service.
+type Option func(*service)
+
+// WithNotifier tells the page owner and thread participants about new
+// comments once the comment's event is relayed from the outbox.
+func WithNotifier(n notifications.Notifier) Option {
+	return func(s *service) { s.notifier = n }
+}
+
+func NewService(repo Repository, pages PageLookup, opts ...Option) Service {
+	s := &service{repo: repo, pages: pages, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *service) GetComments(pageID, userID uint, resolved *bool) ([]Comment, error) {
+	if _, err := s.pages.GetPageByID(pageID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetThreads(pageID, resolved)
+}
+
+func (s *service) CreateComment(pageID uint, input CommentInput, userID uint) (*Comment, error) {
+	page, err := s.pages.GetPageByID(pageID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := &Comment{PageID: pageID, UserID: userID, Body: strings.TrimSpace(input.Body)}
+	if comment.Body == "" {
+		return nil, ErrEmptyBody
+	}
+	var root *Comment
+	if input.ParentID != nil {
+		if input.Anchor != nil {
+			return nil, fmt.Errorf("%w: replies cannot be anchored", ErrInvalidAnchor)
+		}
+		if root, err = s.loadComment(pageID, *input.ParentID); err != nil {
+			return nil, err
+		}
+		// Replies to replies join the same thread.
+		if root.ParentID != nil {
+			if root, err = s.loadComment(pageID, *root.ParentID); err != nil {
+				return nil, err
+			}
+		}
+		comment.ParentID = &root.ID
+	} else if input.Anchor != nil {
+		if comment.Anchor, err = s.resolveAnchor(pageID, userID, *input.Anchor); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.repo.Transaction(func(repo Repository) error {
+		if err := repo.CreateComment(comment); err != nil {
+			return err
+		}
+		// A new reply reopens a resolved thread.
+		if root != nil && root.ResolvedAt != nil {
+			root.ResolvedAt, root.ResolvedBy = nil, nil
+			if err := repo.UpdateComment(root); err != nil {
+				return err
+			}
+		}
+		return repo.AddEvent(s.event(CommentCreated, page, comment, userID))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+func (s *service) UpdateComment(pageID, id uint, input CommentUpdateInput, userID uint) (*Comment, error) {
+	body := strings.TrimSpace(input.Body)
+	if body == "" {
+		return nil, ErrEmptyBody
+	}
+	comment, err := s.authoredComment(pageID, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	comment.Body = body
+	comment.EditedAt = &now
+	if err := s.repo.UpdateComment(comment); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// DeleteComment removes a comment; deleting the first comment of a thread
+// removes the whole thread.
+func (s *service) DeleteComment(pageID, id, userID uint) error {
+	if _, err := s.authoredComment(pageID, id, userID); err != nil {
+		return err
+	}
+	return s.repo.DeleteComment(id)
+}
+
+// SetResolved resolves or reopens a thread. Anyone who can read the page
+// may do either.
+func (s *service) SetResolved(pageID, id uint, resolved bool, userID uint) (*Comment, error) {
+	page, err := s.pages.GetPageByID(pageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := s.loadComment(pageID, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.ParentID != nil {
+		return nil, ErrNotThread
+	}
+
+	newlyResolved := resolved && comment.ResolvedAt == nil
+	if newlyResolved {
+		now := s.now()
+		comment.ResolvedAt, comment.ResolvedBy = &now, &userID
+	} else if !resolved {
+		comment.ResolvedAt, comment.ResolvedBy = nil, nil
+	}
+	err = s.repo.Transaction(func(repo Repository) error {
+		if err := repo.UpdateComment(comment); err != nil {
+			return err
+		}
+		if !newlyResolved {
+			return nil
+		}
+		return repo.AddEvent(s.event(ThreadResolved, page, comment, userID))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// loadComment loads a comment and checks it belongs to the page.
+func (s *service) loadComment(pageID, id uint) (*Comment, error) {
+	comment, err := s.repo.GetCommentByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if comment == nil || comment.PageID != pageID {
+		return nil, ErrCommentNotFound
+	}
+	return comment, nil
+}
+
+// authoredComment loads a comment the user wrote on a page they can still
+// read.
+func (s *service) authoredComment(pageID, id, userID uint) (*Comment, error) {
+	if _, err := s.pages.GetPageByID(pageID, userID); err != nil {
+		return nil, err
+	}
+	comment, err := s.loadComment(pageID, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.UserID != userID {
+		return nil, ErrNotAuthor
+	}
+	return comment, nil
+}
+
+// resolveAnchor checks the block exists on the page and that the range, if
+// any, lies within its text.
+func (s *service) resolveAnchor(pageID, userID uint, input AnchorInput) (*Anchor, error) {
+	blocks, err := s.pages.GetBlocks(pageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	var block *pages.Block
+	for i := range blocks {
+		if blocks[i].ID == input.BlockID {
+			block = &blocks[i]
+			break
+		}
+	}
+	if block == nil {
+		return nil, fmt.Errorf("%w: block not found", ErrInvalidAnchor)
+	}
+
+	anchor := &Anchor{BlockID: block.ID}
+	if input.Start == nil && input.End == nil {
+		return anchor, nil
+	}
+	text := []rune(block.Text)
+	if input.Start == nil || input.End == nil || *input.Start >= *input.End || *input.End > len(text) {
+		return nil, fmt.Errorf("%w: range outside the block text", ErrInvalidAnchor)
+	}
+	anchor.Start, anchor.End = input.Start, input.End
+	anchor.Quote = string(text[*input.Start:*input.End])
+	return anchor, nil
+}
+
+func (s *service) HandleCommentEvent(ctx context.Context, _ uint64, e CommentEvent) error {
+	if e.Type != CommentCreated || s.notifier == nil {
+		return nil
+	}
+	comment, err := s.repo.GetCommentByID(e.CommentID)
+	if err != nil {
+		return err
+	}
+	if comment == nil {
+		// Deleted before it was delivered.
+		return nil
+	}
+	return s.notify(ctx, &pages.Page{ID: e.PageID, UserID: e.OwnerID, Title: e.PageTitle}, comment)
+}
+
+// notify tells the page owner and everyone else in the thread about a new
+// comment, skipping anyone who can no longer read the page. The notifier
+// skips the comment's own author. Notifications are keyed by comment and
+// recipient, so notifying again after a failure reaches only those who
+// were missed.
+func (s *service) notify(ctx context.Context, page *pages.Page, comment *Comment) error {
+	recipients := []uint{page.UserID}
+	title := fmt.Sprintf("New comment on %s", page.Title)
+	if comment.ParentID != nil {
+		title = fmt.Sprintf("New reply on %s", page.Title)
+		// Later repliers were not around for this one.
+		ids, err := s.repo.GetParticipants(*comment.ParentID, comment.ID)
+		if err != nil {
+			return err
+		}
+		recipients = append(recipients, ids...)
+	}
+
+	seen := make(map[uint]bool, len(recipients))
+	for _, id := range recipients {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if _, err := s.pages.GetPageByID(page.ID, id); err != nil {
+			if errors.Is(err, pages.ErrPageNotFound) {
+				continue
+			}
+			return err
+		}
+		key := fmt.Sprintf("comment:%d:%d", comment.ID, id)
+		err := s.notifier.Notify(ctx, &notifications.Notification{
+			UserID:   id,
+			Type:     notifications.TypeComment,
+			ActorID:  &comment.UserID,
+			Title:    title,
+			Body:     comment.Body,
+			Link:     fmt.Sprintf("/pages/%d?comment=%d", page.ID, comment.ID),
+			DedupKey: &key,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HandlePageEvent removes a deleted page's comments.
+func (s *service) HandlePageEvent(_ context.Context, _ uint64, e pages.PageEvent) error {
+	if e.Type != pages.PageDeleted {
+		return nil
+	}
+	return s.repo.DeleteByPage(e.PageID)
+}
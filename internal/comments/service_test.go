@@ -0,0 +1,322 @@
+package comments
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"flowboard-backend-go/internal/notifications"
+	"flowboard-backend-go/internal/pages"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memRepo is an in-memory Repository for service tests.
+type memRepo struct {
+	comments map[uint]*Comment
+	events   []CommentEvent
+	relayed  int // events already handed to relay
+	nextID   uint
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{comments: map[uint]*Comment{}}
+}
+
+func (r *memRepo) Transaction(fn func(repo Repository) error) error {
+	return fn(r)
+}
+
+func (r *memRepo) AddEvent(e CommentEvent) error {
+	r.events = append(r.events, e)
+	return nil
+}
+
+// relay hands the events recorded since the last call to s, the way the
+// outbox relay does.
+func (r *memRepo) relay(t *testing.T, s Service) {
+	for ; r.relayed < len(r.events); r.relayed++ {
+		require.NoError(t, s.HandleCommentEvent(context.Background(), uint64(r.relayed+1), r.events[r.relayed]))
+	}
+}
+
+func (r *memRepo) CreateComment(comment *Comment) error {
+	r.nextID++
+	comment.ID = r.nextID
+	cp := *comment
+	r.comments[comment.ID] = &cp
+	return nil
+}
+
+func (r *memRepo) GetCommentByID(id uint) (*Comment, error) {
+	if c, ok := r.comments[id]; ok {
+		cp := *c
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (r *memRepo) GetThreads(pageID uint, resolved *bool) ([]Comment, error) {
+	var out []Comment
+	for _, c := range r.sorted() {
+		if c.PageID != pageID || c.ParentID != nil {
+			continue
+		}
+		if resolved != nil && *resolved != (c.ResolvedAt != nil) {
+			continue
+		}
+		thread := *c
+		for _, reply := range r.sorted() {
+			if reply.ParentID != nil && *reply.ParentID == c.ID {
+				thread.Replies = append(thread.Replies, *reply)
+			}
+		}
+		out = append(out, thread)
+	}
+	return out, nil
+}
+
+func (r *memRepo) sorted() []*Comment {
+	out := make([]*Comment, 0, len(r.comments))
+	for _, c := range r.comments {
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func (r *memRepo) GetParticipants(rootID, beforeID uint) ([]uint, error) {
+	var ids []uint
+	for _, c := range r.sorted() {
+		if c.ID >= beforeID {
+			continue
+		}
+		if c.ID == rootID || (c.ParentID != nil && *c.ParentID == rootID) {
+			ids = append(ids, c.UserID)
+		}
+	}
+	return ids, nil
+}
+
+func (r *memRepo) UpdateComment(comment *Comment) error {
+	cp := *comment
+	r.comments[comment.ID] = &cp
+	return nil
+}
+
+func (r *memRepo) DeleteComment(id uint) error {
+	for cid, c := range r.comments {
+		if cid == id || (c.ParentID != nil && *c.ParentID == id) {
+			delete(r.comments, cid)
+		}
+	}
+	return nil
+}
+
+func (r *memRepo) DeleteByPage(pageID uint) error {
+	for id, c := range r.comments {
+		if c.PageID == pageID {
+			delete(r.comments, id)
+		}
+	}
+	return nil
+}
+
+// stubPages serves page 100, owned by user 1 and readable by users 1-3,
+// with a single block "b1".
+type stubPages struct{}
+
+func (stubPages) GetPageByID(id, userID uint) (*pages.Page, error) {
+	if id == 100 && userID >= 1 && userID <= 3 {
+		return &pages.Page{ID: id, UserID: 1, Title: "Roadmap"}, nil
+	}
+	return nil, pages.ErrPageNotFound
+}
+
+func (p stubPages) GetBlocks(pageID, userID uint) ([]pages.Block, error) {
+	if _, err := p.GetPageByID(pageID, userID); err != nil {
+		return nil, err
+	}
+	return []pages.Block{{ID: "b1", PageID: pageID, Text: "Ship the beta"}}, nil
+}
+
+// recordingNotifier records notifications, dropping self-notifications
+// and repeated keys like the notification center does.
+type recordingNotifier struct {
+	sent []*notifications.Notification
+	keys map[string]bool
+}
+
+func (r *recordingNotifier) Notify(_ context.Context, n *notifications.Notification) error {
+	if n.ActorID != nil && *n.ActorID == n.UserID {
+		return nil
+	}
+	if n.DedupKey != nil {
+		if r.keys[*n.DedupKey] {
+			return nil
+		}
+		if r.keys == nil {
+			r.keys = map[string]bool{}
+		}
+		r.keys[*n.DedupKey] = true
+	}
+	r.sent = append(r.sent, n)
+	return nil
+}
+
+func (r *recordingNotifier) recipients() []uint {
+	var ids []uint
+	for _, n := range r.sent {
+		ids = append(ids, n.UserID)
+	}
+	return ids
+}
+
+func intPtr(v int) *int { return &v }
+
+func TestThreadsAndReplies(t *testing.T) {
+	notifier := &recordingNotifier{}
+	repo := newMemRepo()
+	s := NewService(repo, stubPages{}, WithNotifier(notifier))
+
+	root, err := s.CreateComment(100, CommentInput{
+		Body:   " Is this date realistic? ",
+		Anchor: &AnchorInput{BlockID: "b1", Start: intPtr(9), End: intPtr(13)},
+	}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "Is this date realistic?", root.Body)
+	require.NotNil(t, root.Anchor)
+	assert.Equal(t, "beta", root.Anchor.Quote)
+	assert.Empty(t, notifier.sent, "notifications wait for the relay")
+	repo.relay(t, s)
+	assert.Equal(t, []uint{1}, notifier.recipients())
+
+	reply, err := s.CreateComment(100, CommentInput{Body: "Yes", ParentID: &root.ID}, 1)
+	require.NoError(t, err)
+	nested, err := s.CreateComment(100, CommentInput{Body: "Great", ParentID: &reply.ID}, 3)
+	require.NoError(t, err)
+	assert.Equal(t, root.ID, *nested.ParentID, "replies to replies join the thread")
+	repo.relay(t, s)
+	assert.Equal(t, []uint{1, 2, 1, 2}, notifier.recipients())
+	require.NoError(t, s.HandleCommentEvent(context.Background(), 3, repo.events[2]))
+	assert.Len(t, notifier.sent, 4, "a redelivered event notifies nobody twice")
+
+	threads, err := s.GetComments(100, 3, nil)
+	require.NoError(t, err)
+	require.Len(t, threads, 1)
+	assert.Len(t, threads[0].Replies, 2)
+
+	_, err = s.GetComments(100, 9, nil)
+	assert.ErrorIs(t, err, pages.ErrPageNotFound)
+}
+
+func TestAnchorValidation(t *testing.T) {
+	s := NewService(newMemRepo(), stubPages{})
+
+	_, err := s.CreateComment(100, CommentInput{Body: "x", Anchor: &AnchorInput{BlockID: "nope"}}, 1)
+	assert.ErrorIs(t, err, ErrInvalidAnchor)
+	_, err = s.CreateComment(100, CommentInput{Body: "x", Anchor: &AnchorInput{BlockID: "b1", Start: intPtr(3)}}, 1)
+	assert.ErrorIs(t, err, ErrInvalidAnchor)
+	_, err = s.CreateComment(100, CommentInput{Body: "x", Anchor: &AnchorInput{BlockID: "b1", Start: intPtr(5), End: intPtr(50)}}, 1)
+	assert.ErrorIs(t, err, ErrInvalidAnchor)
+
+	root, err := s.CreateComment(100, CommentInput{Body: "x", Anchor: &AnchorInput{BlockID: "b1"}}, 1)
+	require.NoError(t, err)
+	_, err = s.CreateComment(100, CommentInput{Body: "y", ParentID: &root.ID, Anchor: &AnchorInput{BlockID: "b1"}}, 1)
+	assert.ErrorIs(t, err, ErrInvalidAnchor)
+}
+
+func TestEditDeleteAndResolve(t *testing.T) {
+	s := NewService(newMemRepo(), stubPages{})
+	root, _ := s.CreateComment(100, CommentInput{Body: "Typo here"}, 2)
+	reply, _ := s.CreateComment(100, CommentInput{Body: "Fixed", ParentID: &root.ID}, 1)
+
+	_, err := s.UpdateComment(100, root.ID, CommentUpdateInput{Body: "mine now"}, 1)
+	assert.ErrorIs(t, err, ErrNotAuthor)
+	edited, err := s.UpdateComment(100, root.ID, CommentUpdateInput{Body: "Typo in title"}, 2)
+	require.NoError(t, err)
+	assert.NotNil(t, edited.EditedAt)
+
+	_, err = s.SetResolved(100, reply.ID, true, 1)
+	assert.ErrorIs(t, err, ErrNotThread)
+	resolved, err := s.SetResolved(100, root.ID, true, 3)
+	require.NoError(t, err)
+	require.NotNil(t, resolved.ResolvedBy)
+	assert.Equal(t, uint(3), *resolved.ResolvedBy)
+
+	open := false
+	threads, _ := s.GetComments(100, 1, &open)
+	assert.Empty(t, threads)
+
+	// Replying reopens the thread.
+	_, err = s.CreateComment(100, CommentInput{Body: "Not quite", ParentID: &root.ID}, 2)
+	require.NoError(t, err)
+	threads, _ = s.GetComments(100, 1, &open)
+	assert.Len(t, threads, 1)
+
+	assert.ErrorIs(t, s.DeleteComment(100, root.ID, 1), ErrNotAuthor)
+	assert.ErrorIs(t, s.DeleteComment(200, root.ID, 2), pages.ErrPageNotFound)
+	require.NoError(t, s.DeleteComment(100, root.ID, 2))
+	threads, _ = s.GetComments(100, 1, nil)
+	assert.Empty(t, threads)
+}
+
+func TestEmptyBodyRejected(t *testing.T) {
+	s := NewService(newMemRepo(), stubPages{})
+
+	_, err := s.CreateComment(100, CommentInput{Body: " \n\t "}, 2)
+	assert.ErrorIs(t, err, ErrEmptyBody)
+	root, err := s.CreateComment(100, CommentInput{Body: "Typo here"}, 2)
+	require.NoError(t, err)
+	_, err = s.UpdateComment(100, root.ID, CommentUpdateInput{Body: "  "}, 2)
+	assert.ErrorIs(t, err, ErrEmptyBody)
+}
+
+func TestPageDeletedRemovesComments(t *testing.T) {
+	repo := newMemRepo()
+	s := NewService(repo, stubPages{})
+	root, _ := s.CreateComment(100, CommentInput{Body: "Typo here"}, 2)
+	_, _ = s.CreateComment(100, CommentInput{Body: "Fixed", ParentID: &root.ID}, 1)
+	repo.comments[99] = &Comment{ID: 99, PageID: 200, UserID: 1, Body: "Elsewhere"}
+
+	ctx := context.Background()
+	require.NoError(t, s.HandlePageEvent(ctx, 1, pages.PageEvent{Type: pages.PageUpdated, PageID: 100}))
+	assert.Len(t, repo.comments, 3)
+	require.NoError(t, s.HandlePageEvent(ctx, 2, pages.PageEvent{Type: pages.PageDeleted, PageID: 100}))
+	assert.Len(t, repo.comments, 1)
+	assert.Contains(t, repo.comments, uint(99))
+}
+
+func TestCommentEvents(t *testing.T) {
+	repo := newMemRepo()
+	s := NewService(repo, stubPages{})
+
+	root, err := s.CreateComment(100, CommentInput{Body: strings.Repeat("a", maxExcerpt+10)}, 2)
+	require.NoError(t, err)
+	_, err = s.CreateComment(100, CommentInput{Body: "Agreed", ParentID: &root.ID}, 3)
+	require.NoError(t, err)
+	_, err = s.SetResolved(100, root.ID, true, 1)
+	require.NoError(t, err)
+	// Resolving again and reopening record nothing.
+	_, err = s.SetResolved(100, root.ID, true, 1)
+	require.NoError(t, err)
+	_, err = s.SetResolved(100, root.ID, false, 1)
+	require.NoError(t, err)
+	_, err = s.CreateComment(999, CommentInput{Body: "Lost"}, 2)
+	require.Error(t, err)
+
+	require.Len(t, repo.events, 3)
+	first := repo.events[0]
+	assert.Equal(t, CommentCreated, first.Type)
+	assert.Equal(t, "Roadmap", first.PageTitle)
+	assert.Equal(t, uint(1), first.OwnerID)
+	assert.Equal(t, uint(2), first.ActorID)
+	assert.Len(t, []rune(first.Excerpt), maxExcerpt)
+
+	assert.Equal(t, root.ID, repo.events[1].ThreadID)
+	assert.Equal(t, "Agreed", repo.events[1].Excerpt)
+	assert.Equal(t, ThreadResolved, repo.events[2].Type)
+	assert.Equal(t, uint(1), repo.events[2].ActorID)
+}
@@ -1,7 +1,6 @@
 package database
 
 import (
-	"fmt"
 	"log"
 
 	"flowboard-backend-go/pkg/config"
@@ -11,11 +10,8 @@ import (
 )
 
 func Connect(cfg *config.Config) *gorm.DB {
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
-		cfg.DBHost, cfg.DBUser, cfg.DBPass, cfg.DBName, cfg.DBPort,
-	)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	// TranslateError surfaces unique violations as gorm.ErrDuplicatedKey.
+	db, err := gorm.Open(postgres.Open(cfg.DatabaseDSN()), &gorm.Config{TranslateError: true})
 	if err != nil {
 		log.Fatal("Failed to connect to DB:", err)
 	}
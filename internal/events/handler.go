@@ -0,0 +1,156 @@
+package events
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"flowboard-backend-go/internal/realtime"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// heartbeatInterval keeps proxies from closing an idle stream.
+	heartbeatInterval = 15 * time.Second
+	// retryMillis is the reconnect delay suggested to EventSource clients.
+	retryMillis = 3000
+	// sentWindow is how many event IDs a stream remembers in order to skip
+	// events it has already sent.
+	sentWindow = 1024
+)
+
+type Handler struct {
+	service Service
+	auth    *realtime.Authenticator
+}
+
+func NewHandler(service Service, auth *realtime.Authenticator) *Handler {
+	return &Handler{service: service, auth: auth}
+}
+
+// Stream serves the user's page changes, notifications and share events as
+// Server-Sent Events. EventSource cannot set headers, so browsers pass a
+// stream ticket as ?ticket=. A reconnecting client sends Last-Event-ID (or
+// ?lastEventId=) and first receives what it missed; if that is no longer
+// possible it receives a "reset" event and should refetch its state.
+func (h *Handler) Stream(c *gin.Context) {
+	userID, ok := h.auth.Authenticate(c)
+	if !ok {
+		return
+	}
+
+	lastRaw := c.GetHeader("Last-Event-ID")
+	if lastRaw == "" {
+		lastRaw = c.Query("lastEventId")
+	}
+	var lastID uint64
+	if lastRaw != "" {
+		id, err := strconv.ParseUint(lastRaw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Last-Event-ID"})
+			return
+		}
+		lastID = id
+	}
+
+	// Subscribe before replaying so nothing falls between the two. IDs do
+	// not arrive in order, so events already sent, in the replay or live,
+	// are remembered and skipped rather than compared with the last ID.
+	sub, err := h.service.Subscribe(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer sub.Close()
+
+	var replay *Replay
+	if lastRaw != "" {
+		if replay, err = h.service.Replay(userID, lastID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	w := c.Writer
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", retryMillis)
+
+	sent := newSentIDs(sentWindow)
+	if replay != nil {
+		sent.add(lastID)
+		if replay.Reset {
+			writeEvent(w, replay.LastID, "reset", "{}")
+		}
+		for _, ev := range replay.Events {
+			if sent.add(ev.ID) {
+				writeEvent(w, ev.ID, ev.Type, ev.Data)
+			}
+		}
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+			w.Flush()
+		case ev, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if !sent.add(ev.ID) {
+				continue
+			}
+			if err := writeEvent(w, ev.ID, ev.Type, ev.Data); err != nil {
+				return
+			}
+			w.Flush()
+		}
+	}
+}
+
+// writeEvent writes one SSE frame. data is single-line JSON.
+func writeEvent(w io.Writer, id uint64, eventType, data string) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, eventType, data)
+	return err
+}
+
+// sentIDs remembers the last IDs added to it, up to a fixed number.
+type sentIDs struct {
+	set  map[uint64]bool
+	ring []uint64
+	next int
+}
+
+func newSentIDs(size int) *sentIDs {
+	return &sentIDs{set: make(map[uint64]bool, size), ring: make([]uint64, 0, size)}
+}
+
+// add records id and reports whether it was not remembered yet.
+func (s *sentIDs) add(id uint64) bool {
+	if s.set[id] {
+		return false
+	}
+	if len(s.ring) < cap(s.ring) {
+		s.ring = append(s.ring, id)
+	} else {
+		delete(s.set, s.ring[s.next])
+		s.ring[s.next] = id
+		s.next = (s.next + 1) % len(s.ring)
+	}
+	s.set[id] = true
+	return true
+}
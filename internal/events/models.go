@@ -0,0 +1,31 @@
+package events
+
+import "time"
+
+// Event is one entry of the bounded log that backs the SSE stream. Its
+// audience is either a single user or every member of a workspace; Data
+// holds the JSON payload sent to clients.
+type Event struct {
+	ID          uint64    `gorm:"primaryKey" json:"id"`
+	UserID      *uint     `gorm:"index" json:"userId,omitempty"`
+	WorkspaceID *uint     `gorm:"index" json:"workspaceId,omitempty"`
+	Type        string    `gorm:"size:64;not null" json:"type"`
+	Data        string    `gorm:"type:jsonb;not null" json:"data"`
+	CreatedAt   time.Time `gorm:"index" json:"createdAt"`
+	// SourceID is the outbox message a page event came from, so a
+	// redelivered message is logged once.
+	SourceID *uint64 `gorm:"uniqueIndex" json:"-"`
+}
+
+// NotificationCreated is the event type of a new in-app notification.
+const NotificationCreated = "notification.created"
+
+// Replay is the backlog a reconnecting client missed. When Reset is set
+// the backlog could not be replayed in full, either because part of it was
+// pruned or because it is too long; the client should refetch its state
+// and continue from LastID.
+type Replay struct {
+	Events []Event
+	Reset  bool
+	LastID uint64
+}
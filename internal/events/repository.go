@@ -0,0 +1,88 @@
+package events
+
+import (
+	"errors"
+	"time"
+
+	"flowboard-backend-go/internal/workspaces"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	// Append stores e and reports whether it is new: an event whose
+	// SourceID is already logged is skipped.
+	Append(e *Event) (bool, error)
+	GetByID(id uint64) (*Event, error)
+	// Since returns up to limit events addressed to the user or to one of
+	// the workspaces that come after afterID or, when since is set, were
+	// created at or after since, in ID order.
+	Since(userID uint, workspaceIDs []uint, afterID uint64, since time.Time, limit int) ([]Event, error)
+	// Bounds returns the lowest and highest event IDs in the log, or zeros
+	// if it is empty.
+	Bounds() (first, last uint64, err error)
+	Prune(before time.Time) (int64, error)
+	WorkspaceIDs(userID uint) ([]uint, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Append(e *Event) (bool, error) {
+	res := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(e)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *repository) GetByID(id uint64) (*Event, error) {
+	var e Event
+	if err := r.db.First(&e, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (r *repository) Since(userID uint, workspaceIDs []uint, afterID uint64, since time.Time, limit int) ([]Event, error) {
+	audience := r.db.Where("user_id = ?", userID)
+	if len(workspaceIDs) > 0 {
+		audience = audience.Or("workspace_id IN ?", workspaceIDs)
+	}
+	window := r.db.Where("id > ?", afterID)
+	if !since.IsZero() {
+		window = window.Or("created_at >= ?", since)
+	}
+	var list []Event
+	err := r.db.Where(window).Where(audience).
+		Order("id").Limit(limit).Find(&list).Error
+	return list, err
+}
+
+func (r *repository) Bounds() (uint64, uint64, error) {
+	var b struct {
+		First uint64
+		Last  uint64
+	}
+	err := r.db.Model(&Event{}).
+		Select("COALESCE(MIN(id), 0) AS first, COALESCE(MAX(id), 0) AS last").
+		Scan(&b).Error
+	return b.First, b.Last, err
+}
+
+func (r *repository) Prune(before time.Time) (int64, error) {
+	res := r.db.Where("created_at < ?", before).Delete(&Event{})
+	return res.RowsAffected, res.Error
+}
+
+func (r *repository) WorkspaceIDs(userID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&workspaces.Member{}).Where("user_id = ?", userID).Pluck("workspace_id", &ids).Error
+	return ids, err
+}
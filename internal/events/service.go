@@ -0,0 +1,366 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"flowboard-backend-go/internal/notifications"
+	"flowboard-backend-go/internal/pages"
+	"flowboard-backend-go/internal/workspaces"
+	"flowboard-backend-go/pkg/logger"
+	"flowboard-backend-go/pkg/pubsub"
+)
+
+// Topic carries appended events between replicas.
+const Topic = "events"
+
+const (
+	// Retention is how long events stay replayable.
+	Retention = 24 * time.Hour
+	// maxReplay is the longest backlog replayed on reconnect; longer ones
+	// reset the client instead.
+	maxReplay = 500
+	// replayMargin reaches back before the last event a client saw. IDs
+	// are taken before commit, so an event with a lower ID can become
+	// visible after one with a higher ID has already been streamed.
+	replayMargin = 5 * time.Second
+	// maxInlineData is the largest payload relayed through pub/sub;
+	// receivers load larger events from the log.
+	maxInlineData = 6000
+	// noticeBuffer is how many relayed events may wait for dispatch before
+	// new ones are dropped.
+	noticeBuffer = 256
+	// subscriptionBuffer is how many events may wait for a slow client
+	// before its subscription is closed.
+	subscriptionBuffer = 64
+	// membershipTTL is how long a subscription trusts its list of
+	// workspaces before reloading it, so removed members stop receiving
+	// workspace events.
+	membershipTTL  = time.Minute
+	publishTimeout = 5 * time.Second
+)
+
+// Service keeps the event log behind GET /api/events and fans new events
+// out to the streams open on this replica. It receives page changes from
+// the outbox, and notifications and share events from their services.
+type Service interface {
+	pages.EventHandler
+	notifications.EventPublisher
+	workspaces.EventPublisher
+
+	// Replay returns the user's events after afterID, plus those logged up
+	// to replayMargin before it, which the client may have missed or may
+	// already have; clients skip IDs they have seen.
+	Replay(userID uint, afterID uint64) (*Replay, error)
+	// Subscribe delivers the user's events as they are appended on any
+	// replica.
+	Subscribe(userID uint) (*Subscription, error)
+	// Run dispatches appended events to subscriptions until ctx is done.
+	Run(ctx context.Context)
+	Prune(before time.Time) (int64, error)
+}
+
+// Subscription is one open stream. C is closed when the subscription is,
+// including when the client falls too far behind; it should reconnect and
+// replay from the last event it received.
+type Subscription struct {
+	C <-chan Event
+
+	s         *service
+	userID    uint
+	ch        chan Event
+	closed    bool
+	spaces    map[uint]bool
+	checkedAt time.Time
+}
+
+// Close ends the subscription. It is safe to call more than once.
+func (sub *Subscription) Close() {
+	sub.s.mu.Lock()
+	defer sub.s.mu.Unlock()
+	sub.s.drop(sub)
+}
+
+type service struct {
+	repo    Repository
+	ps      pubsub.PubSub
+	notices chan Event
+	now     func() time.Time
+
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+// NewService subscribes to appended events on ps. Call Run to start
+// dispatching.
+func NewService(repo Repository, ps pubsub.PubSub) Service {
+	s := &service{
+		repo:    repo,
+		ps:      ps,
+		notices: make(chan Event, noticeBuffer),
+		now:     time.Now,
+		subs:    map[*Subscription]struct{}{},
+	}
+	ps.Subscribe(Topic, s.receive)
+	return s
+}
+
+// HandlePageEvent logs a page change for the page's workspace, or for its
+// owner if it is personal.
+func (s *service) HandlePageEvent(ctx context.Context, eventID uint64, e pages.PageEvent) error {
+	ev := &Event{Type: string(e.Type), WorkspaceID: e.WorkspaceID, SourceID: &eventID}
+	if e.WorkspaceID == nil {
+		owner := e.OwnerID
+		ev.UserID = &owner
+	}
+	return s.append(ctx, ev, e)
+}
+
+// PublishNotification logs a notification for its recipient. Failures are
+// logged, never returned: the notification has already been stored.
+func (s *service) PublishNotification(n *notifications.Notification) {
+	userID := n.UserID
+	if err := s.append(context.Background(), &Event{Type: NotificationCreated, UserID: &userID}, n); err != nil {
+		logger.Log.Errorw("Logging notification event failed", "notificationID", n.ID, "error", err)
+	}
+}
+
+// PublishShareEvent logs a share event for its recipient; failures are
+// logged like notifications'.
+func (s *service) PublishShareEvent(e workspaces.ShareEvent) {
+	userID := e.UserID
+	if err := s.append(context.Background(), &Event{Type: string(e.Type), UserID: &userID}, e); err != nil {
+		logger.Log.Errorw("Logging share event failed", "invitationID", e.InvitationID, "error", err)
+	}
+}
+
+// append stores the event and relays it to every replica. Once stored,
+// the event is replayable, so a failed relay only delays live streams
+// until clients reconnect; it is logged rather than returned.
+func (s *service) append(ctx context.Context, ev *Event, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	ev.Data = string(payload)
+	ev.CreatedAt = s.now()
+	fresh, err := s.repo.Append(ev)
+	if err != nil || !fresh {
+		return err
+	}
+
+	relay := *ev
+	if len(relay.Data) > maxInlineData {
+		relay.Data = ""
+	}
+	msg, err := json.Marshal(relay)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+	if err := s.ps.Publish(ctx, Topic, msg); err != nil {
+		logger.Log.Errorw("Publishing event failed", "eventID", ev.ID, "error", err)
+	}
+	return nil
+}
+
+func (s *service) Replay(userID uint, afterID uint64) (*Replay, error) {
+	first, last, err := s.repo.Bounds()
+	if err != nil {
+		return nil, err
+	}
+	if last == 0 {
+		return &Replay{LastID: afterID}, nil
+	}
+	// Events after afterID were pruned.
+	if first > afterID+1 {
+		return &Replay{Reset: true, LastID: last}, nil
+	}
+
+	var since time.Time
+	seen, err := s.repo.GetByID(afterID)
+	if err != nil {
+		return nil, err
+	}
+	if seen != nil {
+		since = seen.CreatedAt.Add(-replayMargin)
+	}
+	spaces, err := s.repo.WorkspaceIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	list, err := s.repo.Since(userID, spaces, afterID, since, maxReplay+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) > maxReplay {
+		return &Replay{Reset: true, LastID: last}, nil
+	}
+	r := &Replay{Events: list, LastID: afterID}
+	for _, ev := range list {
+		r.LastID = max(r.LastID, ev.ID)
+	}
+	return r, nil
+}
+
+func (s *service) Subscribe(userID uint) (*Subscription, error) {
+	ids, err := s.repo.WorkspaceIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan Event, subscriptionBuffer)
+	sub := &Subscription{C: ch, s: s, userID: userID, ch: ch}
+	sub.setSpaces(ids, s.now())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subs[sub] = struct{}{}
+	return sub, nil
+}
+
+func (s *service) Run(ctx context.Context) {
+	defer s.shutdown()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-s.notices:
+			s.dispatch(ev)
+		}
+	}
+}
+
+func (s *service) Prune(before time.Time) (int64, error) {
+	return s.repo.Prune(before)
+}
+
+// receive is the pub/sub handler; it must not block.
+func (s *service) receive(payload []byte) {
+	var ev Event
+	if err := json.Unmarshal(payload, &ev); err != nil {
+		logger.Log.Warnw("Ignoring malformed event", "error", err)
+		return
+	}
+	select {
+	case s.notices <- ev:
+	default:
+		logger.Log.Warnw("Event dropped, dispatch is behind", "eventID", ev.ID, "type", ev.Type)
+	}
+}
+
+func (s *service) dispatch(ev Event) {
+	var targets []*Subscription
+	for _, sub := range s.subscriptions() {
+		if s.matches(sub, ev) {
+			targets = append(targets, sub)
+		}
+		// A new member's workspaces are reloaded on its next match.
+		if ev.Type == string(workspaces.ShareJoined) && ev.UserID != nil && *ev.UserID == sub.userID {
+			sub.checkedAt = time.Time{}
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	if ev.Data == "" {
+		stored, err := s.repo.GetByID(ev.ID)
+		if err != nil || stored == nil {
+			logger.Log.Errorw("Loading event failed", "eventID", ev.ID, "error", err)
+			return
+		}
+		ev = *stored
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sub := range targets {
+		if sub.closed {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			s.drop(sub)
+		}
+	}
+}
+
+// matches reports whether sub is in ev's audience. It runs on the
+// dispatch goroutine, which owns the subscription's workspace list.
+func (s *service) matches(sub *Subscription, ev Event) bool {
+	if ev.UserID != nil {
+		return *ev.UserID == sub.userID
+	}
+	if ev.WorkspaceID == nil {
+		return false
+	}
+	if now := s.now(); now.Sub(sub.checkedAt) > membershipTTL {
+		ids, err := s.repo.WorkspaceIDs(sub.userID)
+		if err != nil {
+			logger.Log.Errorw("Loading workspaces failed", "userID", sub.userID, "error", err)
+			return false
+		}
+		sub.setSpaces(ids, now)
+	}
+	return sub.spaces[*ev.WorkspaceID]
+}
+
+func (sub *Subscription) setSpaces(ids []uint, at time.Time) {
+	sub.spaces = make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		sub.spaces[id] = true
+	}
+	sub.checkedAt = at
+}
+
+func (s *service) subscriptions() []*Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]*Subscription, 0, len(s.subs))
+	for sub := range s.subs {
+		list = append(list, sub)
+	}
+	return list
+}
+
+// drop closes sub; s.mu must be held.
+func (s *service) drop(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	close(sub.ch)
+	delete(s.subs, sub)
+}
+
+func (s *service) shutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subs {
+		s.drop(sub)
+	}
+}
+
+// RunPruner deletes events older than Retention every interval until ctx
+// is done.
+func RunPruner(ctx context.Context, s Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.Prune(time.Now().Add(-Retention))
+			if err != nil {
+				logger.Log.Errorw("Pruning events failed", "error", err)
+			} else if n > 0 {
+				logger.Log.Infow("Pruned events", "count", n)
+			}
+		}
+	}
+}
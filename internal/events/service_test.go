@@ -0,0 +1,368 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"flowboard-backend-go/internal/notifications"
+	"flowboard-backend-go/internal/pages"
+	"flowboard-backend-go/internal/realtime"
+	"flowboard-backend-go/internal/workspaces"
+	"flowboard-backend-go/pkg/pubsub"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memRepo struct {
+	mu      sync.Mutex
+	events  []Event
+	nextID  uint64
+	members map[uint][]uint
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{members: map[uint][]uint{}}
+}
+
+func (r *memRepo) Append(e *Event) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e.SourceID != nil {
+		for _, old := range r.events {
+			if old.SourceID != nil && *old.SourceID == *e.SourceID {
+				return false, nil
+			}
+		}
+	}
+	r.nextID++
+	e.ID = r.nextID
+	r.events = append(r.events, *e)
+	return true, nil
+}
+
+func (r *memRepo) GetByID(id uint64) (*Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.events {
+		if e.ID == id {
+			return &e, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memRepo) Since(userID uint, workspaceIDs []uint, afterID uint64, since time.Time, limit int) ([]Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var list []Event
+	for _, e := range r.events {
+		inWindow := e.ID > afterID || (!since.IsZero() && !e.CreatedAt.Before(since))
+		if !inWindow || len(list) == limit {
+			continue
+		}
+		in := e.UserID != nil && *e.UserID == userID
+		for _, ws := range workspaceIDs {
+			in = in || (e.WorkspaceID != nil && *e.WorkspaceID == ws)
+		}
+		if in {
+			list = append(list, e)
+		}
+	}
+	return list, nil
+}
+
+func (r *memRepo) Bounds() (uint64, uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.events) == 0 {
+		return 0, 0, nil
+	}
+	return r.events[0].ID, r.events[len(r.events)-1].ID, nil
+}
+
+func (r *memRepo) Prune(before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kept := r.events[:0]
+	for _, e := range r.events {
+		if !e.CreatedAt.Before(before) {
+			kept = append(kept, e)
+		}
+	}
+	n := int64(len(r.events) - len(kept))
+	r.events = kept
+	return n, nil
+}
+
+func (r *memRepo) WorkspaceIDs(userID uint) ([]uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]uint(nil), r.members[userID]...), nil
+}
+
+// stubTokens accepts tokens of the form "user-<id>".
+type stubTokens struct{}
+
+func (stubTokens) Verify(token string) (*jwt.RegisteredClaims, error) {
+	id, ok := strings.CutPrefix(token, "user-")
+	if !ok {
+		return nil, errors.New("invalid token")
+	}
+	return &jwt.RegisteredClaims{Subject: id}, nil
+}
+
+func newTestServer(t *testing.T, repo Repository) (*httptest.Server, Service) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	s := NewService(repo, pubsub.NewLocal())
+	ctx, cancel := context.WithCancel(context.Background())
+	go s.Run(ctx)
+
+	r := gin.New()
+	r.GET("/events", NewHandler(s, realtime.NewAuthenticator(stubTokens{}, nil)).Stream)
+	srv := httptest.NewServer(r)
+	t.Cleanup(func() {
+		cancel()
+		srv.Close()
+	})
+	return srv, s
+}
+
+type frame struct {
+	id, event, data string
+}
+
+type stream struct {
+	frames chan frame
+}
+
+// open connects as userID and parses frames in the background. Comments
+// and the retry hint are skipped.
+func open(t *testing.T, srv *httptest.Server, userID uint, lastEventID string) *stream {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer user-"+strconv.Itoa(int(userID)))
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	t.Cleanup(func() { resp.Body.Close() })
+
+	st := &stream{frames: make(chan frame, 16)}
+	go func() {
+		sc := bufio.NewScanner(resp.Body)
+		var f frame
+		for sc.Scan() {
+			line := sc.Text()
+			switch {
+			case line == "":
+				if f.event != "" {
+					st.frames <- f
+				}
+				f = frame{}
+			case strings.HasPrefix(line, "id: "):
+				f.id = line[4:]
+			case strings.HasPrefix(line, "event: "):
+				f.event = line[7:]
+			case strings.HasPrefix(line, "data: "):
+				f.data = line[6:]
+			}
+		}
+	}()
+	return st
+}
+
+func (st *stream) next(t *testing.T) frame {
+	t.Helper()
+	select {
+	case f := <-st.frames:
+		return f
+	case <-time.After(2 * time.Second):
+		t.Fatal("no event received")
+		return frame{}
+	}
+}
+
+func (st *stream) none(t *testing.T) {
+	t.Helper()
+	select {
+	case f := <-st.frames:
+		t.Fatalf("unexpected event %+v", f)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// waitSubscribed waits until n streams are registered, so events
+// published next reach them.
+func waitSubscribed(t *testing.T, s Service, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		return len(s.(*service).subscriptions()) == n
+	}, time.Second, 5*time.Millisecond)
+}
+
+// handle feeds a page event to s as the outbox relay would, under the
+// given message ID.
+func handle(t *testing.T, s Service, id uint64, e pages.PageEvent) {
+	t.Helper()
+	require.NoError(t, s.HandlePageEvent(context.Background(), id, e))
+}
+
+func TestStreamDeliversToAudience(t *testing.T) {
+	repo := newMemRepo()
+	repo.members[1] = []uint{7}
+	srv, s := newTestServer(t, repo)
+
+	member := open(t, srv, 1, "")
+	outsider := open(t, srv, 2, "")
+	waitSubscribed(t, s, 2)
+
+	ws := uint(7)
+	handle(t, s, 1, pages.PageEvent{Type: pages.PageUpdated, PageID: 3, WorkspaceID: &ws, OwnerID: 1, Title: "Plan"})
+	handle(t, s, 2, pages.PageEvent{Type: pages.PageCreated, PageID: 4, OwnerID: 2, Title: "Diary"})
+	s.PublishNotification(&notifications.Notification{ID: 9, UserID: 2, Type: notifications.TypeMention, Title: "Hi"})
+
+	f := member.next(t)
+	assert.Equal(t, "1", f.id)
+	assert.Equal(t, "page.updated", f.event)
+	assert.Contains(t, f.data, `"title":"Plan"`)
+	member.none(t)
+
+	assert.Equal(t, "page.created", outsider.next(t).event)
+	f = outsider.next(t)
+	assert.Equal(t, NotificationCreated, f.event)
+	assert.Equal(t, "3", f.id)
+}
+
+func TestStreamPicksUpNewMembership(t *testing.T) {
+	repo := newMemRepo()
+	srv, s := newTestServer(t, repo)
+	st := open(t, srv, 5, "")
+	waitSubscribed(t, s, 1)
+
+	repo.mu.Lock()
+	repo.members[5] = []uint{7}
+	repo.mu.Unlock()
+	s.PublishShareEvent(workspaces.ShareEvent{Type: workspaces.ShareJoined, UserID: 5, WorkspaceID: 7})
+	assert.Equal(t, "share.joined", st.next(t).event)
+
+	ws := uint(7)
+	handle(t, s, 1, pages.PageEvent{Type: pages.PageCreated, PageID: 3, WorkspaceID: &ws, OwnerID: 1})
+	assert.Equal(t, "page.created", st.next(t).event)
+}
+
+func TestStreamResumesFromLastEventID(t *testing.T) {
+	repo := newMemRepo()
+	srv, s := newTestServer(t, repo)
+	for i := 1; i <= 3; i++ {
+		handle(t, s, uint64(i), pages.PageEvent{Type: pages.PageUpdated, PageID: uint(i), OwnerID: 1})
+	}
+	handle(t, s, 4, pages.PageEvent{Type: pages.PageUpdated, PageID: 9, OwnerID: 2})
+
+	st := open(t, srv, 1, "1")
+	assert.Equal(t, "2", st.next(t).id)
+	assert.Equal(t, "3", st.next(t).id)
+	st.none(t)
+
+	waitSubscribed(t, s, 1)
+	handle(t, s, 5, pages.PageEvent{Type: pages.PageDeleted, PageID: 1, OwnerID: 1})
+	f := st.next(t)
+	assert.Equal(t, "5", f.id)
+	assert.Equal(t, "page.deleted", f.event)
+}
+
+func TestStreamDeliversEventsCommittedOutOfOrder(t *testing.T) {
+	repo := newMemRepo()
+	srv, s := newTestServer(t, repo)
+	st := open(t, srv, 1, "")
+	waitSubscribed(t, s, 1)
+
+	// Event 3 takes its ID before event 6 but commits after it.
+	handle(t, s, 1, pages.PageEvent{Type: pages.PageCreated, PageID: 1, OwnerID: 1})
+	repo.mu.Lock()
+	repo.nextID = 5
+	repo.mu.Unlock()
+	handle(t, s, 2, pages.PageEvent{Type: pages.PageUpdated, PageID: 1, OwnerID: 1})
+	repo.mu.Lock()
+	repo.nextID = 2
+	repo.mu.Unlock()
+	handle(t, s, 3, pages.PageEvent{Type: pages.PageDeleted, PageID: 1, OwnerID: 1})
+
+	assert.Equal(t, "1", st.next(t).id)
+	assert.Equal(t, "6", st.next(t).id)
+	assert.Equal(t, "3", st.next(t).id)
+	st.none(t)
+
+	resumed := open(t, srv, 1, "6")
+	assert.Equal(t, "1", resumed.next(t).id)
+	f := resumed.next(t)
+	assert.Equal(t, "3", f.id, "replayed although it is below Last-Event-ID")
+	assert.Equal(t, "page.deleted", f.event)
+	resumed.none(t)
+}
+
+func TestRedeliveredPageEventIsLoggedOnce(t *testing.T) {
+	repo := newMemRepo()
+	srv, s := newTestServer(t, repo)
+	st := open(t, srv, 1, "")
+	waitSubscribed(t, s, 1)
+
+	handle(t, s, 1, pages.PageEvent{Type: pages.PageCreated, PageID: 3, OwnerID: 1})
+	handle(t, s, 1, pages.PageEvent{Type: pages.PageCreated, PageID: 3, OwnerID: 1})
+	handle(t, s, 2, pages.PageEvent{Type: pages.PageUpdated, PageID: 3, OwnerID: 1})
+
+	assert.Equal(t, "page.created", st.next(t).event)
+	assert.Equal(t, "page.updated", st.next(t).event)
+	st.none(t)
+	assert.Len(t, repo.events, 2)
+}
+
+func TestReplayResetsWhenBacklogIsGone(t *testing.T) {
+	repo := newMemRepo()
+	s := NewService(repo, pubsub.NewLocal()).(*service)
+	clock := time.Now().Add(-48 * time.Hour)
+	s.now = func() time.Time { return clock }
+	handle(t, s, 1, pages.PageEvent{Type: pages.PageUpdated, PageID: 1, OwnerID: 1})
+	handle(t, s, 2, pages.PageEvent{Type: pages.PageUpdated, PageID: 2, OwnerID: 1})
+	clock = time.Now()
+	handle(t, s, 3, pages.PageEvent{Type: pages.PageUpdated, PageID: 3, OwnerID: 1})
+
+	n, err := s.Prune(time.Now().Add(-Retention))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	r, err := s.Replay(1, 1)
+	require.NoError(t, err)
+	assert.True(t, r.Reset, "event 2 was pruned")
+	assert.Equal(t, uint64(3), r.LastID)
+
+	r, err = s.Replay(1, 2)
+	require.NoError(t, err)
+	assert.False(t, r.Reset)
+	require.Len(t, r.Events, 1)
+	assert.Equal(t, uint64(3), r.Events[0].ID)
+
+	owner := uint(1)
+	for i := 0; i <= maxReplay; i++ {
+		_, err := repo.Append(&Event{Type: "page.updated", UserID: &owner, Data: "{}", CreatedAt: clock})
+		require.NoError(t, err)
+	}
+	r, err = s.Replay(1, 3)
+	require.NoError(t, err)
+	assert.True(t, r.Reset, "too long to replay")
+}
@@ -0,0 +1,51 @@
+package favorites
+
+import "time"
+
+// Favorite is a page the user starred; Position gives their manual order.
+type Favorite struct {
+	UserID    uint      `gorm:"primaryKey" json:"userId"`
+	PageID    uint      `gorm:"primaryKey;index" json:"pageId"`
+	Position  int       `gorm:"not null" json:"position"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Pin keeps a page at the top of the user's sidebar.
+type Pin struct {
+	UserID    uint      `gorm:"primaryKey" json:"userId"`
+	PageID    uint      `gorm:"primaryKey;index" json:"pageId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// RecentView is the last time a user opened a page. There is one row per
+// user and page, so repeated views only move it to the front.
+type RecentView struct {
+	UserID   uint      `gorm:"primaryKey" json:"userId"`
+	PageID   uint      `gorm:"primaryKey;index" json:"pageId"`
+	ViewedAt time.Time `gorm:"not null;index" json:"viewedAt"`
+}
+
+// PageSummary is the slice of a page the navigation lists need.
+type PageSummary struct {
+	ID        uint      `json:"id"`
+	Title     string    `json:"title"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Entry is one item of a favorites, pins or recent list. Position orders
+// favorites from 0; the other lists leave it 0.
+type Entry struct {
+	Page     PageSummary `json:"page"`
+	Position int         `json:"position"`
+	At       time.Time   `json:"at"`
+}
+
+// PageRefInput identifies the page to favorite or pin
+type PageRefInput struct {
+	PageID uint `json:"pageId" binding:"required"`
+}
+
+// ReorderInput lists all favorite page IDs in their new order
+type ReorderInput struct {
+	PageIDs []uint `json:"pageIds" binding:"required"`
+}
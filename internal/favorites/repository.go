@@ -0,0 +1,134 @@
+package favorites
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	GetFavorites(userID uint) ([]Favorite, error)
+	AddFavorite(userID, pageID uint) error
+	RemoveFavorite(userID, pageID uint) error
+	ReorderFavorites(userID uint, pageIDs []uint) error
+
+	GetPins(userID uint) ([]Pin, error)
+	AddPin(userID, pageID uint) error
+	RemovePin(userID, pageID uint) error
+
+	GetRecent(userID uint, limit int) ([]RecentView, error)
+	// RecordView upserts the view and trims the user's history to keep rows.
+	RecordView(userID, pageID uint, at time.Time, keep int) error
+
+	// RemovePage deletes every user's favorite, pin and recent view of the
+	// page.
+	RemovePage(pageID uint) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) GetFavorites(userID uint) ([]Favorite, error) {
+	var list []Favorite
+	if err := r.db.Where("user_id = ?", userID).Order("position").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// AddFavorite appends the page to the end of the user's favorites. Adding
+// an existing favorite is a no-op.
+func (r *repository) AddFavorite(userID, pageID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var next int
+		err := tx.Model(&Favorite{}).
+			Where("user_id = ?", userID).
+			Select("COALESCE(MAX(position), -1) + 1").
+			Scan(&next).Error
+		if err != nil {
+			return err
+		}
+		fav := Favorite{UserID: userID, PageID: pageID, Position: next}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&fav).Error
+	})
+}
+
+func (r *repository) RemoveFavorite(userID, pageID uint) error {
+	return r.db.Where("user_id = ? AND page_id = ?", userID, pageID).Delete(&Favorite{}).Error
+}
+
+func (r *repository) ReorderFavorites(userID uint, pageIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for i, pageID := range pageIDs {
+			err := tx.Model(&Favorite{}).
+				Where("user_id = ? AND page_id = ?", userID, pageID).
+				Update("position", i).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *repository) GetPins(userID uint) ([]Pin, error) {
+	var list []Pin
+	if err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *repository) AddPin(userID, pageID uint) error {
+	pin := Pin{UserID: userID, PageID: pageID}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&pin).Error
+}
+
+func (r *repository) RemovePin(userID, pageID uint) error {
+	return r.db.Where("user_id = ? AND page_id = ?", userID, pageID).Delete(&Pin{}).Error
+}
+
+func (r *repository) GetRecent(userID uint, limit int) ([]RecentView, error) {
+	var list []RecentView
+	err := r.db.Where("user_id = ?", userID).Order("viewed_at DESC").Limit(limit).Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *repository) RecordView(userID, pageID uint, at time.Time, keep int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		view := RecentView{UserID: userID, PageID: pageID, ViewedAt: at}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "page_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"viewed_at"}),
+		}).Create(&view).Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec(
+			`DELETE FROM recent_views WHERE user_id = ? AND page_id NOT IN (
+				SELECT page_id FROM recent_views WHERE user_id = ? ORDER BY viewed_at DESC LIMIT ?
+			)`,
+			userID, userID, keep,
+		).Error
+	})
+}
+
+func (r *repository) RemovePage(pageID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []any{&Favorite{}, &Pin{}, &RecentView{}} {
+			if err := tx.Where("page_id = ?", pageID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
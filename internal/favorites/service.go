@@ -0,0 +1,192 @@
+package favorites
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"flowboard-backend-go/internal/pages"
+)
+
+var (
+	ErrInvalidOrder = errors.New("page IDs must list every favorite exactly once")
+)
+
+// recentLimit caps how many recently viewed pages are kept per user.
+const recentLimit = 50
+
+// PageLookup resolves pages with access checks; satisfied by pages.Service.
+type PageLookup interface {
+	GetPageByID(id, userID uint) (*pages.Page, error)
+	GetPagesByIDs(ids []uint, userID uint) ([]pages.Page, error)
+}
+
+// Service manages the per-user navigation lists. Entries for deleted pages
+// are removed when the page.deleted event arrives; entries for pages the
+// user can no longer read are kept but left out of the lists.
+type Service interface {
+	pages.EventHandler
+
+	GetFavorites(userID uint) ([]Entry, error)
+	AddFavorite(pageID, userID uint) error
+	RemoveFavorite(pageID, userID uint) error
+	ReorderFavorites(pageIDs []uint, userID uint) ([]Entry, error)
+
+	GetPins(userID uint) ([]Entry, error)
+	AddPin(pageID, userID uint) error
+	RemovePin(pageID, userID uint) error
+
+	GetRecent(userID uint, limit int) ([]Entry, error)
+	RecordView(userID, pageID uint) error
+}
+
+type service struct {
+	repo  Repository
+	pages PageLookup
+}
+
+func NewService(repo Repository, pages PageLookup) Service {
+	return &service{repo: repo, pages: pages}
+}
+
+func (s *service) GetFavorites(userID uint) ([]Entry, error) {
+	favs, err := s.repo.GetFavorites(userID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(favs))
+	for i, f := range favs {
+		ids[i] = f.PageID
+	}
+	summaries, err := s.summaries(ids, userID)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(favs))
+	for _, f := range favs {
+		if summary, ok := summaries[f.PageID]; ok {
+			entries = append(entries, Entry{Page: summary, Position: f.Position, At: f.CreatedAt})
+		}
+	}
+	return entries, nil
+}
+
+func (s *service) AddFavorite(pageID, userID uint) error {
+	if _, err := s.pages.GetPageByID(pageID, userID); err != nil {
+		return err
+	}
+	return s.repo.AddFavorite(userID, pageID)
+}
+
+func (s *service) RemoveFavorite(pageID, userID uint) error {
+	return s.repo.RemoveFavorite(userID, pageID)
+}
+
+func (s *service) ReorderFavorites(pageIDs []uint, userID uint) ([]Entry, error) {
+	favs, err := s.repo.GetFavorites(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(favs) != len(pageIDs) {
+		return nil, ErrInvalidOrder
+	}
+	current := make(map[uint]bool, len(favs))
+	for _, f := range favs {
+		current[f.PageID] = true
+	}
+	for _, id := range pageIDs {
+		if !current[id] {
+			return nil, ErrInvalidOrder
+		}
+		delete(current, id) // catches duplicates
+	}
+
+	if err := s.repo.ReorderFavorites(userID, pageIDs); err != nil {
+		return nil, err
+	}
+	return s.GetFavorites(userID)
+}
+
+func (s *service) GetPins(userID uint) ([]Entry, error) {
+	pins, err := s.repo.GetPins(userID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(pins))
+	for i, p := range pins {
+		ids[i] = p.PageID
+	}
+	summaries, err := s.summaries(ids, userID)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(pins))
+	for _, p := range pins {
+		if summary, ok := summaries[p.PageID]; ok {
+			entries = append(entries, Entry{Page: summary, At: p.CreatedAt})
+		}
+	}
+	return entries, nil
+}
+
+func (s *service) AddPin(pageID, userID uint) error {
+	if _, err := s.pages.GetPageByID(pageID, userID); err != nil {
+		return err
+	}
+	return s.repo.AddPin(userID, pageID)
+}
+
+func (s *service) RemovePin(pageID, userID uint) error {
+	return s.repo.RemovePin(userID, pageID)
+}
+
+func (s *service) GetRecent(userID uint, limit int) ([]Entry, error) {
+	if limit <= 0 || limit > recentLimit {
+		limit = recentLimit
+	}
+	views, err := s.repo.GetRecent(userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, len(views))
+	for i, v := range views {
+		ids[i] = v.PageID
+	}
+	summaries, err := s.summaries(ids, userID)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(views))
+	for _, v := range views {
+		if summary, ok := summaries[v.PageID]; ok {
+			entries = append(entries, Entry{Page: summary, At: v.ViewedAt})
+		}
+	}
+	return entries, nil
+}
+
+func (s *service) RecordView(userID, pageID uint) error {
+	return s.repo.RecordView(userID, pageID, time.Now(), recentLimit)
+}
+
+// HandlePageEvent drops a deleted page from every user's lists.
+func (s *service) HandlePageEvent(_ context.Context, _ uint64, e pages.PageEvent) error {
+	if e.Type != pages.PageDeleted {
+		return nil
+	}
+	return s.repo.RemovePage(e.PageID)
+}
+
+// summaries loads the pages behind a list's entries in one lookup, keyed by
+// page ID. Pages the user cannot read are missing from the result.
+func (s *service) summaries(pageIDs []uint, userID uint) (map[uint]PageSummary, error) {
+	list, err := s.pages.GetPagesByIDs(pageIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[uint]PageSummary, len(list))
+	for _, page := range list {
+		out[page.ID] = PageSummary{ID: page.ID, Title: page.Title, UpdatedAt: page.UpdatedAt}
+	}
+	return out, nil
+}
@@ -0,0 +1,267 @@
+package favorites
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"testing"
+	"time"
+
+	"flowboard-backend-go/internal/pages"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memRepo is an in-memory Repository for service tests.
+type memRepo struct {
+	favorites []Favorite
+	pins      []Pin
+	views     []RecentView
+}
+
+func (r *memRepo) GetFavorites(userID uint) ([]Favorite, error) {
+	var out []Favorite
+	for _, f := range r.favorites {
+		if f.UserID == userID {
+			out = append(out, f)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Position < out[j].Position })
+	return out, nil
+}
+
+func (r *memRepo) AddFavorite(userID, pageID uint) error {
+	next := 0
+	for _, f := range r.favorites {
+		if f.UserID != userID {
+			continue
+		}
+		if f.PageID == pageID {
+			return nil
+		}
+		next = max(next, f.Position+1)
+	}
+	r.favorites = append(r.favorites, Favorite{UserID: userID, PageID: pageID, Position: next})
+	return nil
+}
+
+func (r *memRepo) RemoveFavorite(userID, pageID uint) error {
+	r.favorites = deleteWhere(r.favorites, func(f Favorite) bool { return f.UserID == userID && f.PageID == pageID })
+	return nil
+}
+
+func (r *memRepo) ReorderFavorites(userID uint, pageIDs []uint) error {
+	for i := range r.favorites {
+		for pos, id := range pageIDs {
+			if r.favorites[i].UserID == userID && r.favorites[i].PageID == id {
+				r.favorites[i].Position = pos
+			}
+		}
+	}
+	return nil
+}
+
+func (r *memRepo) GetPins(userID uint) ([]Pin, error) {
+	var out []Pin
+	for _, p := range r.pins {
+		if p.UserID == userID {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (r *memRepo) AddPin(userID, pageID uint) error {
+	for _, p := range r.pins {
+		if p.UserID == userID && p.PageID == pageID {
+			return nil
+		}
+	}
+	r.pins = append(r.pins, Pin{UserID: userID, PageID: pageID})
+	return nil
+}
+
+func (r *memRepo) RemovePin(userID, pageID uint) error {
+	r.pins = deleteWhere(r.pins, func(p Pin) bool { return p.UserID == userID && p.PageID == pageID })
+	return nil
+}
+
+func (r *memRepo) GetRecent(userID uint, limit int) ([]RecentView, error) {
+	var out []RecentView
+	for _, v := range r.views {
+		if v.UserID == userID {
+			out = append(out, v)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ViewedAt.After(out[j].ViewedAt) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *memRepo) RecordView(userID, pageID uint, at time.Time, keep int) error {
+	r.views = deleteWhere(r.views, func(v RecentView) bool { return v.UserID == userID && v.PageID == pageID })
+	r.views = append(r.views, RecentView{UserID: userID, PageID: pageID, ViewedAt: at})
+	return nil
+}
+
+func (r *memRepo) RemovePage(pageID uint) error {
+	r.favorites = deleteWhere(r.favorites, func(f Favorite) bool { return f.PageID == pageID })
+	r.pins = deleteWhere(r.pins, func(p Pin) bool { return p.PageID == pageID })
+	r.views = deleteWhere(r.views, func(v RecentView) bool { return v.PageID == pageID })
+	return nil
+}
+
+func deleteWhere[T any](list []T, match func(T) bool) []T {
+	out := list[:0]
+	for _, item := range list {
+		if !match(item) {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// stubPages serves a fixed set of pages; readable lists the page IDs each
+// user may open. It counts lookups to catch per-entry queries.
+type stubPages struct {
+	pages    map[uint]pages.Page
+	readable map[uint][]uint
+	lookups  int
+}
+
+func (s *stubPages) canRead(id, userID uint) bool {
+	for _, r := range s.readable[userID] {
+		if r == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *stubPages) GetPageByID(id, userID uint) (*pages.Page, error) {
+	s.lookups++
+	if p, ok := s.pages[id]; ok && s.canRead(id, userID) {
+		return &p, nil
+	}
+	return nil, pages.ErrPageNotFound
+}
+
+func (s *stubPages) GetPagesByIDs(ids []uint, userID uint) ([]pages.Page, error) {
+	s.lookups++
+	var out []pages.Page
+	for _, id := range ids {
+		if p, ok := s.pages[id]; ok && s.canRead(id, userID) {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func newTestService() (*service, *memRepo, *stubPages) {
+	repo := &memRepo{}
+	lookup := &stubPages{
+		pages: map[uint]pages.Page{
+			1: {ID: 1, Title: "Roadmap"},
+			2: {ID: 2, Title: "Notes"},
+			3: {ID: 3, Title: "Team wiki"},
+		},
+		readable: map[uint][]uint{7: {1, 2, 3}, 8: {3}},
+	}
+	return NewService(repo, lookup).(*service), repo, lookup
+}
+
+func titles(entries []Entry) []string {
+	out := make([]string, len(entries))
+	for i, e := range entries {
+		out[i] = e.Page.Title
+	}
+	return out
+}
+
+func TestFavorites_AddReorderAndList(t *testing.T) {
+	s, _, lookup := newTestService()
+
+	for _, id := range []uint{1, 2, 3} {
+		require.NoError(t, s.AddFavorite(id, 7))
+	}
+	assert.ErrorIs(t, s.AddFavorite(9, 7), pages.ErrPageNotFound)
+
+	entries, err := s.ReorderFavorites([]uint{3, 1, 2}, 7)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Team wiki", "Roadmap", "Notes"}, titles(entries))
+	first, err := json.Marshal(entries[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(first), `"position":0`, "the first favorite still reports its position")
+
+	_, err = s.ReorderFavorites([]uint{3, 3, 2}, 7)
+	assert.ErrorIs(t, err, ErrInvalidOrder)
+	_, err = s.ReorderFavorites([]uint{3, 1}, 7)
+	assert.ErrorIs(t, err, ErrInvalidOrder)
+
+	lookup.lookups = 0
+	_, err = s.GetFavorites(7)
+	require.NoError(t, err)
+	assert.Equal(t, 1, lookup.lookups, "pages are loaded in one batch")
+}
+
+func TestLists_HideUnreadablePagesWithoutDeletingThem(t *testing.T) {
+	s, repo, lookup := newTestService()
+	require.NoError(t, s.AddFavorite(3, 8))
+	require.NoError(t, s.AddPin(3, 8))
+	require.NoError(t, s.RecordView(8, 3))
+
+	// The user leaves the workspace holding page 3.
+	lookup.readable[8] = nil
+	favs, err := s.GetFavorites(8)
+	require.NoError(t, err)
+	assert.Empty(t, favs)
+	pins, err := s.GetPins(8)
+	require.NoError(t, err)
+	assert.Empty(t, pins)
+	recent, err := s.GetRecent(8, 0)
+	require.NoError(t, err)
+	assert.Empty(t, recent)
+
+	assert.Len(t, repo.favorites, 1, "reads have no side effects")
+	assert.Len(t, repo.pins, 1)
+	assert.Len(t, repo.views, 1)
+
+	// Rejoining brings the entries back.
+	lookup.readable[8] = []uint{3}
+	favs, _ = s.GetFavorites(8)
+	assert.Equal(t, []string{"Team wiki"}, titles(favs))
+}
+
+func TestRecent_NewestFirst(t *testing.T) {
+	s, _, _ := newTestService()
+	for _, id := range []uint{1, 2, 3, 1} {
+		require.NoError(t, s.RecordView(7, id))
+		time.Sleep(time.Millisecond)
+	}
+
+	recent, err := s.GetRecent(7, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Roadmap", "Team wiki"}, titles(recent))
+}
+
+func TestHandlePageEvent_RemovesDeletedPageEverywhere(t *testing.T) {
+	s, repo, _ := newTestService()
+	require.NoError(t, s.AddFavorite(3, 7))
+	require.NoError(t, s.AddFavorite(3, 8))
+	require.NoError(t, s.AddFavorite(1, 7))
+	require.NoError(t, s.AddPin(3, 7))
+	require.NoError(t, s.RecordView(8, 3))
+
+	ctx := context.Background()
+	require.NoError(t, s.HandlePageEvent(ctx, 1, pages.PageEvent{Type: pages.PageUpdated, PageID: 3}))
+	assert.Len(t, repo.favorites, 3, "only deletions clean up")
+
+	require.NoError(t, s.HandlePageEvent(ctx, 2, pages.PageEvent{Type: pages.PageDeleted, PageID: 3}))
+	require.NoError(t, s.HandlePageEvent(ctx, 2, pages.PageEvent{Type: pages.PageDeleted, PageID: 3}))
+	assert.Equal(t, []Favorite{{UserID: 7, PageID: 1, Position: 1}}, repo.favorites)
+	assert.Empty(t, repo.pins)
+	assert.Empty(t, repo.views)
+}
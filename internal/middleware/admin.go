@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminCheck reports whether a user is an instance administrator.
+type AdminCheck func(userID uint) (bool, error)
+
+// AdminMiddleware lets only administrators through. It must run after
+// AuthMiddleware.
+func AdminMiddleware(isAdmin AdminCheck) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		uid, ok := c.Get(ContextUserIDKey)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		admin, err := isAdmin(uid.(uint))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !admin {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin access required"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,19 +1,154 @@
 package middleware
 
-import "github.com/gin-gonic/gin"
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"flowboard-backend-go/pkg/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSMiddleware applies the configured cross-origin policy. Allowed origins
+// are echoed back (never "*") so credentials keep working, and preflights
+// from unknown origins or asking for unlisted methods/headers are rejected.
+// Credentials are never allowed for the "*" origin, since that would let
+// any site make authenticated requests.
+func CORSMiddleware(cfg config.CORSConfig) gin.HandlerFunc {
+	policy := newCORSPolicy(cfg)
 
-func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*") // for dev only, allow all origins
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		h.Add("Vary", "Origin")
+
+		preflight := c.Request.Method == http.MethodOptions &&
+			c.GetHeader("Access-Control-Request-Method") != ""
+
+		if !policy.allowOrigin(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		h.Set("Access-Control-Allow-Origin", origin)
+		if cfg.AllowCredentials && !policy.anyOrigin {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if len(cfg.ExposedHeaders) > 0 {
+				h.Set("Access-Control-Expose-Headers", strings.Join(cfg.ExposedHeaders, ", "))
+			}
+			c.Next()
+			return
+		}
+
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
+		if !policy.allowMethod(c.GetHeader("Access-Control-Request-Method")) ||
+			!policy.allowHeaders(c.GetHeader("Access-Control-Request-Headers")) {
+			c.AbortWithStatus(http.StatusForbidden)
 			return
 		}
 
-		c.Next()
+		h.Set("Access-Control-Allow-Methods", strings.Join(cfg.AllowedMethods, ", "))
+		if len(cfg.AllowedHeaders) > 0 {
+			h.Set("Access-Control-Allow-Headers", strings.Join(cfg.AllowedHeaders, ", "))
+		}
+		if cfg.MaxAge > 0 {
+			h.Set("Access-Control-Max-Age", strconv.Itoa(cfg.MaxAge))
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+// OriginChecker reports whether an origin is allowed by the CORS policy.
+// Endpoints outside the CORS middleware, such as WebSocket upgrades, use it
+// to apply the same rules.
+func OriginChecker(cfg config.CORSConfig) func(origin string) bool {
+	return newCORSPolicy(cfg).allowOrigin
+}
+
+type corsPolicy struct {
+	anyOrigin bool
+	exact     map[string]bool
+	wildcards [][2]string // prefix and suffix around "*"
+	methods   map[string]bool
+	headers   map[string]bool
+}
+
+func newCORSPolicy(cfg config.CORSConfig) *corsPolicy {
+	p := &corsPolicy{
+		exact:   map[string]bool{},
+		methods: map[string]bool{},
+		headers: map[string]bool{},
+	}
+	for _, o := range cfg.AllowedOrigins {
+		o = strings.ToLower(strings.TrimSuffix(o, "/"))
+		switch {
+		case o == "*":
+			p.anyOrigin = true
+		case strings.Contains(o, "*"):
+			parts := strings.SplitN(o, "*", 2)
+			p.wildcards = append(p.wildcards, [2]string{parts[0], parts[1]})
+		default:
+			p.exact[o] = true
+		}
+	}
+	for _, m := range cfg.AllowedMethods {
+		p.methods[strings.ToUpper(m)] = true
+	}
+	for _, hdr := range cfg.AllowedHeaders {
+		p.headers[strings.ToLower(hdr)] = true
+	}
+	return p
+}
+
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if p.exact[origin] {
+		return true
+	}
+	for _, w := range p.wildcards {
+		if len(origin) <= len(w[0])+len(w[1]) {
+			continue
+		}
+		if !strings.HasPrefix(origin, w[0]) || !strings.HasSuffix(origin, w[1]) {
+			continue
+		}
+		// The wildcard may only stand for subdomain labels, not a path or port.
+		sub := origin[len(w[0]) : len(origin)-len(w[1])]
+		if !strings.ContainsAny(sub, "/:") {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *corsPolicy) allowMethod(method string) bool {
+	return p.methods[strings.ToUpper(method)]
+}
+
+func (p *corsPolicy) allowHeaders(requested string) bool {
+	for _, hdr := range strings.Split(requested, ",") {
+		hdr = strings.ToLower(strings.TrimSpace(hdr))
+		if hdr != "" && !p.headers[hdr] {
+			return false
+		}
 	}
+	return true
 }
@@ -12,12 +12,29 @@ import (
 )
 
 type JWTManager struct {
-	secret string
-	ttl    time.Duration
+	secret  string
+	ttl     time.Duration
+	revoked RevocationCheck
 }
 
-func NewJWTManager(secret string) *JWTManager {
-	return &JWTManager{secret: secret, ttl: 24 * time.Hour}
+// RevocationCheck reports whether the user has revoked the tokens issued
+// to them at issuedAt.
+type RevocationCheck func(userID uint, issuedAt time.Time) (bool, error)
+
+// JWTOption configures optional behaviour of a JWTManager.
+type JWTOption func(*JWTManager)
+
+// WithRevocationCheck makes Verify reject tokens the user has revoked.
+func WithRevocationCheck(check RevocationCheck) JWTOption {
+	return func(j *JWTManager) { j.revoked = check }
+}
+
+func NewJWTManager(secret string, opts ...JWTOption) *JWTManager {
+	j := &JWTManager{secret: secret, ttl: 24 * time.Hour}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
 }
 
 func (j *JWTManager) Generate(userID uint) (string, error) {
@@ -41,6 +58,19 @@ func (j *JWTManager) Verify(tokenStr string) (*jwt.RegisteredClaims, error) {
 	if err != nil || !token.Valid {
 		return nil, errors.New("invalid token")
 	}
+	if j.revoked != nil {
+		uid, err := strconv.ParseUint(claims.Subject, 10, 32)
+		if err != nil {
+			return nil, errors.New("invalid token")
+		}
+		var issuedAt time.Time
+		if claims.IssuedAt != nil {
+			issuedAt = claims.IssuedAt.Time
+		}
+		if revoked, err := j.revoked(uint(uid), issuedAt); err != nil || revoked {
+			return nil, errors.New("invalid token")
+		}
+	}
 	return claims, nil
 }
 
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// RequestIDHeader carries the request ID both ways: a well-formed
+	// incoming value (from a proxy, say) is kept, otherwise one is made up.
+	RequestIDHeader     = "X-Request-ID"
+	ContextRequestIDKey = "requestID"
+	maxRequestIDLen     = 64
+)
+
+// RequestIDMiddleware tags every request with an ID, stored in the context
+// under ContextRequestIDKey and echoed in the response.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Set(ContextRequestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// GetRequestID returns the ID RequestIDMiddleware assigned, or "".
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(ContextRequestIDKey)
+}
+
+// validRequestID accepts short IDs made of letters, digits, '-', '_' and
+// '.', so a client cannot smuggle arbitrary text into logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
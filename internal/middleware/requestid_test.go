@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRequestIDRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestIDMiddleware())
+	r.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, GetRequestID(c)) })
+	return r
+}
+
+func TestRequestID_KeepsWellFormedIncomingID(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(RequestIDHeader, "edge-42.a_b")
+	w := httptest.NewRecorder()
+	newRequestIDRouter().ServeHTTP(w, req)
+
+	assert.Equal(t, "edge-42.a_b", w.Body.String())
+	assert.Equal(t, "edge-42.a_b", w.Header().Get(RequestIDHeader))
+}
+
+func TestRequestID_ReplacesMissingOrMalformedID(t *testing.T) {
+	for _, incoming := range []string{"", "bad id\n", strings.Repeat("x", maxRequestIDLen+1)} {
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		req.Header.Set(RequestIDHeader, incoming)
+		w := httptest.NewRecorder()
+		newRequestIDRouter().ServeHTTP(w, req)
+
+		id := w.Header().Get(RequestIDHeader)
+		assert.Len(t, id, 32, "incoming %q", incoming)
+		assert.Equal(t, id, w.Body.String())
+	}
+}
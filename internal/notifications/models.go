@@ -0,0 +1,75 @@
+package notifications
+
+import "time"
+
+type Type string
+
+const (
+	TypeMention    Type = "mention"
+	TypeShare      Type = "share"
+	TypeComment    Type = "comment"
+	TypeReminder   Type = "reminder"
+	TypeInvitation Type = "invitation"
+)
+
+// Types lists every notification type in display order.
+var Types = []Type{TypeMention, TypeShare, TypeComment, TypeReminder, TypeInvitation}
+
+// Notification is an in-app message to UserID. Link is an app-relative path
+// such as /pages/12; ActorID is the user whose action caused it, if any.
+// DedupKey, when set, names the event the notification is for: a second
+// notification with the same key is dropped, so a redelivered event
+// notifies once.
+type Notification struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"userId"`
+	Type      Type       `gorm:"size:20;not null" json:"type"`
+	ActorID   *uint      `json:"actorId,omitempty"`
+	Title     string     `gorm:"size:255;not null" json:"title"`
+	Body      string     `gorm:"type:text" json:"body"`
+	Link      string     `gorm:"size:500" json:"link"`
+	DedupKey  *string    `gorm:"size:100;uniqueIndex" json:"-"`
+	ReadAt    *time.Time `gorm:"index" json:"readAt"`
+	CreatedAt time.Time  `gorm:"index" json:"createdAt"`
+}
+
+// Preference controls how a user receives one type of notification. Types
+// without a stored row use defaultPreferences.
+type Preference struct {
+	UserID    uint      `gorm:"primaryKey;autoIncrement:false" json:"-"`
+	Type      Type      `gorm:"primaryKey;size:20" json:"type"`
+	InApp     bool      `gorm:"not null" json:"inApp"`
+	Email     bool      `gorm:"not null" json:"email"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+// PreferenceInput changes one type's preference; nil fields keep their
+// current value.
+type PreferenceInput struct {
+	Type  Type  `json:"type" binding:"required,oneof=mention share comment reminder invitation"`
+	InApp *bool `json:"inApp"`
+	Email *bool `json:"email"`
+}
+
+// PreferencesInput is the body of a preferences update
+type PreferencesInput struct {
+	Preferences []PreferenceInput `json:"preferences" binding:"required,dive"`
+}
+
+// ListFilter pages through a user's notifications, newest first.
+type ListFilter struct {
+	UnreadOnly bool
+	Limit      int
+	Offset     int
+}
+
+// defaultPreferences apply until a user changes them. Invitation emails are
+// sent by the workspace service because they carry the single-use accept
+// link, so that channel cannot be toggled here.
+var defaultPreferences = map[Type]Preference{
+	TypeMention:    {Type: TypeMention, InApp: true, Email: true},
+	TypeShare:      {Type: TypeShare, InApp: true, Email: true},
+	TypeComment:    {Type: TypeComment, InApp: true, Email: false},
+	TypeReminder:   {Type: TypeReminder, InApp: true, Email: true},
+	TypeInvitation: {Type: TypeInvitation, InApp: true, Email: false},
+}
@@ -0,0 +1,119 @@
+package notifications
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	// CreateNotification stores n and reports whether it was new: false
+	// means a notification with the same DedupKey already exists.
+	CreateNotification(n *Notification) (bool, error)
+	GetNotificationByID(id uint) (*Notification, error)
+	// GetNotifications returns one page of the user's notifications, newest
+	// first, and the total matching the filter.
+	GetNotifications(userID uint, filter ListFilter) ([]Notification, int64, error)
+	CountUnread(userID uint) (int64, error)
+	MarkRead(id uint, at time.Time) error
+	MarkAllRead(userID uint, at time.Time) (int64, error)
+
+	GetPreferences(userID uint) ([]Preference, error)
+	GetPreference(userID uint, t Type) (*Preference, error)
+	SavePreference(p *Preference) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) CreateNotification(n *Notification) (bool, error) {
+	res := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "dedup_key"}},
+		DoNothing: true,
+	}).Create(n)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *repository) GetNotificationByID(id uint) (*Notification, error) {
+	var n Notification
+	if err := r.db.First(&n, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &n, nil
+}
+
+func (r *repository) GetNotifications(userID uint, filter ListFilter) ([]Notification, int64, error) {
+	scope := func(db *gorm.DB) *gorm.DB {
+		db = db.Where("user_id = ?", userID)
+		if filter.UnreadOnly {
+			db = db.Where("read_at IS NULL")
+		}
+		return db
+	}
+
+	var total int64
+	if err := r.db.Model(&Notification{}).Scopes(scope).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var list []Notification
+	err := r.db.Scopes(scope).
+		Order("created_at DESC, id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&list).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+func (r *repository) CountUnread(userID uint) (int64, error) {
+	var n int64
+	if err := r.db.Model(&Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&n).Error; err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (r *repository) MarkRead(id uint, at time.Time) error {
+	return r.db.Model(&Notification{}).Where("id = ? AND read_at IS NULL", id).UpdateColumn("read_at", at).Error
+}
+
+func (r *repository) MarkAllRead(userID uint, at time.Time) (int64, error) {
+	res := r.db.Model(&Notification{}).Where("user_id = ? AND read_at IS NULL", userID).UpdateColumn("read_at", at)
+	return res.RowsAffected, res.Error
+}
+
+func (r *repository) GetPreferences(userID uint) ([]Preference, error) {
+	var list []Preference
+	if err := r.db.Where("user_id = ?", userID).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *repository) GetPreference(userID uint, t Type) (*Preference, error) {
+	var p Preference
+	if err := r.db.Where("user_id = ? AND type = ?", userID, t).First(&p).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *repository) SavePreference(p *Preference) error {
+	return r.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(p).Error
+}
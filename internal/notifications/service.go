@@ -0,0 +1,240 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"flowboard-backend-go/internal/users"
+	"flowboard-backend-go/pkg/logger"
+	"flowboard-backend-go/pkg/mailer"
+)
+
+var (
+	ErrNotificationNotFound = errors.New("notification not found")
+	ErrEmailNotConfigurable = errors.New("email delivery cannot be changed for this notification type")
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+	// maxTitleLen matches the column size of Notification.Title.
+	maxTitleLen = 255
+)
+
+// UserLookup resolves a recipient's email address; satisfied by
+// users.Service.
+type UserLookup interface {
+	GetByID(id uint) (*users.User, error)
+}
+
+// Notifier is the part of Service other packages use to notify users.
+type Notifier interface {
+	// Notify delivers n to n.UserID through the channels the user's
+	// preferences enable. Users are never notified of their own actions.
+	Notify(ctx context.Context, n *Notification) error
+}
+
+type Service interface {
+	Notifier
+	GetNotifications(userID uint, filter ListFilter) ([]Notification, int64, error)
+	UnreadCount(userID uint) (int64, error)
+	MarkRead(id, userID uint) error
+	MarkAllRead(userID uint) (int64, error)
+	// GetPreferences returns the user's preference for every type, with
+	// defaults filled in.
+	GetPreferences(userID uint) ([]Preference, error)
+	UpdatePreferences(userID uint, input []PreferenceInput) ([]Preference, error)
+}
+
+// EventPublisher receives in-app notifications once they are stored.
+// PublishNotification must not block.
+type EventPublisher interface {
+	PublishNotification(n *Notification)
+}
+
+type service struct {
+	repo   Repository
+	users  UserLookup
+	mailer mailer.Mailer
+	appURL string
+	events EventPublisher
+	now    func() time.Time
+}
+
+// Option configures optional collaborators of the notification service.
+type Option func(*service)
+
+// WithEventPublisher announces every stored in-app notification.
+func WithEventPublisher(p EventPublisher) Option {
+	return func(s *service) { s.events = p }
+}
+
+func NewService(repo Repository, users UserLookup, m mailer.Mailer, appURL string, opts ...Option) Service {
+	s := &service{repo: repo, users: users, mailer: m, appURL: appURL, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *service) Notify(ctx context.Context, n *Notification) error {
+	if n.ActorID != nil && *n.ActorID == n.UserID {
+		return nil
+	}
+	if len(n.Title) > maxTitleLen {
+		n.Title = truncate(n.Title, maxTitleLen)
+	}
+	pref, err := s.preference(n.UserID, n.Type)
+	if err != nil {
+		return err
+	}
+
+	if pref.InApp {
+		created, err := s.repo.CreateNotification(n)
+		if err != nil {
+			return err
+		}
+		if !created {
+			// Already delivered, email included.
+			return nil
+		}
+		if s.events != nil {
+			s.events.PublishNotification(n)
+		}
+	}
+	if pref.Email {
+		if err := s.sendEmail(ctx, n); err != nil {
+			// The in-app copy is already stored; a lost email is not
+			// worth failing the caller's action over.
+			logger.Log.Errorw("Notification email failed", "userID", n.UserID, "type", n.Type, "error", err)
+		}
+	}
+	return nil
+}
+
+func (s *service) sendEmail(ctx context.Context, n *Notification) error {
+	user, err := s.users.GetByID(n.UserID)
+	if err != nil || user == nil {
+		return err
+	}
+
+	body := n.Title + "\n"
+	if n.Body != "" {
+		body += "\n" + n.Body + "\n"
+	}
+	if n.Link != "" {
+		body += fmt.Sprintf("\nOpen in FlowBoard: %s%s\n", s.appURL, n.Link)
+	}
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      []string{user.Email},
+		Subject: n.Title,
+		Text:    body,
+	})
+}
+
+func (s *service) GetNotifications(userID uint, filter ListFilter) ([]Notification, int64, error) {
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = defaultLimit
+	case filter.Limit > maxLimit:
+		filter.Limit = maxLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.repo.GetNotifications(userID, filter)
+}
+
+func (s *service) UnreadCount(userID uint) (int64, error) {
+	return s.repo.CountUnread(userID)
+}
+
+func (s *service) MarkRead(id, userID uint) error {
+	n, err := s.repo.GetNotificationByID(id)
+	if err != nil {
+		return err
+	}
+	if n == nil || n.UserID != userID {
+		return ErrNotificationNotFound
+	}
+	return s.repo.MarkRead(id, s.now())
+}
+
+func (s *service) MarkAllRead(userID uint) (int64, error) {
+	return s.repo.MarkAllRead(userID, s.now())
+}
+
+func (s *service) GetPreferences(userID uint) ([]Preference, error) {
+	stored, err := s.repo.GetPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+	byType := make(map[Type]Preference, len(stored))
+	for _, p := range stored {
+		byType[p.Type] = p
+	}
+
+	list := make([]Preference, len(Types))
+	for i, t := range Types {
+		p, ok := byType[t]
+		if !ok {
+			p = defaultPreferences[t]
+			p.UserID = userID
+		}
+		list[i] = p
+	}
+	return list, nil
+}
+
+func (s *service) UpdatePreferences(userID uint, input []PreferenceInput) ([]Preference, error) {
+	for _, in := range input {
+		if in.Type == TypeInvitation && in.Email != nil && *in.Email {
+			return nil, ErrEmailNotConfigurable
+		}
+	}
+
+	for _, in := range input {
+		p, err := s.preference(userID, in.Type)
+		if err != nil {
+			return nil, err
+		}
+		if in.InApp != nil {
+			p.InApp = *in.InApp
+		}
+		if in.Email != nil {
+			p.Email = *in.Email
+		}
+		if err := s.repo.SavePreference(p); err != nil {
+			return nil, err
+		}
+	}
+	return s.GetPreferences(userID)
+}
+
+// preference returns the user's stored preference for t, or the default.
+func (s *service) preference(userID uint, t Type) (*Preference, error) {
+	p, err := s.repo.GetPreference(userID, t)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		def := defaultPreferences[t]
+		def.UserID = userID
+		p = &def
+	}
+	return p, nil
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 sequence,
+// marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	const ellipsis = "…"
+	cut := n - len(ellipsis)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + ellipsis
+}
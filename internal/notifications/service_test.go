@@ -0,0 +1,229 @@
+package notifications
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"flowboard-backend-go/internal/users"
+	"flowboard-backend-go/pkg/mailer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memRepo is an in-memory Repository for service tests.
+type memRepo struct {
+	notifications []*Notification
+	prefs         map[prefKey]Preference
+}
+
+type prefKey struct {
+	userID uint
+	t      Type
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{prefs: map[prefKey]Preference{}}
+}
+
+func (r *memRepo) CreateNotification(n *Notification) (bool, error) {
+	for _, existing := range r.notifications {
+		if n.DedupKey != nil && existing.DedupKey != nil && *n.DedupKey == *existing.DedupKey {
+			return false, nil
+		}
+	}
+	n.ID = uint(len(r.notifications) + 1)
+	r.notifications = append(r.notifications, n)
+	return true, nil
+}
+
+func (r *memRepo) GetNotificationByID(id uint) (*Notification, error) {
+	if id == 0 || int(id) > len(r.notifications) {
+		return nil, nil
+	}
+	cp := *r.notifications[id-1]
+	return &cp, nil
+}
+
+func (r *memRepo) GetNotifications(userID uint, filter ListFilter) ([]Notification, int64, error) {
+	var out []Notification
+	for i := len(r.notifications) - 1; i >= 0; i-- {
+		n := r.notifications[i]
+		if n.UserID == userID && (!filter.UnreadOnly || n.ReadAt == nil) {
+			out = append(out, *n)
+		}
+	}
+	total := int64(len(out))
+	if filter.Offset < len(out) {
+		out = out[filter.Offset:]
+	} else {
+		out = nil
+	}
+	if len(out) > filter.Limit {
+		out = out[:filter.Limit]
+	}
+	return out, total, nil
+}
+
+func (r *memRepo) CountUnread(userID uint) (int64, error) {
+	_, total, err := r.GetNotifications(userID, ListFilter{UnreadOnly: true})
+	return total, err
+}
+
+func (r *memRepo) MarkRead(id uint, at time.Time) error {
+	if n := r.notifications[id-1]; n.ReadAt == nil {
+		n.ReadAt = &at
+	}
+	return nil
+}
+
+func (r *memRepo) MarkAllRead(userID uint, at time.Time) (int64, error) {
+	var count int64
+	for _, n := range r.notifications {
+		if n.UserID == userID && n.ReadAt == nil {
+			n.ReadAt = &at
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *memRepo) GetPreferences(userID uint) ([]Preference, error) {
+	var out []Preference
+	for _, p := range r.prefs {
+		if p.UserID == userID {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (r *memRepo) GetPreference(userID uint, t Type) (*Preference, error) {
+	if p, ok := r.prefs[prefKey{userID, t}]; ok {
+		return &p, nil
+	}
+	return nil, nil
+}
+
+func (r *memRepo) SavePreference(p *Preference) error {
+	r.prefs[prefKey{p.UserID, p.Type}] = *p
+	return nil
+}
+
+type stubUsers map[uint]*users.User
+
+func (s stubUsers) GetByID(id uint) (*users.User, error) {
+	return s[id], nil
+}
+
+type recordingMailer struct {
+	sent []mailer.Message
+}
+
+func (r *recordingMailer) Send(_ context.Context, msg mailer.Message) error {
+	r.sent = append(r.sent, msg)
+	return nil
+}
+
+func boolPtr(v bool) *bool { return &v }
+
+func TestNotifyFollowsPreferences(t *testing.T) {
+	repo, mail := newMemRepo(), &recordingMailer{}
+	s := NewService(repo, stubUsers{1: {ID: 1, Email: "alex@example.com"}}, mail, "https://app.test")
+	ctx := context.Background()
+	actor := uint(2)
+
+	require.NoError(t, s.Notify(ctx, &Notification{UserID: 1, Type: TypeMention, ActorID: &actor, Title: "Bo mentioned you", Link: "/pages/4"}))
+	assert.Len(t, repo.notifications, 1)
+	require.Len(t, mail.sent, 1)
+	assert.Equal(t, []string{"alex@example.com"}, mail.sent[0].To)
+	assert.Contains(t, mail.sent[0].Text, "https://app.test/pages/4")
+
+	// Comments default to in-app only.
+	require.NoError(t, s.Notify(ctx, &Notification{UserID: 1, Type: TypeComment, ActorID: &actor, Title: "New comment"}))
+	assert.Len(t, repo.notifications, 2)
+	assert.Len(t, mail.sent, 1)
+
+	// Own actions never notify.
+	self := uint(1)
+	require.NoError(t, s.Notify(ctx, &Notification{UserID: 1, Type: TypeMention, ActorID: &self, Title: "x"}))
+	assert.Len(t, repo.notifications, 2)
+
+	_, err := s.UpdatePreferences(1, []PreferenceInput{{Type: TypeMention, InApp: boolPtr(false)}})
+	require.NoError(t, err)
+	require.NoError(t, s.Notify(ctx, &Notification{UserID: 1, Type: TypeMention, ActorID: &actor, Title: "again"}))
+	assert.Len(t, repo.notifications, 2)
+	assert.Len(t, mail.sent, 2, "email stays on when only in-app is disabled")
+}
+
+func TestPreferences(t *testing.T) {
+	s := NewService(newMemRepo(), stubUsers{}, &recordingMailer{}, "")
+
+	prefs, err := s.GetPreferences(1)
+	require.NoError(t, err)
+	require.Len(t, prefs, len(Types))
+	for i, p := range prefs {
+		assert.Equal(t, Types[i], p.Type)
+		assert.True(t, p.InApp)
+	}
+
+	prefs, err = s.UpdatePreferences(1, []PreferenceInput{{Type: TypeComment, Email: boolPtr(true)}})
+	require.NoError(t, err)
+	assert.Equal(t, Preference{UserID: 1, Type: TypeComment, InApp: true, Email: true}, prefs[2])
+
+	_, err = s.UpdatePreferences(1, []PreferenceInput{{Type: TypeInvitation, Email: boolPtr(true)}})
+	assert.ErrorIs(t, err, ErrEmailNotConfigurable)
+}
+
+func TestMarkRead(t *testing.T) {
+	repo := newMemRepo()
+	s := NewService(repo, stubUsers{}, &recordingMailer{}, "")
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.Notify(ctx, &Notification{UserID: 1, Type: TypeComment, Title: "c"}))
+	}
+	require.NoError(t, s.Notify(ctx, &Notification{UserID: 2, Type: TypeComment, Title: "c"}))
+
+	assert.ErrorIs(t, s.MarkRead(4, 1), ErrNotificationNotFound)
+	require.NoError(t, s.MarkRead(1, 1))
+	unread, _ := s.UnreadCount(1)
+	assert.Equal(t, int64(2), unread)
+
+	list, total, err := s.GetNotifications(1, ListFilter{UnreadOnly: true, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, list, 1)
+	assert.Equal(t, uint(3), list[0].ID)
+
+	n, err := s.MarkAllRead(1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	unread, _ = s.UnreadCount(2)
+	assert.Equal(t, int64(1), unread)
+}
+
+func TestNotifyTruncatesLongTitles(t *testing.T) {
+	repo := newMemRepo()
+	s := NewService(repo, stubUsers{}, &recordingMailer{}, "")
+
+	require.NoError(t, s.Notify(context.Background(), &Notification{UserID: 1, Type: TypeComment, Title: strings.Repeat("é", 200)}))
+	title := repo.notifications[0].Title
+	assert.LessOrEqual(t, len(title), maxTitleLen)
+	assert.True(t, utf8.ValidString(title))
+	assert.True(t, strings.HasSuffix(title, "…"))
+}
+
+func TestNotifyOncePerDedupKey(t *testing.T) {
+	repo, mail := newMemRepo(), &recordingMailer{}
+	s := NewService(repo, stubUsers{1: {ID: 1, Email: "alex@example.com"}}, mail, "")
+	key := "reminder:7"
+
+	for i := 0; i < 2; i++ {
+		require.NoError(t, s.Notify(context.Background(), &Notification{UserID: 1, Type: TypeReminder, Title: "Reminder", DedupKey: &key}))
+	}
+	assert.Len(t, repo.notifications, 1)
+	assert.Len(t, mail.sent, 1, "a redelivered event is not emailed again")
+}
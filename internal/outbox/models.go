@@ -0,0 +1,33 @@
+package outbox
+
+import "time"
+
+// Message is a domain event recorded in the same transaction as the change
+// it describes. The relay hands it to every consumer of its type until all
+// of them have succeeded.
+type Message struct {
+	ID            uint64     `gorm:"primaryKey" json:"id"`
+	Type          string     `gorm:"size:64;not null" json:"type"`
+	Payload       string     `gorm:"type:jsonb;not null" json:"payload"`
+	Attempts      int        `gorm:"not null" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"not null;index" json:"nextAttemptAt"`
+	LastError     string     `gorm:"size:1000" json:"lastError,omitempty"`
+	DispatchedAt  *time.Time `gorm:"index" json:"dispatchedAt,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+func (Message) TableName() string {
+	return "outbox_messages"
+}
+
+// Receipt records that a consumer handled a message, so retrying the
+// message after another consumer failed skips it.
+type Receipt struct {
+	MessageID uint64    `gorm:"primaryKey;autoIncrement:false"`
+	Consumer  string    `gorm:"primaryKey;size:64"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+func (Receipt) TableName() string {
+	return "outbox_receipts"
+}
@@ -0,0 +1,176 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"flowboard-backend-go/pkg/logger"
+)
+
+const (
+	// Retention is how long dispatched messages are kept for inspection.
+	Retention = 7 * 24 * time.Hour
+	// relayBatch bounds how many messages one relay pass claims.
+	relayBatch = 50
+	// relayLease postpones claimed messages while their consumers run, so
+	// other relays skip them. A relay that dies leaves them to be retried
+	// once it runs out.
+	relayLease = 5 * time.Minute
+	// baseBackoff is the wait after a message's first failure; it doubles
+	// with every further failure up to maxBackoff.
+	baseBackoff = 5 * time.Second
+	maxBackoff  = time.Hour
+	maxErrorLen = 1000
+)
+
+// Handler consumes one message. Returning an error retries the message
+// later. Messages may be delivered more than once, for instance when the
+// relay stops between handling a message and recording it; Message.ID
+// stays the same, so handlers can discard duplicates.
+type Handler func(ctx context.Context, m *Message) error
+
+type Relay interface {
+	// Subscribe registers a consumer for the given message types. The name
+	// identifies the consumer in receipts and must stay stable across
+	// deploys. Subscribe before the relay starts.
+	Subscribe(name string, fn Handler, types ...string)
+	// Dispatch hands every due message to its consumers and returns how
+	// many messages it handled. Several replicas may run it at once; each
+	// message is claimed by one of them at a time.
+	Dispatch(ctx context.Context) (int, error)
+	Prune(before time.Time) (int64, error)
+}
+
+type consumer struct {
+	name  string
+	fn    Handler
+	types map[string]bool
+}
+
+type relay struct {
+	repo      Repository
+	consumers []consumer
+	now       func() time.Time
+}
+
+func NewRelay(repo Repository) Relay {
+	return &relay{repo: repo, now: time.Now}
+}
+
+func (r *relay) Subscribe(name string, fn Handler, types ...string) {
+	c := consumer{name: name, fn: fn, types: map[string]bool{}}
+	for _, t := range types {
+		c.types[t] = true
+	}
+	r.consumers = append(r.consumers, c)
+}
+
+func (r *relay) Dispatch(ctx context.Context) (int, error) {
+	handled := 0
+	for {
+		var due []Message
+		err := r.repo.Transaction(func(repo Repository) error {
+			var err error
+			if due, err = repo.ClaimDue(r.now(), relayBatch); err != nil {
+				return err
+			}
+			ids := make([]uint64, len(due))
+			for i := range due {
+				ids[i] = due[i].ID
+			}
+			return repo.Postpone(ids, r.now().Add(relayLease))
+		})
+		if err != nil {
+			return handled, err
+		}
+
+		for i := range due {
+			if err := r.deliver(ctx, &due[i]); err != nil {
+				return handled, err
+			}
+			handled++
+		}
+		if len(due) < relayBatch || ctx.Err() != nil {
+			return handled, nil
+		}
+	}
+}
+
+// deliver runs every consumer of m that has not handled it yet. The
+// message is dispatched once all of them succeed; otherwise it is retried
+// after a backoff. Only failures to record the outcome are returned.
+func (r *relay) deliver(ctx context.Context, m *Message) error {
+	done, err := r.repo.GetConsumers(m.ID)
+	if err != nil {
+		return err
+	}
+	handled := map[string]bool{}
+	for _, name := range done {
+		handled[name] = true
+	}
+
+	var failures []string
+	for _, c := range r.consumers {
+		if !c.types[m.Type] || handled[c.name] {
+			continue
+		}
+		if err := c.fn(ctx, m); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", c.name, err))
+			continue
+		}
+		if err := r.repo.AddReceipt(m.ID, c.name); err != nil {
+			return err
+		}
+	}
+
+	if len(failures) == 0 {
+		return r.repo.MarkDispatched(m.ID, r.now())
+	}
+	m.Attempts++
+	msg := strings.Join(failures, "; ")
+	if len(msg) > maxErrorLen {
+		msg = msg[:maxErrorLen]
+	}
+	return r.repo.MarkFailed(m.ID, m.Attempts, r.now().Add(backoff(m.Attempts)), msg)
+}
+
+func (r *relay) Prune(before time.Time) (int64, error) {
+	return r.repo.PruneDispatched(before)
+}
+
+// backoff is the wait after the given number of failed attempts.
+func backoff(attempts int) time.Duration {
+	d := baseBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+// RunRelay dispatches due messages every interval and prunes old ones
+// hourly until ctx is cancelled.
+func RunRelay(ctx context.Context, r Relay, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	prune := time.NewTicker(time.Hour)
+	defer prune.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.Dispatch(ctx); err != nil {
+				logger.Log.Errorw("Relaying outbox messages failed", "error", err)
+			}
+		case <-prune.C:
+			n, err := r.Prune(time.Now().Add(-Retention))
+			if err != nil {
+				logger.Log.Errorw("Pruning outbox failed", "error", err)
+			} else if n > 0 {
+				logger.Log.Infow("Pruned outbox messages", "count", n)
+			}
+		}
+	}
+}
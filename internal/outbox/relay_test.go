@@ -0,0 +1,167 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memRepo struct {
+	messages []*Message
+	receipts map[uint64][]string
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{receipts: map[uint64][]string{}}
+}
+
+func (r *memRepo) add(eventType, payload string, at time.Time) *Message {
+	m := &Message{ID: uint64(len(r.messages) + 1), Type: eventType, Payload: payload, NextAttemptAt: at, CreatedAt: at}
+	r.messages = append(r.messages, m)
+	return m
+}
+
+func (r *memRepo) Transaction(fn func(repo Repository) error) error {
+	return fn(r)
+}
+
+func (r *memRepo) ClaimDue(now time.Time, limit int) ([]Message, error) {
+	var list []Message
+	for _, m := range r.messages {
+		if m.DispatchedAt == nil && !m.NextAttemptAt.After(now) && len(list) < limit {
+			list = append(list, *m)
+		}
+	}
+	return list, nil
+}
+
+func (r *memRepo) Postpone(ids []uint64, until time.Time) error {
+	for _, id := range ids {
+		r.messages[id-1].NextAttemptAt = until
+	}
+	return nil
+}
+
+func (r *memRepo) GetConsumers(messageID uint64) ([]string, error) {
+	return r.receipts[messageID], nil
+}
+
+func (r *memRepo) AddReceipt(messageID uint64, consumer string) error {
+	r.receipts[messageID] = append(r.receipts[messageID], consumer)
+	return nil
+}
+
+func (r *memRepo) MarkDispatched(id uint64, at time.Time) error {
+	r.messages[id-1].DispatchedAt = &at
+	return nil
+}
+
+func (r *memRepo) MarkFailed(id uint64, attempts int, next time.Time, lastError string) error {
+	m := r.messages[id-1]
+	m.Attempts, m.NextAttemptAt, m.LastError = attempts, next, lastError
+	return nil
+}
+
+func (r *memRepo) PruneDispatched(before time.Time) (int64, error) {
+	var n int64
+	for _, m := range r.messages {
+		if m.DispatchedAt != nil && m.DispatchedAt.Before(before) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// counter is a consumer that fails while fail is set.
+type counter struct {
+	seen []uint64
+	fail bool
+}
+
+func (c *counter) handle(_ context.Context, m *Message) error {
+	if c.fail {
+		return errors.New("unavailable")
+	}
+	c.seen = append(c.seen, m.ID)
+	return nil
+}
+
+func newTestRelay(repo Repository) (*relay, *time.Time) {
+	clock := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	r := NewRelay(repo).(*relay)
+	r.now = func() time.Time { return clock }
+	return r, &clock
+}
+
+func TestDispatchRoutesByType(t *testing.T) {
+	repo := newMemRepo()
+	r, clock := newTestRelay(repo)
+	pages, users := &counter{}, &counter{}
+	r.Subscribe("pages", pages.handle, "page.created", "page.updated")
+	r.Subscribe("users", users.handle, "user.registered")
+
+	repo.add("page.created", "{}", *clock)
+	repo.add("user.registered", "{}", *clock)
+	repo.add("page.updated", "{}", *clock)
+	repo.add("page.created", "{}", clock.Add(time.Minute))
+
+	n, err := r.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, n, "the last message is not due yet")
+	assert.Equal(t, []uint64{1, 3}, pages.seen)
+	assert.Equal(t, []uint64{2}, users.seen)
+	for _, m := range repo.messages[:3] {
+		assert.NotNil(t, m.DispatchedAt)
+	}
+	assert.Nil(t, repo.messages[3].DispatchedAt)
+
+	n, err = r.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n, "dispatched messages are not handed out again")
+}
+
+func TestFailedConsumerIsRetriedAlone(t *testing.T) {
+	repo := newMemRepo()
+	r, clock := newTestRelay(repo)
+	ok, flaky := &counter{}, &counter{fail: true}
+	r.Subscribe("ok", ok.handle, "page.deleted")
+	r.Subscribe("flaky", flaky.handle, "page.deleted")
+	m := repo.add("page.deleted", "{}", *clock)
+
+	_, err := r.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, m.DispatchedAt)
+	assert.Equal(t, 1, m.Attempts)
+	assert.Equal(t, "flaky: unavailable", m.LastError)
+	assert.Equal(t, clock.Add(baseBackoff), m.NextAttemptAt)
+
+	// Not retried before the backoff has passed.
+	*clock = clock.Add(baseBackoff - time.Second)
+	n, err := r.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	*clock = clock.Add(time.Second)
+	_, err = r.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, m.Attempts)
+	assert.Equal(t, clock.Add(2*baseBackoff), m.NextAttemptAt)
+
+	flaky.fail = false
+	*clock = clock.Add(2 * baseBackoff)
+	_, err = r.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.NotNil(t, m.DispatchedAt)
+	assert.Equal(t, []uint64{1}, ok.seen, "consumers that succeeded are skipped on retry")
+	assert.Equal(t, []uint64{1}, flaky.seen)
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	assert.Equal(t, baseBackoff, backoff(1))
+	assert.Equal(t, 4*baseBackoff, backoff(3))
+	assert.Equal(t, maxBackoff, backoff(30))
+}
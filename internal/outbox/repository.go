@@ -0,0 +1,113 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Append records a domain event. Call it with the transaction that makes
+// the change, so the event is stored if and only if the change commits.
+func Append(db *gorm.DB, eventType string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return db.Create(&Message{Type: eventType, Payload: string(data), NextAttemptAt: time.Now()}).Error
+}
+
+type Repository interface {
+	// Transaction runs fn with a Repository bound to a single database
+	// transaction; any error rolls everything back.
+	Transaction(fn func(repo Repository) error) error
+
+	// ClaimDue locks up to limit undispatched messages due at or before
+	// now, oldest first. Rows locked by another transaction are skipped, so
+	// concurrent relays never claim the same message. Must run inside
+	// Transaction.
+	ClaimDue(now time.Time, limit int) ([]Message, error)
+	// Postpone moves the next attempt of the messages to until.
+	Postpone(ids []uint64, until time.Time) error
+	// GetConsumers lists the consumers that have handled the message.
+	GetConsumers(messageID uint64) ([]string, error)
+	AddReceipt(messageID uint64, consumer string) error
+	MarkDispatched(id uint64, at time.Time) error
+	MarkFailed(id uint64, attempts int, next time.Time, lastError string) error
+	// PruneDispatched deletes messages dispatched before the given time,
+	// with their receipts.
+	PruneDispatched(before time.Time) (int64, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Transaction(fn func(repo Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&repository{db: tx})
+	})
+}
+
+func (r *repository) ClaimDue(now time.Time, limit int) ([]Message, error) {
+	var list []Message
+	err := r.db.
+		Where("dispatched_at IS NULL AND next_attempt_at <= ?", now).
+		Order("id").
+		Limit(limit).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *repository) Postpone(ids []uint64, until time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Model(&Message{}).Where("id IN ?", ids).UpdateColumn("next_attempt_at", until).Error
+}
+
+func (r *repository) GetConsumers(messageID uint64) ([]string, error) {
+	var names []string
+	err := r.db.Model(&Receipt{}).Where("message_id = ?", messageID).Pluck("consumer", &names).Error
+	return names, err
+}
+
+func (r *repository) AddReceipt(messageID uint64, consumer string) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&Receipt{MessageID: messageID, Consumer: consumer, CreatedAt: time.Now()}).Error
+}
+
+func (r *repository) MarkDispatched(id uint64, at time.Time) error {
+	return r.db.Model(&Message{}).Where("id = ?", id).UpdateColumn("dispatched_at", at).Error
+}
+
+func (r *repository) MarkFailed(id uint64, attempts int, next time.Time, lastError string) error {
+	return r.db.Model(&Message{}).Where("id = ?", id).UpdateColumns(map[string]any{
+		"attempts":        attempts,
+		"next_attempt_at": next,
+		"last_error":      lastError,
+	}).Error
+}
+
+func (r *repository) PruneDispatched(before time.Time) (int64, error) {
+	var n int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		old := tx.Model(&Message{}).Select("id").Where("dispatched_at < ?", before)
+		if err := tx.Where("message_id IN (?)", old).Delete(&Receipt{}).Error; err != nil {
+			return err
+		}
+		res := tx.Where("dispatched_at < ?", before).Delete(&Message{})
+		n = res.RowsAffected
+		return res.Error
+	})
+	return n, err
+}
@@ -0,0 +1,77 @@
+package pages
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"flowboard-backend-go/internal/outbox"
+)
+
+type EventType string
+
+const (
+	PageCreated EventType = "page.created"
+	PageUpdated EventType = "page.updated"
+	PageDeleted EventType = "page.deleted"
+)
+
+// EventTypes lists every page event type, for subscribing to all of them.
+var EventTypes = []string{string(PageCreated), string(PageUpdated), string(PageDeleted)}
+
+// PageEvent describes a change to a page. It carries metadata only;
+// subscribers fetch the content if they need it.
+type PageEvent struct {
+	Type        EventType `json:"type"`
+	PageID      uint      `json:"pageId"`
+	ParentID    *uint     `json:"parentId,omitempty"`
+	WorkspaceID *uint     `json:"workspaceId,omitempty"`
+	OwnerID     uint      `json:"ownerId"`
+	ActorID     uint      `json:"actorId"`
+	Title       string    `json:"title"`
+	Version     int       `json:"version"`
+	// Mentioned lists the users the change mentions for the first time.
+	Mentioned []uint `json:"mentioned,omitempty"`
+	// Shared is set when the change moved the page into WorkspaceID.
+	Shared bool      `json:"shared,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// EventHandler consumes page events relayed from the outbox. An event may
+// arrive more than once, always with the same eventID, so handlers can
+// discard duplicates. Returning an error retries the event later.
+type EventHandler interface {
+	HandlePageEvent(ctx context.Context, eventID uint64, e PageEvent) error
+}
+
+// Consume adapts h to an outbox handler; subscribe it to EventTypes.
+func Consume(h EventHandler) outbox.Handler {
+	return func(ctx context.Context, m *outbox.Message) error {
+		var e PageEvent
+		if err := json.Unmarshal([]byte(m.Payload), &e); err != nil {
+			return err
+		}
+		return h.HandlePageEvent(ctx, m.ID, e)
+	}
+}
+
+// record writes the event to the outbox. Call it on the transaction's
+// service so the event commits together with the change.
+func (s *service) record(t EventType, page *Page, actorID uint) error {
+	return s.repo.AddEvent(s.event(t, page, actorID))
+}
+
+// event describes a change to page made by actorID.
+func (s *service) event(t EventType, page *Page, actorID uint) PageEvent {
+	return PageEvent{
+		Type:        t,
+		PageID:      page.ID,
+		ParentID:    page.ParentID,
+		WorkspaceID: page.WorkspaceID,
+		OwnerID:     page.UserID,
+		ActorID:     actorID,
+		Title:       page.Title,
+		Version:     page.Version,
+		At:          time.Now(),
+	}
+}
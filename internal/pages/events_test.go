@@ -0,0 +1,73 @@
+package pages
+
+import (
+	"context"
+	"testing"
+
+	"flowboard-backend-go/internal/outbox"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (r *memRepo) eventTypes() []EventType {
+	list := make([]EventType, len(r.events))
+	for i, e := range r.events {
+		list[i] = e.Type
+	}
+	return list
+}
+
+func TestEventsFollowCommittedChanges(t *testing.T) {
+	repo := newMemRepo()
+	s := NewService(repo)
+
+	parent, err := s.CreatePage(PageInput{Title: "Parent", Content: "p"}, 1)
+	require.NoError(t, err)
+	child, err := s.CreatePage(PageInput{Title: "Child", Content: "c", ParentID: &parent.ID}, 1)
+	require.NoError(t, err)
+	require.Len(t, repo.events, 2)
+	assert.Equal(t, child.ID, repo.events[1].PageID)
+	assert.Equal(t, &parent.ID, repo.events[1].ParentID)
+	assert.Equal(t, uint(1), repo.events[1].ActorID)
+
+	_, err = s.UpdatePage(child.ID, PageInput{Title: "Child v2", Content: "c2", ParentID: &parent.ID}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Child v2", repo.events[2].Title)
+
+	// Failed changes record nothing.
+	_, err = s.UpdatePage(child.ID, PageInput{Title: "Stolen", Content: "x"}, 2)
+	require.Error(t, err)
+	require.Error(t, s.DeletePage(child.ID, 2))
+	assert.Len(t, repo.events, 3)
+
+	_, err = s.MovePage(child.ID, MoveInput{}, 1)
+	require.NoError(t, err)
+	require.NoError(t, s.DeletePage(child.ID, 1))
+
+	assert.Equal(t, []EventType{PageCreated, PageCreated, PageUpdated, PageUpdated, PageDeleted}, repo.eventTypes())
+	assert.Equal(t, child.ID, repo.events[4].PageID)
+}
+
+func TestConsumeDecodesOutboxMessages(t *testing.T) {
+	h := &recordingHandler{}
+	fn := Consume(h)
+	err := fn(context.Background(), &outbox.Message{ID: 7, Type: string(PageUpdated), Payload: `{"type":"page.updated","pageId":3,"title":"Plan"}`})
+	require.NoError(t, err)
+	require.Len(t, h.events, 1)
+	assert.Equal(t, uint64(7), h.ids[0])
+	assert.Equal(t, "Plan", h.events[0].Title)
+
+	assert.Error(t, fn(context.Background(), &outbox.Message{ID: 8, Payload: "{"}))
+}
+
+type recordingHandler struct {
+	ids    []uint64
+	events []PageEvent
+}
+
+func (h *recordingHandler) HandlePageEvent(_ context.Context, id uint64, e PageEvent) error {
+	h.ids = append(h.ids, id)
+	h.events = append(h.events, e)
+	return nil
+}
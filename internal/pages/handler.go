@@ -1,22 +1,39 @@
 package pages
 
 import (
+	"archive/zip"
+	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"path"
 	"strconv"
+	"strings"
 
+	"flowboard-backend-go/internal/audit"
 	"flowboard-backend-go/internal/middleware"
+	"flowboard-backend-go/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 )
 
+// ViewRecorder is told whenever a user opens a page.
+type ViewRecorder interface {
+	RecordView(userID, pageID uint) error
+}
+
 type Handler struct {
 	service Service
+	views   ViewRecorder
+	audit   audit.Recorder
 }
 
-func NewHandler(service Service) *Handler {
+func NewHandler(service Service, views ViewRecorder, auditLog audit.Recorder) *Handler {
 	return &Handler{
 		service: service,
+		views:   views,
+		audit:   auditLog,
 	}
 }
 
@@ -35,6 +52,33 @@ func getUserID(c *gin.Context) (uint, error) {
 	return uid, nil
 }
 
+// parseID reads a numeric path parameter
+func parseID(c *gin.Context, name string) (uint, bool) {
+	id64, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		return 0, false
+	}
+	return uint(id64), true
+}
+
+// respondError maps service errors to HTTP statuses
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrPageNotFound), errors.Is(err, ErrBlockNotFound), errors.Is(err, ErrTagNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidBlock), errors.Is(err, ErrInvalidImport), errors.Is(err, ErrInvalidMerge),
+		errors.Is(err, ErrInvalidMove), errors.Is(err, ErrInvalidDestination), errors.Is(err, ErrTagScope):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrTagExists), errors.Is(err, ErrVersionConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
 func (h *Handler) CreatePage(c *gin.Context) {
 	var input PageInput
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -50,9 +94,10 @@ func (h *Handler) CreatePage(c *gin.Context) {
 
 	page, err := h.service.CreatePage(input, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
+	h.record(c, audit.PageCreated, page.ID, map[string]any{"title": page.Title})
 
 	c.JSON(http.StatusCreated, gin.H{"success": true, "data": page})
 }
@@ -64,15 +109,58 @@ func (h *Handler) GetAllPages(c *gin.Context) {
 		return
 	}
 
-	pages, err := h.service.GetAllPagesByUser(userID)
+	filter, err := parsePageFilter(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pages, err := h.service.GetAllPagesByUser(userID, filter)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": pages})
 }
 
+// parsePageFilter reads ?tags=1,2&match=any|all&workspaceId=3
+func parsePageFilter(c *gin.Context) (PageFilter, error) {
+	filter := PageFilter{TagMatch: c.DefaultQuery("match", "any")}
+	if filter.TagMatch != "any" && filter.TagMatch != "all" {
+		return filter, errors.New("match must be any or all")
+	}
+	if v := c.Query("workspaceId"); v != "" {
+		id64, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return filter, errors.New("invalid workspaceId")
+		}
+		id := uint(id64)
+		filter.WorkspaceID = &id
+	}
+	ids, err := parseIDList(c.Query("tags"))
+	if err != nil {
+		return filter, errors.New("invalid tags filter")
+	}
+	filter.TagIDs = ids
+	return filter, nil
+}
+
+func parseIDList(v string) ([]uint, error) {
+	var ids []uint
+	for _, part := range strings.Split(v, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		id64, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, uint(id64))
+	}
+	return ids, nil
+}
+
 func (h *Handler) GetPageByID(c *gin.Context) {
 	userID, err := getUserID(c)
 	if err != nil {
@@ -90,14 +178,16 @@ func (h *Handler) GetPageByID(c *gin.Context) {
 
 	page, err := h.service.GetPageByID(id, userID)
 	if err != nil {
-		if err == ErrPageNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "page not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
+	if h.views != nil {
+		if err := h.views.RecordView(userID, id); err != nil {
+			logger.Log.Warnw("Recording page view failed", "pageID", id, "userID", userID, "error", err)
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": page})
 }
 
@@ -124,13 +214,10 @@ func (h *Handler) UpdatePage(c *gin.Context) {
 
 	page, err := h.service.UpdatePage(id, input, userID)
 	if err != nil {
-		if err == ErrPageNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "page not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
+	h.record(c, audit.PageUpdated, page.ID, map[string]any{"title": page.Title, "version": page.Version})
 
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": page})
 }
@@ -151,11 +238,588 @@ func (h *Handler) DeletePage(c *gin.Context) {
 	id := uint(id64)
 
 	if err := h.service.DeletePage(id, userID); err != nil {
-		if err == ErrPageNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "page not found"})
+		respondError(c, err)
+		return
+	}
+	h.record(c, audit.PageDeleted, id, nil)
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// record adds a page action to the audit trail.
+func (h *Handler) record(c *gin.Context, action audit.Action, pageID uint, details map[string]any) {
+	h.audit.Record(audit.FromRequest(c), action, audit.Target{Type: audit.TargetPage, ID: pageID}, details)
+}
+
+func (h *Handler) GetBlocks(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	pageID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	blocks, err := h.service.GetBlocks(pageID, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": blocks})
+}
+
+func (h *Handler) InsertBlock(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	pageID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input BlockInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	block, err := h.service.InsertBlock(pageID, input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	h.record(c, audit.PageUpdated, pageID, map[string]any{"blockInserted": block.ID})
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": block})
+}
+
+func (h *Handler) UpdateBlock(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	pageID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input BlockUpdateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	block, err := h.service.UpdateBlock(pageID, c.Param("blockId"), input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	h.record(c, audit.PageUpdated, pageID, map[string]any{"blockUpdated": block.ID})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": block})
+}
+
+func (h *Handler) MoveBlock(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	pageID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input BlockMoveInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	blocks, err := h.service.MoveBlock(pageID, c.Param("blockId"), input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	h.record(c, audit.PageUpdated, pageID, map[string]any{"blockMoved": c.Param("blockId")})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": blocks})
+}
+
+func (h *Handler) DeleteBlock(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	pageID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteBlock(pageID, c.Param("blockId"), userID); err != nil {
+		respondError(c, err)
+		return
+	}
+	h.record(c, audit.PageUpdated, pageID, map[string]any{"blockDeleted": c.Param("blockId")})
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+const (
+	maxImportUpload   = 32 << 20 // multipart body
+	maxImportFileSize = 5 << 20  // single Markdown document
+	maxImportEntries  = 2000     // files inside one zip
+	maxImportTotal    = 64 << 20 // all documents of one upload, unpacked
+)
+
+// GetBacklinks lists the pages that link to this one.
+func (h *Handler) GetBacklinks(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	list, err := h.service.GetBacklinks(id, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": list})
+}
+
+func (h *Handler) ExportPage(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	switch c.DefaultQuery("format", "md") {
+	case "md", "markdown":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported export format"})
+		return
+	}
+
+	filename, data, err := h.service.ExportMarkdown(id, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, "text/markdown; charset=utf-8", data)
+}
+
+// ImportPages accepts .md files and/or .zip archives in the "files" form
+// field. An optional "parentId" form value nests the imported pages.
+func (h *Handler) ImportPages(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportUpload)
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid multipart upload"})
+		return
+	}
+
+	var parentID *uint
+	if v := c.PostForm("parentId"); v != "" {
+		id64, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid parentId"})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		id := uint(id64)
+		parentID = &id
+	}
+
+	var files []MarkdownFile
+	budget := maxImportTotal
+	for _, fh := range form.File["files"] {
+		read, err := readImportUpload(fh, &budget)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		files = append(files, read...)
+	}
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no files uploaded"})
+		return
+	}
+
+	imported, err := h.service.ImportMarkdown(files, parentID, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	for _, p := range imported {
+		h.record(c, audit.PageCreated, p.Page.ID, map[string]any{"title": p.Page.Title, "importedFrom": p.Path})
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": imported})
+}
+
+// readImportUpload returns the Markdown documents in one uploaded file,
+// unpacking zip archives while keeping their folder structure. The bytes
+// read are charged against budget, which bounds the unpacked size of the
+// whole upload.
+func readImportUpload(fh *multipart.FileHeader, budget *int) ([]MarkdownFile, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if !strings.EqualFold(path.Ext(fh.Filename), ".zip") {
+		data, err := readLimited(f, budget)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fh.Filename, err)
+		}
+		return []MarkdownFile{{Path: path.Base(fh.Filename), Data: data}}, nil
+	}
+
+	zr, err := zip.NewReader(f, fh.Size)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid zip archive", fh.Filename)
+	}
+	if len(zr.File) > maxImportEntries {
+		return nil, fmt.Errorf("%s: too many files in archive", fh.Filename)
+	}
+
+	var out []MarkdownFile
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+		if _, ok := cleanImportPath(zf.Name); !ok {
+			continue
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", zf.Name, err)
+		}
+		data, err := readLimited(rc, budget)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", zf.Name, err)
+		}
+		out = append(out, MarkdownFile{Path: zf.Name, Data: data})
+	}
+	return out, nil
+}
+
+// readLimited reads one document of at most maxImportFileSize bytes and
+// deducts its size from budget.
+func readLimited(r io.Reader, budget *int) ([]byte, error) {
+	limit := min(maxImportFileSize, *budget)
+	data, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > limit {
+		if limit < maxImportFileSize {
+			return nil, errors.New("upload too large")
+		}
+		return nil, errors.New("file too large")
+	}
+	*budget -= len(data)
+	return data, nil
+}
+
+// RenderPage returns the page content as sanitized HTML. Pass ?toc=true to
+// also get a table of contents built from its headings.
+func (h *Handler) RenderPage(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	withTOC, _ := strconv.ParseBool(c.DefaultQuery("toc", "false"))
+
+	rendered, err := h.service.RenderPage(id, userID, withTOC)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": rendered})
+}
+
+func (h *Handler) DuplicatePage(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input DuplicateInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	page, err := h.service.DuplicatePage(id, input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	h.record(c, audit.PageCreated, page.ID, map[string]any{"title": page.Title, "duplicateOf": id})
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": page})
+}
+
+func (h *Handler) MovePage(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input MoveInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.service.MovePage(id, input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	h.record(c, audit.PageUpdated, page.ID, map[string]any{"movedTo": page.ParentID})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": page})
+}
+
+// ReorderPage moves a page among its siblings: {"afterId": 12} places it
+// after page 12, {"afterId": null} first.
+func (h *Handler) ReorderPage(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input ReorderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	page, err := h.service.ReorderPage(id, input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	h.record(c, audit.PageUpdated, page.ID, map[string]any{"reorderedAfter": input.AfterID})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": page})
+}
+
+func (h *Handler) GetTags(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var workspaceID *uint
+	if v := c.Query("workspaceId"); v != "" {
+		id64, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workspaceId"})
+			return
+		}
+		id := uint(id64)
+		workspaceID = &id
+	}
+
+	tags, err := h.service.GetTags(userID, workspaceID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": tags})
+}
+
+func (h *Handler) CreateTag(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input TagInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag, err := h.service.CreateTag(input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": tag})
+}
+
+func (h *Handler) UpdateTag(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input TagUpdateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag, err := h.service.UpdateTag(id, input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": tag})
+}
+
+func (h *Handler) MergeTag(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input TagMergeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag, err := h.service.MergeTags(id, input.TargetID, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": tag})
+}
+
+func (h *Handler) DeleteTag(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteTag(id, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+func (h *Handler) AttachTags(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	pageID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input PageTagsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tags, err := h.service.AttachTags(pageID, input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": tags})
+}
+
+func (h *Handler) DetachTag(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	pageID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	tagID, ok := parseID(c, "tagId")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DetachTag(pageID, tagID, userID); err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -0,0 +1,65 @@
+package pages
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"flowboard-backend-go/internal/audit"
+	"flowboard-backend-go/internal/middleware"
+	"flowboard-backend-go/internal/workspaces"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// discardAudit drops audit entries.
+type discardAudit struct{}
+
+func (discardAudit) Record(audit.Request, audit.Action, audit.Target, map[string]any) {}
+
+// newTestRouter serves the page routes, authenticating every request as
+// the user in the X-User header.
+func newTestRouter(s Service) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		id, _ := strconv.ParseUint(c.GetHeader("X-User"), 10, 32)
+		c.Set(middleware.ContextUserIDKey, uint(id))
+	})
+	h := NewHandler(s, nil, discardAudit{})
+	r.GET("/pages/:id", h.GetPageByID)
+	r.PUT("/pages/:id", h.UpdatePage)
+	r.DELETE("/pages/:id", h.DeletePage)
+	return r
+}
+
+func TestPageHandlers_MapServiceErrors(t *testing.T) {
+	ws := uint(10)
+	access := stubWorkspaces{{ws, 1}: workspaces.RoleEditor, {ws, 2}: workspaces.RoleViewer}
+	s := NewService(newMemRepo(), WithWorkspaceAccess(access))
+	page, err := s.CreatePage(PageInput{Title: "Spec", Content: "v1", WorkspaceID: &ws}, 1)
+	require.NoError(t, err)
+	r := newTestRouter(s)
+
+	do := func(method string, userID uint, body string) int {
+		req := httptest.NewRequest(method, "/pages/"+strconv.Itoa(int(page.ID)), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User", strconv.Itoa(int(userID)))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, 2, ""))
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, 3, ""))
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPut, 2, `{"title":"Spec","content":"viewer"}`))
+	assert.Equal(t, http.StatusForbidden, do(http.MethodDelete, 2, ""))
+
+	stale := `{"title":"Spec","content":"mine","version":` + strconv.Itoa(page.Version) + `}`
+	assert.Equal(t, http.StatusOK, do(http.MethodPut, 1, `{"title":"Spec","content":"theirs"}`))
+	assert.Equal(t, http.StatusConflict, do(http.MethodPut, 1, stale))
+}
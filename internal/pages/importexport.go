@@ -0,0 +1,253 @@
+package pages
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidImport = errors.New("invalid import")
+
+// MarkdownFile is one document to import. Folders in Path (as found in a
+// zip archive) become the page hierarchy.
+type MarkdownFile struct {
+	Path string
+	Data []byte
+}
+
+// ImportedPage reports what a single imported file turned into.
+type ImportedPage struct {
+	Path string   `json:"path"`
+	Page *Page    `json:"page"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+type frontMatter struct {
+	Title string
+	Tags  []string
+}
+
+var (
+	frontMatterRe = regexp.MustCompile(`(?s)\A---\r?\n(.*?)\r?\n---[ \t]*(?:\r?\n|\z)`)
+	slugRe        = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// splitFrontMatter separates a leading YAML front-matter block from the
+// Markdown body. Documents without front matter are returned unchanged.
+func splitFrontMatter(src string) (frontMatter, string, error) {
+	var fm frontMatter
+	m := frontMatterRe.FindStringSubmatchIndex(src)
+	if m == nil {
+		return fm, src, nil
+	}
+
+	var raw struct {
+		Title string      `yaml:"title"`
+		Tags  interface{} `yaml:"tags"`
+	}
+	if err := yaml.Unmarshal([]byte(src[m[2]:m[3]]), &raw); err != nil {
+		return fm, src, fmt.Errorf("%w: front matter: %v", ErrInvalidImport, err)
+	}
+	fm.Title = strings.TrimSpace(raw.Title)
+
+	// Tags may be a YAML list or a comma-separated string.
+	switch v := raw.Tags.(type) {
+	case string:
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				fm.Tags = append(fm.Tags, t)
+			}
+		}
+	case []interface{}:
+		for _, t := range v {
+			if str := strings.TrimSpace(fmt.Sprint(t)); str != "" {
+				fm.Tags = append(fm.Tags, str)
+			}
+		}
+	}
+
+	return fm, strings.TrimLeft(src[m[1]:], "\r\n"), nil
+}
+
+func formatFrontMatter(fm frontMatter) (string, error) {
+	raw := struct {
+		Title string   `yaml:"title"`
+		Tags  []string `yaml:"tags,omitempty"`
+	}{fm.Title, fm.Tags}
+	out, err := yaml.Marshal(raw)
+	if err != nil {
+		return "", err
+	}
+	return "---\n" + string(out) + "---\n\n", nil
+}
+
+// ExportMarkdown returns the page as a Markdown document with front matter
+// and a suggested file name.
+func (s *service) ExportMarkdown(id, userID uint) (string, []byte, error) {
+	page, err := s.GetPageByID(id, userID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	fm := frontMatter{Title: page.Title}
+	for _, t := range page.Tags {
+		fm.Tags = append(fm.Tags, t.Name)
+	}
+	header, err := formatFrontMatter(fm)
+	if err != nil {
+		return "", nil, err
+	}
+	content := page.Content
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return slugify(page.Title) + ".md", []byte(header + content), nil
+}
+
+// ImportMarkdown creates one page per file under parentID. A folder becomes
+// a page too: either the sibling file with the folder's name ("Notes.md"
+// next to "Notes/") or, if there is none, an empty page titled after it.
+func (s *service) ImportMarkdown(files []MarkdownFile, parentID *uint, userID uint) ([]ImportedPage, error) {
+	if parentID != nil {
+		if _, err := s.editablePage(*parentID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	byStem := map[string]*MarkdownFile{}
+	for i := range files {
+		p, ok := cleanImportPath(files[i].Path)
+		if !ok {
+			continue
+		}
+		files[i].Path = p
+		stem := strings.TrimSuffix(p, path.Ext(p))
+		if prev, ok := byStem[stem]; ok {
+			return nil, fmt.Errorf("%w: %s and %s would import as the same page", ErrInvalidImport, prev.Path, p)
+		}
+		byStem[stem] = &files[i]
+	}
+	if len(byStem) == 0 {
+		return nil, fmt.Errorf("%w: no markdown files", ErrInvalidImport)
+	}
+
+	stems := make([]string, 0, len(byStem))
+	for stem := range byStem {
+		stems = append(stems, stem)
+	}
+	sort.Strings(stems)
+
+	// One transaction, so a failing document leaves no partial tree behind.
+	// Mentions ride the pages' events and are notified only once it
+	// commits.
+	var result []ImportedPage
+	err := s.inTx(func(tx *service) error {
+		imp := &importer{svc: tx, userID: userID, files: byStem, pages: map[string]*uint{".": parentID}}
+		for _, stem := range stems {
+			if _, err := imp.importStem(stem); err != nil {
+				return err
+			}
+		}
+		result = imp.result
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type importer struct {
+	svc    *service
+	userID uint
+	files  map[string]*MarkdownFile
+	pages  map[string]*uint // stem or folder path -> created page ID
+	result []ImportedPage
+}
+
+// importStem creates the page for stem (a file path without extension, or
+// a folder path), creating its ancestors first.
+func (imp *importer) importStem(stem string) (*uint, error) {
+	if id, ok := imp.pages[stem]; ok {
+		return id, nil
+	}
+	parent, err := imp.importStem(path.Dir(stem))
+	if err != nil {
+		return nil, err
+	}
+
+	input := PageInput{Title: path.Base(stem), ParentID: parent}
+	var tags []string
+	src := ""
+	if f, ok := imp.files[stem]; ok {
+		fm, body, err := splitFrontMatter(string(f.Data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Path, err)
+		}
+		input.Content = body
+		tags = fm.Tags
+		if fm.Title != "" {
+			input.Title = fm.Title
+		} else if h := firstHeading(body); h != "" {
+			input.Title = h
+		}
+		src = f.Path
+	}
+
+	page, err := imp.svc.CreatePage(input, imp.userID)
+	if err != nil {
+		return nil, err
+	}
+	imp.pages[stem] = &page.ID
+	if len(tags) > 0 {
+		if page.Tags, err = imp.svc.AttachTags(page.ID, PageTagsInput{Names: tags}, imp.userID); err != nil {
+			return nil, err
+		}
+	}
+	if src != "" {
+		page.Blocks = nil
+		imp.result = append(imp.result, ImportedPage{Path: src, Page: page, Tags: tags})
+	}
+	return &page.ID, nil
+}
+
+// cleanImportPath normalizes an archive path and filters out anything that
+// is not a visible Markdown file.
+func cleanImportPath(p string) (string, bool) {
+	p = path.Clean("/" + strings.ReplaceAll(p, "\\", "/"))[1:]
+	if p == "" || strings.HasPrefix(p, "__MACOSX/") {
+		return "", false
+	}
+	for _, part := range strings.Split(p, "/") {
+		if strings.HasPrefix(part, ".") {
+			return "", false
+		}
+	}
+	switch strings.ToLower(path.Ext(p)) {
+	case ".md", ".markdown":
+		return p, true
+	}
+	return "", false
+}
+
+func firstHeading(body string) string {
+	for _, b := range ParseMarkdown(body) {
+		if b.Type == BlockHeading && b.Props.Level == 1 {
+			return b.Text
+		}
+	}
+	return ""
+}
+
+func slugify(title string) string {
+	slug := strings.Trim(slugRe.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if slug == "" {
+		return "page"
+	}
+	return slug
+}
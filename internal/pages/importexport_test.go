@@ -0,0 +1,145 @@
+package pages
+
+import (
+	"strings"
+	"testing"
+
+	"flowboard-backend-go/internal/workspaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	fm, body, err := splitFrontMatter("---\ntitle: Roadmap\ntags: [q1, planning]\n---\n\n# Heading\ntext")
+	require.NoError(t, err)
+	assert.Equal(t, "Roadmap", fm.Title)
+	assert.Equal(t, []string{"q1", "planning"}, fm.Tags)
+	assert.Equal(t, "# Heading\ntext", body)
+
+	fm, _, err = splitFrontMatter("---\ntags: a, b\n---\nbody")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, fm.Tags)
+
+	fm, body, err = splitFrontMatter("no front matter\n---\n")
+	require.NoError(t, err)
+	assert.Empty(t, fm.Title)
+	assert.Equal(t, "no front matter\n---\n", body)
+}
+
+func TestImportMarkdown_BuildsHierarchyFromFolders(t *testing.T) {
+	repo := newMemRepo()
+	s := NewService(repo)
+
+	files := []MarkdownFile{
+		{Path: "Projects/Roadmap.md", Data: []byte("---\ntitle: Roadmap 2026\ntags: [plan]\n---\nsteps")},
+		{Path: "Projects.md", Data: []byte("# All projects\n\nindex")},
+		{Path: "Archive/Old/notes.md", Data: []byte("old notes")},
+		{Path: "__MACOSX/Projects/._Roadmap.md", Data: []byte("junk")},
+		{Path: "image.png", Data: []byte("binary")},
+	}
+	imported, err := s.ImportMarkdown(files, nil, 7)
+	require.NoError(t, err)
+	require.Len(t, imported, 3)
+
+	byTitle := map[string]*Page{}
+	for _, p := range repo.pages {
+		byTitle[p.Title] = p
+		assert.Equal(t, uint(7), p.UserID)
+	}
+	require.Len(t, byTitle, 5) // three files plus the "Archive" and "Old" folder pages
+
+	projects := byTitle["All projects"]
+	roadmap := byTitle["Roadmap 2026"]
+	require.NotNil(t, projects)
+	require.NotNil(t, roadmap)
+	assert.Nil(t, projects.ParentID)
+	assert.Equal(t, projects.ID, *roadmap.ParentID)
+	assert.Equal(t, "steps", roadmap.Content)
+
+	assert.Equal(t, byTitle["Archive"].ID, *byTitle["Old"].ParentID)
+	assert.Equal(t, byTitle["Old"].ID, *byTitle["notes"].ParentID)
+
+	for _, ip := range imported {
+		if ip.Path == "Projects/Roadmap.md" {
+			assert.Equal(t, []string{"plan"}, ip.Tags)
+		}
+	}
+}
+
+func TestImportMarkdown_RejectsDuplicateStems(t *testing.T) {
+	repo := newMemRepo()
+	s := NewService(repo)
+
+	_, err := s.ImportMarkdown([]MarkdownFile{
+		{Path: "Notes/a.md", Data: []byte("one")},
+		{Path: "Notes/a.markdown", Data: []byte("two")},
+	}, nil, 1)
+	assert.ErrorIs(t, err, ErrInvalidImport)
+	assert.ErrorContains(t, err, "Notes/a.md and Notes/a.markdown")
+	assert.Empty(t, repo.pages)
+}
+
+func TestImportMarkdown_NotifiesMentionsAfterCommit(t *testing.T) {
+	ws := uint(7)
+	access := stubWorkspaces{{ws, 1}: workspaces.RoleEditor, {ws, 2}: workspaces.RoleEditor}
+	notifier := &recordingNotifier{}
+	repo := newMemRepo()
+	s := NewService(repo, WithWorkspaceAccess(access), WithNotifier(notifier))
+	team, err := s.CreatePage(PageInput{Title: "Team", Content: "t", WorkspaceID: &ws}, 1)
+	require.NoError(t, err)
+	repo.relay(t, s)
+
+	files := []MarkdownFile{
+		{Path: "a.md", Data: []byte("@[Bo](user:2) please read")},
+		{Path: "b.md", Data: []byte("# Broken")},
+	}
+	repo.failTitle = "Broken"
+	_, err = s.ImportMarkdown(files, &team.ID, 1)
+	require.Error(t, err)
+	repo.relay(t, s)
+	assert.Empty(t, notifier.sent, "a rolled back import mentions nobody")
+
+	repo.failTitle = ""
+	_, err = s.ImportMarkdown(files, &team.ID, 1)
+	require.NoError(t, err)
+	repo.relay(t, s)
+	require.Len(t, notifier.sent, 1)
+	assert.Equal(t, uint(2), notifier.sent[0].UserID)
+}
+
+func TestExportMarkdown_RoundTripsThroughImport(t *testing.T) {
+	repo := newMemRepo()
+	s := NewService(repo)
+
+	page, err := s.CreatePage(PageInput{Title: "Weekly: Sync", Content: "- item"}, 1)
+	require.NoError(t, err)
+
+	name, data, err := s.ExportMarkdown(page.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "weekly-sync.md", name)
+	assert.True(t, strings.HasPrefix(string(data), "---\ntitle: 'Weekly: Sync'\n---\n\n- item"))
+
+	_, _, err = s.ExportMarkdown(page.ID, 2)
+	assert.Equal(t, ErrPageNotFound, err)
+
+	imported, err := s.ImportMarkdown([]MarkdownFile{{Path: name, Data: data}}, nil, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Weekly: Sync", imported[0].Page.Title)
+	assert.Equal(t, "- item\n", imported[0].Page.Content)
+}
+
+func TestReadLimited_ChargesTheUploadBudget(t *testing.T) {
+	budget := maxImportFileSize + 10
+	data, err := readLimited(strings.NewReader(strings.Repeat("a", maxImportFileSize)), &budget)
+	require.NoError(t, err)
+	assert.Len(t, data, maxImportFileSize)
+	assert.Equal(t, 10, budget)
+
+	_, err = readLimited(strings.NewReader(strings.Repeat("a", 11)), &budget)
+	assert.EqualError(t, err, "upload too large")
+
+	budget = maxImportTotal
+	_, err = readLimited(strings.NewReader(strings.Repeat("a", maxImportFileSize+1)), &budget)
+	assert.EqualError(t, err, "file too large")
+}
@@ -0,0 +1,229 @@
+package pages
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	pageRefRe = regexp.MustCompile(`\[\[([^\[\]\n]+)\]\]`)
+	mentionRe = regexp.MustCompile(`@\[([^\]\n]*)\]\(user:(\d+)\)`)
+	// handleRe matches a plain @handle or @email that does not sit inside
+	// a word, an address or a URL.
+	handleRe = regexp.MustCompile(`(?:^|[^\w@./])@([\w.+-]+(?:@[\w-]+(?:\.[\w-]+)+)?)`)
+)
+
+// maxLabelLen matches the column size of PageLink.Label.
+const maxLabelLen = 255
+
+// GetBacklinks lists the pages linking to a page, limited to those the
+// user can read.
+func (s *service) GetBacklinks(id, userID uint) ([]Page, error) {
+	if _, err := s.GetPageByID(id, userID); err != nil {
+		return nil, err
+	}
+	list, err := s.repo.GetBacklinks(id)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := list[:0]
+	for _, p := range list {
+		ok, err := s.canRead(&p, userID)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			visible = append(visible, p)
+		}
+	}
+	return visible, nil
+}
+
+// extractRefs collects the [[Title]] labels, @[Name](user:ID) mentions and
+// plain @handles in blocks, in order and without duplicates. Code blocks
+// are skipped.
+func extractRefs(blocks []Block) (labels []string, mentions []PageLink, handles []string) {
+	seenLabel := map[string]bool{}
+	seenUser := map[uint]bool{}
+	seenHandle := map[string]bool{}
+	for _, b := range blocks {
+		if b.Type == BlockCode {
+			continue
+		}
+		for _, m := range pageRefRe.FindAllStringSubmatch(b.Text, -1) {
+			label := strings.TrimSpace(m[1])
+			key := strings.ToLower(label)
+			if label == "" || len(label) > maxLabelLen || seenLabel[key] {
+				continue
+			}
+			seenLabel[key] = true
+			labels = append(labels, label)
+		}
+		for _, m := range mentionRe.FindAllStringSubmatch(b.Text, -1) {
+			id64, err := strconv.ParseUint(m[2], 10, 32)
+			if err != nil || seenUser[uint(id64)] {
+				continue
+			}
+			id := uint(id64)
+			seenUser[id] = true
+			label := strings.TrimSpace(m[1])
+			if len(label) > maxLabelLen {
+				label = ""
+			}
+			mentions = append(mentions, PageLink{Kind: LinkMention, TargetUserID: &id, Label: label})
+		}
+		for _, m := range handleRe.FindAllStringSubmatch(b.Text, -1) {
+			handle := strings.TrimRight(m[1], ".-")
+			key := strings.ToLower(handle)
+			if handle == "" || len(handle) > maxLabelLen || seenHandle[key] {
+				continue
+			}
+			seenHandle[key] = true
+			handles = append(handles, handle)
+		}
+	}
+	return labels, mentions, handles
+}
+
+// syncLinks re-parses the page's content and replaces its link records.
+// It returns the users mentioned for the first time, who have not been
+// notified yet. Mentions of users who cannot read the page are dropped.
+func (s *service) syncLinks(page *Page, userID uint) ([]uint, error) {
+	blocks := page.Blocks
+	if blocks == nil {
+		blocks = ParseMarkdown(page.Content)
+	}
+	labels, mentions, handles := extractRefs(blocks)
+	mentions, err := s.resolveHandles(page, mentions, handles)
+	if err != nil {
+		return nil, err
+	}
+
+	old, err := s.repo.GetLinks(page.ID)
+	if err != nil {
+		return nil, err
+	}
+	previous := map[string]uint{}
+	alreadyMentioned := map[uint]bool{}
+	for _, l := range old {
+		switch {
+		case l.Kind == LinkPage && l.TargetPageID != nil:
+			previous[strings.ToLower(l.Label)] = *l.TargetPageID
+		case l.Kind == LinkMention && l.TargetUserID != nil:
+			alreadyMentioned[*l.TargetUserID] = true
+		}
+	}
+
+	var links []PageLink
+	linked := map[uint]bool{}
+	for _, label := range labels {
+		target, err := s.resolvePageRef(page, label, previous[strings.ToLower(label)], userID)
+		if err != nil {
+			return nil, err
+		}
+		if target == 0 || linked[target] {
+			continue
+		}
+		linked[target] = true
+		links = append(links, PageLink{Kind: LinkPage, TargetPageID: &target, Label: label})
+	}
+
+	var mentioned []uint
+	for _, m := range mentions {
+		ok, err := s.canRead(page, *m.TargetUserID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		links = append(links, m)
+		if !alreadyMentioned[*m.TargetUserID] {
+			mentioned = append(mentioned, *m.TargetUserID)
+		}
+	}
+
+	return mentioned, s.repo.ReplaceLinks(page.ID, links)
+}
+
+// resolvePageRef finds the page a [[label]] points at. A label that already
+// resolved on an earlier save keeps its target as long as the user can
+// still read it, even if that page has since been renamed; otherwise the
+// label is matched against page titles in the source page's space. It
+// returns 0 when nothing matches.
+func (s *service) resolvePageRef(page *Page, label string, previous uint, userID uint) (uint, error) {
+	if previous != 0 && previous != page.ID {
+		_, err := s.GetPageByID(previous, userID)
+		if err == nil {
+			return previous, nil
+		}
+		if !errors.Is(err, ErrPageNotFound) && !errors.Is(err, ErrForbidden) {
+			return 0, err
+		}
+	}
+
+	candidates, err := s.repo.FindPagesByTitle(label, page.WorkspaceID, page.UserID)
+	if err != nil {
+		return 0, err
+	}
+	for _, c := range candidates {
+		if c.ID != page.ID {
+			return c.ID, nil
+		}
+	}
+	return 0, nil
+}
+
+// resolveHandles adds the users named by plain @handles to mentions. A
+// handle resolves only when exactly one user who can read the page
+// matches it; users already mentioned are not added twice.
+func (s *service) resolveHandles(page *Page, mentions []PageLink, handles []string) ([]PageLink, error) {
+	if s.users == nil {
+		return mentions, nil
+	}
+	seen := map[uint]bool{}
+	for _, m := range mentions {
+		seen[*m.TargetUserID] = true
+	}
+	for _, handle := range handles {
+		candidates, err := s.users.FindByHandle(handle)
+		if err != nil {
+			return nil, err
+		}
+		var found []uint
+		for _, u := range candidates {
+			ok, err := s.canRead(page, u.ID)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				found = append(found, u.ID)
+			}
+		}
+		if len(found) != 1 || seen[found[0]] {
+			continue
+		}
+		id := found[0]
+		seen[id] = true
+		mentions = append(mentions, PageLink{Kind: LinkMention, TargetUserID: &id, Label: handle})
+	}
+	return mentions, nil
+}
+
+// canRead reports whether userID may read page.
+func (s *service) canRead(page *Page, userID uint) (bool, error) {
+	if page.WorkspaceID == nil {
+		return page.UserID == userID, nil
+	}
+	if s.workspaces == nil {
+		return false, nil
+	}
+	role, err := s.workspaces.MemberRole(*page.WorkspaceID, userID)
+	if err != nil {
+		return false, err
+	}
+	return role != "", nil
+}
@@ -0,0 +1,162 @@
+package pages
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"flowboard-backend-go/internal/notifications"
+	"flowboard-backend-go/internal/users"
+	"flowboard-backend-go/internal/workspaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubUsers resolves handles against fixed users by email or local part.
+type stubUsers []users.User
+
+func (d stubUsers) FindByHandle(handle string) ([]users.User, error) {
+	var out []users.User
+	for _, u := range d {
+		local, _, _ := strings.Cut(u.Email, "@")
+		if strings.EqualFold(u.Email, handle) || strings.EqualFold(local, handle) {
+			out = append(out, u)
+		}
+	}
+	return out, nil
+}
+
+// recordingNotifier records notifications, dropping repeated keys like
+// the notification center does.
+type recordingNotifier struct {
+	sent []*notifications.Notification
+	keys map[string]bool
+}
+
+func (r *recordingNotifier) Notify(_ context.Context, n *notifications.Notification) error {
+	if n.DedupKey != nil {
+		if r.keys[*n.DedupKey] {
+			return nil
+		}
+		if r.keys == nil {
+			r.keys = map[string]bool{}
+		}
+		r.keys[*n.DedupKey] = true
+	}
+	r.sent = append(r.sent, n)
+	return nil
+}
+
+func TestExtractRefs(t *testing.T) {
+	blocks := ParseMarkdown("See [[Roadmap]] and [[ roadmap ]], ask @[Bo](user:2).\n\n" +
+		"```\n[[Not a link]] @[X](user:9) @code\n```\n\n- [[Specs]] with @[Bo again](user:2) and @[Cy](user:3)\n\n" +
+		"Ping @dana, @Dana and @eve@example.com. Not bob@example.com or https://x.test/@frank")
+
+	labels, mentions, handles := extractRefs(blocks)
+	assert.Equal(t, []string{"Roadmap", "Specs"}, labels)
+	require.Len(t, mentions, 2)
+	assert.Equal(t, uint(2), *mentions[0].TargetUserID)
+	assert.Equal(t, "Bo", mentions[0].Label)
+	assert.Equal(t, uint(3), *mentions[1].TargetUserID)
+	assert.Equal(t, []string{"dana", "eve@example.com"}, handles)
+}
+
+func TestLinksSurviveRenameAndFeedBacklinks(t *testing.T) {
+	s := NewService(newMemRepo())
+
+	target, err := s.CreatePage(PageInput{Title: "Roadmap", Content: "r"}, 1)
+	require.NoError(t, err)
+	src, err := s.CreatePage(PageInput{Title: "Weekly", Content: "Read [[Roadmap]] and [[Nowhere]]"}, 1)
+	require.NoError(t, err)
+
+	back, err := s.GetBacklinks(target.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Weekly"}, titles(back))
+
+	_, err = s.UpdatePage(target.ID, PageInput{Title: "Roadmap 2025", Content: "r"}, 1)
+	require.NoError(t, err)
+	// A new page takes the old title; the existing link stays on the
+	// renamed page.
+	_, err = s.CreatePage(PageInput{Title: "Roadmap", Content: "impostor"}, 1)
+	require.NoError(t, err)
+	_, err = s.UpdatePage(src.ID, PageInput{Title: "Weekly", Content: "Read [[Roadmap]] again"}, 1)
+	require.NoError(t, err)
+
+	back, err = s.GetBacklinks(target.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Weekly"}, titles(back))
+
+	// Someone else's personal pages are invisible.
+	_, err = s.GetBacklinks(target.ID, 2)
+	assert.ErrorIs(t, err, ErrPageNotFound)
+}
+
+func TestMentionsNotifyOnce(t *testing.T) {
+	ws := uint(7)
+	access := stubWorkspaces{{ws, 1}: workspaces.RoleEditor, {ws, 2}: workspaces.RoleEditor}
+	notifier := &recordingNotifier{}
+	repo := newMemRepo()
+	s := NewService(repo, WithWorkspaceAccess(access), WithNotifier(notifier))
+
+	page, err := s.CreatePage(PageInput{
+		Title:       "Plan",
+		Content:     "@[Bo](user:2) and @[Outsider](user:3) please review",
+		WorkspaceID: &ws,
+	}, 1)
+	require.NoError(t, err)
+	assert.Empty(t, notifier.sent, "notifications wait for the relay")
+	repo.relay(t, s)
+	require.Len(t, notifier.sent, 1, "users who cannot read the page are not mentioned")
+	assert.Equal(t, uint(2), notifier.sent[0].UserID)
+	assert.Equal(t, notifications.TypeMention, notifier.sent[0].Type)
+	assert.Len(t, repo.links[page.ID], 1)
+	require.NoError(t, s.HandlePageEvent(context.Background(), 1, repo.events[0]))
+	assert.Len(t, notifier.sent, 1, "a redelivered event notifies nobody twice")
+
+	_, err = s.UpdatePage(page.ID, PageInput{Title: "Plan", Content: "@[Bo](user:2) please review today"}, 1)
+	require.NoError(t, err)
+	repo.relay(t, s)
+	assert.Len(t, notifier.sent, 1, "existing mentions are not notified again")
+
+	blk, err := s.InsertBlock(page.ID, BlockInput{Type: BlockParagraph, Text: "cc @[Al](user:1)"}, 2)
+	require.NoError(t, err)
+	repo.relay(t, s)
+	require.Len(t, notifier.sent, 2)
+	assert.Equal(t, uint(1), notifier.sent[1].UserID)
+
+	require.NoError(t, s.DeleteBlock(page.ID, blk.ID, 1))
+	assert.Len(t, repo.links[page.ID], 1)
+}
+
+func TestMentionsResolvePlainHandles(t *testing.T) {
+	ws := uint(7)
+	access := stubWorkspaces{{ws, 1}: workspaces.RoleEditor, {ws, 2}: workspaces.RoleViewer, {ws, 4}: workspaces.RoleViewer}
+	directory := stubUsers{
+		{ID: 2, Email: "bo@example.com"},
+		{ID: 3, Email: "bo@elsewhere.test"},
+		{ID: 4, Email: "cy@example.com"},
+		{ID: 5, Email: "cy@elsewhere.test"},
+		{ID: 6, Email: "dee@example.com"},
+	}
+	notifier := &recordingNotifier{}
+	repo := newMemRepo()
+	s := NewService(repo, WithWorkspaceAccess(access), WithNotifier(notifier), WithUserDirectory(directory))
+
+	page, err := s.CreatePage(PageInput{
+		Title:       "Plan",
+		Content:     "@bo and @CY@example.com, also @[Bo](user:2) and @dee",
+		WorkspaceID: &ws,
+	}, 1)
+	require.NoError(t, err)
+
+	var got []uint
+	for _, l := range repo.links[page.ID] {
+		got = append(got, *l.TargetUserID)
+	}
+	// bo@elsewhere.test cannot read the page, so @bo is unambiguous; dee
+	// is not a member.
+	assert.ElementsMatch(t, []uint{2, 4}, got)
+	repo.relay(t, s)
+	assert.Len(t, notifier.sent, 2)
+}
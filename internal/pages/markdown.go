@@ -0,0 +1,276 @@
+package pages
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The Markdown codec keeps Page.Content and the block list interchangeable.
+// It understands the subset of Markdown that maps onto block types; anything
+// else is kept verbatim as paragraph text.
+
+var (
+	headingRe  = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	todoRe     = regexp.MustCompile(`^(\s*)[-*+]\s+\[([ xX])\]\s?(.*)$`)
+	bulletRe   = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	orderedRe  = regexp.MustCompile(`^(\s*)\d+[.)]\s+(.*)$`)
+	imageRe    = regexp.MustCompile(`^!\[([^\]]*)\]\(([^)\s]+)\)$`)
+	embedRe    = regexp.MustCompile(`^<(https?://[^>\s]+)>$`)
+	dividerRe  = regexp.MustCompile(`^(?:-{3,}|\*{3,}|_{3,})$`)
+	fenceRe    = regexp.MustCompile("^(```+|~~~+)\\s*([\\w+-]*)\\s*$")
+	quoteLnRe  = regexp.MustCompile(`^>\s?(.*)$`)
+	indentUnit = 2
+)
+
+// ParseMarkdown splits Markdown source into blocks in document order.
+// Returned blocks have no ID or PageID yet.
+func ParseMarkdown(src string) []Block {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	var blocks []Block
+	var para []string
+
+	flush := func() {
+		if len(para) > 0 {
+			blocks = append(blocks, Block{Type: BlockParagraph, Text: strings.Join(para, "\n")})
+			para = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed == "" {
+			flush()
+			continue
+		}
+
+		if m := fenceRe.FindStringSubmatch(trimmed); m != nil {
+			flush()
+			var body []string
+			for i++; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), m[1]) {
+					break
+				}
+				body = append(body, lines[i])
+			}
+			blocks = append(blocks, Block{
+				Type:  BlockCode,
+				Text:  strings.Join(body, "\n"),
+				Props: BlockProps{Language: m[2]},
+			})
+			continue
+		}
+
+		if m := headingRe.FindStringSubmatch(trimmed); m != nil {
+			flush()
+			blocks = append(blocks, Block{
+				Type:  BlockHeading,
+				Text:  strings.TrimSpace(strings.TrimRight(m[2], "#")),
+				Props: BlockProps{Level: len(m[1])},
+			})
+			continue
+		}
+
+		if dividerRe.MatchString(trimmed) {
+			flush()
+			blocks = append(blocks, Block{Type: BlockDivider})
+			continue
+		}
+
+		if m := todoRe.FindStringSubmatch(line); m != nil {
+			flush()
+			blocks = append(blocks, Block{
+				Type:  BlockTodo,
+				Text:  m[3],
+				Props: BlockProps{Checked: m[2] != " ", Indent: indentLevel(m[1])},
+			})
+			continue
+		}
+
+		if m := bulletRe.FindStringSubmatch(line); m != nil {
+			flush()
+			blocks = append(blocks, Block{
+				Type:  BlockListItem,
+				Text:  m[2],
+				Props: BlockProps{Indent: indentLevel(m[1])},
+			})
+			continue
+		}
+
+		if m := orderedRe.FindStringSubmatch(line); m != nil {
+			flush()
+			blocks = append(blocks, Block{
+				Type:  BlockListItem,
+				Text:  m[2],
+				Props: BlockProps{Ordered: true, Indent: indentLevel(m[1])},
+			})
+			continue
+		}
+
+		if quoteLnRe.MatchString(trimmed) {
+			flush()
+			var body []string
+			for ; i < len(lines); i++ {
+				m := quoteLnRe.FindStringSubmatch(strings.TrimSpace(lines[i]))
+				if m == nil {
+					i--
+					break
+				}
+				body = append(body, m[1])
+			}
+			blocks = append(blocks, Block{Type: BlockQuote, Text: strings.Join(body, "\n")})
+			continue
+		}
+
+		if m := imageRe.FindStringSubmatch(trimmed); m != nil && len(para) == 0 {
+			blocks = append(blocks, Block{Type: BlockImage, Text: m[1], Props: BlockProps{URL: m[2]}})
+			continue
+		}
+
+		if m := embedRe.FindStringSubmatch(trimmed); m != nil && len(para) == 0 {
+			blocks = append(blocks, Block{Type: BlockEmbed, Props: BlockProps{URL: m[1]}})
+			continue
+		}
+
+		para = append(para, line)
+	}
+	flush()
+
+	for i := range blocks {
+		blocks[i].Position = i
+	}
+	return blocks
+}
+
+func indentLevel(ws string) int {
+	ws = strings.ReplaceAll(ws, "\t", strings.Repeat(" ", indentUnit))
+	return len(ws) / indentUnit
+}
+
+// RenderMarkdown serializes blocks back to Markdown. Consecutive list and
+// todo items are kept tight; other blocks are separated by a blank line.
+func RenderMarkdown(blocks []Block) string {
+	var b strings.Builder
+	ordinals := map[int]int{} // running number per indent level for ordered lists
+
+	for i, blk := range blocks {
+		if i > 0 {
+			if isListBlock(blocks[i-1]) && isListBlock(blk) {
+				b.WriteString("\n")
+			} else {
+				b.WriteString("\n\n")
+			}
+		}
+		if !isListBlock(blk) {
+			ordinals = map[int]int{}
+		}
+
+		indent := strings.Repeat(" ", blk.Props.Indent*indentUnit)
+		switch blk.Type {
+		case BlockHeading:
+			level := blk.Props.Level
+			if level < 1 || level > 6 {
+				level = 1
+			}
+			b.WriteString(strings.Repeat("#", level) + " " + blk.Text)
+		case BlockListItem:
+			if blk.Props.Ordered {
+				ordinals[blk.Props.Indent]++
+				fmt.Fprintf(&b, "%s%d. %s", indent, ordinals[blk.Props.Indent], blk.Text)
+			} else {
+				delete(ordinals, blk.Props.Indent)
+				b.WriteString(indent + "- " + blk.Text)
+			}
+		case BlockTodo:
+			mark := " "
+			if blk.Props.Checked {
+				mark = "x"
+			}
+			b.WriteString(indent + "- [" + mark + "] " + blk.Text)
+		case BlockCode:
+			b.WriteString("```" + blk.Props.Language + "\n" + blk.Text + "\n```")
+		case BlockQuote:
+			lines := strings.Split(blk.Text, "\n")
+			for j, l := range lines {
+				lines[j] = strings.TrimRight("> "+l, " ")
+			}
+			b.WriteString(strings.Join(lines, "\n"))
+		case BlockImage:
+			b.WriteString("![" + blk.Text + "](" + blk.Props.URL + ")")
+		case BlockDivider:
+			b.WriteString("---")
+		case BlockEmbed:
+			b.WriteString("<" + blk.Props.URL + ">")
+		default:
+			b.WriteString(blk.Text)
+		}
+	}
+	return b.String()
+}
+
+func isListBlock(b Block) bool {
+	return b.Type == BlockListItem || b.Type == BlockTodo
+}
+
+// reconcileBlockIDs gives freshly parsed blocks the IDs of the blocks they
+// replace, so rewriting Content does not churn IDs of unchanged blocks.
+// Exact matches win; otherwise a block of the same type at the same
+// position keeps its ID. Everything else gets a new ID.
+func reconcileBlockIDs(old, fresh []Block) {
+	used := map[string]bool{}
+	byContent := map[string][]string{}
+	for _, b := range old {
+		key := string(b.Type) + "\x00" + b.Text
+		byContent[key] = append(byContent[key], b.ID)
+	}
+
+	for i := range fresh {
+		key := string(fresh[i].Type) + "\x00" + fresh[i].Text
+		for len(byContent[key]) > 0 {
+			id := byContent[key][0]
+			byContent[key] = byContent[key][1:]
+			if !used[id] {
+				fresh[i].ID = id
+				used[id] = true
+				break
+			}
+		}
+	}
+	for i := range fresh {
+		if fresh[i].ID != "" {
+			continue
+		}
+		if i < len(old) && old[i].Type == fresh[i].Type && !used[old[i].ID] {
+			fresh[i].ID = old[i].ID
+			used[old[i].ID] = true
+			continue
+		}
+		fresh[i].ID = newBlockID()
+	}
+}
+
+// legacyBlocks parses the Content of a page written before blocks existed.
+// Its blocks are not stored, so their IDs are derived from the page and
+// position: every load hands out the same IDs and anchors to them hold.
+func legacyBlocks(page *Page) []Block {
+	blocks := ParseMarkdown(page.Content)
+	for i := range blocks {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%d", page.ID, i)))
+		blocks[i].ID = hex.EncodeToString(sum[:8])
+		blocks[i].PageID = page.ID
+	}
+	return blocks
+}
+
+func newBlockID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err) // crypto/rand never fails on supported platforms
+	}
+	return hex.EncodeToString(buf)
+}
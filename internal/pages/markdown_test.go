@@ -0,0 +1,62 @@
+package pages
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const sampleMarkdown = "# Weekly sync\n\n" +
+	"Agenda for today.\nSecond line.\n\n" +
+	"- first\n  - nested\n1. one\n2. two\n- [ ] open task\n- [x] done task\n\n" +
+	"```go\nfmt.Println(\"hi\")\n```\n\n" +
+	"> quoted\n> text\n\n" +
+	"![diagram](https://example.com/d.png)\n\n" +
+	"---\n\n" +
+	"<https://example.com/video>"
+
+func TestParseMarkdown_BlockTypes(t *testing.T) {
+	blocks := ParseMarkdown(sampleMarkdown)
+
+	types := make([]BlockType, len(blocks))
+	for i, b := range blocks {
+		types[i] = b.Type
+		assert.Equal(t, i, b.Position)
+	}
+	assert.Equal(t, []BlockType{
+		BlockHeading, BlockParagraph,
+		BlockListItem, BlockListItem, BlockListItem, BlockListItem, BlockTodo, BlockTodo,
+		BlockCode, BlockQuote, BlockImage, BlockDivider, BlockEmbed,
+	}, types)
+
+	assert.Equal(t, 1, blocks[0].Props.Level)
+	assert.Equal(t, "Agenda for today.\nSecond line.", blocks[1].Text)
+	assert.Equal(t, 1, blocks[3].Props.Indent)
+	assert.True(t, blocks[4].Props.Ordered)
+	assert.False(t, blocks[6].Props.Checked)
+	assert.True(t, blocks[7].Props.Checked)
+	assert.Equal(t, "go", blocks[8].Props.Language)
+	assert.Equal(t, "quoted\ntext", blocks[9].Text)
+	assert.Equal(t, "https://example.com/d.png", blocks[10].Props.URL)
+	assert.Equal(t, "https://example.com/video", blocks[12].Props.URL)
+}
+
+func TestRenderMarkdown_RoundTrip(t *testing.T) {
+	rendered := RenderMarkdown(ParseMarkdown(sampleMarkdown))
+	assert.Equal(t, sampleMarkdown, rendered)
+	assert.Equal(t, rendered, RenderMarkdown(ParseMarkdown(rendered)))
+}
+
+func TestReconcileBlockIDs_KeepsIDsOfUnchangedBlocks(t *testing.T) {
+	old := ParseMarkdown("# Title\n\nfirst\n\nsecond")
+	reconcileBlockIDs(nil, old)
+
+	fresh := ParseMarkdown("# Title\n\nnew paragraph\n\nfirst\n\nsecond")
+	reconcileBlockIDs(old, fresh)
+
+	assert.Equal(t, old[0].ID, fresh[0].ID)
+	assert.Equal(t, old[1].ID, fresh[2].ID)
+	assert.Equal(t, old[2].ID, fresh[3].ID)
+	assert.NotEmpty(t, fresh[1].ID)
+	assert.NotContains(t, []string{old[0].ID, old[1].ID, old[2].ID}, fresh[1].ID)
+}
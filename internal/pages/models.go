@@ -2,17 +2,216 @@ package pages
 
 import "time"
 
+// Page is personal to UserID unless WorkspaceID is set, in which case every
+// member of that workspace can reach it. Rank orders siblings (see
+// pkg/rank); pages that predate ranks have an empty one until rebalanced.
 type Page struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Title     string    `gorm:"not null" json:"title"`
-	Content   string    `gorm:"type:text" json:"content"`
-	UserID    uint      `gorm:"not null" json:"userId"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Title       string    `gorm:"not null" json:"title"`
+	Content     string    `gorm:"type:text" json:"content"`
+	UserID      uint      `gorm:"not null" json:"userId"`
+	WorkspaceID *uint     `gorm:"index" json:"workspaceId,omitempty"`
+	ParentID    *uint     `gorm:"index" json:"parentId"`
+	Rank        string    `gorm:"not null;default:''" json:"rank"`
+	Version     int       `gorm:"not null;default:1" json:"version"`
+	Blocks      []Block   `gorm:"foreignKey:PageID" json:"blocks,omitempty"`
+	Tags        []Tag     `gorm:"many2many:page_tags" json:"tags,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
 }
 
-// PageInput for creating or updating a page
+// PageInput for creating or updating a page. WorkspaceID only applies to
+// new top-level pages; child pages inherit their parent's workspace. An
+// update with Version set fails with ErrVersionConflict unless the page is
+// still at that version.
 type PageInput struct {
-	Title   string `json:"title" binding:"required"`
-	Content string `json:"content" binding:"required"`
+	Title       string `json:"title" binding:"required"`
+	Content     string `json:"content" binding:"required"`
+	ParentID    *uint  `json:"parentId"`
+	WorkspaceID *uint  `json:"workspaceId"`
+	Version     *int   `json:"version"`
+}
+
+// PageTreeInput describes a page to create together with its sub-pages.
+// Children are created under the new page; their own ParentID and
+// WorkspaceID are ignored.
+type PageTreeInput struct {
+	PageInput
+	Children []PageTreeInput
+}
+
+// PageFilter narrows page listings. TagMatch is "any" (default) or "all".
+// With WorkspaceID set the listing covers that workspace instead of the
+// user's personal pages.
+type PageFilter struct {
+	TagIDs      []uint
+	TagMatch    string
+	WorkspaceID *uint
+}
+
+// DuplicateInput controls how much of a page is copied. The copy is placed
+// right after the original.
+type DuplicateInput struct {
+	Title           string `json:"title"`
+	IncludeChildren bool   `json:"includeChildren"`
+	IncludeTags     bool   `json:"includeTags"`
+}
+
+// MoveInput describes a page's new location. With a parent the workspace
+// follows the parent. Without one the page becomes top-level in
+// WorkspaceID, in the user's personal space when Personal is set, or else
+// in the workspace it is already in. Position is the index among the new
+// siblings; nil appends.
+type MoveInput struct {
+	ParentID    *uint `json:"parentId"`
+	WorkspaceID *uint `json:"workspaceId"`
+	Personal    bool  `json:"personal"`
+	Position    *int  `json:"position" binding:"omitempty,min=0"`
+}
+
+// ReorderInput places a page right after AfterID among its siblings; nil
+// moves it first.
+type ReorderInput struct {
+	AfterID *uint `json:"afterId"`
+}
+
+type LinkKind string
+
+const (
+	LinkPage    LinkKind = "page"
+	LinkMention LinkKind = "mention"
+)
+
+// PageLink records a reference found in a page's content: a [[Title]] link
+// to another page, or a mention written as @[Name](user:ID) or as a plain
+// @handle or @email that resolves to a single reader of the page. Links
+// point at IDs, so renaming the target does not break them; Label is the
+// text as written, which keeps a link attached to the same page when its
+// source is re-saved.
+type PageLink struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	SourceID     uint      `gorm:"not null;index" json:"sourceId"`
+	Kind         LinkKind  `gorm:"size:16;not null" json:"kind"`
+	TargetPageID *uint     `gorm:"index" json:"targetPageId,omitempty"`
+	TargetUserID *uint     `gorm:"index" json:"targetUserId,omitempty"`
+	Label        string    `gorm:"size:255" json:"label"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// Tag labels pages. Tags without a WorkspaceID are private to UserID;
+// workspace tags are shared by all members of that workspace. Names are
+// unique within a scope regardless of case, which two partial indexes
+// enforce, one per kind of scope.
+type Tag struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"not null;index;uniqueIndex:idx_tags_personal_name,priority:1,where:workspace_id IS NULL" json:"userId"`
+	WorkspaceID *uint     `gorm:"index;uniqueIndex:idx_tags_workspace_name,priority:1,where:workspace_id IS NOT NULL" json:"workspaceId,omitempty"`
+	Name        string    `gorm:"size:64;not null;uniqueIndex:idx_tags_personal_name,priority:2,expression:lower(name);uniqueIndex:idx_tags_workspace_name,priority:2,expression:lower(name)" json:"name"`
+	Color       string    `gorm:"size:16" json:"color"`
+	PageCount   int64     `gorm:"->;-:migration" json:"pageCount"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// PageTag is the join table between pages and tags.
+type PageTag struct {
+	PageID uint `gorm:"primaryKey"`
+	TagID  uint `gorm:"primaryKey;index"`
+}
+
+func (PageTag) TableName() string {
+	return "page_tags"
+}
+
+// TagInput for creating a tag
+type TagInput struct {
+	Name        string `json:"name" binding:"required,max=64"`
+	Color       string `json:"color" binding:"omitempty,hexcolor"`
+	WorkspaceID *uint  `json:"workspaceId"`
+}
+
+// TagUpdateInput for renaming or recoloring a tag
+type TagUpdateInput struct {
+	Name  *string `json:"name" binding:"omitempty,min=1,max=64"`
+	Color *string `json:"color" binding:"omitempty,hexcolor"`
+}
+
+// TagMergeInput merges the tag in the path into TargetID
+type TagMergeInput struct {
+	TargetID uint `json:"targetId" binding:"required"`
+}
+
+// PageTagsInput attaches tags by ID and/or by name. Unknown names create
+// new personal tags.
+type PageTagsInput struct {
+	TagIDs []uint   `json:"tagIds"`
+	Names  []string `json:"names"`
+}
+
+type BlockType string
+
+const (
+	BlockParagraph BlockType = "paragraph"
+	BlockHeading   BlockType = "heading"
+	BlockListItem  BlockType = "list_item"
+	BlockTodo      BlockType = "todo"
+	BlockCode      BlockType = "code"
+	BlockQuote     BlockType = "quote"
+	BlockImage     BlockType = "image"
+	BlockDivider   BlockType = "divider"
+	BlockEmbed     BlockType = "embed"
+)
+
+func (t BlockType) Valid() bool {
+	switch t {
+	case BlockParagraph, BlockHeading, BlockListItem, BlockTodo, BlockCode,
+		BlockQuote, BlockImage, BlockDivider, BlockEmbed:
+		return true
+	}
+	return false
+}
+
+// BlockProps holds the type-specific attributes of a block. Unused fields
+// are left zero.
+type BlockProps struct {
+	Level    int    `json:"level,omitempty"`    // heading: 1-6
+	Ordered  bool   `json:"ordered,omitempty"`  // list_item
+	Indent   int    `json:"indent,omitempty"`   // list_item, todo nesting depth
+	Checked  bool   `json:"checked,omitempty"`  // todo
+	Language string `json:"language,omitempty"` // code
+	URL      string `json:"url,omitempty"`      // image, embed
+}
+
+// Block is one structural element of a page. IDs are generated once and
+// survive edits and moves; Position orders blocks within the page.
+type Block struct {
+	ID        string     `gorm:"primaryKey;size:32" json:"id"`
+	PageID    uint       `gorm:"not null;index" json:"pageId"`
+	Type      BlockType  `gorm:"size:20;not null" json:"type"`
+	Text      string     `gorm:"type:text" json:"text"`
+	Props     BlockProps `gorm:"serializer:json;type:jsonb" json:"props"`
+	Position  int        `gorm:"not null" json:"position"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// BlockInput for inserting a block. A nil AfterID appends the block at the
+// end of the page; an empty AfterID inserts it first.
+type BlockInput struct {
+	Type    BlockType  `json:"type" binding:"required"`
+	Text    string     `json:"text"`
+	Props   BlockProps `json:"props"`
+	AfterID *string    `json:"afterId"`
+}
+
+// BlockUpdateInput for editing a block; omitted fields are left unchanged.
+type BlockUpdateInput struct {
+	Type  *BlockType  `json:"type"`
+	Text  *string     `json:"text"`
+	Props *BlockProps `json:"props"`
+}
+
+// BlockMoveInput places a block after AfterID, or first when it is empty.
+type BlockMoveInput struct {
+	AfterID string `json:"afterId"`
 }
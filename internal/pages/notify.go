@@ -0,0 +1,50 @@
+package pages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"flowboard-backend-go/internal/notifications"
+)
+
+// HandlePageEvent notifies the users a change mentions for the first time
+// and, when it shares the page with a workspace, the workspace's other
+// members. Notifications are keyed by event, so a redelivered event
+// notifies nobody twice.
+func (s *service) HandlePageEvent(ctx context.Context, eventID uint64, e PageEvent) error {
+	if s.notifier == nil {
+		return nil
+	}
+	var errs []error
+	for _, id := range e.Mentioned {
+		errs = append(errs, s.notify(ctx, eventID, e, id, notifications.TypeMention,
+			fmt.Sprintf("You were mentioned in %s", e.Title)))
+	}
+	if e.Shared && e.WorkspaceID != nil && s.workspaces != nil {
+		members, err := s.workspaces.MemberIDs(*e.WorkspaceID)
+		if err != nil {
+			return err
+		}
+		for _, id := range members {
+			if id == e.ActorID {
+				continue
+			}
+			errs = append(errs, s.notify(ctx, eventID, e, id, notifications.TypeShare,
+				fmt.Sprintf("%s was shared with your workspace", e.Title)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *service) notify(ctx context.Context, eventID uint64, e PageEvent, userID uint, t notifications.Type, title string) error {
+	key := fmt.Sprintf("page-event:%d:%s:%d", eventID, t, userID)
+	return s.notifier.Notify(ctx, &notifications.Notification{
+		UserID:   userID,
+		Type:     t,
+		ActorID:  &e.ActorID,
+		Title:    title,
+		Link:     fmt.Sprintf("/pages/%d", e.PageID),
+		DedupKey: &key,
+	})
+}
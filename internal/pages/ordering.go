@@ -0,0 +1,181 @@
+package pages
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"flowboard-backend-go/pkg/logger"
+	"flowboard-backend-go/pkg/rank"
+)
+
+// maxRankLength is the key length past which a sibling set is rebalanced.
+const maxRankLength = 24
+
+// rebalanceBatch caps how many long keys one rebalancer pass looks at.
+const rebalanceBatch = 500
+
+// ReorderPage places a page right after input.AfterID among its siblings.
+// Only the moved page's rank is written.
+func (s *service) ReorderPage(id uint, input ReorderInput, userID uint) (*Page, error) {
+	var page *Page
+	err := s.inTx(func(tx *service) error {
+		p, err := tx.editablePage(id, userID)
+		if err != nil {
+			return err
+		}
+		siblings, err := tx.repo.GetSiblingPages(p.ParentID, p.WorkspaceID, p.UserID)
+		if err != nil {
+			return err
+		}
+		siblings = pagesExcept(siblings, p.ID)
+
+		at := 0
+		if input.AfterID != nil {
+			if at = indexOfPage(siblings, *input.AfterID); at < 0 {
+				return ErrPageNotFound
+			}
+			at++
+		}
+		if p.Rank, err = tx.rankAt(siblings, at); err != nil {
+			return err
+		}
+		if err := tx.repo.SetPageRanks(map[uint]string{p.ID: p.Rank}); err != nil {
+			return err
+		}
+		page = p
+		return tx.record(PageUpdated, p, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// RebalanceRanks respreads every sibling set holding a key that is empty
+// or longer than maxRankLength, and returns how many sets it rewrote. Each
+// set is locked and rewritten in its own transaction, just like a reorder,
+// and skipped if it no longer needs it, so replicas ticking at once wait
+// for each other instead of rewriting a set twice.
+func (s *service) RebalanceRanks() (int, error) {
+	candidates, err := s.repo.GetPagesToRebalance(maxRankLength, rebalanceBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	done := map[string]bool{}
+	for _, p := range candidates {
+		set := siblingSet(&p)
+		if done[set] {
+			continue
+		}
+		done[set] = true
+
+		err := s.inTx(func(tx *service) error {
+			siblings, err := tx.repo.GetSiblingPages(p.ParentID, p.WorkspaceID, p.UserID)
+			if err != nil || !needsRebalance(siblings) {
+				return err
+			}
+			n++
+			return tx.respread(siblings)
+		})
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// needsRebalance reports whether any of siblings has an empty or overlong
+// rank.
+func needsRebalance(siblings []Page) bool {
+	for i := range siblings {
+		if siblings[i].Rank == "" || len(siblings[i].Rank) > maxRankLength {
+			return true
+		}
+	}
+	return false
+}
+
+// RunRankRebalancer calls RebalanceRanks every interval until ctx ends.
+func RunRankRebalancer(ctx context.Context, s Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.RebalanceRanks()
+			if err != nil {
+				logger.Log.Errorw("Rebalancing page ranks failed", "error", err)
+			} else if n > 0 {
+				logger.Log.Infow("Rebalanced page ranks", "sets", n)
+			}
+		}
+	}
+}
+
+// rankAt returns a key that sorts at index at of siblings. When the
+// neighbouring keys leave no room, or predate ranks, the other siblings are
+// given fresh keys as well.
+func (s *service) rankAt(siblings []Page, at int) (string, error) {
+	keys := make([]string, len(siblings))
+	for i := range siblings {
+		keys[i] = siblings[i].Rank
+	}
+	key, respread := rank.Insert(keys, at)
+	if respread == nil {
+		return key, nil
+	}
+	ranks := make(map[uint]string, len(siblings))
+	for i := range siblings {
+		siblings[i].Rank = respread[i]
+		ranks[siblings[i].ID] = respread[i]
+	}
+	return key, s.repo.SetPageRanks(ranks)
+}
+
+// respread assigns fresh, evenly spaced ranks to siblings in their current
+// order.
+func (s *service) respread(siblings []Page) error {
+	keys := rank.Spread(len(siblings))
+	ranks := make(map[uint]string, len(siblings))
+	for i := range siblings {
+		siblings[i].Rank = keys[i]
+		ranks[siblings[i].ID] = keys[i]
+	}
+	return s.repo.SetPageRanks(ranks)
+}
+
+// siblingSet identifies the ordering scope a page belongs to.
+func siblingSet(p *Page) string {
+	switch {
+	case p.ParentID != nil:
+		return fmt.Sprintf("p%d", *p.ParentID)
+	case p.WorkspaceID != nil:
+		return fmt.Sprintf("w%d", *p.WorkspaceID)
+	default:
+		return fmt.Sprintf("u%d", p.UserID)
+	}
+}
+
+func indexOfPage(pages []Page, id uint) int {
+	for i := range pages {
+		if pages[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func pagesExcept(pages []Page, id uint) []Page {
+	out := make([]Page, 0, len(pages))
+	for _, p := range pages {
+		if p.ID != id {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package pages
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReorderPage_WritesOnlyTheMovedRank(t *testing.T) {
+	repo := newMemRepo()
+	s := NewService(repo)
+
+	a, _ := s.CreatePage(PageInput{Title: "A", Content: "a"}, 1)
+	b, _ := s.CreatePage(PageInput{Title: "B", Content: "b"}, 1)
+	c, _ := s.CreatePage(PageInput{Title: "C", Content: "c"}, 1)
+	top, _ := repo.GetSiblingPages(nil, nil, 1)
+	require.Equal(t, []string{"A", "B", "C"}, titles(top))
+
+	_, err := s.ReorderPage(c.ID, ReorderInput{AfterID: &a.ID}, 1)
+	require.NoError(t, err)
+	_, err = s.ReorderPage(b.ID, ReorderInput{}, 1)
+	require.NoError(t, err)
+
+	top, _ = repo.GetSiblingPages(nil, nil, 1)
+	assert.Equal(t, []string{"B", "A", "C"}, titles(top))
+	assert.Equal(t, a.Rank, repo.pages[a.ID].Rank)
+
+	_, err = s.ReorderPage(a.ID, ReorderInput{AfterID: &a.ID}, 1)
+	assert.Equal(t, ErrPageNotFound, err)
+}
+
+func TestReorderPage_RespreadsWhenKeysRunOut(t *testing.T) {
+	repo := newMemRepo()
+	s := NewService(repo)
+
+	first, _ := s.CreatePage(PageInput{Title: "first", Content: "x"}, 1)
+	last, _ := s.CreatePage(PageInput{Title: "last", Content: "x"}, 1)
+	// Pages from before ranks existed have none and sort by ID.
+	legacy, _ := s.CreatePage(PageInput{Title: "legacy", Content: "x"}, 1)
+	repo.pages[first.ID].Rank = ""
+	repo.pages[last.ID].Rank = ""
+	repo.pages[legacy.ID].Rank = ""
+
+	_, err := s.ReorderPage(legacy.ID, ReorderInput{AfterID: &first.ID}, 1)
+	require.NoError(t, err)
+	top, _ := repo.GetSiblingPages(nil, nil, 1)
+	assert.Equal(t, []string{"first", "legacy", "last"}, titles(top))
+	for _, p := range top {
+		assert.NotEmpty(t, p.Rank)
+	}
+}
+
+func TestRebalanceRanks_ShortensLongKeys(t *testing.T) {
+	repo := newMemRepo()
+	s := NewService(repo)
+
+	anchor, _ := s.CreatePage(PageInput{Title: "anchor", Content: "x"}, 1)
+	var want []string
+	for i := 0; i < 200; i++ {
+		p, err := s.CreatePage(PageInput{Title: fmt.Sprint("p", i), Content: "x"}, 1)
+		require.NoError(t, err)
+		// Always inserting right after the anchor grows keys quickly.
+		_, err = s.ReorderPage(p.ID, ReorderInput{AfterID: &anchor.ID}, 1)
+		require.NoError(t, err)
+		want = append([]string{p.Title}, want...)
+	}
+	want = append([]string{"anchor"}, want...)
+
+	before, _ := repo.GetPagesToRebalance(maxRankLength, 100)
+	require.NotEmpty(t, before)
+
+	n, err := s.RebalanceRanks()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	after, _ := repo.GetPagesToRebalance(maxRankLength, 100)
+	assert.Empty(t, after)
+	top, _ := repo.GetSiblingPages(nil, nil, 1)
+	assert.Equal(t, want, titles(top))
+}
+
+func TestRebalanceRanks_SkipsSetsRewrittenMeanwhile(t *testing.T) {
+	repo := newMemRepo()
+	s := NewService(repo)
+
+	anchor, _ := s.CreatePage(PageInput{Title: "anchor", Content: "x"}, 1)
+	for i := 0; i < 100; i++ {
+		p, err := s.CreatePage(PageInput{Title: fmt.Sprint("p", i), Content: "x"}, 1)
+		require.NoError(t, err)
+		_, err = s.ReorderPage(p.ID, ReorderInput{AfterID: &anchor.ID}, 1)
+		require.NoError(t, err)
+	}
+
+	// Another replica rewrites the set after this one listed it.
+	repo.beforeTx = func() {
+		repo.beforeTx = nil
+		n, err := s.RebalanceRanks()
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+	}
+	n, err := s.RebalanceRanks()
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
@@ -0,0 +1,174 @@
+package pages
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// RenderedPage is the sanitized HTML form of a page's content.
+type RenderedPage struct {
+	PageID  uint       `json:"pageId"`
+	Version int        `json:"version"`
+	HTML    string     `json:"html"`
+	TOC     []TOCEntry `json:"toc,omitempty"`
+}
+
+// TOCEntry is one heading, linking to the id it was given in HTML.
+type TOCEntry struct {
+	Level  int    `json:"level"`
+	Text   string `json:"text"`
+	Anchor string `json:"anchor"`
+}
+
+const renderCacheSize = 512
+
+// renderer turns Markdown into HTML and then runs it through an allowlist
+// sanitizer: raw HTML is passed through by goldmark and everything not
+// explicitly allowed (scripts, event handlers, javascript: URLs, ...) is
+// stripped afterwards. Results are cached per page version.
+type renderer struct {
+	md     goldmark.Markdown
+	policy *bluemonday.Policy
+	cache  *renderCache
+}
+
+func newRenderer() *renderer {
+	md := goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("id").Matching(regexp.MustCompile(`^[\w-]+$`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+-]+$`)).OnElements("code")
+	policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	policy.AllowAttrs("checked", "disabled").OnElements("input")
+	policy.RequireNoReferrerOnLinks(true)
+	policy.AddTargetBlankToFullyQualifiedLinks(true)
+
+	return &renderer{md: md, policy: policy, cache: newRenderCache(renderCacheSize)}
+}
+
+func (r *renderer) render(page *Page, withTOC bool) (*RenderedPage, error) {
+	key := fmt.Sprintf("%d:%d:%t", page.ID, page.Version, withTOC)
+	if cached, ok := r.cache.get(key); ok {
+		return cached, nil
+	}
+
+	src := []byte(page.Content)
+	doc := r.md.Parser().Parse(text.NewReader(src))
+
+	var buf bytes.Buffer
+	if err := r.md.Renderer().Render(&buf, src, doc); err != nil {
+		return nil, err
+	}
+
+	out := &RenderedPage{
+		PageID:  page.ID,
+		Version: page.Version,
+		HTML:    r.policy.Sanitize(buf.String()),
+	}
+	if withTOC {
+		out.TOC = collectTOC(doc, src)
+	}
+
+	r.cache.put(key, out)
+	return out, nil
+}
+
+func collectTOC(doc ast.Node, src []byte) []TOCEntry {
+	toc := []TOCEntry{}
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		h, ok := n.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+		anchor := ""
+		if id, ok := h.AttributeString("id"); ok {
+			if b, ok := id.([]byte); ok {
+				anchor = string(b)
+			}
+		}
+		toc = append(toc, TOCEntry{Level: h.Level, Text: nodeText(h, src), Anchor: anchor})
+		return ast.WalkSkipChildren, nil
+	})
+	return toc
+}
+
+// nodeText concatenates the plain text below n, ignoring markup.
+func nodeText(n ast.Node, src []byte) string {
+	var buf bytes.Buffer
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := c.(type) {
+		case *ast.Text:
+			buf.Write(t.Segment.Value(src))
+			if t.SoftLineBreak() {
+				buf.WriteByte(' ')
+			}
+		case *ast.String:
+			buf.Write(t.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return buf.String()
+}
+
+// renderCache is a small LRU of rendered pages. Keys include the page
+// version, so stale entries are never served and simply age out.
+type renderCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+type renderCacheEntry struct {
+	key  string
+	page *RenderedPage
+}
+
+func newRenderCache(size int) *renderCache {
+	return &renderCache{size: size, order: list.New(), items: map[string]*list.Element{}}
+}
+
+func (c *renderCache) get(key string) (*RenderedPage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*renderCacheEntry).page, true
+}
+
+func (c *renderCache) put(key string, page *RenderedPage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value.(*renderCacheEntry).page = page
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.order.PushFront(&renderCacheEntry{key: key, page: page})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*renderCacheEntry).key)
+	}
+}
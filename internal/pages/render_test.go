@@ -0,0 +1,74 @@
+package pages
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender_SanitizesDangerousMarkup(t *testing.T) {
+	r := newRenderer()
+	page := &Page{ID: 1, Version: 1, Content: "# Hello\n\n" +
+		"<script>alert('x')</script>\n\n" +
+		"<img src=\"x.png\" onerror=\"alert(1)\">\n\n" +
+		"[click](javascript:alert(1)) and [safe](https://example.com)\n\n" +
+		"<iframe src=\"https://evil.example\"></iframe>\n\n" +
+		"- [x] done\n\n" +
+		"```go\nx := 1\n```"}
+
+	out, err := r.render(page, false)
+	require.NoError(t, err)
+
+	assert.NotContains(t, out.HTML, "<script")
+	assert.NotContains(t, out.HTML, "onerror")
+	assert.NotContains(t, out.HTML, "javascript:")
+	assert.NotContains(t, out.HTML, "<iframe")
+	assert.Contains(t, out.HTML, `<h1 id="hello">Hello</h1>`)
+	assert.Contains(t, out.HTML, `href="https://example.com"`)
+	assert.Contains(t, out.HTML, `<input checked="" disabled="" type="checkbox"`)
+	assert.Contains(t, out.HTML, `<code class="language-go">`)
+	assert.Nil(t, out.TOC)
+}
+
+func TestRender_TableOfContents(t *testing.T) {
+	r := newRenderer()
+	page := &Page{ID: 2, Version: 1, Content: "# Intro\n\n## Goals *for* Q1\n\ntext\n\n## Goals *for* Q1"}
+
+	out, err := r.render(page, true)
+	require.NoError(t, err)
+	assert.Equal(t, []TOCEntry{
+		{Level: 1, Text: "Intro", Anchor: "intro"},
+		{Level: 2, Text: "Goals for Q1", Anchor: "goals-for-q1"},
+		{Level: 2, Text: "Goals for Q1", Anchor: "goals-for-q1-1"},
+	}, out.TOC)
+}
+
+func TestRender_CacheKeyedByVersion(t *testing.T) {
+	r := newRenderer()
+	page := &Page{ID: 3, Version: 1, Content: "first"}
+
+	first, err := r.render(page, false)
+	require.NoError(t, err)
+
+	page.Content = "second"
+	cached, _ := r.render(page, false)
+	assert.Same(t, first, cached)
+
+	page.Version = 2
+	fresh, _ := r.render(page, false)
+	assert.Contains(t, fresh.HTML, "second")
+}
+
+func TestRenderCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newRenderCache(2)
+	c.put("a", &RenderedPage{HTML: "a"})
+	c.put("b", &RenderedPage{HTML: "b"})
+	c.get("a")
+	c.put("c", &RenderedPage{HTML: "c"})
+
+	_, okA := c.get("a")
+	_, okB := c.get("b")
+	assert.True(t, okA)
+	assert.False(t, okB)
+}
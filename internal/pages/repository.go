@@ -1,18 +1,73 @@
 package pages
 
 import (
+	"errors"
+
+	"flowboard-backend-go/internal/outbox"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Repository interface {
 	CreatePage(page *Page) (*Page, error)
 	GetAllPages() ([]Page, error)
-	GetAllPagesByUser(userID uint) ([]Page, error)
+	GetAllPagesByUser(userID uint, filter PageFilter) ([]Page, error)
 	GetPageByID(id uint) (*Page, error)
+	// GetPagesByIDs loads the pages that exist among ids, without tags.
+	GetPagesByIDs(ids []uint) ([]Page, error)
 	UpdatePage(page *Page) error
 	DeletePage(id uint) error
+	GetBlocksByPage(pageID uint) ([]Block, error)
+
+	// Transaction runs fn with a Repository bound to a single database
+	// transaction; any error rolls everything back.
+	Transaction(fn func(repo Repository) error) error
+	// AddEvent records a page event in the outbox. Call it inside
+	// Transaction so the event commits together with the change.
+	AddEvent(e PageEvent) error
+	GetChildPages(parentID uint) ([]Page, error)
+	// GetSiblingPages lists the pages sharing a parent, or the top-level
+	// pages of a workspace or of a user's personal space, in display order.
+	// The rows are locked FOR UPDATE, so call it inside Transaction before
+	// writing ranks derived from them.
+	GetSiblingPages(parentID, workspaceID *uint, userID uint) ([]Page, error)
+	GetDescendantIDs(id uint) ([]uint, error)
+	SetPageRanks(ranks map[uint]string) error
+	// GetPagesToRebalance returns up to limit pages whose rank is empty or
+	// longer than maxLen.
+	GetPagesToRebalance(maxLen, limit int) ([]Page, error)
+	// ReassignPages moves pages to workspaceID and, when ownerID is set,
+	// hands them to that user.
+	ReassignPages(ids []uint, workspaceID, ownerID *uint) error
+
+	GetTags(userID uint, workspaceID *uint) ([]Tag, error)
+	GetTagByID(id uint) (*Tag, error)
+	GetTagByName(userID uint, workspaceID *uint, name string) (*Tag, error)
+	CreateTag(tag *Tag) error
+	UpdateTag(tag *Tag) error
+	DeleteTag(id uint) error
+	// MergeTags retags every page carrying source with target and deletes
+	// source, in one transaction.
+	MergeTags(sourceID, targetID uint) error
+	AttachTags(pageID uint, tagIDs []uint) error
+	DetachTag(pageID, tagID uint) error
+
+	GetLinks(sourceID uint) ([]PageLink, error)
+	// ReplaceLinks swaps a page's outgoing links for links, in one
+	// transaction.
+	ReplaceLinks(sourceID uint, links []PageLink) error
+	// GetBacklinks lists the distinct pages linking to targetID.
+	GetBacklinks(targetID uint) ([]Page, error)
+	// FindPagesByTitle matches titles case-insensitively among the pages of
+	// a workspace, or of a user's personal space when workspaceID is nil.
+	FindPagesByTitle(title string, workspaceID *uint, userID uint) ([]Page, error)
 }
 
+// rankOrder sorts by rank bytewise; rank keys rely on ASCII ordering, which
+// locale collations do not preserve.
+const rankOrder = `rank COLLATE "C", id`
+
 type repository struct {
 	db *gorm.DB
 }
@@ -21,6 +76,7 @@ func NewRepository(db *gorm.DB) Repository {
 	return &repository{db: db}
 }
 
+// CreatePage stores the page together with any blocks set on it.
 func (r *repository) CreatePage(page *Page) (*Page, error) {
 	if err := r.db.Create(page).Error; err != nil {
 		return nil, err
@@ -38,7 +94,7 @@ func (r *repository) GetAllPages() ([]Page, error) {
 
 func (r *repository) GetPageByID(id uint) (*Page, error) {
 	var page Page
-	if err := r.db.First(&page, id).Error; err != nil {
+	if err := r.db.Preload("Tags").First(&page, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
 		}
@@ -47,18 +103,358 @@ func (r *repository) GetPageByID(id uint) (*Page, error) {
 	return &page, nil
 }
 
-func (r *repository) GetAllPagesByUser(userID uint) ([]Page, error) {
+func (r *repository) GetPagesByIDs(ids []uint) ([]Page, error) {
+	var list []Page
+	if err := r.db.Where("id IN ?", ids).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *repository) GetAllPagesByUser(userID uint, filter PageFilter) ([]Page, error) {
 	var pages []Page
-	if err := r.db.Where("user_id = ?", userID).Find(&pages).Error; err != nil {
+	q := r.db.Preload("Tags")
+	if filter.WorkspaceID != nil {
+		q = q.Where("workspace_id = ?", *filter.WorkspaceID)
+	} else {
+		q = q.Where("user_id = ? AND workspace_id IS NULL", userID)
+	}
+	if len(filter.TagIDs) > 0 {
+		if filter.TagMatch == "all" {
+			q = q.Where("id IN (?)", r.db.Model(&PageTag{}).
+				Select("page_id").
+				Where("tag_id IN ?", filter.TagIDs).
+				Group("page_id").
+				Having("COUNT(DISTINCT tag_id) = ?", len(filter.TagIDs)))
+		} else {
+			q = q.Where("id IN (?)", r.db.Model(&PageTag{}).
+				Select("page_id").
+				Where("tag_id IN ?", filter.TagIDs))
+		}
+	}
+	if err := q.Order(rankOrder).Find(&pages).Error; err != nil {
 		return nil, err
 	}
 	return pages, nil
 }
 
+// UpdatePage saves the page and bumps its version. When page.Blocks is
+// non-nil it replaces the page's block list in the same transaction. It
+// returns ErrVersionConflict, changing nothing, if the stored version no
+// longer matches page.Version.
 func (r *repository) UpdatePage(page *Page) error {
-	return r.db.Save(page).Error
+	read := page.Version
+	page.Version++
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(page).Where("version = ?", read).Select("*").Omit("Blocks", "Tags").Updates(page)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		if page.Blocks == nil {
+			return nil
+		}
+		return replaceBlocks(tx, page.ID, page.Blocks)
+	})
+	if err != nil {
+		page.Version = read
+	}
+	return err
 }
 
+func replaceBlocks(tx *gorm.DB, pageID uint, blocks []Block) error {
+	ids := make([]string, 0, len(blocks))
+	for i := range blocks {
+		blocks[i].PageID = pageID
+		ids = append(ids, blocks[i].ID)
+	}
+
+	stale := tx.Where("page_id = ?", pageID)
+	if len(ids) > 0 {
+		stale = stale.Where("id NOT IN ?", ids)
+	}
+	if err := stale.Delete(&Block{}).Error; err != nil {
+		return err
+	}
+	if len(blocks) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"type", "text", "props", "position", "updated_at"}),
+	}).Create(&blocks).Error
+}
+
+// DeletePage removes the page and its blocks. Child pages move up to the
+// deleted page's parent.
 func (r *repository) DeletePage(id uint) error {
-	return r.db.Delete(&Page{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var page Page
+		if err := tx.Select("id", "parent_id").First(&page, id).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&Page{}).Where("parent_id = ?", id).Update("parent_id", page.ParentID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("page_id = ?", id).Delete(&Block{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("page_id = ?", id).Delete(&PageTag{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("source_id = ? OR target_page_id = ?", id, id).Delete(&PageLink{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Page{}, id).Error
+	})
+}
+
+func (r *repository) GetBlocksByPage(pageID uint) ([]Block, error) {
+	var blocks []Block
+	if err := r.db.Where("page_id = ?", pageID).Order("position").Find(&blocks).Error; err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+func (r *repository) Transaction(fn func(repo Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&repository{db: tx})
+	})
+}
+
+func (r *repository) AddEvent(e PageEvent) error {
+	return outbox.Append(r.db, string(e.Type), e)
+}
+
+func (r *repository) GetChildPages(parentID uint) ([]Page, error) {
+	var pages []Page
+	if err := r.db.Preload("Tags").Where("parent_id = ?", parentID).Order(rankOrder).Find(&pages).Error; err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+func (r *repository) GetSiblingPages(parentID, workspaceID *uint, userID uint) ([]Page, error) {
+	var pages []Page
+	q := r.db.Select("id", "parent_id", "workspace_id", "user_id", "rank")
+	switch {
+	case parentID != nil:
+		q = q.Where("parent_id = ?", *parentID)
+	case workspaceID != nil:
+		q = q.Where("parent_id IS NULL AND workspace_id = ?", *workspaceID)
+	default:
+		q = q.Where("parent_id IS NULL AND workspace_id IS NULL AND user_id = ?", userID)
+	}
+	if err := q.Order(rankOrder).Clauses(clause.Locking{Strength: "UPDATE"}).Find(&pages).Error; err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+func (r *repository) GetDescendantIDs(id uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Raw(
+		`WITH RECURSIVE subtree AS (
+			SELECT id FROM pages WHERE parent_id = ?
+			UNION ALL
+			SELECT p.id FROM pages p JOIN subtree s ON p.parent_id = s.id
+		)
+		SELECT id FROM subtree`,
+		id,
+	).Scan(&ids).Error
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *repository) SetPageRanks(ranks map[uint]string) error {
+	for id, key := range ranks {
+		if err := r.db.Model(&Page{}).Where("id = ?", id).UpdateColumn("rank", key).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *repository) GetPagesToRebalance(maxLen, limit int) ([]Page, error) {
+	var pages []Page
+	err := r.db.Select("id", "parent_id", "workspace_id", "user_id").
+		Where("rank = '' OR LENGTH(rank) > ?", maxLen).
+		Limit(limit).
+		Find(&pages).Error
+	if err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+func (r *repository) ReassignPages(ids []uint, workspaceID, ownerID *uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	updates := map[string]interface{}{"workspace_id": workspaceID}
+	if ownerID != nil {
+		updates["user_id"] = *ownerID
+	}
+	return r.db.Model(&Page{}).Where("id IN ?", ids).Updates(updates).Error
+}
+
+// tagScope restricts a tag query to a user's personal tags or to one
+// workspace's tags.
+func tagScope(db *gorm.DB, userID uint, workspaceID *uint) *gorm.DB {
+	if workspaceID != nil {
+		return db.Where("tags.workspace_id = ?", *workspaceID)
+	}
+	return db.Where("tags.user_id = ? AND tags.workspace_id IS NULL", userID)
+}
+
+func (r *repository) GetTags(userID uint, workspaceID *uint) ([]Tag, error) {
+	var tags []Tag
+	q := r.db.Model(&Tag{}).
+		Select("tags.*, (SELECT COUNT(*) FROM page_tags WHERE page_tags.tag_id = tags.id) AS page_count")
+	if err := tagScope(q, userID, workspaceID).Order("tags.name").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (r *repository) GetTagByID(id uint) (*Tag, error) {
+	var tag Tag
+	if err := r.db.First(&tag, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (r *repository) GetTagByName(userID uint, workspaceID *uint, name string) (*Tag, error) {
+	var tag Tag
+	err := tagScope(r.db, userID, workspaceID).Where("LOWER(tags.name) = LOWER(?)", name).First(&tag).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// CreateTag returns ErrTagExists when the name is taken in the tag's scope.
+// The insert runs in a savepoint, so a clash does not abort an enclosing
+// transaction.
+func (r *repository) CreateTag(tag *Tag) error {
+	return tagError(r.db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(tag).Error
+	}))
+}
+
+func (r *repository) UpdateTag(tag *Tag) error {
+	return tagError(r.db.Save(tag).Error)
+}
+
+// tagError reports a violation of the unique tag name indexes as
+// ErrTagExists.
+func tagError(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrTagExists
+	}
+	return err
+}
+
+func (r *repository) DeleteTag(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tag_id = ?", id).Delete(&PageTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Tag{}, id).Error
+	})
+}
+
+func (r *repository) MergeTags(sourceID, targetID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(
+			`INSERT INTO page_tags (page_id, tag_id)
+			 SELECT page_id, ? FROM page_tags WHERE tag_id = ?
+			 ON CONFLICT DO NOTHING`,
+			targetID, sourceID,
+		).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("tag_id = ?", sourceID).Delete(&PageTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Tag{}, sourceID).Error
+	})
+}
+
+func (r *repository) AttachTags(pageID uint, tagIDs []uint) error {
+	if len(tagIDs) == 0 {
+		return nil
+	}
+	rows := make([]PageTag, len(tagIDs))
+	for i, id := range tagIDs {
+		rows[i] = PageTag{PageID: pageID, TagID: id}
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+func (r *repository) DetachTag(pageID, tagID uint) error {
+	return r.db.Where("page_id = ? AND tag_id = ?", pageID, tagID).Delete(&PageTag{}).Error
+}
+
+func (r *repository) GetLinks(sourceID uint) ([]PageLink, error) {
+	var links []PageLink
+	if err := r.db.Where("source_id = ?", sourceID).Order("id").Find(&links).Error; err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (r *repository) ReplaceLinks(sourceID uint, links []PageLink) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("source_id = ?", sourceID).Delete(&PageLink{}).Error; err != nil {
+			return err
+		}
+		if len(links) == 0 {
+			return nil
+		}
+		for i := range links {
+			links[i].ID = 0
+			links[i].SourceID = sourceID
+		}
+		return tx.Create(&links).Error
+	})
+}
+
+func (r *repository) GetBacklinks(targetID uint) ([]Page, error) {
+	var pages []Page
+	err := r.db.
+		Where("id IN (?)", r.db.Model(&PageLink{}).Select("source_id").Where("target_page_id = ?", targetID)).
+		Order("title, id").
+		Find(&pages).Error
+	if err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+func (r *repository) FindPagesByTitle(title string, workspaceID *uint, userID uint) ([]Page, error) {
+	var pages []Page
+	q := r.db.Select("id", "title", "user_id", "workspace_id").Where("LOWER(title) = LOWER(?)", title)
+	if workspaceID != nil {
+		q = q.Where("workspace_id = ?", *workspaceID)
+	} else {
+		q = q.Where("workspace_id IS NULL AND user_id = ?", userID)
+	}
+	if err := q.Order("id").Find(&pages).Error; err != nil {
+		return nil, err
+	}
+	return pages, nil
 }
@@ -2,77 +2,516 @@ package pages
 
 import (
 	"errors"
+	"strings"
+
+	"flowboard-backend-go/internal/notifications"
+	"flowboard-backend-go/internal/users"
+	"flowboard-backend-go/internal/workspaces"
 )
 
 var (
-	ErrPageNotFound = errors.New("page not found")
+	ErrPageNotFound  = errors.New("page not found")
+	ErrBlockNotFound = errors.New("block not found")
+	ErrInvalidBlock  = errors.New("invalid block")
+	ErrTagNotFound   = errors.New("tag not found")
+	ErrTagExists     = errors.New("a tag with this name already exists")
+	ErrInvalidMerge  = errors.New("cannot merge a tag into itself")
+	ErrForbidden     = errors.New("insufficient permissions")
+	ErrInvalidMove   = errors.New("cannot move a page into itself or its descendants")
+	// ErrInvalidDestination means a move named more than one destination.
+	ErrInvalidDestination = errors.New("personal cannot be combined with parentId or workspaceId")
+	// ErrTagScope means a tag from another workspace, or a personal tag,
+	// was applied to a page outside its scope.
+	ErrTagScope = errors.New("tag does not belong to the page's workspace")
+	// ErrVersionConflict means the page changed since it was read.
+	ErrVersionConflict = errors.New("page was modified concurrently")
 )
 
+// maxConflictRetries bounds how often a read-modify-write of a page is
+// redone after losing a race with another writer.
+const maxConflictRetries = 3
+
+// WorkspaceAccess reports a user's role in a workspace ("" for
+// non-members) and lists its members; satisfied by workspaces.Service.
+type WorkspaceAccess interface {
+	MemberRole(workspaceID, userID uint) (workspaces.Role, error)
+	MemberIDs(workspaceID uint) ([]uint, error)
+}
+
+// UserDirectory resolves plain @handle mentions; satisfied by
+// users.Service.
+type UserDirectory interface {
+	FindByHandle(handle string) ([]users.User, error)
+}
+
 type Service interface {
+	// HandlePageEvent sends the notifications a page change calls for.
+	EventHandler
+
 	CreatePage(input PageInput, userID uint) (*Page, error)
-	GetAllPagesByUser(userID uint) ([]Page, error)
+	// CreatePageTree creates a page and all its sub-pages, or nothing if
+	// any of them fails. The pages are returned depth-first, root first.
+	CreatePageTree(input PageTreeInput, userID uint) ([]*Page, error)
+	GetAllPagesByUser(userID uint, filter PageFilter) ([]Page, error)
 	GetPageByID(id, userID uint) (*Page, error)
+	// GetPagesByIDs returns the pages among ids that the user can read, in
+	// no particular order; missing and inaccessible pages are left out.
+	GetPagesByIDs(ids []uint, userID uint) ([]Page, error)
 	UpdatePage(id uint, input PageInput, userID uint) (*Page, error)
 	DeletePage(id, userID uint) error
+	GetBacklinks(id, userID uint) ([]Page, error)
+
+	GetBlocks(pageID, userID uint) ([]Block, error)
+	InsertBlock(pageID uint, input BlockInput, userID uint) (*Block, error)
+	UpdateBlock(pageID uint, blockID string, input BlockUpdateInput, userID uint) (*Block, error)
+	MoveBlock(pageID uint, blockID string, input BlockMoveInput, userID uint) ([]Block, error)
+	DeleteBlock(pageID uint, blockID string, userID uint) error
+
+	ExportMarkdown(id, userID uint) (filename string, data []byte, err error)
+	ImportMarkdown(files []MarkdownFile, parentID *uint, userID uint) ([]ImportedPage, error)
+	RenderPage(id, userID uint, withTOC bool) (*RenderedPage, error)
+
+	DuplicatePage(id uint, input DuplicateInput, userID uint) (*Page, error)
+	MovePage(id uint, input MoveInput, userID uint) (*Page, error)
+	ReorderPage(id uint, input ReorderInput, userID uint) (*Page, error)
+	RebalanceRanks() (int, error)
+
+	GetTags(userID uint, workspaceID *uint) ([]Tag, error)
+	CreateTag(input TagInput, userID uint) (*Tag, error)
+	UpdateTag(id uint, input TagUpdateInput, userID uint) (*Tag, error)
+	MergeTags(sourceID, targetID, userID uint) (*Tag, error)
+	DeleteTag(id, userID uint) error
+	AttachTags(pageID uint, input PageTagsInput, userID uint) ([]Tag, error)
+	DetachTag(pageID, tagID, userID uint) error
 }
 
 type service struct {
-	repo Repository
+	repo       Repository
+	renderer   *renderer
+	workspaces WorkspaceAccess
+	notifier   notifications.Notifier
+	users      UserDirectory
 }
 
-func NewService(repo Repository) Service {
-	return &service{repo: repo}
+// Option configures optional collaborators of the page service.
+type Option func(*service)
+
+// WithWorkspaceAccess enables workspace-scoped features such as shared tags.
+func WithWorkspaceAccess(access WorkspaceAccess) Option {
+	return func(s *service) { s.workspaces = access }
+}
+
+// WithNotifier notifies users when a page first mentions them or is
+// shared with their workspace. Notifications are sent by HandlePageEvent
+// once the change's event is relayed from the outbox.
+func WithNotifier(n notifications.Notifier) Option {
+	return func(s *service) { s.notifier = n }
+}
+
+// WithUserDirectory resolves plain @handle and @email mentions in addition
+// to the @[Name](user:ID) form.
+func WithUserDirectory(dir UserDirectory) Option {
+	return func(s *service) { s.users = dir }
+}
+
+func NewService(repo Repository, opts ...Option) Service {
+	s := &service{repo: repo, renderer: newRenderer()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *service) CreatePage(input PageInput, userID uint) (*Page, error) {
+	workspaceID := input.WorkspaceID
+	if input.ParentID != nil {
+		parent, err := s.editablePage(*input.ParentID, userID)
+		if err != nil {
+			return nil, err
+		}
+		workspaceID = parent.WorkspaceID
+	} else if workspaceID != nil {
+		if err := s.requireWorkspaceRole(*workspaceID, userID, workspaces.RoleEditor); err != nil {
+			return nil, err
+		}
+	}
+
 	page := &Page{
-		Title:   input.Title,
-		Content: input.Content,
-		UserID:  userID,
+		Title:       input.Title,
+		Content:     input.Content,
+		UserID:      userID,
+		WorkspaceID: workspaceID,
+		ParentID:    input.ParentID,
+		Blocks:      ParseMarkdown(input.Content),
+	}
+	reconcileBlockIDs(nil, page.Blocks)
+
+	err := s.inTx(func(tx *service) error {
+		siblings, err := tx.repo.GetSiblingPages(input.ParentID, workspaceID, userID)
+		if err != nil {
+			return err
+		}
+		if page.Rank, err = tx.rankAt(siblings, len(siblings)); err != nil {
+			return err
+		}
+		if _, err := tx.repo.CreatePage(page); err != nil {
+			return err
+		}
+		e := tx.event(PageCreated, page, userID)
+		if e.Mentioned, err = tx.syncLinks(page, userID); err != nil {
+			return err
+		}
+		return tx.repo.AddEvent(e)
+	})
+	if err != nil {
+		return nil, err
 	}
-	return s.repo.CreatePage(page)
+	return page, nil
 }
 
-func (s *service) GetAllPagesByUser(userID uint) ([]Page, error) {
-	return s.repo.GetAllPagesByUser(userID)
+func (s *service) GetAllPagesByUser(userID uint, filter PageFilter) ([]Page, error) {
+	if filter.WorkspaceID != nil {
+		if err := s.requireWorkspaceRole(*filter.WorkspaceID, userID, workspaces.RoleViewer); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.GetAllPagesByUser(userID, filter)
 }
 
 func (s *service) GetPageByID(id, userID uint) (*Page, error) {
+	return s.accessiblePage(id, userID, workspaces.RoleViewer)
+}
+
+func (s *service) GetPagesByIDs(ids []uint, userID uint) ([]Page, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	list, err := s.repo.GetPagesByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	member := map[uint]bool{} // workspace ID -> whether the user may read it
+	readable := list[:0]
+	for _, page := range list {
+		if page.WorkspaceID == nil {
+			if page.UserID == userID {
+				readable = append(readable, page)
+			}
+			continue
+		}
+		ok, known := member[*page.WorkspaceID]
+		if !known {
+			err := s.requireWorkspaceRole(*page.WorkspaceID, userID, workspaces.RoleViewer)
+			if err != nil && !errors.Is(err, ErrPageNotFound) && !errors.Is(err, ErrForbidden) {
+				return nil, err
+			}
+			ok = err == nil
+			member[*page.WorkspaceID] = ok
+		}
+		if ok {
+			readable = append(readable, page)
+		}
+	}
+	return readable, nil
+}
+
+// editablePage loads a page the user may modify.
+func (s *service) editablePage(id, userID uint) (*Page, error) {
+	return s.accessiblePage(id, userID, workspaces.RoleEditor)
+}
+
+// accessiblePage loads a page and checks the user's access to it. Personal
+// pages are reachable by their owner only; workspace pages by members
+// holding at least min.
+func (s *service) accessiblePage(id, userID uint, min workspaces.Role) (*Page, error) {
 	page, err := s.repo.GetPageByID(id)
 	if err != nil {
 		return nil, err
 	}
-	if page == nil || page.UserID != userID {
+	if page == nil {
 		return nil, ErrPageNotFound
 	}
+	if page.WorkspaceID == nil {
+		if page.UserID != userID {
+			return nil, ErrPageNotFound
+		}
+		return page, nil
+	}
+	if err := s.requireWorkspaceRole(*page.WorkspaceID, userID, min); err != nil {
+		return nil, err
+	}
 	return page, nil
 }
 
+// UpdatePage replaces a page's title and content. Without an expected
+// Version in input, losing a race with another writer is retried.
 func (s *service) UpdatePage(id uint, input PageInput, userID uint) (*Page, error) {
-	page, err := s.repo.GetPageByID(id)
+	for attempt := 0; ; attempt++ {
+		page, err := s.updatePage(id, input, userID)
+		if errors.Is(err, ErrVersionConflict) && input.Version == nil && attempt < maxConflictRetries {
+			continue
+		}
+		return page, err
+	}
+}
+
+func (s *service) updatePage(id uint, input PageInput, userID uint) (*Page, error) {
+	page, err := s.editablePage(id, userID)
 	if err != nil {
 		return nil, err
 	}
-	if page == nil || page.UserID != userID {
-		return nil, ErrPageNotFound
+	if input.Version != nil && *input.Version != page.Version {
+		return nil, ErrVersionConflict
 	}
 
 	page.Title = input.Title
-	page.Content = input.Content
+	contentChanged := page.Content != input.Content
+	if contentChanged {
+		old, err := s.repo.GetBlocksByPage(page.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(old) == 0 && page.Content != "" {
+			old = legacyBlocks(page)
+		}
+		page.Content = input.Content
+		page.Blocks = ParseMarkdown(input.Content)
+		reconcileBlockIDs(old, page.Blocks)
+	}
 
-	if err := s.repo.UpdatePage(page); err != nil {
+	err = s.inTx(func(tx *service) error {
+		if err := tx.repo.UpdatePage(page); err != nil {
+			return err
+		}
+		e := tx.event(PageUpdated, page, userID)
+		if contentChanged {
+			if e.Mentioned, err = tx.syncLinks(page, userID); err != nil {
+				return err
+			}
+		}
+		return tx.repo.AddEvent(e)
+	})
+	if err != nil {
 		return nil, err
 	}
+	page.Blocks = nil
 	return page, nil
 }
 
+func (s *service) RenderPage(id, userID uint, withTOC bool) (*RenderedPage, error) {
+	page, err := s.GetPageByID(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.renderer.render(page, withTOC)
+}
+
 func (s *service) DeletePage(id, userID uint) error {
-	page, err := s.repo.GetPageByID(id)
+	page, err := s.editablePage(id, userID)
 	if err != nil {
 		return err
 	}
-	if page == nil || page.UserID != userID {
-		return ErrPageNotFound
+	return s.inTx(func(tx *service) error {
+		if err := tx.repo.DeletePage(id); err != nil {
+			return err
+		}
+		return tx.record(PageDeleted, page, userID)
+	})
+}
+
+func (s *service) GetBlocks(pageID, userID uint) ([]Block, error) {
+	_, blocks, err := s.loadBlocks(pageID, userID, workspaces.RoleViewer)
+	return blocks, err
+}
+
+func (s *service) InsertBlock(pageID uint, input BlockInput, userID uint) (*Block, error) {
+	blk := Block{ID: newBlockID(), PageID: pageID, Type: input.Type, Text: input.Text, Props: input.Props}
+	var at int
+	page, err := s.editBlocks(pageID, userID, func(blocks []Block) ([]Block, error) {
+		if err := validateBlock(&blk); err != nil {
+			return nil, err
+		}
+		at = len(blocks)
+		if input.AfterID != nil {
+			var err error
+			if at, err = insertionIndex(blocks, *input.AfterID); err != nil {
+				return nil, err
+			}
+		}
+		return append(blocks[:at], append([]Block{blk}, blocks[at:]...)...), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &page.Blocks[at], nil
+}
+
+func (s *service) UpdateBlock(pageID uint, blockID string, input BlockUpdateInput, userID uint) (*Block, error) {
+	var i int
+	page, err := s.editBlocks(pageID, userID, func(blocks []Block) ([]Block, error) {
+		if i = indexOfBlock(blocks, blockID); i < 0 {
+			return nil, ErrBlockNotFound
+		}
+		if input.Type != nil {
+			blocks[i].Type = *input.Type
+		}
+		if input.Text != nil {
+			blocks[i].Text = *input.Text
+		}
+		if input.Props != nil {
+			blocks[i].Props = *input.Props
+		}
+		if err := validateBlock(&blocks[i]); err != nil {
+			return nil, err
+		}
+		return blocks, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &page.Blocks[i], nil
+}
+
+func (s *service) MoveBlock(pageID uint, blockID string, input BlockMoveInput, userID uint) ([]Block, error) {
+	page, err := s.editBlocks(pageID, userID, func(blocks []Block) ([]Block, error) {
+		i := indexOfBlock(blocks, blockID)
+		if i < 0 || input.AfterID == blockID {
+			return nil, ErrBlockNotFound
+		}
+		blk := blocks[i]
+		blocks = append(blocks[:i], blocks[i+1:]...)
+
+		at, err := insertionIndex(blocks, input.AfterID)
+		if err != nil {
+			return nil, err
+		}
+		return append(blocks[:at], append([]Block{blk}, blocks[at:]...)...), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page.Blocks, nil
+}
+
+func (s *service) DeleteBlock(pageID uint, blockID string, userID uint) error {
+	_, err := s.editBlocks(pageID, userID, func(blocks []Block) ([]Block, error) {
+		i := indexOfBlock(blocks, blockID)
+		if i < 0 {
+			return nil, ErrBlockNotFound
+		}
+		return append(blocks[:i], blocks[i+1:]...), nil
+	})
+	return err
+}
+
+// editBlocks applies edit to the page's current blocks and saves the
+// result. When another write lands in between, edit is applied again to
+// the fresh blocks, so concurrent block operations all take effect.
+func (s *service) editBlocks(pageID, userID uint, edit func(blocks []Block) ([]Block, error)) (*Page, error) {
+	for attempt := 0; ; attempt++ {
+		page, blocks, err := s.loadBlocks(pageID, userID, workspaces.RoleEditor)
+		if err != nil {
+			return nil, err
+		}
+		if blocks, err = edit(blocks); err != nil {
+			return nil, err
+		}
+		err = s.saveBlocks(page, blocks, userID)
+		if errors.Is(err, ErrVersionConflict) && attempt < maxConflictRetries {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return page, nil
+	}
+}
+
+// loadBlocks checks access to the page and returns it with its blocks.
+// Pages written before blocks existed get theirs parsed from Content.
+func (s *service) loadBlocks(pageID, userID uint, min workspaces.Role) (*Page, []Block, error) {
+	page, err := s.accessiblePage(pageID, userID, min)
+	if err != nil {
+		return nil, nil, err
+	}
+	blocks, err := s.repo.GetBlocksByPage(pageID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(blocks) == 0 && page.Content != "" {
+		blocks = legacyBlocks(page)
+	}
+	return page, blocks, nil
+}
+
+// saveBlocks renumbers blocks, regenerates the Markdown content from them
+// and persists both together with the page's links.
+func (s *service) saveBlocks(page *Page, blocks []Block, userID uint) error {
+	if blocks == nil {
+		blocks = []Block{}
+	}
+	for i := range blocks {
+		blocks[i].Position = i
+	}
+	page.Blocks = blocks
+	page.Content = RenderMarkdown(blocks)
+
+	return s.inTx(func(tx *service) error {
+		if err := tx.repo.UpdatePage(page); err != nil {
+			return err
+		}
+		var err error
+		e := tx.event(PageUpdated, page, userID)
+		if e.Mentioned, err = tx.syncLinks(page, userID); err != nil {
+			return err
+		}
+		return tx.repo.AddEvent(e)
+	})
+}
+
+func indexOfBlock(blocks []Block, id string) int {
+	for i := range blocks {
+		if blocks[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// insertionIndex returns the slice index just after afterID, or 0 when
+// afterID is empty.
+func insertionIndex(blocks []Block, afterID string) (int, error) {
+	if afterID == "" {
+		return 0, nil
+	}
+	i := indexOfBlock(blocks, afterID)
+	if i < 0 {
+		return 0, ErrBlockNotFound
+	}
+	return i + 1, nil
+}
+
+func validateBlock(b *Block) error {
+	if !b.Type.Valid() {
+		return ErrInvalidBlock
+	}
+	switch b.Type {
+	case BlockHeading:
+		if b.Props.Level == 0 {
+			b.Props.Level = 1
+		}
+		if b.Props.Level < 1 || b.Props.Level > 6 {
+			return ErrInvalidBlock
+		}
+	case BlockImage, BlockEmbed:
+		if !strings.HasPrefix(b.Props.URL, "http://") && !strings.HasPrefix(b.Props.URL, "https://") {
+			return ErrInvalidBlock
+		}
+	case BlockDivider:
+		b.Text = ""
+	}
+	if b.Props.Indent < 0 {
+		return ErrInvalidBlock
 	}
-	return s.repo.DeletePage(id)
+	return nil
 }
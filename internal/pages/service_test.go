@@ -0,0 +1,563 @@
+package pages
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"flowboard-backend-go/internal/workspaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memRepo is an in-memory Repository for service tests.
+type memRepo struct {
+	pages    map[uint]*Page
+	blocks   map[uint][]Block
+	tags     map[uint]*Tag
+	pageTags map[PageTag]bool
+	links    map[uint][]PageLink
+	events   []PageEvent
+	relayed  int // events already handed to relay
+	nextID   uint
+	// beforeTx, when set, runs once as the next transaction starts,
+	// standing in for a concurrent writer that commits first.
+	beforeTx func()
+	// failTitle makes CreatePage fail for pages with this title.
+	failTitle string
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{
+		pages:    map[uint]*Page{},
+		blocks:   map[uint][]Block{},
+		tags:     map[uint]*Tag{},
+		pageTags: map[PageTag]bool{},
+		links:    map[uint][]PageLink{},
+	}
+}
+
+func (r *memRepo) CreatePage(page *Page) (*Page, error) {
+	if r.failTitle != "" && page.Title == r.failTitle {
+		return nil, errors.New("insert failed")
+	}
+	r.nextID++
+	page.ID = r.nextID
+	page.Version = 1
+	r.blocks[page.ID] = page.Blocks
+	cp := *page
+	r.pages[page.ID] = &cp
+	return page, nil
+}
+
+func (r *memRepo) GetAllPages() ([]Page, error) {
+	var out []Page
+	for _, p := range r.pages {
+		out = append(out, *p)
+	}
+	return out, nil
+}
+
+func (r *memRepo) GetAllPagesByUser(userID uint, filter PageFilter) ([]Page, error) {
+	var out []Page
+	for _, p := range r.pages {
+		if filter.WorkspaceID != nil {
+			if !sameWorkspace(p.WorkspaceID, filter.WorkspaceID) {
+				continue
+			}
+		} else if p.UserID != userID || p.WorkspaceID != nil {
+			continue
+		}
+		matched := 0
+		for _, id := range filter.TagIDs {
+			if r.pageTags[PageTag{PageID: p.ID, TagID: id}] {
+				matched++
+			}
+		}
+		if len(filter.TagIDs) > 0 && (matched == 0 || filter.TagMatch == "all" && matched < len(filter.TagIDs)) {
+			continue
+		}
+		out = append(out, *p)
+	}
+	return out, nil
+}
+
+func (r *memRepo) GetPageByID(id uint) (*Page, error) {
+	p, ok := r.pages[id]
+	if !ok {
+		return nil, nil
+	}
+	cp := *p
+	cp.Tags = nil
+	for pt := range r.pageTags {
+		if pt.PageID == id {
+			cp.Tags = append(cp.Tags, *r.tags[pt.TagID])
+		}
+	}
+	return &cp, nil
+}
+
+func (r *memRepo) GetPagesByIDs(ids []uint) ([]Page, error) {
+	var out []Page
+	for _, id := range ids {
+		if p, ok := r.pages[id]; ok {
+			out = append(out, *p)
+		}
+	}
+	return out, nil
+}
+
+func (r *memRepo) UpdatePage(page *Page) error {
+	if r.pages[page.ID].Version != page.Version {
+		return ErrVersionConflict
+	}
+	page.Version++
+	if page.Blocks != nil {
+		r.blocks[page.ID] = append([]Block(nil), page.Blocks...)
+	}
+	cp := *page
+	r.pages[page.ID] = &cp
+	return nil
+}
+
+func (r *memRepo) DeletePage(id uint) error {
+	delete(r.pages, id)
+	delete(r.blocks, id)
+	delete(r.links, id)
+	return nil
+}
+
+func (r *memRepo) GetBlocksByPage(pageID uint) ([]Block, error) {
+	return append([]Block(nil), r.blocks[pageID]...), nil
+}
+
+// Transaction runs fn and, like the database, undoes its writes if it
+// fails.
+func (r *memRepo) Transaction(fn func(repo Repository) error) error {
+	if hook := r.beforeTx; hook != nil {
+		r.beforeTx = nil
+		hook()
+	}
+	saved := *r
+	saved.pages = maps.Clone(r.pages)
+	saved.blocks = maps.Clone(r.blocks)
+	saved.tags = maps.Clone(r.tags)
+	saved.pageTags = maps.Clone(r.pageTags)
+	saved.links = maps.Clone(r.links)
+	saved.events = slices.Clone(r.events)
+	if err := fn(r); err != nil {
+		*r = saved
+		return err
+	}
+	return nil
+}
+
+func (r *memRepo) AddEvent(e PageEvent) error {
+	r.events = append(r.events, e)
+	return nil
+}
+
+// relay hands the events committed since the last call to s, the way the
+// outbox relay does.
+func (r *memRepo) relay(t *testing.T, s Service) {
+	for ; r.relayed < len(r.events); r.relayed++ {
+		require.NoError(t, s.HandlePageEvent(context.Background(), uint64(r.relayed+1), r.events[r.relayed]))
+	}
+}
+
+// sorted returns copies of the pages matching keep in display order.
+func (r *memRepo) sorted(keep func(p *Page) bool) []Page {
+	var out []Page
+	for _, p := range r.pages {
+		if keep(p) {
+			out = append(out, *p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Rank != out[j].Rank {
+			return out[i].Rank < out[j].Rank
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+func (r *memRepo) GetChildPages(parentID uint) ([]Page, error) {
+	children := r.sorted(func(p *Page) bool { return p.ParentID != nil && *p.ParentID == parentID })
+	for i := range children {
+		full, _ := r.GetPageByID(children[i].ID)
+		children[i] = *full
+	}
+	return children, nil
+}
+
+func (r *memRepo) GetSiblingPages(parentID, workspaceID *uint, userID uint) ([]Page, error) {
+	return r.sorted(func(p *Page) bool {
+		if parentID != nil {
+			return p.ParentID != nil && *p.ParentID == *parentID
+		}
+		if p.ParentID != nil || !sameWorkspace(p.WorkspaceID, workspaceID) {
+			return false
+		}
+		return workspaceID != nil || p.UserID == userID
+	}), nil
+}
+
+func (r *memRepo) GetDescendantIDs(id uint) ([]uint, error) {
+	var ids []uint
+	children, _ := r.GetChildPages(id)
+	for _, c := range children {
+		sub, _ := r.GetDescendantIDs(c.ID)
+		ids = append(append(ids, c.ID), sub...)
+	}
+	return ids, nil
+}
+
+func (r *memRepo) SetPageRanks(ranks map[uint]string) error {
+	for id, key := range ranks {
+		r.pages[id].Rank = key
+	}
+	return nil
+}
+
+func (r *memRepo) GetPagesToRebalance(maxLen, limit int) ([]Page, error) {
+	out := r.sorted(func(p *Page) bool { return p.Rank == "" || len(p.Rank) > maxLen })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *memRepo) ReassignPages(ids []uint, workspaceID, ownerID *uint) error {
+	for _, id := range ids {
+		r.pages[id].WorkspaceID = workspaceID
+		if ownerID != nil {
+			r.pages[id].UserID = *ownerID
+		}
+	}
+	return nil
+}
+
+func (r *memRepo) inScope(t *Tag, userID uint, workspaceID *uint) bool {
+	if workspaceID != nil {
+		return t.WorkspaceID != nil && *t.WorkspaceID == *workspaceID
+	}
+	return t.WorkspaceID == nil && t.UserID == userID
+}
+
+func (r *memRepo) GetTags(userID uint, workspaceID *uint) ([]Tag, error) {
+	var out []Tag
+	for _, t := range r.tags {
+		if !r.inScope(t, userID, workspaceID) {
+			continue
+		}
+		cp := *t
+		for pt := range r.pageTags {
+			if pt.TagID == t.ID {
+				cp.PageCount++
+			}
+		}
+		out = append(out, cp)
+	}
+	return out, nil
+}
+
+func (r *memRepo) GetTagByID(id uint) (*Tag, error) {
+	if t, ok := r.tags[id]; ok {
+		cp := *t
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (r *memRepo) GetTagByName(userID uint, workspaceID *uint, name string) (*Tag, error) {
+	for _, t := range r.tags {
+		if r.inScope(t, userID, workspaceID) && strings.EqualFold(t.Name, name) {
+			cp := *t
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memRepo) CreateTag(tag *Tag) error {
+	if existing, _ := r.GetTagByName(tag.UserID, tag.WorkspaceID, tag.Name); existing != nil {
+		return ErrTagExists
+	}
+	r.nextID++
+	tag.ID = r.nextID
+	cp := *tag
+	r.tags[tag.ID] = &cp
+	return nil
+}
+
+func (r *memRepo) UpdateTag(tag *Tag) error {
+	cp := *tag
+	r.tags[tag.ID] = &cp
+	return nil
+}
+
+func (r *memRepo) DeleteTag(id uint) error {
+	for pt := range r.pageTags {
+		if pt.TagID == id {
+			delete(r.pageTags, pt)
+		}
+	}
+	delete(r.tags, id)
+	return nil
+}
+
+func (r *memRepo) MergeTags(sourceID, targetID uint) error {
+	for pt := range r.pageTags {
+		if pt.TagID == sourceID {
+			r.pageTags[PageTag{PageID: pt.PageID, TagID: targetID}] = true
+		}
+	}
+	return r.DeleteTag(sourceID)
+}
+
+func (r *memRepo) AttachTags(pageID uint, tagIDs []uint) error {
+	for _, id := range tagIDs {
+		r.pageTags[PageTag{PageID: pageID, TagID: id}] = true
+	}
+	return nil
+}
+
+func (r *memRepo) DetachTag(pageID, tagID uint) error {
+	delete(r.pageTags, PageTag{PageID: pageID, TagID: tagID})
+	return nil
+}
+
+func TestBlocks_InsertMoveDeleteKeepContentInSync(t *testing.T) {
+	s := NewService(newMemRepo())
+
+	page, err := s.CreatePage(PageInput{Title: "Doc", Content: "# Title\n\nintro"}, 1)
+	require.NoError(t, err)
+
+	blocks, err := s.GetBlocks(page.ID, 1)
+	require.NoError(t, err)
+	require.Len(t, blocks, 2)
+	headingID, introID := blocks[0].ID, blocks[1].ID
+
+	todo, err := s.InsertBlock(page.ID, BlockInput{Type: BlockTodo, Text: "ship it", AfterID: &headingID}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, todo.Position)
+
+	got, err := s.GetPageByID(page.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "# Title\n\n- [ ] ship it\n\nintro", got.Content)
+
+	_, err = s.MoveBlock(page.ID, introID, BlockMoveInput{AfterID: ""}, 1)
+	require.NoError(t, err)
+	text := "shipped"
+	_, err = s.UpdateBlock(page.ID, todo.ID, BlockUpdateInput{Text: &text}, 1)
+	require.NoError(t, err)
+	require.NoError(t, s.DeleteBlock(page.ID, headingID, 1))
+
+	got, _ = s.GetPageByID(page.ID, 1)
+	assert.Equal(t, "intro\n\n- [ ] shipped", got.Content)
+
+	blocks, _ = s.GetBlocks(page.ID, 1)
+	assert.Equal(t, []string{introID, todo.ID}, []string{blocks[0].ID, blocks[1].ID})
+}
+
+func TestBlocks_LegacyPageIDsAreStable(t *testing.T) {
+	repo := newMemRepo()
+	s := NewService(repo)
+	page, err := s.CreatePage(PageInput{Title: "Old", Content: "# Title\n\nintro"}, 1)
+	require.NoError(t, err)
+	delete(repo.blocks, page.ID) // written before blocks existed
+
+	first, err := s.GetBlocks(page.ID, 1)
+	require.NoError(t, err)
+	again, err := s.GetBlocks(page.ID, 1)
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+	assert.Equal(t, first, again)
+	assert.NotEqual(t, first[0].ID, first[1].ID)
+
+	// The first content edit stores the blocks under the IDs handed out.
+	_, err = s.UpdatePage(page.ID, PageInput{Title: "Old", Content: "# Title\n\nintro, revised"}, 1)
+	require.NoError(t, err)
+	blocks, _ := s.GetBlocks(page.ID, 1)
+	assert.Equal(t, []string{first[0].ID, first[1].ID}, []string{blocks[0].ID, blocks[1].ID})
+}
+
+func TestBlocks_ConcurrentEditsAreNotLost(t *testing.T) {
+	repo := newMemRepo()
+	s := NewService(repo)
+	page, err := s.CreatePage(PageInput{Title: "Doc", Content: "intro"}, 1)
+	require.NoError(t, err)
+
+	repo.beforeTx = func() {
+		_, err := s.InsertBlock(page.ID, BlockInput{Type: BlockParagraph, Text: "theirs"}, 1)
+		require.NoError(t, err)
+	}
+	_, err = s.InsertBlock(page.ID, BlockInput{Type: BlockParagraph, Text: "mine"}, 1)
+	require.NoError(t, err)
+
+	got, err := s.GetPageByID(page.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "intro\n\ntheirs\n\nmine", got.Content)
+	assert.Equal(t, 3, got.Version)
+}
+
+func TestUpdatePage_ExpectedVersion(t *testing.T) {
+	repo := newMemRepo()
+	s := NewService(repo)
+	page, err := s.CreatePage(PageInput{Title: "Doc", Content: "v1"}, 1)
+	require.NoError(t, err)
+	read := page.Version
+
+	_, err = s.UpdatePage(page.ID, PageInput{Title: "Doc", Content: "theirs"}, 1)
+	require.NoError(t, err)
+	_, err = s.UpdatePage(page.ID, PageInput{Title: "Doc", Content: "mine", Version: &read}, 1)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+
+	// A writer racing in between the check and the write is caught too.
+	got, _ := s.GetPageByID(page.ID, 1)
+	read = got.Version
+	repo.beforeTx = func() {
+		repo.beforeTx = nil
+		_, err := s.UpdatePage(page.ID, PageInput{Title: "Doc", Content: "racer"}, 1)
+		require.NoError(t, err)
+	}
+	_, err = s.UpdatePage(page.ID, PageInput{Title: "Doc", Content: "mine", Version: &read}, 1)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	got, _ = s.GetPageByID(page.ID, 1)
+	assert.Equal(t, "racer", got.Content)
+}
+
+func TestBlocks_Validation(t *testing.T) {
+	s := NewService(newMemRepo())
+	page, err := s.CreatePage(PageInput{Title: "Doc", Content: "text"}, 1)
+	require.NoError(t, err)
+
+	_, err = s.InsertBlock(page.ID, BlockInput{Type: "table"}, 1)
+	assert.Equal(t, ErrInvalidBlock, err)
+
+	_, err = s.InsertBlock(page.ID, BlockInput{Type: BlockImage, Props: BlockProps{URL: "javascript:alert(1)"}}, 1)
+	assert.Equal(t, ErrInvalidBlock, err)
+
+	missing := "nope"
+	_, err = s.InsertBlock(page.ID, BlockInput{Type: BlockParagraph, AfterID: &missing}, 1)
+	assert.Equal(t, ErrBlockNotFound, err)
+
+	_, err = s.GetBlocks(page.ID, 2)
+	assert.Equal(t, ErrPageNotFound, err)
+}
+
+func TestTags_MergeRetagsPagesAndFilters(t *testing.T) {
+	s := NewService(newMemRepo())
+
+	a, _ := s.CreatePage(PageInput{Title: "A", Content: "a"}, 1)
+	b, _ := s.CreatePage(PageInput{Title: "B", Content: "b"}, 1)
+
+	_, err := s.AttachTags(a.ID, PageTagsInput{Names: []string{"Design", "q1"}}, 1)
+	require.NoError(t, err)
+	_, err = s.AttachTags(b.ID, PageTagsInput{Names: []string{"design-old"}}, 1)
+	require.NoError(t, err)
+
+	tags, _ := s.GetTags(1, nil)
+	ids := map[string]uint{}
+	for _, tag := range tags {
+		ids[tag.Name] = tag.ID
+	}
+	require.Len(t, ids, 3)
+
+	_, err = s.CreateTag(TagInput{Name: "design"}, 1)
+	assert.Equal(t, ErrTagExists, err)
+
+	target, err := s.MergeTags(ids["design-old"], ids["Design"], 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Design", target.Name)
+
+	any, _ := s.GetAllPagesByUser(1, PageFilter{TagIDs: []uint{ids["Design"]}})
+	assert.Len(t, any, 2)
+	all, _ := s.GetAllPagesByUser(1, PageFilter{TagIDs: []uint{ids["Design"], ids["q1"]}, TagMatch: "all"})
+	require.Len(t, all, 1)
+	assert.Equal(t, a.ID, all[0].ID)
+
+	_, err = s.UpdateTag(ids["Design"], TagUpdateInput{}, 2)
+	assert.Equal(t, ErrTagNotFound, err)
+	_, err = s.MergeTags(ids["q1"], ids["q1"], 1)
+	assert.Equal(t, ErrInvalidMerge, err)
+}
+
+func TestTags_AttachKeepsToThePageScope(t *testing.T) {
+	wsA, wsB := uint(10), uint(11)
+	access := stubWorkspaces{{wsA, 1}: workspaces.RoleEditor, {wsB, 1}: workspaces.RoleEditor, {wsA, 2}: workspaces.RoleEditor}
+	s := NewService(newMemRepo(), WithWorkspaceAccess(access))
+
+	personal, _ := s.CreatePage(PageInput{Title: "Diary", Content: "d"}, 1)
+	team, _ := s.CreatePage(PageInput{Title: "Spec", Content: "s", WorkspaceID: &wsA}, 1)
+	mine, _ := s.CreateTag(TagInput{Name: "private"}, 1)
+	other, _ := s.CreateTag(TagInput{Name: "roadmap", WorkspaceID: &wsB}, 1)
+
+	_, err := s.AttachTags(team.ID, PageTagsInput{TagIDs: []uint{mine.ID}}, 1)
+	assert.ErrorIs(t, err, ErrTagScope, "personal tag on a workspace page")
+	_, err = s.AttachTags(team.ID, PageTagsInput{TagIDs: []uint{other.ID}}, 1)
+	assert.ErrorIs(t, err, ErrTagScope, "another workspace's tag")
+	_, err = s.AttachTags(personal.ID, PageTagsInput{TagIDs: []uint{other.ID}}, 1)
+	assert.ErrorIs(t, err, ErrTagScope, "workspace tag on a personal page")
+
+	// Names resolve and create tags in the page's workspace, which other
+	// members then share.
+	tags, err := s.AttachTags(team.ID, PageTagsInput{Names: []string{"launch"}}, 1)
+	require.NoError(t, err)
+	require.Len(t, tags, 1)
+	require.NotNil(t, tags[0].WorkspaceID)
+	assert.Equal(t, wsA, *tags[0].WorkspaceID)
+	again, err := s.AttachTags(team.ID, PageTagsInput{Names: []string{"Launch"}}, 2)
+	require.NoError(t, err)
+	require.Len(t, again, 1)
+	assert.Equal(t, tags[0].ID, again[0].ID)
+
+	personalTags, _ := s.GetTags(1, nil)
+	assert.Len(t, personalTags, 1, "no personal tag was created")
+}
+
+func (r *memRepo) GetLinks(sourceID uint) ([]PageLink, error) {
+	return append([]PageLink(nil), r.links[sourceID]...), nil
+}
+
+func (r *memRepo) ReplaceLinks(sourceID uint, links []PageLink) error {
+	cp := make([]PageLink, len(links))
+	for i, l := range links {
+		l.SourceID = sourceID
+		cp[i] = l
+	}
+	r.links[sourceID] = cp
+	return nil
+}
+
+func (r *memRepo) GetBacklinks(targetID uint) ([]Page, error) {
+	return r.sorted(func(p *Page) bool {
+		for _, l := range r.links[p.ID] {
+			if l.TargetPageID != nil && *l.TargetPageID == targetID {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+func (r *memRepo) FindPagesByTitle(title string, workspaceID *uint, userID uint) ([]Page, error) {
+	var out []Page
+	for id := uint(1); id <= r.nextID; id++ {
+		p, ok := r.pages[id]
+		if !ok || !strings.EqualFold(p.Title, title) {
+			continue
+		}
+		if workspaceID != nil && p.WorkspaceID != nil && *p.WorkspaceID == *workspaceID ||
+			workspaceID == nil && p.WorkspaceID == nil && p.UserID == userID {
+			out = append(out, *p)
+		}
+	}
+	return out, nil
+}
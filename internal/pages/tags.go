@@ -0,0 +1,211 @@
+package pages
+
+import (
+	"errors"
+	"strings"
+
+	"flowboard-backend-go/internal/workspaces"
+)
+
+func (s *service) GetTags(userID uint, workspaceID *uint) ([]Tag, error) {
+	if workspaceID != nil {
+		if err := s.requireWorkspaceRole(*workspaceID, userID, workspaces.RoleViewer); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.GetTags(userID, workspaceID)
+}
+
+func (s *service) CreateTag(input TagInput, userID uint) (*Tag, error) {
+	if input.WorkspaceID != nil {
+		if err := s.requireWorkspaceRole(*input.WorkspaceID, userID, workspaces.RoleEditor); err != nil {
+			return nil, err
+		}
+	}
+	name := strings.TrimSpace(input.Name)
+	if existing, err := s.repo.GetTagByName(userID, input.WorkspaceID, name); err != nil {
+		return nil, err
+	} else if existing != nil {
+		return nil, ErrTagExists
+	}
+
+	tag := &Tag{UserID: userID, WorkspaceID: input.WorkspaceID, Name: name, Color: input.Color}
+	if err := s.repo.CreateTag(tag); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// UpdateTag renames or recolors a tag. Pages reference tags by ID, so a
+// rename is a single-row update that every tagged page sees at once.
+func (s *service) UpdateTag(id uint, input TagUpdateInput, userID uint) (*Tag, error) {
+	tag, err := s.editableTag(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		existing, err := s.repo.GetTagByName(tag.UserID, tag.WorkspaceID, name)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil && existing.ID != tag.ID {
+			return nil, ErrTagExists
+		}
+		tag.Name = name
+	}
+	if input.Color != nil {
+		tag.Color = *input.Color
+	}
+
+	if err := s.repo.UpdateTag(tag); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// MergeTags folds source into target. Both must live in the same scope.
+func (s *service) MergeTags(sourceID, targetID, userID uint) (*Tag, error) {
+	if sourceID == targetID {
+		return nil, ErrInvalidMerge
+	}
+	source, err := s.editableTag(sourceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.editableTag(targetID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !sameScope(source, target) {
+		return nil, ErrForbidden
+	}
+
+	if err := s.repo.MergeTags(source.ID, target.ID); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+func (s *service) DeleteTag(id, userID uint) error {
+	if _, err := s.editableTag(id, userID); err != nil {
+		return err
+	}
+	return s.repo.DeleteTag(id)
+}
+
+// AttachTags adds tags to a page and returns the page's resulting tag
+// list. Tags must share the page's scope: its workspace's tags, or the
+// owner's personal tags on a personal page. Unknown names create tags in
+// that scope.
+func (s *service) AttachTags(pageID uint, input PageTagsInput, userID uint) ([]Tag, error) {
+	page, err := s.editablePage(pageID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(input.TagIDs)+len(input.Names))
+	for _, id := range input.TagIDs {
+		tag, err := s.editableTag(id, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !sameScope(tag, &Tag{UserID: page.UserID, WorkspaceID: page.WorkspaceID}) {
+			return nil, ErrTagScope
+		}
+		ids = append(ids, id)
+	}
+	for _, name := range input.Names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		tag, err := s.repo.GetTagByName(page.UserID, page.WorkspaceID, name)
+		if err != nil {
+			return nil, err
+		}
+		if tag == nil {
+			tag = &Tag{UserID: userID, WorkspaceID: page.WorkspaceID, Name: name}
+			if err := s.repo.CreateTag(tag); errors.Is(err, ErrTagExists) {
+				// Created concurrently by another request; use that one.
+				if tag, err = s.repo.GetTagByName(page.UserID, page.WorkspaceID, name); err == nil && tag == nil {
+					err = ErrTagNotFound
+				}
+				if err != nil {
+					return nil, err
+				}
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		ids = append(ids, tag.ID)
+	}
+
+	if err := s.repo.AttachTags(pageID, ids); err != nil {
+		return nil, err
+	}
+	tagged, err := s.repo.GetPageByID(pageID)
+	if err != nil {
+		return nil, err
+	}
+	return tagged.Tags, nil
+}
+
+func (s *service) DetachTag(pageID, tagID, userID uint) error {
+	if _, err := s.editablePage(pageID, userID); err != nil {
+		return err
+	}
+	return s.repo.DetachTag(pageID, tagID)
+}
+
+// editableTag loads a tag the user may modify or apply: their own personal
+// tags, or tags of a workspace where they are at least an editor.
+func (s *service) editableTag(id, userID uint) (*Tag, error) {
+	tag, err := s.repo.GetTagByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if tag == nil {
+		return nil, ErrTagNotFound
+	}
+	if tag.WorkspaceID == nil {
+		if tag.UserID != userID {
+			return nil, ErrTagNotFound
+		}
+		return tag, nil
+	}
+	if err := s.requireWorkspaceRole(*tag.WorkspaceID, userID, workspaces.RoleEditor); err != nil {
+		if err == ErrPageNotFound {
+			return nil, ErrTagNotFound
+		}
+		return nil, err
+	}
+	return tag, nil
+}
+
+// requireWorkspaceRole checks the user's workspace role. Non-members get
+// ErrPageNotFound so the workspace's existence is not revealed.
+func (s *service) requireWorkspaceRole(workspaceID, userID uint, min workspaces.Role) error {
+	if s.workspaces == nil {
+		return ErrForbidden
+	}
+	role, err := s.workspaces.MemberRole(workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return ErrPageNotFound
+	}
+	if !role.AtLeast(min) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func sameScope(a, b *Tag) bool {
+	if a.WorkspaceID == nil || b.WorkspaceID == nil {
+		return a.WorkspaceID == nil && b.WorkspaceID == nil && a.UserID == b.UserID
+	}
+	return *a.WorkspaceID == *b.WorkspaceID
+}
@@ -0,0 +1,299 @@
+package pages
+
+import (
+	"errors"
+
+	"flowboard-backend-go/internal/workspaces"
+)
+
+// inTx runs fn against a copy of the service whose repository is bound to a
+// single transaction.
+func (s *service) inTx(fn func(tx *service) error) error {
+	return s.repo.Transaction(func(repo Repository) error {
+		tx := *s
+		tx.repo = repo
+		return fn(&tx)
+	})
+}
+
+func (s *service) CreatePageTree(input PageTreeInput, userID uint) ([]*Page, error) {
+	var created []*Page
+	err := s.inTx(func(tx *service) error {
+		return tx.createTree(input, userID, &created)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (s *service) createTree(input PageTreeInput, userID uint, created *[]*Page) error {
+	page, err := s.CreatePage(input.PageInput, userID)
+	if err != nil {
+		return err
+	}
+	*created = append(*created, page)
+	for _, child := range input.Children {
+		child.ParentID = &page.ID
+		child.WorkspaceID = nil
+		if err := s.createTree(child, userID, created); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DuplicatePage copies a page next to the original. Children are copied
+// recursively and tags re-attached when requested. The copies belong to
+// the acting user.
+func (s *service) DuplicatePage(id uint, input DuplicateInput, userID uint) (*Page, error) {
+	var dup *Page
+	err := s.inTx(func(tx *service) error {
+		src, err := tx.GetPageByID(id, userID)
+		if err != nil {
+			return err
+		}
+		if err := tx.requireDestination(src.ParentID, src.WorkspaceID, userID); err != nil {
+			return err
+		}
+
+		siblings, err := tx.repo.GetSiblingPages(src.ParentID, src.WorkspaceID, src.UserID)
+		if err != nil {
+			return err
+		}
+		key, err := tx.rankAt(siblings, indexOfPage(siblings, src.ID)+1)
+		if err != nil {
+			return err
+		}
+
+		title := input.Title
+		if title == "" {
+			title = src.Title + " (copy)"
+		}
+		if dup, err = tx.copyPage(src, title, src.ParentID, key, input, userID); err != nil {
+			return err
+		}
+		return tx.record(PageCreated, dup, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetPageByID(dup.ID)
+}
+
+// copyPage creates a copy of src under parentID with the given rank, then
+// copies its children when asked to.
+func (s *service) copyPage(src *Page, title string, parentID *uint, key string, input DuplicateInput, userID uint) (*Page, error) {
+	blocks, err := s.repo.GetBlocksByPage(src.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) == 0 {
+		blocks = ParseMarkdown(src.Content)
+	}
+	for i := range blocks {
+		blocks[i].ID = newBlockID()
+		blocks[i].PageID = 0
+	}
+
+	page, err := s.repo.CreatePage(&Page{
+		Title:       title,
+		Content:     src.Content,
+		UserID:      userID,
+		WorkspaceID: src.WorkspaceID,
+		ParentID:    parentID,
+		Rank:        key,
+		Blocks:      blocks,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The copy links to the same pages and mentions the same people as
+	// the original; nobody is notified again.
+	links, err := s.repo.GetLinks(src.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceLinks(page.ID, links); err != nil {
+		return nil, err
+	}
+
+	if input.IncludeTags && len(src.Tags) > 0 {
+		ids := make([]uint, len(src.Tags))
+		for i, t := range src.Tags {
+			ids[i] = t.ID
+		}
+		if err := s.repo.AttachTags(page.ID, ids); err != nil {
+			return nil, err
+		}
+	}
+
+	if !input.IncludeChildren {
+		return page, nil
+	}
+	children, err := s.repo.GetChildPages(src.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range children {
+		child := &children[i]
+		if _, err := s.copyPage(child, child.Title, &page.ID, child.Rank, input, userID); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// MovePage re-parents a page and its subtree, reorders it among its new
+// siblings and, when the destination lies in another workspace or in the
+// user's personal space, moves the whole subtree there.
+func (s *service) MovePage(id uint, input MoveInput, userID uint) (*Page, error) {
+	if input.Personal && (input.ParentID != nil || input.WorkspaceID != nil) {
+		return nil, ErrInvalidDestination
+	}
+	var moved *Page
+	err := s.inTx(func(tx *service) error {
+		page, err := tx.editablePage(id, userID)
+		if err != nil {
+			return err
+		}
+
+		workspaceID := page.WorkspaceID
+		shared := false
+		switch {
+		case input.ParentID != nil:
+			parent, err := tx.editablePage(*input.ParentID, userID)
+			if err != nil {
+				return err
+			}
+			if err := tx.checkNoCycle(page.ID, parent.ID); err != nil {
+				return err
+			}
+			workspaceID = parent.WorkspaceID
+		case input.Personal:
+			workspaceID = nil
+		case input.WorkspaceID != nil:
+			workspaceID = input.WorkspaceID
+		}
+		if input.ParentID == nil {
+			if err := tx.requireDestination(nil, workspaceID, userID); err != nil {
+				return err
+			}
+		}
+
+		if !sameWorkspace(page.WorkspaceID, workspaceID) {
+			subtree, err := tx.repo.GetDescendantIDs(page.ID)
+			if err != nil {
+				return err
+			}
+			if page.WorkspaceID != nil {
+				if err := tx.mayTakeOutOfWorkspace(page, subtree, userID); err != nil {
+					return err
+				}
+			}
+			// Pages taken into personal space become the mover's.
+			var ownerID *uint
+			if workspaceID == nil {
+				ownerID = &userID
+				page.UserID = userID
+			}
+			if err := tx.repo.ReassignPages(subtree, workspaceID, ownerID); err != nil {
+				return err
+			}
+			shared = workspaceID != nil
+		}
+		siblings, err := tx.repo.GetSiblingPages(input.ParentID, workspaceID, page.UserID)
+		if err != nil {
+			return err
+		}
+		siblings = pagesExcept(siblings, page.ID)
+		at := len(siblings)
+		if input.Position != nil && *input.Position < at {
+			at = *input.Position
+		}
+		if page.Rank, err = tx.rankAt(siblings, at); err != nil {
+			return err
+		}
+
+		page.ParentID = input.ParentID
+		page.WorkspaceID = workspaceID
+		if err := tx.repo.UpdatePage(page); err != nil {
+			return err
+		}
+		moved = page
+		e := tx.event(PageUpdated, page, userID)
+		e.Shared = shared
+		return tx.repo.AddEvent(e)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return moved, nil
+}
+
+// mayTakeOutOfWorkspace checks that the user may move page and its
+// descendants out of the page's workspace. Workspace admins may; other
+// members only if they wrote every page of the subtree, so nobody carries
+// off the others' pages.
+func (s *service) mayTakeOutOfWorkspace(page *Page, descendants []uint, userID uint) error {
+	err := s.requireWorkspaceRole(*page.WorkspaceID, userID, workspaces.RoleAdmin)
+	if !errors.Is(err, ErrForbidden) {
+		return err
+	}
+	if page.UserID != userID {
+		return ErrForbidden
+	}
+	if len(descendants) == 0 {
+		return nil
+	}
+	list, err := s.repo.GetPagesByIDs(descendants)
+	if err != nil {
+		return err
+	}
+	for _, p := range list {
+		if p.UserID != userID {
+			return ErrForbidden
+		}
+	}
+	return nil
+}
+
+// requireDestination checks that the user may add pages at a location:
+// under parentID, at the top of a workspace, or in their personal space.
+func (s *service) requireDestination(parentID, workspaceID *uint, userID uint) error {
+	if parentID != nil {
+		_, err := s.editablePage(*parentID, userID)
+		return err
+	}
+	if workspaceID != nil {
+		return s.requireWorkspaceRole(*workspaceID, userID, workspaces.RoleEditor)
+	}
+	return nil
+}
+
+// checkNoCycle rejects moving a page under itself or one of its
+// descendants by walking up from the new parent.
+func (s *service) checkNoCycle(pageID, parentID uint) error {
+	for id := &parentID; id != nil; {
+		if *id == pageID {
+			return ErrInvalidMove
+		}
+		p, err := s.repo.GetPageByID(*id)
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			return ErrPageNotFound
+		}
+		id = p.ParentID
+	}
+	return nil
+}
+
+func sameWorkspace(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
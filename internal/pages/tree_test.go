@@ -0,0 +1,217 @@
+package pages
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"flowboard-backend-go/internal/notifications"
+	"flowboard-backend-go/internal/workspaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubWorkspaces grants roles from a fixed table keyed by workspace, user.
+type stubWorkspaces map[[2]uint]workspaces.Role
+
+func (w stubWorkspaces) MemberRole(workspaceID, userID uint) (workspaces.Role, error) {
+	return w[[2]uint{workspaceID, userID}], nil
+}
+
+func (w stubWorkspaces) MemberIDs(workspaceID uint) ([]uint, error) {
+	var ids []uint
+	for key := range w {
+		if key[0] == workspaceID {
+			ids = append(ids, key[1])
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+func titles(pages []Page) []string {
+	out := make([]string, len(pages))
+	for i, p := range pages {
+		out[i] = p.Title
+	}
+	return out
+}
+
+func TestCreatePageTree_CreatesAllOrNothing(t *testing.T) {
+	repo := newMemRepo()
+	s := NewService(repo)
+	tree := PageTreeInput{
+		PageInput: PageInput{Title: "Sprint 12", Content: "plan"},
+		Children: []PageTreeInput{
+			{PageInput: PageInput{Title: "Goals", Content: "g"}, Children: []PageTreeInput{
+				{PageInput: PageInput{Title: "Metrics", Content: "m"}},
+			}},
+			{PageInput: PageInput{Title: "Retro", Content: "r"}},
+		},
+	}
+
+	created, err := s.CreatePageTree(tree, 1)
+	require.NoError(t, err)
+	require.Len(t, created, 4)
+	assert.Equal(t, "Sprint 12", created[0].Title)
+	assert.Equal(t, created[0].ID, *created[1].ParentID)
+	assert.Equal(t, created[1].ID, *created[2].ParentID, "Metrics nests under Goals")
+	assert.Equal(t, created[0].ID, *created[3].ParentID)
+
+	repo.failTitle = "Retro"
+	before := len(repo.pages)
+	_, err = s.CreatePageTree(tree, 1)
+	assert.Error(t, err)
+	assert.Len(t, repo.pages, before, "no partial tree is left behind")
+	assert.Len(t, repo.events, 4)
+}
+
+func TestDuplicatePage_DeepCopiesChildrenAndTags(t *testing.T) {
+	repo := newMemRepo()
+	s := NewService(repo)
+
+	root, _ := s.CreatePage(PageInput{Title: "Roadmap", Content: "# Q1\n\nplans"}, 1)
+	_, _ = s.CreatePage(PageInput{Title: "Next", Content: "n"}, 1)
+	child, _ := s.CreatePage(PageInput{Title: "Goals", Content: "g", ParentID: &root.ID}, 1)
+	_, _ = s.CreatePage(PageInput{Title: "Metrics", Content: "m", ParentID: &child.ID}, 1)
+	_, err := s.AttachTags(root.ID, PageTagsInput{Names: []string{"planning"}}, 1)
+	require.NoError(t, err)
+
+	dup, err := s.DuplicatePage(root.ID, DuplicateInput{IncludeChildren: true, IncludeTags: true}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Roadmap (copy)", dup.Title)
+	assert.Equal(t, "# Q1\n\nplans", dup.Content)
+	require.Len(t, dup.Tags, 1)
+	assert.Equal(t, "planning", dup.Tags[0].Name)
+
+	top, _ := repo.GetSiblingPages(nil, nil, 1)
+	assert.Equal(t, []string{"Roadmap", "Roadmap (copy)", "Next"}, titles(top))
+
+	children, _ := repo.GetChildPages(dup.ID)
+	require.Len(t, children, 1)
+	assert.Equal(t, "Goals", children[0].Title)
+	assert.NotEqual(t, child.ID, children[0].ID)
+	grandchildren, _ := repo.GetChildPages(children[0].ID)
+	assert.Equal(t, []string{"Metrics"}, titles(grandchildren))
+
+	srcBlocks, _ := s.GetBlocks(root.ID, 1)
+	dupBlocks, _ := s.GetBlocks(dup.ID, 1)
+	require.Len(t, dupBlocks, len(srcBlocks))
+	assert.NotEqual(t, srcBlocks[0].ID, dupBlocks[0].ID)
+
+	shallow, err := s.DuplicatePage(root.ID, DuplicateInput{Title: "Draft"}, 1)
+	require.NoError(t, err)
+	assert.Empty(t, shallow.Tags)
+	children, _ = repo.GetChildPages(shallow.ID)
+	assert.Empty(t, children)
+
+	_, err = s.DuplicatePage(root.ID, DuplicateInput{}, 2)
+	assert.Equal(t, ErrPageNotFound, err)
+}
+
+func TestMovePage_ReparentsAndReorders(t *testing.T) {
+	repo := newMemRepo()
+	s := NewService(repo)
+
+	a, _ := s.CreatePage(PageInput{Title: "A", Content: "a"}, 1)
+	b, _ := s.CreatePage(PageInput{Title: "B", Content: "b"}, 1)
+	c, _ := s.CreatePage(PageInput{Title: "C", Content: "c"}, 1)
+	a1, _ := s.CreatePage(PageInput{Title: "A1", Content: "a1", ParentID: &a.ID}, 1)
+
+	pos := 0
+	moved, err := s.MovePage(c.ID, MoveInput{ParentID: &a.ID, Position: &pos}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, a.ID, *moved.ParentID)
+
+	children, _ := repo.GetChildPages(a.ID)
+	assert.Equal(t, []string{"C", "A1"}, titles(children))
+	top, _ := repo.GetSiblingPages(nil, nil, 1)
+	assert.Equal(t, []string{"A", "B"}, titles(top))
+
+	_, err = s.MovePage(a.ID, MoveInput{ParentID: &a1.ID}, 1)
+	assert.Equal(t, ErrInvalidMove, err)
+	_, err = s.MovePage(a.ID, MoveInput{ParentID: &a.ID}, 1)
+	assert.Equal(t, ErrInvalidMove, err)
+
+	_, err = s.MovePage(b.ID, MoveInput{ParentID: &a.ID}, 2)
+	assert.Equal(t, ErrPageNotFound, err)
+}
+
+func TestMovePage_AcrossWorkspaces(t *testing.T) {
+	ws := uint(10)
+	access := stubWorkspaces{{ws, 1}: workspaces.RoleEditor, {ws, 2}: workspaces.RoleViewer, {ws, 3}: workspaces.RoleAdmin}
+	repo := newMemRepo()
+	notifier := &recordingNotifier{}
+	s := NewService(repo, WithWorkspaceAccess(access), WithNotifier(notifier))
+
+	root, _ := s.CreatePage(PageInput{Title: "Spec", Content: "s"}, 1)
+	child, _ := s.CreatePage(PageInput{Title: "Notes", Content: "n", ParentID: &root.ID}, 1)
+
+	_, err := s.MovePage(root.ID, MoveInput{WorkspaceID: &ws}, 1)
+	require.NoError(t, err)
+	repo.relay(t, s)
+	require.Len(t, notifier.sent, 2, "the other members hear about the shared page")
+	for i, id := range []uint{2, 3} {
+		assert.Equal(t, id, notifier.sent[i].UserID)
+		assert.Equal(t, notifications.TypeShare, notifier.sent[i].Type)
+		assert.Equal(t, fmt.Sprintf("/pages/%d", root.ID), notifier.sent[i].Link)
+	}
+
+	got, err := s.GetPageByID(child.ID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, ws, *got.WorkspaceID)
+	listed, _ := s.GetAllPagesByUser(2, PageFilter{WorkspaceID: &ws})
+	assert.Len(t, listed, 2)
+
+	_, err = s.MovePage(child.ID, MoveInput{WorkspaceID: &ws}, 2)
+	assert.Equal(t, ErrForbidden, err)
+	other := uint(11)
+	_, err = s.MovePage(root.ID, MoveInput{WorkspaceID: &other}, 1)
+	assert.Equal(t, ErrPageNotFound, err)
+
+	// An admin may pull someone else's page into their personal space.
+	_, err = s.MovePage(root.ID, MoveInput{Personal: true}, 3)
+	require.NoError(t, err)
+	got, err = s.GetPageByID(child.ID, 3)
+	require.NoError(t, err)
+	assert.Nil(t, got.WorkspaceID)
+	_, err = s.GetPageByID(child.ID, 1)
+	assert.Equal(t, ErrPageNotFound, err)
+	repo.relay(t, s)
+	assert.Len(t, notifier.sent, 2, "leaving a workspace shares nothing")
+}
+
+func TestMovePage_OutOfWorkspaceNeedsTheWholeSubtree(t *testing.T) {
+	ws := uint(10)
+	access := stubWorkspaces{{ws, 1}: workspaces.RoleEditor, {ws, 4}: workspaces.RoleEditor}
+	repo := newMemRepo()
+	s := NewService(repo, WithWorkspaceAccess(access))
+
+	root, _ := s.CreatePage(PageInput{Title: "Spec", Content: "s", WorkspaceID: &ws}, 1)
+	mine, _ := s.CreatePage(PageInput{Title: "Draft", Content: "d", ParentID: &root.ID}, 1)
+	theirs, _ := s.CreatePage(PageInput{Title: "Review", Content: "r", ParentID: &mine.ID}, 4)
+
+	// Without a destination the page stays in its workspace.
+	moved, err := s.MovePage(mine.ID, MoveInput{}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, ws, *moved.WorkspaceID)
+	assert.Nil(t, moved.ParentID)
+
+	_, err = s.MovePage(mine.ID, MoveInput{Personal: true, WorkspaceID: &ws}, 1)
+	assert.Equal(t, ErrInvalidDestination, err)
+
+	_, err = s.MovePage(mine.ID, MoveInput{Personal: true}, 1)
+	assert.Equal(t, ErrForbidden, err, "Review was written by someone else")
+	got, err := s.GetPageByID(theirs.ID, 4)
+	require.NoError(t, err)
+	assert.Equal(t, ws, *got.WorkspaceID)
+
+	_, err = s.MovePage(theirs.ID, MoveInput{Personal: true}, 4)
+	require.NoError(t, err)
+	_, err = s.MovePage(mine.ID, MoveInput{Personal: true}, 1)
+	require.NoError(t, err, "an author may take out a subtree they wrote entirely")
+	got, err = s.GetPageByID(mine.ID, 1)
+	require.NoError(t, err)
+	assert.Nil(t, got.WorkspaceID)
+}
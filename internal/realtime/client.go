@@ -0,0 +1,263 @@
+package realtime
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait  = 10 * time.Second
+	pongWait   = 60 * time.Second
+	pingPeriod = pongWait * 9 / 10
+	// sendBuffer is how many messages may queue for a client before it is
+	// considered too slow and disconnected.
+	sendBuffer = 64
+	// maxMessageSize bounds what a client may send.
+	maxMessageSize = 4096
+	// maxSubscriptions bounds how many pages one connection may follow.
+	maxSubscriptions = 100
+)
+
+// clientMessage is what clients send: subscribe or unsubscribe to pages,
+// or heartbeat or leave pages they have open. Mode applies to heartbeats.
+type clientMessage struct {
+	Action  string `json:"action"`
+	PageIDs []uint `json:"pageIds"`
+	Mode    Mode   `json:"mode"`
+}
+
+// serverMessage acknowledges a client message. Page events are sent as
+// pages.PageEvent and presence events as PresenceEvent, whose types start
+// with "page." and "presence." respectively.
+type serverMessage struct {
+	Type    string `json:"type"`
+	PageIDs []uint `json:"pageIds,omitempty"`
+	Denied  []uint `json:"denied,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type accessEntry struct {
+	read    bool
+	edit    bool
+	checked time.Time
+}
+
+// sentBeat is the last heartbeat passed on for a page.
+type sentBeat struct {
+	mode Mode
+	at   time.Time
+}
+
+// client is one WebSocket connection. subs is guarded by the hub's lock;
+// beats belongs to the read loop.
+type client struct {
+	hub    *Hub
+	conn   *websocket.Conn
+	id     string
+	userID uint
+	send   chan []byte
+	done   chan struct{}
+	once   sync.Once
+	subs   map[uint]bool
+	beats  map[uint]sentBeat
+
+	mu     sync.Mutex
+	access map[uint]accessEntry
+}
+
+func newClient(hub *Hub, conn *websocket.Conn, userID uint) *client {
+	id := make([]byte, 12)
+	rand.Read(id)
+	return &client{
+		hub:    hub,
+		conn:   conn,
+		id:     hex.EncodeToString(id),
+		userID: userID,
+		send:   make(chan []byte, sendBuffer),
+		done:   make(chan struct{}),
+		subs:   map[uint]bool{},
+		beats:  map[uint]sentBeat{},
+		access: map[uint]accessEntry{},
+	}
+}
+
+// push queues msg without blocking; a client whose queue is full is
+// disconnected rather than holding up everyone else.
+func (c *client) push(msg []byte) {
+	select {
+	case <-c.done:
+	case c.send <- msg:
+	default:
+		c.close()
+	}
+}
+
+func (c *client) reply(m serverMessage) {
+	msg, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+	c.push(msg)
+}
+
+func (c *client) close() {
+	c.once.Do(func() { close(c.done) })
+}
+
+func (c *client) cachedAccess(pageID uint) (e accessEntry, fresh bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, found := c.access[pageID]
+	if !found || time.Since(e.checked) > accessTTL {
+		return accessEntry{}, false
+	}
+	return e, true
+}
+
+func (c *client) rememberAccess(pageID uint, read, edit bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.access[pageID] = accessEntry{read: read, edit: edit, checked: time.Now()}
+}
+
+// readPump handles client messages until the connection fails or closes.
+func (c *client) readPump() {
+	defer func() {
+		c.leavePages(c.openPages())
+		c.hub.remove(c)
+		c.close()
+	}()
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		var m clientMessage
+		if err := c.conn.ReadJSON(&m); err != nil {
+			if _, ok := err.(*json.SyntaxError); ok {
+				c.reply(serverMessage{Type: "error", Error: "invalid message"})
+				continue
+			}
+			return
+		}
+		c.handle(m)
+	}
+}
+
+func (c *client) handle(m clientMessage) {
+	switch m.Action {
+	case "subscribe":
+		if c.hub.subscriptions(c)+len(m.PageIDs) > maxSubscriptions {
+			c.reply(serverMessage{Type: "error", Error: "too many subscriptions"})
+			return
+		}
+		joined, denied := c.hub.join(c, m.PageIDs)
+		c.reply(serverMessage{Type: "subscribed", PageIDs: joined, Denied: denied})
+	case "unsubscribe":
+		c.hub.leave(c, m.PageIDs...)
+		c.reply(serverMessage{Type: "unsubscribed", PageIDs: m.PageIDs})
+	case "heartbeat":
+		c.heartbeat(m.PageIDs, m.Mode)
+	case "leave":
+		c.leavePages(m.PageIDs)
+	default:
+		c.reply(serverMessage{Type: "error", Error: "unknown action"})
+	}
+}
+
+// heartbeat marks the user present on pageIDs. Unchanged heartbeats are
+// passed on at most every beatThrottle, which stays well inside
+// presenceTTL. Users who cannot edit a page are shown as viewing it
+// whatever mode they claim.
+func (c *client) heartbeat(pageIDs []uint, mode Mode) {
+	if mode == "" {
+		mode = ModeViewing
+	}
+	if mode != ModeViewing && mode != ModeEditing {
+		c.reply(serverMessage{Type: "error", Error: "invalid mode"})
+		return
+	}
+	now := time.Now()
+	var denied []uint
+	for _, id := range pageIDs {
+		last, open := c.beats[id]
+		if !open && len(c.beats) >= maxSubscriptions {
+			c.reply(serverMessage{Type: "error", Error: "too many open pages"})
+			return
+		}
+		if open && last.mode == mode && now.Sub(last.at) < beatThrottle {
+			continue
+		}
+		read, edit := c.hub.access(c, id)
+		if !read {
+			denied = append(denied, id)
+			continue
+		}
+		shown := mode
+		if !edit {
+			shown = ModeViewing
+		}
+		c.beats[id] = sentBeat{mode: mode, at: now}
+		c.hub.presence.publish(beat{PageID: id, UserID: c.userID, Conn: c.id, Mode: shown})
+	}
+	if len(denied) > 0 {
+		c.reply(serverMessage{Type: "error", Error: "page not found", Denied: denied})
+	}
+}
+
+func (c *client) leavePages(pageIDs []uint) {
+	for _, id := range pageIDs {
+		if _, open := c.beats[id]; !open {
+			continue
+		}
+		delete(c.beats, id)
+		c.hub.presence.publish(beat{PageID: id, UserID: c.userID, Conn: c.id, Leave: true})
+	}
+}
+
+func (c *client) openPages() []uint {
+	ids := make([]uint, 0, len(c.beats))
+	for id := range c.beats {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// writePump sends queued messages and keepalive pings. It owns all writes
+// to the connection and closes it on the way out.
+func (c *client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				c.close()
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.close()
+				return
+			}
+		case <-c.done:
+			c.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+				time.Now().Add(writeWait))
+			return
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package realtime
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"flowboard-backend-go/internal/middleware"
+	"flowboard-backend-go/internal/pages"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+)
+
+// TokenVerifier validates access tokens; satisfied by
+// middleware.JWTManager.
+type TokenVerifier interface {
+	Verify(token string) (*jwt.RegisteredClaims, error)
+}
+
+type Handler struct {
+	hub      *Hub
+	auth     *Authenticator
+	upgrader websocket.Upgrader
+}
+
+func NewHandler(hub *Hub, auth *Authenticator, allowOrigin func(origin string) bool) *Handler {
+	return &Handler{hub: hub, auth: auth, upgrader: NewUpgrader(allowOrigin)}
+}
+
+// NewUpgrader builds a WebSocket upgrader that applies the CORS origin
+// policy to browser handshakes. Requests without an Origin header come
+// from non-browser clients and are accepted.
+func NewUpgrader(allowOrigin func(origin string) bool) websocket.Upgrader {
+	return websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			return origin == "" || allowOrigin(origin)
+		},
+	}
+}
+
+// getUserID safely retrieves user ID from context
+func getUserID(c *gin.Context) (uint, error) {
+	uidVal, exists := c.Get(middleware.ContextUserIDKey)
+	if !exists {
+		return 0, fmt.Errorf("unauthorized")
+	}
+
+	uid, ok := uidVal.(uint)
+	if !ok {
+		return 0, fmt.Errorf("invalid user ID type")
+	}
+
+	return uid, nil
+}
+
+// GetPresence lists who has the page open, on any replica.
+func (h *Handler) GetPresence(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	id := uint(id64)
+
+	if _, err := h.hub.pages.GetPageByID(id, userID); err != nil {
+		switch {
+		case errors.Is(err, pages.ErrPageNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, pages.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": h.hub.Presence(id)})
+}
+
+// IssueTicket hands out a single-use ticket for opening a WebSocket or
+// event stream from a browser.
+func (h *Handler) IssueTicket(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	ticket, expiresAt, err := h.auth.IssueTicket(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": gin.H{"ticket": ticket, "expiresAt": expiresAt}})
+}
+
+// ServeWS upgrades an authenticated request to a page event stream.
+func (h *Handler) ServeWS(c *gin.Context) {
+	userID, ok := h.auth.Authenticate(c)
+	if !ok {
+		return
+	}
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already written the error response.
+		return
+	}
+	client := newClient(h.hub, conn, userID)
+	go client.writePump()
+	go client.readPump()
+}
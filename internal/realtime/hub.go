@@ -0,0 +1,274 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"flowboard-backend-go/internal/pages"
+	"flowboard-backend-go/internal/workspaces"
+	"flowboard-backend-go/pkg/logger"
+	"flowboard-backend-go/pkg/pubsub"
+)
+
+const (
+	// eventBuffer is how many events may wait for dispatch before new ones
+	// are dropped.
+	eventBuffer = 256
+	// accessTTL is how long a client's read access to a page is trusted
+	// before it is checked again, so revoked members stop receiving events.
+	accessTTL = time.Minute
+)
+
+// PageLookup resolves pages with access checks; satisfied by pages.Service.
+type PageLookup interface {
+	GetPageByID(id, userID uint) (*pages.Page, error)
+}
+
+// WorkspaceAccess reports workspace membership; satisfied by
+// workspaces.Service.
+type WorkspaceAccess interface {
+	MemberRole(workspaceID, userID uint) (workspaces.Role, error)
+}
+
+// Hub routes page events to the WebSocket clients subscribed to them.
+// Each page has a room; a client joins the rooms of the pages it
+// subscribes to. Creates and deletes are also sent to the parent page's
+// room so open trees can update.
+type Hub struct {
+	pages       PageLookup
+	workspaces  WorkspaceAccess
+	presence    *presence
+	events      chan pages.PageEvent
+	unsubscribe func()
+
+	mu    sync.RWMutex
+	rooms map[uint]map[*client]struct{}
+}
+
+// NewHub subscribes to page events and presence on ps. Call Run to start
+// dispatching.
+func NewHub(ps pubsub.PubSub, lookup PageLookup, access WorkspaceAccess) *Hub {
+	h := &Hub{
+		pages:      lookup,
+		workspaces: access,
+		presence:   newPresence(ps),
+		events:     make(chan pages.PageEvent, eventBuffer),
+		rooms:      map[uint]map[*client]struct{}{},
+	}
+	h.unsubscribe = ps.Subscribe(PagesTopic, h.receive)
+	return h
+}
+
+// Run dispatches events until ctx is done, then disconnects every client.
+func (h *Hub) Run(ctx context.Context) {
+	sweep := time.NewTicker(presenceSweep)
+	defer sweep.Stop()
+	defer h.shutdown()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e := <-h.events:
+			h.dispatch(e)
+		case e := <-h.presence.changes:
+			h.dispatchPresence(e)
+		case <-sweep.C:
+			h.presence.expire()
+		}
+	}
+}
+
+// Presence lists who has pageID open on any replica.
+func (h *Hub) Presence(pageID uint) []PresenceEntry {
+	return h.presence.List(pageID)
+}
+
+// receive is the pub/sub handler; it must not block.
+func (h *Hub) receive(payload []byte) {
+	var e pages.PageEvent
+	if err := json.Unmarshal(payload, &e); err != nil {
+		logger.Log.Warnw("Ignoring malformed page event", "error", err)
+		return
+	}
+	select {
+	case h.events <- e:
+	default:
+		logger.Log.Warnw("Page event dropped, dispatch is behind", "pageID", e.PageID, "type", e.Type)
+	}
+}
+
+func (h *Hub) dispatch(e pages.PageEvent) {
+	msg, err := json.Marshal(e)
+	if err != nil {
+		logger.Log.Errorw("Encoding page event failed", "pageID", e.PageID, "error", err)
+		return
+	}
+
+	rooms := []uint{e.PageID}
+	if e.ParentID != nil && (e.Type == pages.PageCreated || e.Type == pages.PageDeleted) {
+		rooms = append(rooms, *e.ParentID)
+	}
+
+	sent := map[*client]bool{}
+	for _, roomID := range rooms {
+		for _, c := range h.members(roomID) {
+			if sent[c] {
+				continue
+			}
+			// A deleted page can no longer be checked; its subscribers had
+			// access when they joined.
+			if !(e.Type == pages.PageDeleted && roomID == e.PageID) && !h.canRead(c, roomID) {
+				continue
+			}
+			sent[c] = true
+			c.push(msg)
+		}
+	}
+
+	if e.Type == pages.PageDeleted {
+		h.closeRoom(e.PageID)
+	}
+}
+
+// dispatchPresence tells the page's subscribers that someone arrived,
+// left or switched between viewing and editing.
+func (h *Hub) dispatchPresence(e PresenceEvent) {
+	msg, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	for _, c := range h.members(e.PageID) {
+		if h.canRead(c, e.PageID) {
+			c.push(msg)
+		}
+	}
+}
+
+func (h *Hub) members(pageID uint) []*client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	list := make([]*client, 0, len(h.rooms[pageID]))
+	for c := range h.rooms[pageID] {
+		list = append(list, c)
+	}
+	return list
+}
+
+// canRead reports whether c may still see pageID.
+func (h *Hub) canRead(c *client, pageID uint) bool {
+	read, _ := h.access(c, pageID)
+	return read
+}
+
+// access reports whether c may still see pageID and whether it may edit
+// it, consulting the page service at most once per accessTTL.
+func (h *Hub) access(c *client, pageID uint) (read, edit bool) {
+	if e, fresh := c.cachedAccess(pageID); fresh {
+		return e.read, e.edit
+	}
+	page, err := h.pages.GetPageByID(pageID, c.userID)
+	if err == nil {
+		read, edit = true, h.canEdit(page, c.userID)
+	}
+	c.rememberAccess(pageID, read, edit)
+	if !read {
+		h.leave(c, pageID)
+	}
+	return read, edit
+}
+
+// canEdit reports whether userID may change page: its owner for personal
+// pages, an editor or above for workspace pages.
+func (h *Hub) canEdit(page *pages.Page, userID uint) bool {
+	if page.WorkspaceID == nil {
+		return page.UserID == userID
+	}
+	role, err := h.workspaces.MemberRole(*page.WorkspaceID, userID)
+	if err != nil {
+		logger.Log.Errorw("Checking workspace role failed", "pageID", page.ID, "error", err)
+		return false
+	}
+	return role.AtLeast(workspaces.RoleEditor)
+}
+
+// join adds c to the rooms of pageIDs it can read and returns the IDs it
+// joined and those it was refused.
+func (h *Hub) join(c *client, pageIDs []uint) (joined, denied []uint) {
+	for _, id := range pageIDs {
+		page, err := h.pages.GetPageByID(id, c.userID)
+		if err != nil {
+			denied = append(denied, id)
+			continue
+		}
+		c.rememberAccess(id, true, h.canEdit(page, c.userID))
+		h.mu.Lock()
+		if h.rooms[id] == nil {
+			h.rooms[id] = map[*client]struct{}{}
+		}
+		h.rooms[id][c] = struct{}{}
+		c.subs[id] = true
+		h.mu.Unlock()
+		joined = append(joined, id)
+	}
+	return joined, denied
+}
+
+func (h *Hub) leave(c *client, pageIDs ...uint) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, id := range pageIDs {
+		h.removeLocked(c, id)
+	}
+}
+
+// subscriptions returns how many pages c is subscribed to.
+func (h *Hub) subscriptions(c *client) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(c.subs)
+}
+
+// remove takes c out of every room.
+func (h *Hub) remove(c *client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for id := range c.subs {
+		h.removeLocked(c, id)
+	}
+}
+
+func (h *Hub) closeRoom(pageID uint) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.rooms[pageID] {
+		delete(c.subs, pageID)
+	}
+	delete(h.rooms, pageID)
+}
+
+func (h *Hub) removeLocked(c *client, pageID uint) {
+	delete(c.subs, pageID)
+	delete(h.rooms[pageID], c)
+	if len(h.rooms[pageID]) == 0 {
+		delete(h.rooms, pageID)
+	}
+}
+
+func (h *Hub) shutdown() {
+	h.unsubscribe()
+	h.presence.close()
+	h.mu.Lock()
+	clients := map[*client]struct{}{}
+	for _, room := range h.rooms {
+		for c := range room {
+			clients[c] = struct{}{}
+		}
+	}
+	h.rooms = map[uint]map[*client]struct{}{}
+	h.mu.Unlock()
+	for c := range clients {
+		c.close()
+	}
+}
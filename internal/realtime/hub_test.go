@@ -0,0 +1,187 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"flowboard-backend-go/internal/middleware"
+	"flowboard-backend-go/internal/pages"
+	"flowboard-backend-go/internal/workspaces"
+	"flowboard-backend-go/pkg/pubsub"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubPages grants access per (pageID, userID) to pages of workspace
+// stubWorkspace, where everyone but viewers is an editor. forbidden
+// pages exist but are closed to everyone.
+type stubPages struct {
+	mu        sync.Mutex
+	access    map[[2]uint]bool
+	viewers   map[uint]bool
+	forbidden map[uint]bool
+}
+
+const stubWorkspace = uint(7)
+
+func (s *stubPages) GetPageByID(id, userID uint) (*pages.Page, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.forbidden[id] {
+		return nil, pages.ErrForbidden
+	}
+	if !s.access[[2]uint{id, userID}] {
+		return nil, pages.ErrPageNotFound
+	}
+	ws := stubWorkspace
+	return &pages.Page{ID: id, WorkspaceID: &ws}, nil
+}
+
+func (s *stubPages) MemberRole(workspaceID, userID uint) (workspaces.Role, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.viewers[userID] {
+		return workspaces.RoleViewer, nil
+	}
+	return workspaces.RoleEditor, nil
+}
+
+// stubTokens accepts tokens of the form "user-<id>".
+type stubTokens struct{}
+
+func (stubTokens) Verify(token string) (*jwt.RegisteredClaims, error) {
+	id, ok := strings.CutPrefix(token, "user-")
+	if !ok {
+		return nil, errors.New("invalid token")
+	}
+	return &jwt.RegisteredClaims{Subject: id}, nil
+}
+
+func newTestServer(t *testing.T, lookup *stubPages) (*httptest.Server, *Publisher) {
+	t.Helper()
+	ps := pubsub.NewLocal()
+	srv, _ := serve(t, ps, lookup)
+	return srv, NewPublisher(ps)
+}
+
+// serve runs a hub on ps behind a test server, as one replica would.
+func serve(t *testing.T, ps pubsub.PubSub, lookup *stubPages) (*httptest.Server, *Hub) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	hub := NewHub(ps, lookup, lookup)
+	ctx, cancel := context.WithCancel(context.Background())
+	go hub.Run(ctx)
+
+	r := gin.New()
+	h := NewHandler(hub, NewAuthenticator(stubTokens{}, nil), func(origin string) bool { return origin == "https://app.example" })
+	r.GET("/ws", h.ServeWS)
+	r.GET("/pages/:id/presence", func(c *gin.Context) {
+		uid, _ := strconv.ParseUint(c.Query("as"), 10, 32)
+		c.Set(middleware.ContextUserIDKey, uint(uid))
+	}, h.GetPresence)
+	srv := httptest.NewServer(r)
+	t.Cleanup(func() {
+		cancel()
+		srv.Close()
+	})
+	return srv, hub
+}
+
+func dial(t *testing.T, srv *httptest.Server, userID uint) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+	header := http.Header{"Authorization": {"Bearer user-" + strconv.Itoa(int(userID))}}
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func readJSON(t *testing.T, conn *websocket.Conn) map[string]any {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var m map[string]any
+	require.NoError(t, conn.ReadJSON(&m))
+	return m
+}
+
+func TestHubDeliversSubscribedPageEvents(t *testing.T) {
+	lookup := &stubPages{access: map[[2]uint]bool{{10, 1}: true, {10, 2}: true}}
+	srv, pub := newTestServer(t, lookup)
+
+	alice := dial(t, srv, 1)
+	require.NoError(t, alice.WriteJSON(clientMessage{Action: "subscribe", PageIDs: []uint{10, 99}}))
+	ack := readJSON(t, alice)
+	assert.Equal(t, "subscribed", ack["type"])
+	assert.Equal(t, []any{float64(10)}, ack["pageIds"])
+	assert.Equal(t, []any{float64(99)}, ack["denied"])
+
+	bob := dial(t, srv, 2)
+	require.NoError(t, bob.WriteJSON(clientMessage{Action: "subscribe", PageIDs: []uint{10}}))
+	assert.Equal(t, "subscribed", readJSON(t, bob)["type"])
+
+	parent := uint(10)
+	require.NoError(t, pub.HandlePageEvent(context.Background(), 0, pages.PageEvent{Type: pages.PageUpdated, PageID: 20}))
+	require.NoError(t, pub.HandlePageEvent(context.Background(), 0, pages.PageEvent{Type: pages.PageCreated, PageID: 11, ParentID: &parent, ActorID: 2}))
+	require.NoError(t, pub.HandlePageEvent(context.Background(), 0, pages.PageEvent{Type: pages.PageUpdated, PageID: 10, Title: "Roadmap"}))
+
+	// Unrelated pages are filtered out; children show up in the parent's
+	// room.
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		e := readJSON(t, conn)
+		assert.Equal(t, "page.created", e["type"])
+		assert.Equal(t, float64(11), e["pageId"])
+		e = readJSON(t, conn)
+		assert.Equal(t, "page.updated", e["type"])
+		assert.Equal(t, "Roadmap", e["title"])
+	}
+
+	require.NoError(t, bob.WriteJSON(clientMessage{Action: "unsubscribe", PageIDs: []uint{10}}))
+	assert.Equal(t, "unsubscribed", readJSON(t, bob)["type"])
+
+	require.NoError(t, pub.HandlePageEvent(context.Background(), 0, pages.PageEvent{Type: pages.PageDeleted, PageID: 10}))
+	assert.Equal(t, "page.deleted", readJSON(t, alice)["type"])
+
+	bob.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	_, _, err := bob.ReadMessage()
+	assert.Error(t, err, "unsubscribed clients get nothing")
+}
+
+func TestServeWSRejectsBadHandshakes(t *testing.T) {
+	srv, _ := newTestServer(t, &stubPages{})
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	_, resp, err = websocket.DefaultDialer.Dial(url, http.Header{"Authorization": {"Bearer forged"}})
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// Access tokens are not accepted in the URL, where they would be logged.
+	_, resp, err = websocket.DefaultDialer.Dial(url+"?token=user-1", nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	header := http.Header{"Origin": {"https://evil.example"}, "Authorization": {"Bearer user-1"}}
+	_, resp, err = websocket.DefaultDialer.Dial(url, header)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	header = http.Header{"Origin": {"https://app.example"}, "Authorization": {"Bearer user-1"}}
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	require.NoError(t, err)
+	conn.Close()
+}
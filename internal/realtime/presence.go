@@ -0,0 +1,234 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"flowboard-backend-go/pkg/logger"
+	"flowboard-backend-go/pkg/pubsub"
+)
+
+// PresenceTopic carries presence heartbeats between replicas.
+const PresenceTopic = "presence"
+
+const (
+	// presenceTTL is how long a heartbeat keeps a connection present.
+	// Clients send one every 15 seconds per open page.
+	presenceTTL = 45 * time.Second
+	// beatThrottle bounds how often one connection's unchanged heartbeat
+	// for a page is passed on to other replicas.
+	beatThrottle = 10 * time.Second
+	// presenceSweep is how often expired entries are cleared.
+	presenceSweep = 5 * time.Second
+)
+
+type Mode string
+
+const (
+	ModeViewing Mode = "viewing"
+	ModeEditing Mode = "editing"
+)
+
+// PresenceEntry is one user who has a page open. A user with the page
+// open in several places counts once, as editing if any of them is.
+type PresenceEntry struct {
+	UserID   uint      `json:"userId"`
+	Mode     Mode      `json:"mode"`
+	Since    time.Time `json:"since"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// Presence event types sent to clients subscribed to the page.
+const (
+	PresenceJoined  = "presence.joined"
+	PresenceLeft    = "presence.left"
+	PresenceChanged = "presence.changed"
+)
+
+type PresenceEvent struct {
+	Type   string `json:"type"`
+	PageID uint   `json:"pageId"`
+	UserID uint   `json:"userId"`
+	Mode   Mode   `json:"mode,omitempty"`
+}
+
+// beat is what replicas exchange: a connection is on a page, or has left
+// it when Leave is set.
+type beat struct {
+	PageID uint   `json:"pageId"`
+	UserID uint   `json:"userId"`
+	Conn   string `json:"conn"`
+	Mode   Mode   `json:"mode,omitempty"`
+	Leave  bool   `json:"leave,omitempty"`
+}
+
+type presenceConn struct {
+	userID   uint
+	mode     Mode
+	since    time.Time
+	lastSeen time.Time
+}
+
+// presence tracks who has which page open across all replicas. Every
+// replica publishes its connections' heartbeats and builds the same
+// picture from everyone's, timing entries by its own clock. A replica
+// that has just started learns about existing viewers with their next
+// heartbeat.
+type presence struct {
+	ps          pubsub.PubSub
+	now         func() time.Time
+	changes     chan PresenceEvent
+	unsubscribe func()
+
+	mu    sync.Mutex
+	pages map[uint]map[string]*presenceConn
+}
+
+func newPresence(ps pubsub.PubSub) *presence {
+	p := &presence{
+		ps:      ps,
+		now:     time.Now,
+		changes: make(chan PresenceEvent, eventBuffer),
+		pages:   map[uint]map[string]*presenceConn{},
+	}
+	p.unsubscribe = ps.Subscribe(PresenceTopic, p.receive)
+	return p
+}
+
+// publish announces b to every replica, this one included.
+func (p *presence) publish(b beat) {
+	payload, err := json.Marshal(b)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+	if err := p.ps.Publish(ctx, PresenceTopic, payload); err != nil {
+		logger.Log.Errorw("Publishing presence failed", "pageID", b.PageID, "error", err)
+	}
+}
+
+// receive is the pub/sub handler; it must not block.
+func (p *presence) receive(payload []byte) {
+	var b beat
+	if err := json.Unmarshal(payload, &b); err != nil {
+		logger.Log.Warnw("Ignoring malformed presence beat", "error", err)
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	before, had := p.entryLocked(b.PageID, b.UserID)
+	conns := p.pages[b.PageID]
+	if b.Leave {
+		delete(conns, b.Conn)
+		if len(conns) == 0 {
+			delete(p.pages, b.PageID)
+		}
+	} else {
+		if conns == nil {
+			conns = map[string]*presenceConn{}
+			p.pages[b.PageID] = conns
+		}
+		now := p.now()
+		c := conns[b.Conn]
+		if c == nil {
+			c = &presenceConn{userID: b.UserID, since: now}
+			conns[b.Conn] = c
+		}
+		c.mode = b.Mode
+		c.lastSeen = now
+	}
+	p.noteLocked(b.PageID, b.UserID, before, had)
+}
+
+// expire drops connections whose heartbeats stopped.
+func (p *presence) expire() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	cutoff := p.now().Add(-presenceTTL)
+	for pageID, conns := range p.pages {
+		for key, c := range conns {
+			if c.lastSeen.Before(cutoff) {
+				before, had := p.entryLocked(pageID, c.userID)
+				delete(conns, key)
+				p.noteLocked(pageID, c.userID, before, had)
+			}
+		}
+		if len(conns) == 0 {
+			delete(p.pages, pageID)
+		}
+	}
+}
+
+// List returns who has pageID open, longest present first.
+func (p *presence) List(pageID uint) []PresenceEntry {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	seen := map[uint]bool{}
+	list := []PresenceEntry{}
+	for _, c := range p.pages[pageID] {
+		if !seen[c.userID] {
+			seen[c.userID] = true
+			e, _ := p.entryLocked(pageID, c.userID)
+			list = append(list, e)
+		}
+	}
+	slices.SortFunc(list, func(a, b PresenceEntry) int {
+		if c := a.Since.Compare(b.Since); c != 0 {
+			return c
+		}
+		return int(a.UserID) - int(b.UserID)
+	})
+	return list
+}
+
+// entryLocked aggregates the user's connections to pageID.
+func (p *presence) entryLocked(pageID, userID uint) (PresenceEntry, bool) {
+	e := PresenceEntry{UserID: userID}
+	found := false
+	for _, c := range p.pages[pageID] {
+		if c.userID != userID {
+			continue
+		}
+		if !found || c.since.Before(e.Since) {
+			e.Since = c.since
+		}
+		if c.lastSeen.After(e.LastSeen) {
+			e.LastSeen = c.lastSeen
+		}
+		if c.mode == ModeEditing || e.Mode == "" {
+			e.Mode = c.mode
+		}
+		found = true
+	}
+	return e, found
+}
+
+// noteLocked queues the event, if any, for the user's change on pageID.
+func (p *presence) noteLocked(pageID, userID uint, before PresenceEntry, had bool) {
+	after, has := p.entryLocked(pageID, userID)
+	e := PresenceEvent{PageID: pageID, UserID: userID, Mode: after.Mode}
+	switch {
+	case !had && has:
+		e.Type = PresenceJoined
+	case had && !has:
+		e.Type, e.Mode = PresenceLeft, ""
+	case had && before.Mode != after.Mode:
+		e.Type = PresenceChanged
+	default:
+		return
+	}
+	select {
+	case p.changes <- e:
+	default:
+		logger.Log.Warnw("Presence event dropped, dispatch is behind", "pageID", pageID)
+	}
+}
+
+func (p *presence) close() {
+	p.unsubscribe()
+}
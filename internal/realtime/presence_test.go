@@ -0,0 +1,125 @@
+package realtime
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"flowboard-backend-go/pkg/pubsub"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func drain(p *presence) []PresenceEvent {
+	var list []PresenceEvent
+	for {
+		select {
+		case e := <-p.changes:
+			list = append(list, e)
+		default:
+			return list
+		}
+	}
+}
+
+func TestPresenceAggregatesConnectionsAndExpires(t *testing.T) {
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	p := newPresence(pubsub.NewLocal())
+	p.now = func() time.Time { return now }
+
+	p.publish(beat{PageID: 1, UserID: 5, Conn: "tab-a", Mode: ModeViewing})
+	now = now.Add(time.Second)
+	p.publish(beat{PageID: 1, UserID: 5, Conn: "tab-b", Mode: ModeEditing})
+	p.publish(beat{PageID: 1, UserID: 6, Conn: "tab-c", Mode: ModeViewing})
+
+	assert.Equal(t, []PresenceEvent{
+		{Type: PresenceJoined, PageID: 1, UserID: 5, Mode: ModeViewing},
+		{Type: PresenceChanged, PageID: 1, UserID: 5, Mode: ModeEditing},
+		{Type: PresenceJoined, PageID: 1, UserID: 6, Mode: ModeViewing},
+	}, drain(p))
+
+	list := p.List(1)
+	require.Len(t, list, 2)
+	assert.Equal(t, uint(5), list[0].UserID, "longest present first")
+	assert.Equal(t, ModeEditing, list[0].Mode)
+
+	// Closing one of two tabs leaves the user present.
+	p.publish(beat{PageID: 1, UserID: 5, Conn: "tab-b", Leave: true})
+	assert.Equal(t, []PresenceEvent{{Type: PresenceChanged, PageID: 1, UserID: 5, Mode: ModeViewing}}, drain(p))
+
+	// Only user 6 keeps sending heartbeats.
+	now = now.Add(30 * time.Second)
+	p.publish(beat{PageID: 1, UserID: 6, Conn: "tab-c", Mode: ModeViewing})
+	now = now.Add(20 * time.Second)
+	p.expire()
+	assert.Equal(t, []PresenceEvent{{Type: PresenceLeft, PageID: 1, UserID: 5}}, drain(p))
+	assert.Len(t, p.List(1), 1)
+}
+
+func TestPresenceIsSharedAcrossReplicas(t *testing.T) {
+	lookup := &stubPages{access: map[[2]uint]bool{{10, 1}: true, {10, 2}: true}}
+	ps := pubsub.NewLocal()
+	srvA, _ := serve(t, ps, lookup)
+	srvB, _ := serve(t, ps, lookup)
+
+	bob := dial(t, srvB, 2)
+	require.NoError(t, bob.WriteJSON(clientMessage{Action: "subscribe", PageIDs: []uint{10}}))
+	assert.Equal(t, "subscribed", readJSON(t, bob)["type"])
+
+	alice := dial(t, srvA, 1)
+	require.NoError(t, alice.WriteJSON(clientMessage{Action: "heartbeat", PageIDs: []uint{10}, Mode: ModeEditing}))
+	e := readJSON(t, bob)
+	assert.Equal(t, PresenceJoined, e["type"])
+	assert.Equal(t, float64(1), e["userId"])
+	assert.Equal(t, "editing", e["mode"])
+
+	resp, err := http.Get(srvB.URL + "/pages/10/presence?as=2")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var body struct {
+		Data []PresenceEntry `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.Len(t, body.Data, 1)
+	assert.Equal(t, uint(1), body.Data[0].UserID)
+
+	outsider, err := http.Get(srvB.URL + "/pages/10/presence?as=3")
+	require.NoError(t, err)
+	outsider.Body.Close()
+	assert.Equal(t, http.StatusNotFound, outsider.StatusCode)
+
+	lookup.mu.Lock()
+	lookup.forbidden = map[uint]bool{10: true}
+	lookup.mu.Unlock()
+	forbidden, err := http.Get(srvB.URL + "/pages/10/presence?as=2")
+	require.NoError(t, err)
+	forbidden.Body.Close()
+	assert.Equal(t, http.StatusForbidden, forbidden.StatusCode)
+
+	alice.Close()
+	e = readJSON(t, bob)
+	assert.Equal(t, PresenceLeft, e["type"])
+	assert.Equal(t, float64(1), e["userId"])
+}
+
+func TestPresenceShowsReadOnlyMembersAsViewing(t *testing.T) {
+	lookup := &stubPages{
+		access:  map[[2]uint]bool{{10, 1}: true, {10, 2}: true},
+		viewers: map[uint]bool{2: true},
+	}
+	srv, _ := serve(t, pubsub.NewLocal(), lookup)
+
+	alice := dial(t, srv, 1)
+	require.NoError(t, alice.WriteJSON(clientMessage{Action: "subscribe", PageIDs: []uint{10}}))
+	assert.Equal(t, "subscribed", readJSON(t, alice)["type"])
+
+	viewer := dial(t, srv, 2)
+	require.NoError(t, viewer.WriteJSON(clientMessage{Action: "heartbeat", PageIDs: []uint{10}, Mode: ModeEditing}))
+	e := readJSON(t, alice)
+	assert.Equal(t, PresenceJoined, e["type"])
+	assert.Equal(t, float64(2), e["userId"])
+	assert.Equal(t, "viewing", e["mode"])
+}
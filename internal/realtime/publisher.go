@@ -0,0 +1,38 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"flowboard-backend-go/internal/pages"
+	"flowboard-backend-go/pkg/pubsub"
+)
+
+// PagesTopic carries pages.PageEvent messages between replicas.
+const PagesTopic = "pages"
+
+// publishTimeout bounds how long relaying an event waits on the pub/sub
+// backend.
+const publishTimeout = 5 * time.Second
+
+// Publisher forwards page events from the outbox to the pub/sub backend;
+// it satisfies pages.EventHandler. A redelivered event reaches clients
+// twice, which is harmless: events carry the page version.
+type Publisher struct {
+	ps pubsub.PubSub
+}
+
+func NewPublisher(ps pubsub.PubSub) *Publisher {
+	return &Publisher{ps: ps}
+}
+
+func (p *Publisher) HandlePageEvent(ctx context.Context, _ uint64, e pages.PageEvent) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+	return p.ps.Publish(ctx, PagesTopic, payload)
+}
@@ -0,0 +1,146 @@
+package realtime
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ticketTTL is how long a stream ticket stays valid. Clients fetch one
+// right before opening the connection.
+const ticketTTL = 30 * time.Second
+
+// Ticket is a single-use credential for opening a stream from a browser,
+// which cannot send an Authorization header on a WebSocket or EventSource
+// handshake. Tickets travel in the URL and so end up in access logs; they
+// expire quickly and only their hash is stored.
+type Ticket struct {
+	Hash      string    `gorm:"primaryKey;size:64"`
+	UserID    uint      `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+func (Ticket) TableName() string { return "stream_tickets" }
+
+// TicketRepository stores stream tickets. It is shared by every replica,
+// so a ticket issued by one can be redeemed on another.
+type TicketRepository interface {
+	// CreateTicket stores t and drops tickets that expired before now.
+	CreateTicket(t *Ticket, now time.Time) error
+	// TakeTicket deletes and returns the ticket with hash, or nil if there
+	// is none. Concurrent callers never both get the same ticket.
+	TakeTicket(hash string) (*Ticket, error)
+}
+
+type ticketRepository struct {
+	db *gorm.DB
+}
+
+func NewTicketRepository(db *gorm.DB) TicketRepository {
+	return &ticketRepository{db: db}
+}
+
+func (r *ticketRepository) CreateTicket(t *Ticket, now time.Time) error {
+	if err := r.db.Where("expires_at < ?", now).Delete(&Ticket{}).Error; err != nil {
+		return err
+	}
+	return r.db.Create(t).Error
+}
+
+func (r *ticketRepository) TakeTicket(hash string) (*Ticket, error) {
+	var taken []Ticket
+	if err := r.db.Clauses(clause.Returning{}).Where("hash = ?", hash).Delete(&taken).Error; err != nil {
+		return nil, err
+	}
+	if len(taken) == 0 {
+		return nil, nil
+	}
+	return &taken[0], nil
+}
+
+// Authenticator resolves the user of a WebSocket or SSE handshake from its
+// Authorization header or, for browsers, a stream ticket.
+type Authenticator struct {
+	tokens  TokenVerifier
+	tickets TicketRepository
+	now     func() time.Time
+}
+
+// NewAuthenticator accepts bearer tokens checked by tokens and, when
+// tickets is not nil, tickets issued by IssueTicket.
+func NewAuthenticator(tokens TokenVerifier, tickets TicketRepository) *Authenticator {
+	return &Authenticator{tokens: tokens, tickets: tickets, now: time.Now}
+}
+
+// IssueTicket creates a ticket for userID and returns it with its expiry.
+func (a *Authenticator) IssueTicket(userID uint) (string, time.Time, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, err
+	}
+	ticket := base64.RawURLEncoding.EncodeToString(buf)
+	now := a.now()
+	t := &Ticket{Hash: hashTicket(ticket), UserID: userID, ExpiresAt: now.Add(ticketTTL)}
+	if err := a.tickets.CreateTicket(t, now); err != nil {
+		return "", time.Time{}, err
+	}
+	return ticket, t.ExpiresAt, nil
+}
+
+// Authenticate resolves the user of a handshake, responding 401 when it
+// cannot. Browsers cannot set headers on the handshake, so they pass a
+// ticket as the "ticket" query parameter instead. Access tokens are never
+// accepted in the URL.
+func (a *Authenticator) Authenticate(c *gin.Context) (uint, bool) {
+	if ticket := c.Query("ticket"); ticket != "" {
+		return a.redeem(c, ticket)
+	}
+
+	parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "token required"})
+		return 0, false
+	}
+	claims, err := a.tokens.Verify(parts[1])
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return 0, false
+	}
+	userID, err := strconv.ParseUint(claims.Subject, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return 0, false
+	}
+	return uint(userID), true
+}
+
+func (a *Authenticator) redeem(c *gin.Context, ticket string) (uint, bool) {
+	if a.tickets == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid ticket"})
+		return 0, false
+	}
+	t, err := a.tickets.TakeTicket(hashTicket(ticket))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return 0, false
+	}
+	if t == nil || !a.now().Before(t.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid ticket"})
+		return 0, false
+	}
+	return t.UserID, true
+}
+
+func hashTicket(ticket string) string {
+	sum := sha256.Sum256([]byte(ticket))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,79 @@
+package realtime
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memTickets is an in-memory TicketRepository.
+type memTickets struct {
+	mu      sync.Mutex
+	tickets map[string]Ticket
+}
+
+func (r *memTickets) CreateTicket(t *Ticket, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for hash, old := range r.tickets {
+		if old.ExpiresAt.Before(now) {
+			delete(r.tickets, hash)
+		}
+	}
+	r.tickets[t.Hash] = *t
+	return nil
+}
+
+func (r *memTickets) TakeTicket(hash string) (*Ticket, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.tickets[hash]
+	if !ok {
+		return nil, nil
+	}
+	delete(r.tickets, hash)
+	return &t, nil
+}
+
+func TestTicketsAreSingleUseAndShortLived(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &memTickets{tickets: map[string]Ticket{}}
+	auth := NewAuthenticator(stubTokens{}, repo)
+	now := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	auth.now = func() time.Time { return now }
+
+	r := gin.New()
+	r.GET("/whoami", func(c *gin.Context) {
+		if userID, ok := auth.Authenticate(c); ok {
+			c.JSON(http.StatusOK, gin.H{"userId": userID})
+		}
+	})
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/whoami"+query, nil))
+		return w
+	}
+
+	ticket, expiresAt, err := auth.IssueTicket(4)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(ticketTTL), expiresAt)
+	assert.NotContains(t, repo.tickets, ticket, "only the hash is stored")
+
+	w := get("?ticket=" + ticket)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"userId":4`)
+	assert.Equal(t, http.StatusUnauthorized, get("?ticket="+ticket).Code, "a ticket works once")
+
+	ticket, _, err = auth.IssueTicket(4)
+	require.NoError(t, err)
+	now = now.Add(ticketTTL)
+	assert.Equal(t, http.StatusUnauthorized, get("?ticket="+ticket).Code, "expired")
+
+	assert.Equal(t, http.StatusUnauthorized, get("?token=user-4").Code)
+}
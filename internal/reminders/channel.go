@@ -0,0 +1,36 @@
+package reminders
+
+import (
+	"context"
+	"fmt"
+
+	"flowboard-backend-go/internal/notifications"
+)
+
+// NotificationChannel delivers reminders through the notification center,
+// which stores the in-app copy and emails the user if their preferences
+// ask for it.
+type NotificationChannel struct {
+	notifier notifications.Notifier
+}
+
+func NewNotificationChannel(n notifications.Notifier) *NotificationChannel {
+	return &NotificationChannel{notifier: n}
+}
+
+// Deliver keys the notification by reminder, so a redelivered reminder
+// is dropped by the notification center.
+func (c *NotificationChannel) Deliver(ctx context.Context, reminder *Reminder) error {
+	key := fmt.Sprintf("reminder:%d", reminder.ID)
+	n := &notifications.Notification{
+		UserID:   reminder.UserID,
+		Type:     notifications.TypeReminder,
+		Title:    fmt.Sprintf("Reminder: %s", reminder.Subject),
+		Body:     reminder.Note,
+		DedupKey: &key,
+	}
+	if reminder.TargetPageID != nil {
+		n.Link = fmt.Sprintf("/pages/%d", *reminder.TargetPageID)
+	}
+	return c.notifier.Notify(ctx, n)
+}
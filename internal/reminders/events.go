@@ -0,0 +1,61 @@
+package reminders
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"flowboard-backend-go/internal/outbox"
+)
+
+type EventType string
+
+// ReminderFired is recorded in the transaction that marks a reminder
+// fired; delivering it is left to the outbox relay.
+const ReminderFired EventType = "reminder.fired"
+
+// EventTypes lists every reminder event type, for subscribing to all of
+// them.
+var EventTypes = []string{string(ReminderFired)}
+
+// ReminderEvent carries a fired reminder with its computed fields as they
+// were when it was claimed.
+type ReminderEvent struct {
+	Type         EventType `json:"type"`
+	ReminderID   uint      `json:"reminderId"`
+	UserID       uint      `json:"userId"`
+	TargetPageID *uint     `json:"targetPageId,omitempty"`
+	Subject      string    `json:"subject"`
+	Note         string    `json:"note"`
+	At           time.Time `json:"at"`
+}
+
+// EventHandler consumes reminder events relayed from the outbox. An event
+// may arrive more than once, always with the same eventID.
+type EventHandler interface {
+	HandleReminderEvent(ctx context.Context, eventID uint64, e ReminderEvent) error
+}
+
+// Consume adapts h to an outbox handler; subscribe it to EventTypes.
+func Consume(h EventHandler) outbox.Handler {
+	return func(ctx context.Context, m *outbox.Message) error {
+		var e ReminderEvent
+		if err := json.Unmarshal([]byte(m.Payload), &e); err != nil {
+			return err
+		}
+		return h.HandleReminderEvent(ctx, m.ID, e)
+	}
+}
+
+// event describes reminder firing now.
+func (s *service) event(reminder *Reminder) ReminderEvent {
+	return ReminderEvent{
+		Type:         ReminderFired,
+		ReminderID:   reminder.ID,
+		UserID:       reminder.UserID,
+		TargetPageID: reminder.TargetPageID,
+		Subject:      reminder.Subject,
+		Note:         reminder.Note,
+		At:           s.now(),
+	}
+}
@@ -0,0 +1,39 @@
+package reminders
+
+import "time"
+
+// Reminder notifies UserID about a page or task. It fires either at the
+// absolute RemindAt or MinutesBefore the task's due date; relative reminders
+// follow the due date when it changes and never fire for undated tasks.
+type Reminder struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	UserID        uint       `gorm:"not null;index" json:"userId"`
+	PageID        *uint      `gorm:"index" json:"pageId,omitempty"`
+	TaskID        *uint      `gorm:"index" json:"taskId,omitempty"`
+	Note          string     `gorm:"size:500" json:"note"`
+	RemindAt      *time.Time `gorm:"index" json:"remindAt,omitempty"`
+	MinutesBefore *int       `json:"minutesBefore,omitempty"`
+	FiredAt       *time.Time `gorm:"index" json:"firedAt"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+
+	// FireAt is when the reminder is due, resolved against the task's
+	// current due date; nil when that date is unset.
+	FireAt *time.Time `gorm:"->;-:migration" json:"fireAt"`
+	// TargetPageID is the page the reminder leads to: PageID, or the
+	// task's page.
+	TargetPageID *uint `gorm:"->;-:migration" json:"targetPageId"`
+	// Subject is the title of the page or task, filled in on load.
+	Subject string `gorm:"->;-:migration" json:"subject"`
+}
+
+// ReminderInput for creating a reminder. Exactly one of PageID and TaskID
+// and exactly one of RemindAt and MinutesBefore must be set;
+// MinutesBefore requires TaskID.
+type ReminderInput struct {
+	PageID        *uint      `json:"pageId"`
+	TaskID        *uint      `json:"taskId"`
+	Note          string     `json:"note" binding:"max=500"`
+	RemindAt      *time.Time `json:"remindAt"`
+	MinutesBefore *int       `json:"minutesBefore" binding:"omitempty,min=0"`
+}
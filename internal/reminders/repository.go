@@ -0,0 +1,118 @@
+package reminders
+
+import (
+	"errors"
+	"time"
+
+	"flowboard-backend-go/internal/outbox"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	// Transaction runs fn with a Repository bound to a single database
+	// transaction; any error rolls everything back.
+	Transaction(fn func(repo Repository) error) error
+
+	CreateReminder(reminder *Reminder) error
+	GetReminderByID(id uint) (*Reminder, error)
+	// GetPendingByUser lists the user's unfired reminders, soonest first.
+	GetPendingByUser(userID uint) ([]Reminder, error)
+	DeleteReminder(id uint) error
+
+	// ClaimDue locks up to limit unfired reminders due at or before now.
+	// Rows locked by another transaction are skipped, so concurrent
+	// schedulers never claim the same reminder. Must run inside
+	// Transaction.
+	ClaimDue(now time.Time, limit int) ([]Reminder, error)
+	MarkFired(ids []uint, at time.Time) error
+	// AddEvent records a reminder event in the outbox. Call it inside
+	// Transaction so the event commits together with the claim.
+	AddEvent(e ReminderEvent) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// fireAt resolves when a reminder is due: its absolute time, or the
+// task's current due date less the offset.
+const fireAt = `CASE WHEN reminders.minutes_before IS NULL THEN reminders.remind_at
+	ELSE tasks.due_date - reminders.minutes_before * interval '1 minute' END`
+
+// withTargets joins the page or task a reminder points at and selects the
+// computed columns.
+func withTargets(db *gorm.DB) *gorm.DB {
+	return db.Table("reminders").
+		Select("reminders.*, " + fireAt + " AS fire_at, pages.id AS target_page_id, " +
+			"COALESCE(tasks.title, pages.title, '') AS subject").
+		Joins("LEFT JOIN tasks ON tasks.id = reminders.task_id").
+		Joins("LEFT JOIN pages ON pages.id = COALESCE(reminders.page_id, tasks.page_id)")
+}
+
+func (r *repository) Transaction(fn func(repo Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&repository{db: tx})
+	})
+}
+
+func (r *repository) CreateReminder(reminder *Reminder) error {
+	return r.db.Create(reminder).Error
+}
+
+func (r *repository) GetReminderByID(id uint) (*Reminder, error) {
+	var reminder Reminder
+	if err := r.db.Scopes(withTargets).Where("reminders.id = ?", id).Take(&reminder).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &reminder, nil
+}
+
+func (r *repository) GetPendingByUser(userID uint) ([]Reminder, error) {
+	var list []Reminder
+	err := r.db.Scopes(withTargets).
+		Where("reminders.user_id = ? AND reminders.fired_at IS NULL", userID).
+		Order("fire_at IS NULL, fire_at, reminders.id").
+		Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *repository) DeleteReminder(id uint) error {
+	return r.db.Delete(&Reminder{}, id).Error
+}
+
+func (r *repository) ClaimDue(now time.Time, limit int) ([]Reminder, error) {
+	var list []Reminder
+	err := r.db.Scopes(withTargets).
+		Where("reminders.fired_at IS NULL AND "+fireAt+" <= ?", now).
+		Order("fire_at, reminders.id").
+		Limit(limit).
+		Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "reminders"}, Options: "SKIP LOCKED"}).
+		Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *repository) MarkFired(ids []uint, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Model(&Reminder{}).Where("id IN ?", ids).UpdateColumn("fired_at", at).Error
+}
+
+func (r *repository) AddEvent(e ReminderEvent) error {
+	return outbox.Append(r.db, string(e.Type), e)
+}
@@ -0,0 +1,200 @@
+package reminders
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"flowboard-backend-go/internal/pages"
+	"flowboard-backend-go/internal/tasks"
+	"flowboard-backend-go/pkg/logger"
+)
+
+var (
+	ErrReminderNotFound  = errors.New("reminder not found")
+	ErrInvalidTarget     = errors.New("reminder needs exactly one of pageId and taskId")
+	ErrInvalidSchedule   = errors.New("reminder needs exactly one of remindAt and minutesBefore")
+	ErrRelativeNeedsTask = errors.New("minutesBefore is only allowed for task reminders")
+	ErrInThePast         = errors.New("remindAt must be in the future")
+)
+
+// fireBatch bounds how many reminders one scheduler transaction claims.
+const fireBatch = 100
+
+// PageLookup resolves pages with access checks; satisfied by pages.Service.
+type PageLookup interface {
+	GetPageByID(id, userID uint) (*pages.Page, error)
+}
+
+// TaskLookup resolves tasks with access checks; satisfied by tasks.Service.
+type TaskLookup interface {
+	GetTask(id, userID uint) (*tasks.Task, error)
+}
+
+// Channel delivers a fired reminder to its user. Deliver is called with
+// the reminder's computed fields loaded. A failed delivery is retried, so
+// Deliver may see the same reminder again and must not deliver it twice.
+type Channel interface {
+	Deliver(ctx context.Context, reminder *Reminder) error
+}
+
+type Service interface {
+	GetReminders(userID uint) ([]Reminder, error)
+	CreateReminder(input ReminderInput, userID uint) (*Reminder, error)
+	DeleteReminder(id, userID uint) error
+	// FireDue marks every reminder that has come due fired and queues a
+	// ReminderFired event for it in the same transaction. Several replicas
+	// may run it at once; each reminder is claimed by exactly one of them.
+	FireDue(ctx context.Context) (int, error)
+	// EventHandler delivers queued reminders through all channels.
+	EventHandler
+}
+
+type service struct {
+	repo     Repository
+	pages    PageLookup
+	tasks    TaskLookup
+	channels []Channel
+	now      func() time.Time
+}
+
+func NewService(repo Repository, pages PageLookup, tasks TaskLookup, channels ...Channel) Service {
+	return &service{repo: repo, pages: pages, tasks: tasks, channels: channels, now: time.Now}
+}
+
+func (s *service) GetReminders(userID uint) ([]Reminder, error) {
+	return s.repo.GetPendingByUser(userID)
+}
+
+func (s *service) CreateReminder(input ReminderInput, userID uint) (*Reminder, error) {
+	if (input.PageID == nil) == (input.TaskID == nil) {
+		return nil, ErrInvalidTarget
+	}
+	if (input.RemindAt == nil) == (input.MinutesBefore == nil) {
+		return nil, ErrInvalidSchedule
+	}
+	if input.MinutesBefore != nil && input.TaskID == nil {
+		return nil, ErrRelativeNeedsTask
+	}
+	if input.RemindAt != nil && !input.RemindAt.After(s.now()) {
+		return nil, ErrInThePast
+	}
+
+	if input.PageID != nil {
+		if _, err := s.pages.GetPageByID(*input.PageID, userID); err != nil {
+			return nil, err
+		}
+	} else if _, err := s.tasks.GetTask(*input.TaskID, userID); err != nil {
+		return nil, err
+	}
+
+	reminder := &Reminder{
+		UserID:        userID,
+		PageID:        input.PageID,
+		TaskID:        input.TaskID,
+		Note:          strings.TrimSpace(input.Note),
+		RemindAt:      input.RemindAt,
+		MinutesBefore: input.MinutesBefore,
+	}
+	if err := s.repo.CreateReminder(reminder); err != nil {
+		return nil, err
+	}
+	return s.repo.GetReminderByID(reminder.ID)
+}
+
+func (s *service) DeleteReminder(id, userID uint) error {
+	reminder, err := s.repo.GetReminderByID(id)
+	if err != nil {
+		return err
+	}
+	if reminder == nil || reminder.UserID != userID {
+		return ErrReminderNotFound
+	}
+	return s.repo.DeleteReminder(id)
+}
+
+func (s *service) FireDue(ctx context.Context) (int, error) {
+	fired := 0
+	for {
+		// Claim, mark and queue the batch in one short transaction. The
+		// relay delivers after commit, so slow channels never hold the row
+		// locks, and a failed delivery is retried rather than lost.
+		var due []Reminder
+		err := s.repo.Transaction(func(repo Repository) error {
+			var err error
+			if due, err = repo.ClaimDue(s.now(), fireBatch); err != nil {
+				return err
+			}
+			ids := make([]uint, len(due))
+			for i := range due {
+				ids[i] = due[i].ID
+			}
+			if err := repo.MarkFired(ids, s.now()); err != nil {
+				return err
+			}
+			for i := range due {
+				if err := repo.AddEvent(s.event(&due[i])); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fired, err
+		}
+		fired += len(due)
+		if len(due) < fireBatch || ctx.Err() != nil {
+			return fired, nil
+		}
+	}
+}
+
+// HandleReminderEvent hands a fired reminder to every channel. A reminder
+// whose user has lost access to the page in the meantime is dropped
+// silently. Any failure is returned so the relay delivers the event again.
+func (s *service) HandleReminderEvent(ctx context.Context, _ uint64, e ReminderEvent) error {
+	if e.Type != ReminderFired || e.TargetPageID == nil {
+		return nil
+	}
+	if _, err := s.pages.GetPageByID(*e.TargetPageID, e.UserID); err != nil {
+		if errors.Is(err, pages.ErrPageNotFound) {
+			return nil
+		}
+		return err
+	}
+	reminder := &Reminder{
+		ID:           e.ReminderID,
+		UserID:       e.UserID,
+		Note:         e.Note,
+		FiredAt:      &e.At,
+		TargetPageID: e.TargetPageID,
+		Subject:      e.Subject,
+	}
+	var errs []error
+	for _, ch := range s.channels {
+		if err := ch.Deliver(ctx, reminder); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RunScheduler fires due reminders every interval until ctx is cancelled.
+func RunScheduler(ctx context.Context, s Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.FireDue(ctx)
+			if err != nil {
+				logger.Log.Errorw("Firing reminders failed", "error", err)
+			} else if n > 0 {
+				logger.Log.Infow("Fired reminders", "count", n)
+			}
+		}
+	}
+}
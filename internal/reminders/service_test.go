@@ -0,0 +1,339 @@
+package reminders
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"flowboard-backend-go/internal/pages"
+	"flowboard-backend-go/internal/tasks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memStore is shared by every memRepo, the way replicas share a database.
+// Claimed rows stay locked until the claiming transaction ends, and events
+// are queued only when it commits.
+type memStore struct {
+	mu        sync.Mutex
+	reminders map[uint]*Reminder
+	taskDue   map[uint]*time.Time
+	locked    map[uint]bool
+	events    []ReminderEvent
+	failMark  bool
+	nextID    uint
+}
+
+// memRepo is an in-memory Repository for service tests.
+type memRepo struct {
+	store  *memStore
+	claims []uint
+	events []ReminderEvent
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{store: &memStore{
+		reminders: map[uint]*Reminder{},
+		taskDue:   map[uint]*time.Time{},
+		locked:    map[uint]bool{},
+	}}
+}
+
+func (r *memRepo) Transaction(fn func(repo Repository) error) error {
+	tx := &memRepo{store: r.store}
+	err := fn(tx)
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, id := range tx.claims {
+		delete(r.store.locked, id)
+	}
+	if err == nil {
+		r.store.events = append(r.store.events, tx.events...)
+	}
+	return err
+}
+
+// resolve fills the computed columns the way the SQL query does.
+func (r *memRepo) resolve(rem Reminder) Reminder {
+	rem.FireAt = rem.RemindAt
+	if rem.MinutesBefore != nil {
+		rem.FireAt = nil
+		if due := r.store.taskDue[*rem.TaskID]; due != nil {
+			at := due.Add(-time.Duration(*rem.MinutesBefore) * time.Minute)
+			rem.FireAt = &at
+		}
+	}
+	target := uint(100)
+	rem.TargetPageID = &target
+	return rem
+}
+
+func (r *memRepo) CreateReminder(reminder *Reminder) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.nextID++
+	reminder.ID = r.store.nextID
+	cp := *reminder
+	r.store.reminders[reminder.ID] = &cp
+	return nil
+}
+
+func (r *memRepo) GetReminderByID(id uint) (*Reminder, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if rem, ok := r.store.reminders[id]; ok {
+		cp := r.resolve(*rem)
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (r *memRepo) GetPendingByUser(userID uint) ([]Reminder, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	var out []Reminder
+	for _, rem := range r.store.reminders {
+		if rem.UserID == userID && rem.FiredAt == nil {
+			out = append(out, r.resolve(*rem))
+		}
+	}
+	return out, nil
+}
+
+func (r *memRepo) DeleteReminder(id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	delete(r.store.reminders, id)
+	return nil
+}
+
+func (r *memRepo) ClaimDue(now time.Time, limit int) ([]Reminder, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	var out []Reminder
+	for id, rem := range r.store.reminders {
+		res := r.resolve(*rem)
+		if rem.FiredAt != nil || r.store.locked[id] || res.FireAt == nil || res.FireAt.After(now) {
+			continue
+		}
+		out = append(out, res)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	for _, rem := range out {
+		r.store.locked[rem.ID] = true
+		r.claims = append(r.claims, rem.ID)
+	}
+	return out, nil
+}
+
+func (r *memRepo) MarkFired(ids []uint, at time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if r.store.failMark {
+		return errors.New("connection lost")
+	}
+	for _, id := range ids {
+		r.store.reminders[id].FiredAt = &at
+	}
+	return nil
+}
+
+func (r *memRepo) AddEvent(e ReminderEvent) error {
+	r.events = append(r.events, e)
+	return nil
+}
+
+// relay hands every queued event to s, the way the outbox relay does, and
+// returns how many deliveries failed.
+func (r *memRepo) relay(s *service) int {
+	failed := 0
+	for i, e := range r.store.events {
+		if err := s.HandleReminderEvent(context.Background(), uint64(i+1), e); err != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+// stubPages lets user 1 read page 100 only.
+type stubPages struct{}
+
+func (stubPages) GetPageByID(id, userID uint) (*pages.Page, error) {
+	if id == 100 && userID == 1 {
+		return &pages.Page{ID: id, UserID: userID}, nil
+	}
+	return nil, pages.ErrPageNotFound
+}
+
+// stubTasks lets user 1 read task 5 only.
+type stubTasks struct{}
+
+func (stubTasks) GetTask(id, userID uint) (*tasks.Task, error) {
+	if id == 5 && userID == 1 {
+		return &tasks.Task{ID: id, PageID: 100}, nil
+	}
+	return nil, tasks.ErrTaskNotFound
+}
+
+// recorder is a Channel that records delivered reminder IDs. Its first
+// fail deliveries fail.
+type recorder struct {
+	mu   sync.Mutex
+	ids  []uint
+	fail int
+}
+
+func (c *recorder) Deliver(_ context.Context, reminder *Reminder) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fail > 0 {
+		c.fail--
+		return errors.New("mail server unavailable")
+	}
+	c.ids = append(c.ids, reminder.ID)
+	return nil
+}
+
+func intPtr(v int) *int { return &v }
+
+func TestCreateReminderValidation(t *testing.T) {
+	s := NewService(newMemRepo(), stubPages{}, stubTasks{}).(*service)
+	now := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	page, task, later, earlier := uint(100), uint(5), now.Add(time.Hour), now.Add(-time.Hour)
+
+	_, err := s.CreateReminder(ReminderInput{RemindAt: &later}, 1)
+	assert.ErrorIs(t, err, ErrInvalidTarget)
+	_, err = s.CreateReminder(ReminderInput{PageID: &page, TaskID: &task, RemindAt: &later}, 1)
+	assert.ErrorIs(t, err, ErrInvalidTarget)
+	_, err = s.CreateReminder(ReminderInput{PageID: &page}, 1)
+	assert.ErrorIs(t, err, ErrInvalidSchedule)
+	_, err = s.CreateReminder(ReminderInput{PageID: &page, MinutesBefore: intPtr(10)}, 1)
+	assert.ErrorIs(t, err, ErrRelativeNeedsTask)
+	_, err = s.CreateReminder(ReminderInput{PageID: &page, RemindAt: &earlier}, 1)
+	assert.ErrorIs(t, err, ErrInThePast)
+	_, err = s.CreateReminder(ReminderInput{PageID: &page, RemindAt: &later}, 2)
+	assert.ErrorIs(t, err, pages.ErrPageNotFound)
+	_, err = s.CreateReminder(ReminderInput{TaskID: &task, MinutesBefore: intPtr(10)}, 2)
+	assert.ErrorIs(t, err, tasks.ErrTaskNotFound)
+
+	rem, err := s.CreateReminder(ReminderInput{PageID: &page, RemindAt: &later, Note: " check "}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "check", rem.Note)
+	assert.Equal(t, later, *rem.FireAt)
+
+	assert.ErrorIs(t, s.DeleteReminder(rem.ID, 2), ErrReminderNotFound)
+	assert.NoError(t, s.DeleteReminder(rem.ID, 1))
+}
+
+func TestFireDueRelativeToTaskDueDate(t *testing.T) {
+	repo := newMemRepo()
+	ch := &recorder{}
+	s := NewService(repo, stubPages{}, stubTasks{}, ch).(*service)
+	now := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	task := uint(5)
+
+	rem, err := s.CreateReminder(ReminderInput{TaskID: &task, MinutesBefore: intPtr(30)}, 1)
+	require.NoError(t, err)
+	assert.Nil(t, rem.FireAt, "an undated task has nothing to be relative to")
+
+	due := now.Add(time.Hour)
+	repo.store.taskDue[task] = &due
+	n, err := s.FireDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// Moving the due date earlier brings the reminder forward.
+	due = now.Add(20 * time.Minute)
+	n, err = s.FireDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Zero(t, repo.relay(s))
+	assert.Equal(t, []uint{rem.ID}, ch.ids)
+
+	n, err = s.FireDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, n, "a reminder fires only once")
+	pending, _ := s.GetReminders(1)
+	assert.Empty(t, pending)
+}
+
+func TestFireDueConcurrentSchedulersFireOnce(t *testing.T) {
+	repo := newMemRepo()
+	ch := &recorder{}
+	now := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	page := uint(100)
+	for i := 0; i < 250; i++ {
+		at := now.Add(-time.Duration(i) * time.Second)
+		require.NoError(t, repo.CreateReminder(&Reminder{UserID: 1, PageID: &page, RemindAt: &at}))
+	}
+	// A reminder for a user who can no longer read the page is dropped.
+	at := now.Add(-time.Minute)
+	require.NoError(t, repo.CreateReminder(&Reminder{UserID: 2, PageID: &page, RemindAt: &at}))
+
+	s := NewService(repo, stubPages{}, stubTasks{}, ch).(*service)
+	s.now = func() time.Time { return now }
+	var wg sync.WaitGroup
+	total := make([]int, 3)
+	for i := range total {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			n, err := s.FireDue(context.Background())
+			assert.NoError(t, err)
+			total[i] = n
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 251, total[0]+total[1]+total[2])
+	assert.Len(t, repo.store.events, 251)
+	assert.Zero(t, repo.relay(s))
+	seen := map[uint]bool{}
+	for _, id := range ch.ids {
+		assert.False(t, seen[id], "reminder %d delivered twice", id)
+		seen[id] = true
+	}
+	assert.Len(t, seen, 250)
+}
+
+func TestFireDueQueuesDeliveryWithTheClaim(t *testing.T) {
+	repo := newMemRepo()
+	ch := &recorder{fail: 1}
+	s := NewService(repo, stubPages{}, stubTasks{}, ch).(*service)
+	now := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	page := uint(100)
+	at := now.Add(-time.Minute)
+	require.NoError(t, repo.CreateReminder(&Reminder{UserID: 1, PageID: &page, RemindAt: &at, Note: "ship it"}))
+
+	// A claim that does not commit queues nothing and leaves the reminder due.
+	repo.store.failMark = true
+	_, err := s.FireDue(context.Background())
+	require.Error(t, err)
+	assert.Empty(t, repo.store.events)
+	repo.store.failMark = false
+
+	n, err := s.FireDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Empty(t, ch.ids, "delivery waits for the relay")
+	require.Len(t, repo.store.events, 1)
+	e := repo.store.events[0]
+	assert.Equal(t, ReminderFired, e.Type)
+	assert.Equal(t, "ship it", e.Note)
+
+	// A failed delivery is reported so the relay tries again.
+	assert.Equal(t, 1, repo.relay(s))
+	assert.Empty(t, ch.ids)
+	assert.Zero(t, repo.relay(s))
+	assert.Len(t, ch.ids, 1)
+}
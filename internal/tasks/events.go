@@ -0,0 +1,74 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"flowboard-backend-go/internal/outbox"
+	"flowboard-backend-go/internal/pages"
+)
+
+type EventType string
+
+const (
+	TaskCreated EventType = "task.created"
+	TaskUpdated EventType = "task.updated"
+	// TaskCompleted replaces TaskUpdated when an update moves a task to
+	// done.
+	TaskCompleted EventType = "task.completed"
+	TaskDeleted   EventType = "task.deleted"
+)
+
+// EventTypes lists every task event type, for subscribing to all of them.
+var EventTypes = []string{string(TaskCreated), string(TaskUpdated), string(TaskCompleted), string(TaskDeleted)}
+
+// TaskEvent describes a change to a task. It names the page and its
+// audience so consumers need not look them up.
+type TaskEvent struct {
+	Type        EventType `json:"type"`
+	TaskID      uint      `json:"taskId"`
+	Title       string    `json:"title"`
+	Status      Status    `json:"status"`
+	AssigneeID  *uint     `json:"assigneeId,omitempty"`
+	PageID      uint      `json:"pageId"`
+	PageTitle   string    `json:"pageTitle"`
+	WorkspaceID *uint     `json:"workspaceId,omitempty"`
+	OwnerID     uint      `json:"ownerId"`
+	ActorID     uint      `json:"actorId"`
+	At          time.Time `json:"at"`
+}
+
+// EventHandler consumes task events relayed from the outbox. An event may
+// arrive more than once, always with the same eventID.
+type EventHandler interface {
+	HandleTaskEvent(ctx context.Context, eventID uint64, e TaskEvent) error
+}
+
+// Consume adapts h to an outbox handler; subscribe it to EventTypes.
+func Consume(h EventHandler) outbox.Handler {
+	return func(ctx context.Context, m *outbox.Message) error {
+		var e TaskEvent
+		if err := json.Unmarshal([]byte(m.Payload), &e); err != nil {
+			return err
+		}
+		return h.HandleTaskEvent(ctx, m.ID, e)
+	}
+}
+
+// event describes a change to task, made by actorID on page.
+func (s *service) event(t EventType, page *pages.Page, task *Task, actorID uint) TaskEvent {
+	return TaskEvent{
+		Type:        t,
+		TaskID:      task.ID,
+		Title:       task.Title,
+		Status:      task.Status,
+		AssigneeID:  task.AssigneeID,
+		PageID:      page.ID,
+		PageTitle:   page.Title,
+		WorkspaceID: page.WorkspaceID,
+		OwnerID:     page.UserID,
+		ActorID:     actorID,
+		At:          s.now(),
+	}
+}
@@ -0,0 +1,262 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"flowboard-backend-go/internal/middleware"
+	"flowboard-backend-go/internal/pages"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{
+		service: service,
+	}
+}
+
+// getUserID safely retrieves user ID from context
+func getUserID(c *gin.Context) (uint, error) {
+	uidVal, exists := c.Get(middleware.ContextUserIDKey)
+	if !exists {
+		return 0, fmt.Errorf("unauthorized")
+	}
+
+	uid, ok := uidVal.(uint)
+	if !ok {
+		return 0, fmt.Errorf("invalid user ID type")
+	}
+
+	return uid, nil
+}
+
+// parseID reads a numeric path parameter
+func parseID(c *gin.Context, name string) (uint, bool) {
+	id64, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		return 0, false
+	}
+	return uint(id64), true
+}
+
+// respondError maps service errors to HTTP statuses
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrTaskNotFound), errors.Is(err, pages.ErrPageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrForbidden), errors.Is(err, pages.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidAssignee):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// parseTaskFilter reads ?assigneeId=&status=todo,done&dueFrom=&dueTo=
+// &pageId=&limit=&offset=. Dates are RFC 3339 timestamps or plain
+// YYYY-MM-DD days; a plain dueTo day is included in the range.
+func parseTaskFilter(c *gin.Context) (TaskFilter, error) {
+	var filter TaskFilter
+
+	for _, name := range []string{"assigneeId", "pageId"} {
+		v := c.Query(name)
+		if v == "" {
+			continue
+		}
+		id64, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return filter, fmt.Errorf("invalid %s", name)
+		}
+		id := uint(id64)
+		if name == "assigneeId" {
+			filter.AssigneeID = &id
+		} else {
+			filter.PageID = &id
+		}
+	}
+
+	for _, part := range strings.Split(c.Query("status"), ",") {
+		switch st := Status(strings.TrimSpace(part)); st {
+		case "":
+		case StatusTodo, StatusInProgress, StatusDone:
+			filter.Statuses = append(filter.Statuses, st)
+		default:
+			return filter, fmt.Errorf("invalid status %q", st)
+		}
+	}
+
+	if v := c.Query("dueFrom"); v != "" {
+		t, _, err := parseDate(v)
+		if err != nil {
+			return filter, errors.New("invalid dueFrom")
+		}
+		filter.DueFrom = &t
+	}
+	if v := c.Query("dueTo"); v != "" {
+		t, day, err := parseDate(v)
+		if err != nil {
+			return filter, errors.New("invalid dueTo")
+		}
+		if day {
+			t = t.AddDate(0, 0, 1)
+		}
+		filter.DueTo = &t
+	}
+
+	var err error
+	if v := c.Query("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			return filter, errors.New("invalid limit")
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if filter.Offset, err = strconv.Atoi(v); err != nil {
+			return filter, errors.New("invalid offset")
+		}
+	}
+	return filter, nil
+}
+
+// parseDate accepts an RFC 3339 timestamp or a YYYY-MM-DD day (UTC) and
+// reports which one it got.
+func parseDate(v string) (time.Time, bool, error) {
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	return t, false, err
+}
+
+func (h *Handler) GetPageTasks(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	pageID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	list, err := h.service.GetPageTasks(pageID, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": list})
+}
+
+func (h *Handler) CreateTask(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	pageID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input TaskInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, err := h.service.CreateTask(pageID, input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": task})
+}
+
+func (h *Handler) UpdateTask(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input TaskInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	task, err := h.service.UpdateTask(id, input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": task})
+}
+
+func (h *Handler) DeleteTask(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteTask(id, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+func (h *Handler) FindTasks(c *gin.Context) {
+	h.listTasks(c, h.service.FindTasks)
+}
+
+// MyTasks lists the current user's open tasks; pass ?status= to include
+// other states.
+func (h *Handler) MyTasks(c *gin.Context) {
+	h.listTasks(c, h.service.MyTasks)
+}
+
+func (h *Handler) listTasks(c *gin.Context, find func(TaskFilter, uint) ([]Task, int64, error)) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter, err := parseTaskFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, total, err := find(filter, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": list, "total": total})
+}
@@ -0,0 +1,59 @@
+package tasks
+
+import "time"
+
+type Status string
+
+const (
+	StatusTodo       Status = "todo"
+	StatusInProgress Status = "in_progress"
+	StatusDone       Status = "done"
+)
+
+type Priority string
+
+const (
+	PriorityLow    Priority = "low"
+	PriorityMedium Priority = "medium"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+// Task is a to-do attached to a page. Anyone who can read the page can see
+// its tasks; editing them requires edit access to the page.
+type Task struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	PageID      uint       `gorm:"not null;index" json:"pageId"`
+	Title       string     `gorm:"size:255;not null" json:"title"`
+	Status      Status     `gorm:"size:20;not null;default:'todo';index" json:"status"`
+	Priority    Priority   `gorm:"size:20;not null;default:'medium'" json:"priority"`
+	AssigneeID  *uint      `gorm:"index" json:"assigneeId"`
+	DueDate     *time.Time `gorm:"index" json:"dueDate"`
+	CompletedAt *time.Time `json:"completedAt"`
+	CreatedBy   uint       `gorm:"not null" json:"createdBy"`
+	PageTitle   string     `gorm:"->;-:migration" json:"pageTitle,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// TaskInput for creating or replacing a task. Status defaults to todo and
+// Priority to medium.
+type TaskInput struct {
+	Title      string     `json:"title" binding:"required,max=255"`
+	Status     Status     `json:"status" binding:"omitempty,oneof=todo in_progress done"`
+	Priority   Priority   `json:"priority" binding:"omitempty,oneof=low medium high urgent"`
+	AssigneeID *uint      `json:"assigneeId"`
+	DueDate    *time.Time `json:"dueDate"`
+}
+
+// TaskFilter narrows cross-page task listings. DueFrom is inclusive and
+// DueTo exclusive.
+type TaskFilter struct {
+	AssigneeID *uint
+	Statuses   []Status
+	DueFrom    *time.Time
+	DueTo      *time.Time
+	PageID     *uint
+	Limit      int
+	Offset     int
+}
@@ -0,0 +1,123 @@
+package tasks
+
+import (
+	"errors"
+
+	"flowboard-backend-go/internal/outbox"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	// Transaction runs fn with a Repository bound to a single database
+	// transaction; any error rolls everything back.
+	Transaction(fn func(repo Repository) error) error
+	// AddEvent records a task event in the outbox. Call it inside
+	// Transaction so the event commits together with the change.
+	AddEvent(e TaskEvent) error
+	CreateTask(task *Task) error
+	GetTaskByID(id uint) (*Task, error)
+	GetTasksByPage(pageID uint) ([]Task, error)
+	UpdateTask(task *Task) error
+	DeleteTask(id uint) error
+	// FindTasks lists tasks on pages the user can read: their personal
+	// pages and pages of workspaceIDs. It also returns the unpaged total.
+	FindTasks(userID uint, workspaceIDs []uint, filter TaskFilter) ([]Task, int64, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// taskOrder puts tasks with the nearest due date first and undated ones
+// last.
+const taskOrder = "tasks.due_date IS NULL, tasks.due_date, tasks.id"
+
+func (r *repository) Transaction(fn func(repo Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&repository{db: tx})
+	})
+}
+
+func (r *repository) AddEvent(e TaskEvent) error {
+	return outbox.Append(r.db, string(e.Type), e)
+}
+
+func (r *repository) CreateTask(task *Task) error {
+	return r.db.Create(task).Error
+}
+
+func (r *repository) GetTaskByID(id uint) (*Task, error) {
+	var task Task
+	if err := r.db.First(&task, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (r *repository) GetTasksByPage(pageID uint) ([]Task, error) {
+	var list []Task
+	if err := r.db.Where("page_id = ?", pageID).Order(taskOrder).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *repository) UpdateTask(task *Task) error {
+	return r.db.Save(task).Error
+}
+
+func (r *repository) DeleteTask(id uint) error {
+	return r.db.Delete(&Task{}, id).Error
+}
+
+func (r *repository) FindTasks(userID uint, workspaceIDs []uint, filter TaskFilter) ([]Task, int64, error) {
+	scope := func(db *gorm.DB) *gorm.DB {
+		db = db.Joins("JOIN pages ON pages.id = tasks.page_id")
+		if len(workspaceIDs) > 0 {
+			db = db.Where("((pages.user_id = ? AND pages.workspace_id IS NULL) OR pages.workspace_id IN ?)", userID, workspaceIDs)
+		} else {
+			db = db.Where("pages.user_id = ? AND pages.workspace_id IS NULL", userID)
+		}
+		if filter.AssigneeID != nil {
+			db = db.Where("tasks.assignee_id = ?", *filter.AssigneeID)
+		}
+		if len(filter.Statuses) > 0 {
+			db = db.Where("tasks.status IN ?", filter.Statuses)
+		}
+		if filter.DueFrom != nil {
+			db = db.Where("tasks.due_date >= ?", *filter.DueFrom)
+		}
+		if filter.DueTo != nil {
+			db = db.Where("tasks.due_date < ?", *filter.DueTo)
+		}
+		if filter.PageID != nil {
+			db = db.Where("tasks.page_id = ?", *filter.PageID)
+		}
+		return db
+	}
+
+	var total int64
+	if err := r.db.Model(&Task{}).Scopes(scope).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var list []Task
+	err := r.db.Model(&Task{}).Scopes(scope).
+		Select("tasks.*, pages.title AS page_title").
+		Order(taskOrder).
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&list).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
@@ -0,0 +1,260 @@
+package tasks
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"flowboard-backend-go/internal/pages"
+	"flowboard-backend-go/internal/workspaces"
+)
+
+var (
+	ErrTaskNotFound    = errors.New("task not found")
+	ErrInvalidAssignee = errors.New("assignee has no access to this page")
+	ErrForbidden       = errors.New("insufficient permissions")
+)
+
+// defaultLimit and maxLimit bound cross-page listings.
+const (
+	defaultLimit = 50
+	maxLimit     = 200
+)
+
+// PageLookup resolves pages with access checks; satisfied by pages.Service.
+type PageLookup interface {
+	GetPageByID(id, userID uint) (*pages.Page, error)
+}
+
+// WorkspaceAccess reports workspace membership; satisfied by
+// workspaces.Service.
+type WorkspaceAccess interface {
+	MemberRole(workspaceID, userID uint) (workspaces.Role, error)
+	GetWorkspacesByUser(userID uint) ([]workspaces.Workspace, error)
+}
+
+type Service interface {
+	GetPageTasks(pageID, userID uint) ([]Task, error)
+	GetTask(id, userID uint) (*Task, error)
+	CreateTask(pageID uint, input TaskInput, userID uint) (*Task, error)
+	UpdateTask(id uint, input TaskInput, userID uint) (*Task, error)
+	DeleteTask(id, userID uint) error
+	FindTasks(filter TaskFilter, userID uint) ([]Task, int64, error)
+	MyTasks(filter TaskFilter, userID uint) ([]Task, int64, error)
+}
+
+type service struct {
+	repo       Repository
+	pages      PageLookup
+	workspaces WorkspaceAccess
+	now        func() time.Time
+}
+
+func NewService(repo Repository, pages PageLookup, workspaces WorkspaceAccess) Service {
+	return &service{repo: repo, pages: pages, workspaces: workspaces, now: time.Now}
+}
+
+func (s *service) GetPageTasks(pageID, userID uint) ([]Task, error) {
+	if _, err := s.pages.GetPageByID(pageID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetTasksByPage(pageID)
+}
+
+// GetTask loads a task the user can read.
+func (s *service) GetTask(id, userID uint) (*Task, error) {
+	task, err := s.repo.GetTaskByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if task == nil {
+		return nil, ErrTaskNotFound
+	}
+	if _, err := s.pages.GetPageByID(task.PageID, userID); err != nil {
+		if errors.Is(err, pages.ErrPageNotFound) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, err
+	}
+	return task, nil
+}
+
+func (s *service) CreateTask(pageID uint, input TaskInput, userID uint) (*Task, error) {
+	page, err := s.editablePage(pageID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	task := &Task{PageID: pageID, CreatedBy: userID}
+	if err := s.applyInput(page, task, input); err != nil {
+		return nil, err
+	}
+	err = s.repo.Transaction(func(repo Repository) error {
+		if err := repo.CreateTask(task); err != nil {
+			return err
+		}
+		return repo.AddEvent(s.event(TaskCreated, page, task, userID))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+func (s *service) UpdateTask(id uint, input TaskInput, userID uint) (*Task, error) {
+	task, page, err := s.editableTask(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	wasDone := task.CompletedAt != nil
+	if err := s.applyInput(page, task, input); err != nil {
+		return nil, err
+	}
+	t := TaskUpdated
+	if !wasDone && task.CompletedAt != nil {
+		t = TaskCompleted
+	}
+	err = s.repo.Transaction(func(repo Repository) error {
+		if err := repo.UpdateTask(task); err != nil {
+			return err
+		}
+		return repo.AddEvent(s.event(t, page, task, userID))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+func (s *service) DeleteTask(id, userID uint) error {
+	task, page, err := s.editableTask(id, userID)
+	if err != nil {
+		return err
+	}
+	return s.repo.Transaction(func(repo Repository) error {
+		if err := repo.DeleteTask(id); err != nil {
+			return err
+		}
+		return repo.AddEvent(s.event(TaskDeleted, page, task, userID))
+	})
+}
+
+// FindTasks lists tasks across every page the user can read.
+func (s *service) FindTasks(filter TaskFilter, userID uint) ([]Task, int64, error) {
+	list, err := s.workspaces.GetWorkspacesByUser(userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	ids := make([]uint, len(list))
+	for i, ws := range list {
+		ids[i] = ws.ID
+	}
+
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = defaultLimit
+	case filter.Limit > maxLimit:
+		filter.Limit = maxLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.repo.FindTasks(userID, ids, filter)
+}
+
+// MyTasks lists tasks assigned to the user. Without a status filter only
+// open tasks are returned.
+func (s *service) MyTasks(filter TaskFilter, userID uint) ([]Task, int64, error) {
+	filter.AssigneeID = &userID
+	if len(filter.Statuses) == 0 {
+		filter.Statuses = []Status{StatusTodo, StatusInProgress}
+	}
+	return s.FindTasks(filter, userID)
+}
+
+// applyInput validates input and copies it onto task, stamping or clearing
+// CompletedAt as the status moves in and out of done.
+func (s *service) applyInput(page *pages.Page, task *Task, input TaskInput) error {
+	if input.AssigneeID != nil {
+		if err := s.checkAssignee(page, *input.AssigneeID); err != nil {
+			return err
+		}
+	}
+
+	status := input.Status
+	if status == "" {
+		status = StatusTodo
+	}
+	priority := input.Priority
+	if priority == "" {
+		priority = PriorityMedium
+	}
+
+	if status == StatusDone && task.CompletedAt == nil {
+		now := s.now()
+		task.CompletedAt = &now
+	} else if status != StatusDone {
+		task.CompletedAt = nil
+	}
+	task.Title = strings.TrimSpace(input.Title)
+	task.Status = status
+	task.Priority = priority
+	task.AssigneeID = input.AssigneeID
+	task.DueDate = input.DueDate
+	return nil
+}
+
+// checkAssignee requires the assignee to be able to read the page: its
+// owner for personal pages, a member for workspace pages.
+func (s *service) checkAssignee(page *pages.Page, assigneeID uint) error {
+	if page.WorkspaceID == nil {
+		if assigneeID != page.UserID {
+			return ErrInvalidAssignee
+		}
+		return nil
+	}
+	role, err := s.workspaces.MemberRole(*page.WorkspaceID, assigneeID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return ErrInvalidAssignee
+	}
+	return nil
+}
+
+// editablePage loads a page the user may add or change tasks on.
+func (s *service) editablePage(pageID, userID uint) (*pages.Page, error) {
+	page, err := s.pages.GetPageByID(pageID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if page.WorkspaceID != nil {
+		role, err := s.workspaces.MemberRole(*page.WorkspaceID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !role.AtLeast(workspaces.RoleEditor) {
+			return nil, ErrForbidden
+		}
+	}
+	return page, nil
+}
+
+func (s *service) editableTask(id, userID uint) (*Task, *pages.Page, error) {
+	task, err := s.repo.GetTaskByID(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if task == nil {
+		return nil, nil, ErrTaskNotFound
+	}
+	page, err := s.editablePage(task.PageID, userID)
+	if err != nil {
+		if errors.Is(err, pages.ErrPageNotFound) {
+			return nil, nil, ErrTaskNotFound
+		}
+		return nil, nil, err
+	}
+	return task, page, nil
+}
@@ -0,0 +1,180 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+
+	"flowboard-backend-go/internal/pages"
+	"flowboard-backend-go/internal/workspaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memRepo is an in-memory Repository for service tests. FindTasks records
+// the arguments it was called with instead of filtering.
+type memRepo struct {
+	tasks  map[uint]*Task
+	events []TaskEvent
+	nextID uint
+
+	lastWorkspaces []uint
+	lastFilter     TaskFilter
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{tasks: map[uint]*Task{}}
+}
+
+func (r *memRepo) Transaction(fn func(repo Repository) error) error {
+	return fn(r)
+}
+
+func (r *memRepo) AddEvent(e TaskEvent) error {
+	r.events = append(r.events, e)
+	return nil
+}
+
+func (r *memRepo) CreateTask(task *Task) error {
+	r.nextID++
+	task.ID = r.nextID
+	cp := *task
+	r.tasks[task.ID] = &cp
+	return nil
+}
+
+func (r *memRepo) GetTaskByID(id uint) (*Task, error) {
+	if t, ok := r.tasks[id]; ok {
+		cp := *t
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (r *memRepo) GetTasksByPage(pageID uint) ([]Task, error) {
+	var out []Task
+	for _, t := range r.tasks {
+		if t.PageID == pageID {
+			out = append(out, *t)
+		}
+	}
+	return out, nil
+}
+
+func (r *memRepo) UpdateTask(task *Task) error {
+	cp := *task
+	r.tasks[task.ID] = &cp
+	return nil
+}
+
+func (r *memRepo) DeleteTask(id uint) error {
+	delete(r.tasks, id)
+	return nil
+}
+
+func (r *memRepo) FindTasks(userID uint, workspaceIDs []uint, filter TaskFilter) ([]Task, int64, error) {
+	r.lastWorkspaces, r.lastFilter = workspaceIDs, filter
+	return nil, 0, nil
+}
+
+// stubWorkspaces grants roles from a fixed table keyed by workspace, user.
+type stubWorkspaces map[[2]uint]workspaces.Role
+
+func (w stubWorkspaces) MemberRole(workspaceID, userID uint) (workspaces.Role, error) {
+	return w[[2]uint{workspaceID, userID}], nil
+}
+
+func (w stubWorkspaces) GetWorkspacesByUser(userID uint) ([]workspaces.Workspace, error) {
+	var out []workspaces.Workspace
+	for key := range w {
+		if key[1] == userID {
+			out = append(out, workspaces.Workspace{ID: key[0]})
+		}
+	}
+	return out, nil
+}
+
+// stubPages serves page 100 in workspace 7 to its members, and page 200
+// as user 1's personal page.
+type stubPages struct{ access stubWorkspaces }
+
+func (p stubPages) GetPageByID(id, userID uint) (*pages.Page, error) {
+	ws := uint(7)
+	switch {
+	case id == 100 && p.access[[2]uint{ws, userID}] != "":
+		return &pages.Page{ID: id, UserID: 1, WorkspaceID: &ws}, nil
+	case id == 200 && userID == 1:
+		return &pages.Page{ID: id, UserID: 1}, nil
+	}
+	return nil, pages.ErrPageNotFound
+}
+
+func TestTaskLifecycle(t *testing.T) {
+	access := stubWorkspaces{{7, 1}: workspaces.RoleEditor, {7, 2}: workspaces.RoleViewer}
+	repo := newMemRepo()
+	s := NewService(repo, stubPages{access}, access).(*service)
+	fixed := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return fixed }
+
+	viewer, outsider := uint(2), uint(3)
+	task, err := s.CreateTask(100, TaskInput{Title: " Draft ", AssigneeID: &viewer}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Draft", task.Title)
+	assert.Equal(t, StatusTodo, task.Status)
+	assert.Equal(t, PriorityMedium, task.Priority)
+	assert.Nil(t, task.CompletedAt)
+
+	_, err = s.CreateTask(100, TaskInput{Title: "x", AssigneeID: &outsider}, 1)
+	assert.ErrorIs(t, err, ErrInvalidAssignee)
+	_, err = s.CreateTask(200, TaskInput{Title: "x", AssigneeID: &viewer}, 1)
+	assert.ErrorIs(t, err, ErrInvalidAssignee)
+
+	_, err = s.CreateTask(100, TaskInput{Title: "x"}, viewer)
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = s.UpdateTask(task.ID, TaskInput{Title: "x"}, outsider)
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+
+	task, err = s.UpdateTask(task.ID, TaskInput{Title: "Draft", Status: StatusDone}, 1)
+	require.NoError(t, err)
+	require.NotNil(t, task.CompletedAt)
+	assert.Equal(t, fixed, *task.CompletedAt)
+
+	s.now = func() time.Time { return fixed.Add(time.Hour) }
+	task, err = s.UpdateTask(task.ID, TaskInput{Title: "Draft", Status: StatusDone}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, fixed, *task.CompletedAt, "re-saving a done task keeps its completion time")
+
+	task, err = s.UpdateTask(task.ID, TaskInput{Title: "Draft", Status: StatusInProgress}, 1)
+	require.NoError(t, err)
+	assert.Nil(t, task.CompletedAt)
+	require.NoError(t, s.DeleteTask(task.ID, 1))
+
+	var types []EventType
+	for _, e := range repo.events {
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []EventType{TaskCreated, TaskCompleted, TaskUpdated, TaskUpdated, TaskDeleted}, types,
+		"only moving into done completes a task; failed changes record nothing")
+	assert.Equal(t, "Draft", repo.events[4].Title)
+	assert.Equal(t, uint(7), *repo.events[0].WorkspaceID)
+}
+
+func TestMyTasksDefaults(t *testing.T) {
+	access := stubWorkspaces{{7, 1}: workspaces.RoleEditor}
+	repo := newMemRepo()
+	s := NewService(repo, stubPages{access}, access)
+
+	_, _, err := s.MyTasks(TaskFilter{Limit: 1000, Offset: -5}, 1)
+	require.NoError(t, err)
+	require.NotNil(t, repo.lastFilter.AssigneeID)
+	assert.Equal(t, uint(1), *repo.lastFilter.AssigneeID)
+	assert.Equal(t, []Status{StatusTodo, StatusInProgress}, repo.lastFilter.Statuses)
+	assert.Equal(t, maxLimit, repo.lastFilter.Limit)
+	assert.Equal(t, 0, repo.lastFilter.Offset)
+	assert.Equal(t, []uint{7}, repo.lastWorkspaces)
+
+	_, _, err = s.MyTasks(TaskFilter{Statuses: []Status{StatusDone}}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []Status{StatusDone}, repo.lastFilter.Statuses)
+	assert.Equal(t, defaultLimit, repo.lastFilter.Limit)
+}
@@ -0,0 +1,56 @@
+package templates
+
+import "time"
+
+// Template is a reusable page skeleton. Title and Content (and those of the
+// sub-pages) may contain {{placeholders}}: built-ins such as {{date}} or
+// {{user.name}}, plus the custom Fields declared on the template.
+// Templates without a WorkspaceID are personal to OwnerID.
+type Template struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	OwnerID     uint      `gorm:"not null;index" json:"ownerId"`
+	WorkspaceID *uint     `gorm:"index" json:"workspaceId,omitempty"`
+	Name        string    `gorm:"size:255;not null" json:"name"`
+	Description string    `gorm:"type:text" json:"description"`
+	Title       string    `gorm:"size:255;not null" json:"title"`
+	Content     string    `gorm:"type:text" json:"content"`
+	Fields      []Field   `gorm:"serializer:json;type:jsonb" json:"fields"`
+	Subpages    []Subpage `gorm:"serializer:json;type:jsonb" json:"subpages"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Field is a custom variable the user fills in when instantiating.
+type Field struct {
+	Name     string `json:"name" binding:"required"`
+	Label    string `json:"label"`
+	Default  string `json:"default"`
+	Required bool   `json:"required"`
+}
+
+// Subpage is a child page created together with the template's page.
+type Subpage struct {
+	Title    string    `json:"title" binding:"required"`
+	Content  string    `json:"content"`
+	Subpages []Subpage `json:"subpages,omitempty"`
+}
+
+// TemplateInput for creating or updating a template
+type TemplateInput struct {
+	Name        string    `json:"name" binding:"required,max=255"`
+	Description string    `json:"description"`
+	Title       string    `json:"title" binding:"required,max=255"`
+	Content     string    `json:"content"`
+	Fields      []Field   `json:"fields" binding:"dive"`
+	Subpages    []Subpage `json:"subpages" binding:"dive"`
+	WorkspaceID *uint     `json:"workspaceId"`
+}
+
+// InstantiateInput supplies custom field values. Timezone (IANA name)
+// controls {{date}} and friends; it defaults to UTC.
+type InstantiateInput struct {
+	Values          map[string]string `json:"values"`
+	ParentID        *uint             `json:"parentId"`
+	IncludeSubpages *bool             `json:"includeSubpages"`
+	Timezone        string            `json:"timezone"`
+}
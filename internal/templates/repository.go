@@ -0,0 +1,64 @@
+package templates
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	CreateTemplate(t *Template) error
+	GetTemplateByID(id uint) (*Template, error)
+	GetPersonalTemplates(userID uint) ([]Template, error)
+	GetWorkspaceTemplates(workspaceID uint) ([]Template, error)
+	UpdateTemplate(t *Template) error
+	DeleteTemplate(id uint) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) CreateTemplate(t *Template) error {
+	return r.db.Create(t).Error
+}
+
+func (r *repository) GetTemplateByID(id uint) (*Template, error) {
+	var t Template
+	if err := r.db.First(&t, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *repository) GetPersonalTemplates(userID uint) ([]Template, error) {
+	var list []Template
+	err := r.db.Where("owner_id = ? AND workspace_id IS NULL", userID).Order("name").Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *repository) GetWorkspaceTemplates(workspaceID uint) ([]Template, error) {
+	var list []Template
+	if err := r.db.Where("workspace_id = ?", workspaceID).Order("name").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *repository) UpdateTemplate(t *Template) error {
+	return r.db.Save(t).Error
+}
+
+func (r *repository) DeleteTemplate(id uint) error {
+	return r.db.Delete(&Template{}, id).Error
+}
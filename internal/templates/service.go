@@ -0,0 +1,211 @@
+package templates
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"flowboard-backend-go/internal/pages"
+	"flowboard-backend-go/internal/users"
+	"flowboard-backend-go/internal/workspaces"
+)
+
+var (
+	ErrTemplateNotFound = errors.New("template not found")
+	ErrForbidden        = errors.New("insufficient permissions")
+	ErrInvalidTimezone  = errors.New("invalid timezone")
+)
+
+// PageCreator creates pages with access checks; satisfied by pages.Service.
+type PageCreator interface {
+	CreatePageTree(input pages.PageTreeInput, userID uint) ([]*pages.Page, error)
+}
+
+// UserDirectory looks up accounts; satisfied by users.Service.
+type UserDirectory interface {
+	GetByID(id uint) (*users.User, error)
+}
+
+// WorkspaceAccess reports a user's role in a workspace; satisfied by
+// workspaces.Service.
+type WorkspaceAccess interface {
+	MemberRole(workspaceID, userID uint) (workspaces.Role, error)
+}
+
+// InstantiateResult is the page created from a template and its sub-pages.
+type InstantiateResult struct {
+	Page     *pages.Page   `json:"page"`
+	Subpages []*pages.Page `json:"subpages"`
+}
+
+type Service interface {
+	GetTemplates(userID uint, workspaceID *uint) ([]Template, error)
+	GetTemplate(id, userID uint) (*Template, error)
+	CreateTemplate(input TemplateInput, userID uint) (*Template, error)
+	UpdateTemplate(id uint, input TemplateInput, userID uint) (*Template, error)
+	DeleteTemplate(id, userID uint) error
+	Instantiate(id uint, input InstantiateInput, userID uint) (*InstantiateResult, error)
+}
+
+type service struct {
+	repo       Repository
+	pages      PageCreator
+	users      UserDirectory
+	workspaces WorkspaceAccess
+}
+
+func NewService(repo Repository, pages PageCreator, users UserDirectory, workspaces WorkspaceAccess) Service {
+	return &service{repo: repo, pages: pages, users: users, workspaces: workspaces}
+}
+
+func (s *service) GetTemplates(userID uint, workspaceID *uint) ([]Template, error) {
+	if workspaceID == nil {
+		return s.repo.GetPersonalTemplates(userID)
+	}
+	if err := s.requireWorkspaceRole(*workspaceID, userID, workspaces.RoleViewer); err != nil {
+		return nil, err
+	}
+	return s.repo.GetWorkspaceTemplates(*workspaceID)
+}
+
+func (s *service) GetTemplate(id, userID uint) (*Template, error) {
+	return s.loadTemplate(id, userID, workspaces.RoleViewer)
+}
+
+func (s *service) CreateTemplate(input TemplateInput, userID uint) (*Template, error) {
+	if input.WorkspaceID != nil {
+		if err := s.requireWorkspaceRole(*input.WorkspaceID, userID, workspaces.RoleEditor); err != nil {
+			return nil, err
+		}
+	}
+	t := &Template{OwnerID: userID, WorkspaceID: input.WorkspaceID}
+	applyInput(t, input)
+	if err := s.repo.CreateTemplate(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// UpdateTemplate replaces the template's definition. The template cannot
+// be moved between personal and workspace scope.
+func (s *service) UpdateTemplate(id uint, input TemplateInput, userID uint) (*Template, error) {
+	t, err := s.loadTemplate(id, userID, workspaces.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	applyInput(t, input)
+	if err := s.repo.UpdateTemplate(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (s *service) DeleteTemplate(id, userID uint) error {
+	if _, err := s.loadTemplate(id, userID, workspaces.RoleEditor); err != nil {
+		return err
+	}
+	return s.repo.DeleteTemplate(id)
+}
+
+// Instantiate renders the template's variables and creates its page (and,
+// unless disabled, its sub-pages) through the page service, in one
+// transaction.
+func (s *service) Instantiate(id uint, input InstantiateInput, userID uint) (*InstantiateResult, error) {
+	t, err := s.loadTemplate(id, userID, workspaces.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	loc := time.UTC
+	if input.Timezone != "" {
+		if loc, err = time.LoadLocation(input.Timezone); err != nil {
+			return nil, ErrInvalidTimezone
+		}
+	}
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	vars, err := buildVariables(t, user, input.Values, time.Now().In(loc))
+	if err != nil {
+		return nil, err
+	}
+
+	tree := pages.PageTreeInput{PageInput: pages.PageInput{
+		Title:    render(t.Title, vars),
+		Content:  render(t.Content, vars),
+		ParentID: input.ParentID,
+	}}
+	if input.IncludeSubpages == nil || *input.IncludeSubpages {
+		tree.Children = subpageTree(t.Subpages, vars)
+	}
+	created, err := s.pages.CreatePageTree(tree, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &InstantiateResult{Page: created[0], Subpages: created[1:]}, nil
+}
+
+func subpageTree(subs []Subpage, vars map[string]string) []pages.PageTreeInput {
+	out := make([]pages.PageTreeInput, len(subs))
+	for i, sub := range subs {
+		out[i] = pages.PageTreeInput{
+			PageInput: pages.PageInput{Title: render(sub.Title, vars), Content: render(sub.Content, vars)},
+			Children:  subpageTree(sub.Subpages, vars),
+		}
+	}
+	return out
+}
+
+// loadTemplate fetches a template the user may access with at least min
+// rights. Personal templates are only visible to their owner.
+func (s *service) loadTemplate(id, userID uint, min workspaces.Role) (*Template, error) {
+	t, err := s.repo.GetTemplateByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, ErrTemplateNotFound
+	}
+	if t.WorkspaceID == nil {
+		if t.OwnerID != userID {
+			return nil, ErrTemplateNotFound
+		}
+		return t, nil
+	}
+	if err := s.requireWorkspaceRole(*t.WorkspaceID, userID, min); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// requireWorkspaceRole checks the user's workspace role. Non-members get
+// ErrTemplateNotFound so the workspace's templates are not revealed.
+func (s *service) requireWorkspaceRole(workspaceID, userID uint, min workspaces.Role) error {
+	role, err := s.workspaces.MemberRole(workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return ErrTemplateNotFound
+	}
+	if !role.AtLeast(min) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func applyInput(t *Template, input TemplateInput) {
+	t.Name = strings.TrimSpace(input.Name)
+	t.Description = input.Description
+	t.Title = input.Title
+	t.Content = input.Content
+	t.Fields = input.Fields
+	t.Subpages = input.Subpages
+	if t.Fields == nil {
+		t.Fields = []Field{}
+	}
+	if t.Subpages == nil {
+		t.Subpages = []Subpage{}
+	}
+}
@@ -0,0 +1,233 @@
+package templates
+
+import (
+	"errors"
+	"testing"
+
+	"flowboard-backend-go/internal/pages"
+	"flowboard-backend-go/internal/users"
+	"flowboard-backend-go/internal/workspaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memRepo is an in-memory Repository for service tests.
+type memRepo struct {
+	templates map[uint]*Template
+	nextID    uint
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{templates: map[uint]*Template{}}
+}
+
+func (r *memRepo) CreateTemplate(t *Template) error {
+	r.nextID++
+	t.ID = r.nextID
+	cp := *t
+	r.templates[t.ID] = &cp
+	return nil
+}
+
+func (r *memRepo) GetTemplateByID(id uint) (*Template, error) {
+	if t, ok := r.templates[id]; ok {
+		cp := *t
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (r *memRepo) GetPersonalTemplates(userID uint) ([]Template, error) {
+	var out []Template
+	for _, t := range r.templates {
+		if t.WorkspaceID == nil && t.OwnerID == userID {
+			out = append(out, *t)
+		}
+	}
+	return out, nil
+}
+
+func (r *memRepo) GetWorkspaceTemplates(workspaceID uint) ([]Template, error) {
+	var out []Template
+	for _, t := range r.templates {
+		if t.WorkspaceID != nil && *t.WorkspaceID == workspaceID {
+			out = append(out, *t)
+		}
+	}
+	return out, nil
+}
+
+func (r *memRepo) UpdateTemplate(t *Template) error {
+	cp := *t
+	r.templates[t.ID] = &cp
+	return nil
+}
+
+func (r *memRepo) DeleteTemplate(id uint) error {
+	delete(r.templates, id)
+	return nil
+}
+
+// stubPages creates page trees the way the page service does: all of the
+// tree or, when a page fails, none of it. Pages titled failTitle fail, and
+// only users listed in editors may add pages under an existing parent.
+type stubPages struct {
+	pages     []*pages.Page
+	editors   map[uint][]uint // parent page ID -> users allowed to edit it
+	failTitle string
+	calls     int
+}
+
+func (s *stubPages) CreatePageTree(input pages.PageTreeInput, userID uint) ([]*pages.Page, error) {
+	s.calls++
+	if input.ParentID != nil && !containsID(s.editors[*input.ParentID], userID) {
+		return nil, pages.ErrForbidden
+	}
+	var created []*pages.Page
+	if err := s.create(input, userID, &created); err != nil {
+		return nil, err
+	}
+	s.pages = append(s.pages, created...)
+	return created, nil
+}
+
+func (s *stubPages) create(input pages.PageTreeInput, userID uint, created *[]*pages.Page) error {
+	if input.Title == s.failTitle {
+		return errors.New("insert failed")
+	}
+	page := &pages.Page{
+		ID:       uint(100 + len(s.pages) + len(*created)),
+		Title:    input.Title,
+		Content:  input.Content,
+		UserID:   userID,
+		ParentID: input.ParentID,
+	}
+	*created = append(*created, page)
+	for _, child := range input.Children {
+		child.ParentID = &page.ID
+		if err := s.create(child, userID, created); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+type stubUsers map[uint]*users.User
+
+func (s stubUsers) GetByID(id uint) (*users.User, error) {
+	return s[id], nil
+}
+
+type stubWorkspaces map[[2]uint]workspaces.Role
+
+func (w stubWorkspaces) MemberRole(workspaceID, userID uint) (workspaces.Role, error) {
+	return w[[2]uint{workspaceID, userID}], nil
+}
+
+func newTestService() (Service, *memRepo, *stubPages) {
+	repo := newMemRepo()
+	creator := &stubPages{editors: map[uint][]uint{50: {1}}}
+	people := stubUsers{1: {ID: 1, Name: "Sam"}, 2: {ID: 2, Name: "Alex"}, 3: {ID: 3, Name: "Kim"}}
+	access := stubWorkspaces{{7, 1}: workspaces.RoleEditor, {7, 2}: workspaces.RoleViewer}
+	return NewService(repo, creator, people, access), repo, creator
+}
+
+var retroTemplate = TemplateInput{
+	Name:    "Retro",
+	Title:   "{{team}} retro",
+	Content: "Facilitated by {{user.name}}",
+	Fields:  []Field{{Name: "team", Required: true}},
+	Subpages: []Subpage{
+		{Title: "Went well", Subpages: []Subpage{{Title: "Shout-outs for {{team}}"}}},
+		{Title: "Action items"},
+	},
+}
+
+func TestInstantiate_CreatesPageWithSubpages(t *testing.T) {
+	s, _, creator := newTestService()
+	tmpl, err := s.CreateTemplate(retroTemplate, 1)
+	require.NoError(t, err)
+
+	parent := uint(50)
+	res, err := s.Instantiate(tmpl.ID, InstantiateInput{Values: map[string]string{"team": "Core"}, ParentID: &parent}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Core retro", res.Page.Title)
+	assert.Equal(t, "Facilitated by Sam", res.Page.Content)
+	assert.Equal(t, parent, *res.Page.ParentID)
+	require.Len(t, res.Subpages, 3)
+	assert.Equal(t, "Went well", res.Subpages[0].Title)
+	assert.Equal(t, "Shout-outs for Core", res.Subpages[1].Title)
+	assert.Equal(t, res.Subpages[0].ID, *res.Subpages[1].ParentID)
+	assert.Equal(t, res.Page.ID, *res.Subpages[2].ParentID)
+	assert.Equal(t, 1, creator.calls, "the whole tree is created in one call")
+
+	skip := false
+	res, err = s.Instantiate(tmpl.ID, InstantiateInput{Values: map[string]string{"team": "Core"}, IncludeSubpages: &skip}, 1)
+	require.NoError(t, err)
+	assert.Empty(t, res.Subpages)
+}
+
+func TestInstantiate_RejectsBadInput(t *testing.T) {
+	s, _, creator := newTestService()
+	tmpl, err := s.CreateTemplate(retroTemplate, 1)
+	require.NoError(t, err)
+
+	_, err = s.Instantiate(tmpl.ID, InstantiateInput{}, 1)
+	var missing *MissingFieldsError
+	assert.ErrorAs(t, err, &missing)
+
+	_, err = s.Instantiate(tmpl.ID, InstantiateInput{Values: map[string]string{"team": "Core"}, Timezone: "Mars/Olympus"}, 1)
+	assert.ErrorIs(t, err, ErrInvalidTimezone)
+
+	_, err = s.Instantiate(tmpl.ID, InstantiateInput{Values: map[string]string{"team": "Core"}}, 2)
+	assert.ErrorIs(t, err, ErrTemplateNotFound, "personal templates stay private")
+	assert.Zero(t, creator.calls)
+}
+
+func TestInstantiate_WorkspaceTemplatePermissions(t *testing.T) {
+	s, _, creator := newTestService()
+	ws := uint(7)
+	input := retroTemplate
+	input.WorkspaceID = &ws
+	tmpl, err := s.CreateTemplate(input, 1)
+	require.NoError(t, err)
+
+	_, err = s.CreateTemplate(input, 2)
+	assert.ErrorIs(t, err, ErrForbidden, "viewers cannot add workspace templates")
+
+	values := map[string]string{"team": "Core"}
+	res, err := s.Instantiate(tmpl.ID, InstantiateInput{Values: values}, 2)
+	require.NoError(t, err, "viewers may use workspace templates")
+	assert.Equal(t, "Facilitated by Alex", res.Page.Content)
+
+	_, err = s.Instantiate(tmpl.ID, InstantiateInput{Values: values}, 3)
+	assert.ErrorIs(t, err, ErrTemplateNotFound, "non-members do not see the template")
+
+	parent := uint(50)
+	before := len(creator.pages)
+	_, err = s.Instantiate(tmpl.ID, InstantiateInput{Values: values, ParentID: &parent}, 2)
+	assert.ErrorIs(t, err, pages.ErrForbidden, "the target parent must be editable")
+	assert.Len(t, creator.pages, before)
+}
+
+func TestInstantiate_FailingSubpageLeavesNothing(t *testing.T) {
+	s, _, creator := newTestService()
+	tmpl, err := s.CreateTemplate(retroTemplate, 1)
+	require.NoError(t, err)
+
+	creator.failTitle = "Action items"
+	res, err := s.Instantiate(tmpl.ID, InstantiateInput{Values: map[string]string{"team": "Core"}}, 1)
+	assert.Error(t, err)
+	assert.Nil(t, res)
+	assert.Empty(t, creator.pages)
+}
@@ -0,0 +1,67 @@
+package templates
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"flowboard-backend-go/internal/users"
+)
+
+var placeholderRe = regexp.MustCompile(`\{\{\s*([\w.-]+)\s*\}\}`)
+
+// MissingFieldsError lists required custom fields that had no value.
+type MissingFieldsError struct {
+	Fields []string
+}
+
+func (e *MissingFieldsError) Error() string {
+	return fmt.Sprintf("missing required fields: %s", strings.Join(e.Fields, ", "))
+}
+
+// buildVariables merges built-in variables with the template's custom
+// fields. Built-ins cannot be overridden by field values.
+func buildVariables(t *Template, user *users.User, values map[string]string, now time.Time) (map[string]string, error) {
+	vars := map[string]string{}
+
+	var missing []string
+	for _, f := range t.Fields {
+		v := strings.TrimSpace(values[f.Name])
+		if v == "" {
+			v = f.Default
+		}
+		if v == "" && f.Required {
+			missing = append(missing, f.Name)
+		}
+		vars[f.Name] = v
+	}
+	if len(missing) > 0 {
+		return nil, &MissingFieldsError{Fields: missing}
+	}
+
+	vars["date"] = now.Format("2006-01-02")
+	vars["time"] = now.Format("15:04")
+	vars["datetime"] = now.Format("2006-01-02 15:04")
+	vars["weekday"] = now.Weekday().String()
+	vars["year"] = now.Format("2006")
+	_, week := now.ISOWeek()
+	vars["week"] = fmt.Sprintf("%d", week)
+	if user != nil {
+		vars["user.name"] = user.Name
+		vars["user.email"] = user.Email
+	}
+	return vars, nil
+}
+
+// render substitutes known placeholders. Unknown ones are left in place so
+// typos stay visible in the created page.
+func render(s string, vars map[string]string) string {
+	return placeholderRe.ReplaceAllStringFunc(s, func(m string) string {
+		name := placeholderRe.FindStringSubmatch(m)[1]
+		if v, ok := vars[name]; ok {
+			return v
+		}
+		return m
+	})
+}
@@ -0,0 +1,47 @@
+package templates
+
+import (
+	"testing"
+	"time"
+
+	"flowboard-backend-go/internal/users"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender_BuiltinsAndCustomFields(t *testing.T) {
+	tmpl := &Template{Fields: []Field{
+		{Name: "team", Default: "Core"},
+		{Name: "facilitator"},
+	}}
+	user := &users.User{Name: "Sam", Email: "sam@example.com"}
+	now := time.Date(2024, time.March, 4, 9, 30, 0, 0, time.UTC)
+
+	vars, err := buildVariables(tmpl, user, map[string]string{"facilitator": "Kim"}, now)
+	require.NoError(t, err)
+
+	out := render("{{team}} retro {{ date }} ({{weekday}}, week {{week}}) by {{user.name}} with {{facilitator}} {{unknown}}", vars)
+	assert.Equal(t, "Core retro 2024-03-04 (Monday, week 10) by Sam with Kim {{unknown}}", out)
+}
+
+func TestBuildVariables_RequiredFields(t *testing.T) {
+	tmpl := &Template{Fields: []Field{
+		{Name: "topic", Required: true},
+		{Name: "owner", Required: true, Default: "me"},
+	}}
+
+	_, err := buildVariables(tmpl, nil, map[string]string{"topic": "  "}, time.Now())
+	var missing *MissingFieldsError
+	require.ErrorAs(t, err, &missing)
+	assert.Equal(t, []string{"topic"}, missing.Fields)
+}
+
+func TestBuildVariables_BuiltinsWin(t *testing.T) {
+	tmpl := &Template{Fields: []Field{{Name: "date"}}}
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	vars, err := buildVariables(tmpl, nil, map[string]string{"date": "tomorrow"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-01", vars["date"])
+}
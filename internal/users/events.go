@@ -0,0 +1,40 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"flowboard-backend-go/internal/outbox"
+)
+
+type EventType string
+
+// UserRegistered is recorded in the outbox together with a new account.
+const UserRegistered EventType = "user.registered"
+
+// UserEvent describes a change to an account.
+type UserEvent struct {
+	Type   EventType `json:"type"`
+	UserID uint      `json:"userId"`
+	Name   string    `json:"name"`
+	Email  string    `json:"email"`
+	At     time.Time `json:"at"`
+}
+
+// EventHandler consumes user.registered events relayed from the outbox. An
+// event may arrive more than once, always with the same eventID.
+type EventHandler interface {
+	HandleUserRegistered(ctx context.Context, eventID uint64, e UserEvent) error
+}
+
+// Consume adapts h to an outbox handler; subscribe it to UserRegistered.
+func Consume(h EventHandler) outbox.Handler {
+	return func(ctx context.Context, m *outbox.Message) error {
+		var e UserEvent
+		if err := json.Unmarshal([]byte(m.Payload), &e); err != nil {
+			return err
+		}
+		return h.HandleUserRegistered(ctx, m.ID, e)
+	}
+}
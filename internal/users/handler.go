@@ -1,6 +1,7 @@
 package users
 
 import (
+	"flowboard-backend-go/internal/audit"
 	"flowboard-backend-go/internal/auth"
 	"flowboard-backend-go/internal/middleware"
 	"flowboard-backend-go/pkg/logger"
@@ -12,12 +13,14 @@ import (
 type Handler struct {
 	service Service
 	jwt     *middleware.JWTManager
+	audit   audit.Recorder
 }
 
-func NewHandler(service Service, jwt *middleware.JWTManager) *Handler {
+func NewHandler(service Service, jwt *middleware.JWTManager, auditLog audit.Recorder) *Handler {
 	return &Handler{
 		service: service,
 		jwt:     jwt,
+		audit:   auditLog,
 	}
 }
 func (h *Handler) Register(c *gin.Context) {
@@ -65,6 +68,9 @@ func (h *Handler) Login(c *gin.Context) {
 	user, err := h.service.Authenticate(in.Email, in.Password)
 	if err != nil {
 		logger.Log.Infow("Login failed", "email", in.Email, "error", err)
+		if err == ErrInvalidCredentials {
+			h.audit.Record(audit.FromRequest(c), audit.LoginFailed, audit.Target{}, map[string]any{"email": in.Email})
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
 	}
@@ -76,6 +82,10 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
+	req := audit.FromRequest(c)
+	req.ActorID = &user.ID
+	h.audit.Record(req, audit.LoginSucceeded, audit.Target{Type: audit.TargetUser, ID: user.ID}, nil)
+
 	logger.Log.Infow("User logged in successfully", "userID", user.ID, "email", user.Email)
 	c.JSON(http.StatusOK, auth.AuthResponse{
 		User:  ToUserResponse(user),
@@ -105,3 +115,23 @@ func (h *Handler) Profile(c *gin.Context) {
 	logger.Log.Infow("Profile fetched", "userID", id)
 	c.JSON(http.StatusOK, gin.H{"user": ToUserResponse(user)})
 }
+
+// RevokeTokens signs the caller out of every session, this one included.
+func (h *Handler) RevokeTokens(c *gin.Context) {
+	uid, exists := c.Get(middleware.ContextUserIDKey)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing user"})
+		return
+	}
+
+	id := uid.(uint)
+	if err := h.service.RevokeTokens(id); err != nil {
+		logger.Log.Errorw("Token revocation failed", "userID", id, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.audit.Record(audit.FromRequest(c), audit.TokensRevoked, audit.Target{Type: audit.TargetUser, ID: id}, nil)
+
+	logger.Log.Infow("Tokens revoked", "userID", id)
+	c.JSON(http.StatusNoContent, nil)
+}
@@ -2,11 +2,16 @@ package users
 
 import "time"
 
+// User is an account. Besides the unique email, two expression indexes
+// serve FindUsersByHandle, which matches the email or its local part
+// regardless of case.
 type User struct {
-	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	Name      string    `json:"name" gorm:"size:255;not null"`
-	Email     string    `json:"email" gorm:"size:255;uniqueIndex;not null"`
-	Password  string    `json:"-" gorm:"size:255;not null"` // Exclude password from JSON responses
-	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
-	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+	ID       uint   `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name     string `json:"name" gorm:"size:255;not null"`
+	Email    string `json:"email" gorm:"size:255;uniqueIndex;not null;index:idx_users_email_lower,expression:lower(email);index:idx_users_handle,expression:lower(split_part(email\\,'@'\\,1))"`
+	Password string `json:"-" gorm:"size:255;not null"` // Exclude password from JSON responses
+	// TokensRevokedAt invalidates every token issued at or before it.
+	TokensRevokedAt *time.Time `json:"-"`
+	CreatedAt       time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt       time.Time  `json:"updatedAt" gorm:"autoUpdateTime"`
 }
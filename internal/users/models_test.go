@@ -0,0 +1,22 @@
+package users
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/schema"
+)
+
+func TestUserIndexesServeHandleLookups(t *testing.T) {
+	s, err := schema.Parse(&User{}, &sync.Map{}, schema.NamingStrategy{})
+	require.NoError(t, err)
+
+	expressions := map[string]string{}
+	for _, idx := range s.ParseIndexes() {
+		expressions[idx.Name] = idx.Fields[0].Expression
+	}
+	assert.Equal(t, "lower(email)", expressions["idx_users_email_lower"])
+	assert.Equal(t, "lower(split_part(email,'@',1))", expressions["idx_users_handle"])
+}
@@ -2,6 +2,10 @@ package users
 
 import (
 	"errors"
+	"strings"
+	"time"
+
+	"flowboard-backend-go/internal/outbox"
 
 	"gorm.io/gorm"
 )
@@ -11,6 +15,12 @@ type Repository interface {
 	CreateUser(u *User) error
 	GetUserByEmail(email string) (*User, error)
 	GetUserByID(id uint) (*User, error)
+	// FindUsersByHandle returns the users a plain @handle may refer to: the
+	// one with that email, or, for a handle without "@", those whose email
+	// starts with handle@. Matching ignores case; the expressions match the
+	// indexes declared on User.
+	FindUsersByHandle(handle string) ([]User, error)
+	RevokeTokens(id uint, at time.Time) error
 }
 
 type repository struct {
@@ -21,9 +31,21 @@ func NewRepository(db *gorm.DB) Repository {
 	return &repository{db: db}
 }
 
-// CreateUser implements Repository.
+// CreateUser implements Repository. The user.registered event is recorded
+// in the same transaction.
 func (r *repository) CreateUser(u *User) error {
-	return r.db.Create(u).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(u).Error; err != nil {
+			return err
+		}
+		return outbox.Append(tx, string(UserRegistered), UserEvent{
+			Type:   UserRegistered,
+			UserID: u.ID,
+			Name:   u.Name,
+			Email:  u.Email,
+			At:     u.CreatedAt,
+		})
+	})
 }
 
 // GetUserByEmail implements Repository.
@@ -48,3 +70,20 @@ func (r *repository) GetUserByID(id uint) (*User, error) {
 	}
 	return &u, nil
 }
+
+func (r *repository) FindUsersByHandle(handle string) ([]User, error) {
+	handle = strings.ToLower(handle)
+	q := r.db.Where("LOWER(email) = ?", handle)
+	if !strings.Contains(handle, "@") {
+		q = r.db.Where("LOWER(split_part(email, '@', 1)) = ?", handle)
+	}
+	var list []User
+	if err := q.Order("id").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *repository) RevokeTokens(id uint, at time.Time) error {
+	return r.db.Model(&User{}).Where("id = ?", id).UpdateColumn("tokens_revoked_at", at).Error
+}
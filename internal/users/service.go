@@ -16,6 +16,17 @@ type Service interface {
 	Register(name, email, password string) (*User, error)
 	Authenticate(email, password string) (*User, error)
 	GetByID(id uint) (*User, error)
+	GetByEmail(email string) (*User, error)
+	// FindByHandle resolves a plain @handle, an email address or its local
+	// part, to the users it may refer to.
+	FindByHandle(handle string) ([]User, error)
+	// RevokeTokens signs the user out everywhere: every token issued so far
+	// stops working.
+	RevokeTokens(userID uint) error
+	// TokensRevoked reports whether a token issued to the user at issuedAt
+	// has been revoked. Token times have second precision, so a token
+	// issued in the same second as a revocation counts as revoked.
+	TokensRevoked(userID uint, issuedAt time.Time) (bool, error)
 }
 
 type service struct {
@@ -91,3 +102,45 @@ func (s *service) GetByID(id uint) (*User, error) {
 	u.Password = ""
 	return u, nil
 }
+
+// GetByEmail implements Service.
+func (s *service) GetByEmail(email string) (*User, error) {
+	u, err := s.repo.GetUserByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, nil
+	}
+	u.Password = ""
+	return u, nil
+}
+
+// FindByHandle implements Service.
+func (s *service) FindByHandle(handle string) ([]User, error) {
+	list, err := s.repo.FindUsersByHandle(handle)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].Password = ""
+	}
+	return list, nil
+}
+
+// RevokeTokens implements Service.
+func (s *service) RevokeTokens(userID uint) error {
+	return s.repo.RevokeTokens(userID, time.Now())
+}
+
+// TokensRevoked implements Service. Tokens of deleted users are revoked.
+func (s *service) TokensRevoked(userID uint, issuedAt time.Time) (bool, error) {
+	u, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return false, err
+	}
+	if u == nil {
+		return true, nil
+	}
+	return u.TokensRevokedAt != nil && !issuedAt.After(*u.TokensRevokedAt), nil
+}
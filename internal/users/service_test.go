@@ -38,6 +38,16 @@ func (m *MockRepo) GetUserByID(id uint) (*User, error) {
 	return u.(*User), args.Error(1)
 }
 
+func (m *MockRepo) FindUsersByHandle(handle string) ([]User, error) {
+	args := m.Called(handle)
+	list, _ := args.Get(0).([]User)
+	return list, args.Error(1)
+}
+
+func (m *MockRepo) RevokeTokens(id uint, at time.Time) error {
+	return m.Called(id, at).Error(0)
+}
+
 func TestRegister_Success(t *testing.T) {
 	mockRepo := new(MockRepo)
 	s := NewService(mockRepo)
@@ -140,3 +150,25 @@ func TestGetByID_RepoError(t *testing.T) {
 	assert.Nil(t, user)
 	assert.EqualError(t, err, "DB error")
 }
+
+func TestTokensRevoked(t *testing.T) {
+	mockRepo := new(MockRepo)
+	service := NewService(mockRepo)
+	revokedAt := time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)
+
+	mockRepo.On("GetUserByID", uint(1)).Return(&User{ID: 1}, nil)
+	mockRepo.On("GetUserByID", uint(2)).Return(&User{ID: 2, TokensRevokedAt: &revokedAt}, nil)
+	mockRepo.On("GetUserByID", uint(3)).Return(nil, nil)
+
+	revoked, err := service.TokensRevoked(1, revokedAt)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	revoked, _ = service.TokensRevoked(2, revokedAt)
+	assert.True(t, revoked, "a token from the second of the revocation is revoked")
+	revoked, _ = service.TokensRevoked(2, revokedAt.Add(time.Second))
+	assert.False(t, revoked, "tokens issued afterwards work")
+
+	revoked, _ = service.TokensRevoked(3, revokedAt)
+	assert.True(t, revoked)
+}
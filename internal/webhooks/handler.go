@@ -0,0 +1,263 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"flowboard-backend-go/internal/audit"
+	"flowboard-backend-go/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+	audit   audit.Recorder
+}
+
+func NewHandler(service Service, auditLog audit.Recorder) *Handler {
+	return &Handler{
+		service: service,
+		audit:   auditLog,
+	}
+}
+
+// getUserID safely retrieves user ID from context
+func getUserID(c *gin.Context) (uint, error) {
+	uidVal, exists := c.Get(middleware.ContextUserIDKey)
+	if !exists {
+		return 0, fmt.Errorf("unauthorized")
+	}
+
+	uid, ok := uidVal.(uint)
+	if !ok {
+		return 0, fmt.Errorf("invalid user ID type")
+	}
+
+	return uid, nil
+}
+
+// parseID reads a numeric path parameter
+func parseID(c *gin.Context, name string) (uint, bool) {
+	id64, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		return 0, false
+	}
+	return uint(id64), true
+}
+
+// respondError maps service errors to HTTP statuses
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrWebhookNotFound), errors.Is(err, ErrDeliveryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidURL), errors.Is(err, ErrUnresolvableURL), errors.Is(err, ErrInternalURL):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrDeliveryPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GetWebhooks lists the user's personal webhooks, or a workspace's with
+// ?workspaceId=.
+func (h *Handler) GetWebhooks(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var workspaceID *uint
+	if v := c.Query("workspaceId"); v != "" {
+		id64, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid workspaceId"})
+			return
+		}
+		id := uint(id64)
+		workspaceID = &id
+	}
+
+	list, err := h.service.GetWebhooks(userID, workspaceID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": list})
+}
+
+// CreateWebhook responds with the signing secret; it is not shown again.
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input WebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hook, err := h.service.CreateWebhook(input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	h.record(c, audit.WebhookCreated, hook)
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": hook, "secret": hook.Secret})
+}
+
+func (h *Handler) UpdateWebhook(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input WebhookUpdateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hook, err := h.service.UpdateWebhook(id, input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	h.record(c, audit.WebhookUpdated, hook)
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": hook})
+}
+
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteWebhook(id, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+	h.record(c, audit.WebhookDeleted, &Webhook{ID: id})
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// record adds a webhook change to the audit trail. Secrets stay out of it.
+func (h *Handler) record(c *gin.Context, action audit.Action, hook *Webhook) {
+	var details map[string]any
+	if hook.URL != "" {
+		details = map[string]any{"url": hook.URL, "events": hook.Events, "active": hook.Active, "workspaceId": hook.WorkspaceID}
+	}
+	h.audit.Record(audit.FromRequest(c), action, audit.Target{Type: audit.TargetWebhook, ID: hook.ID}, details)
+}
+
+// GetDeliveries pages through a webhook's delivery history, newest first.
+// Filter with ?status=pending|succeeded|dead.
+func (h *Handler) GetDeliveries(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	filter := DeliveryFilter{Status: DeliveryStatus(c.Query("status"))}
+	switch filter.Status {
+	case "", DeliveryPending, DeliverySucceeded, DeliveryDead:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+		return
+	}
+	if v := c.Query("limit"); v != "" {
+		if filter.Limit, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+			return
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if filter.Offset, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+			return
+		}
+	}
+
+	list, total, err := h.service.GetDeliveries(id, userID, filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": list, "total": total})
+}
+
+// SendTest sends a ping and responds with the delivery, including the
+// receiver's status code.
+func (h *Handler) SendTest(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.service.SendTest(c.Request.Context(), id, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": delivery})
+}
+
+func (h *Handler) Redeliver(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	id, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	deliveryID, ok := parseID(c, "deliveryId")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.service.Redeliver(id, deliveryID, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"success": true, "data": delivery})
+}
@@ -0,0 +1,85 @@
+package webhooks
+
+import "time"
+
+// EventPing is the type of the test event sent on request. It is delivered
+// regardless of a webhook's subscribed events.
+const EventPing = "ping"
+
+// Events lists the event types a webhook can subscribe to.
+var Events = []string{"page.created", "page.updated", "page.deleted"}
+
+// Webhook posts signed events to URL. A webhook with a WorkspaceID
+// receives events for that workspace's pages and is managed by its admins;
+// one without receives events for its owner's personal pages.
+type Webhook struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	UserID      uint      `gorm:"not null;index" json:"userId"`
+	WorkspaceID *uint     `gorm:"index" json:"workspaceId,omitempty"`
+	URL         string    `gorm:"size:2000;not null" json:"url"`
+	Events      []string  `gorm:"serializer:json;type:jsonb" json:"events"`
+	Secret      string    `gorm:"size:100;not null" json:"-"`
+	Active      bool      `gorm:"not null" json:"active"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Subscribes reports whether the webhook wants events of type t.
+func (w *Webhook) Subscribes(t string) bool {
+	for _, e := range w.Events {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	// DeliveryDead marks a delivery that failed every attempt. It is kept
+	// for inspection and can be redelivered by hand.
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+// Delivery is one event queued for one webhook, with the outcome of its
+// latest attempt. EventID is shared by every attempt so receivers can
+// discard duplicates, and a webhook gets each event once.
+type Delivery struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	WebhookID      uint           `gorm:"not null;uniqueIndex:idx_delivery_webhook_event" json:"webhookId"`
+	EventID        string         `gorm:"size:64;not null;uniqueIndex:idx_delivery_webhook_event" json:"eventId"`
+	EventType      string         `gorm:"size:64;not null" json:"eventType"`
+	Payload        string         `gorm:"type:text;not null" json:"payload"`
+	Status         DeliveryStatus `gorm:"size:20;not null;index" json:"status"`
+	Attempts       int            `gorm:"not null" json:"attempts"`
+	NextAttemptAt  *time.Time     `gorm:"index" json:"nextAttemptAt,omitempty"`
+	ResponseStatus int            `json:"responseStatus,omitempty"`
+	LastError      string         `gorm:"size:1000" json:"lastError,omitempty"`
+	DeliveredAt    *time.Time     `json:"deliveredAt,omitempty"`
+	CreatedAt      time.Time      `gorm:"index" json:"createdAt"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+}
+
+// WebhookInput for creating a webhook
+type WebhookInput struct {
+	WorkspaceID *uint    `json:"workspaceId"`
+	URL         string   `json:"url" binding:"required,url,max=2000"`
+	Events      []string `json:"events" binding:"required,min=1,dive,oneof=page.created page.updated page.deleted"`
+}
+
+// WebhookUpdateInput changes a webhook; nil fields keep their value.
+type WebhookUpdateInput struct {
+	URL    *string  `json:"url" binding:"omitempty,url,max=2000"`
+	Events []string `json:"events" binding:"omitempty,min=1,dive,oneof=page.created page.updated page.deleted"`
+	Active *bool    `json:"active"`
+}
+
+// DeliveryFilter pages through a webhook's deliveries, newest first.
+type DeliveryFilter struct {
+	Status DeliveryStatus
+	Limit  int
+	Offset int
+}
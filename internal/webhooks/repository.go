@@ -0,0 +1,164 @@
+package webhooks
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository interface {
+	// Transaction runs fn with a Repository bound to a single database
+	// transaction; any error rolls everything back.
+	Transaction(fn func(repo Repository) error) error
+
+	CreateWebhook(w *Webhook) error
+	GetWebhookByID(id uint) (*Webhook, error)
+	// GetPersonalWebhooks lists the user's webhooks without a workspace.
+	GetPersonalWebhooks(userID uint) ([]Webhook, error)
+	GetWorkspaceWebhooks(workspaceID uint) ([]Webhook, error)
+	UpdateWebhook(w *Webhook) error
+	// DeleteWebhook removes the webhook and its delivery history.
+	DeleteWebhook(id uint) error
+
+	// CreateDeliveries stores the deliveries, skipping any event already
+	// queued for the same webhook.
+	CreateDeliveries(list []Delivery) error
+	GetDeliveryByID(id uint) (*Delivery, error)
+	// GetDeliveries returns one page of a webhook's deliveries, newest
+	// first, and the total matching the filter.
+	GetDeliveries(webhookID uint, filter DeliveryFilter) ([]Delivery, int64, error)
+	// ClaimDue locks up to limit pending deliveries due at or before now.
+	// Rows locked by another transaction are skipped, so concurrent
+	// workers never claim the same delivery. Must run inside Transaction.
+	ClaimDue(now time.Time, limit int) ([]Delivery, error)
+	// Postpone moves the next attempt of the deliveries to until.
+	Postpone(ids []uint, until time.Time) error
+	SaveDelivery(d *Delivery) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Transaction(fn func(repo Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&repository{db: tx})
+	})
+}
+
+func (r *repository) CreateWebhook(w *Webhook) error {
+	return r.db.Create(w).Error
+}
+
+func (r *repository) GetWebhookByID(id uint) (*Webhook, error) {
+	var w Webhook
+	if err := r.db.First(&w, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (r *repository) GetPersonalWebhooks(userID uint) ([]Webhook, error) {
+	var list []Webhook
+	err := r.db.Where("user_id = ? AND workspace_id IS NULL", userID).Order("id").Find(&list).Error
+	return list, err
+}
+
+func (r *repository) GetWorkspaceWebhooks(workspaceID uint) ([]Webhook, error) {
+	var list []Webhook
+	err := r.db.Where("workspace_id = ?", workspaceID).Order("id").Find(&list).Error
+	return list, err
+}
+
+func (r *repository) UpdateWebhook(w *Webhook) error {
+	return r.db.Save(w).Error
+}
+
+func (r *repository) DeleteWebhook(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&Delivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Webhook{}, id).Error
+	})
+}
+
+func (r *repository) CreateDeliveries(list []Delivery) error {
+	if len(list) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&list).Error
+}
+
+func (r *repository) GetDeliveryByID(id uint) (*Delivery, error) {
+	var d Delivery
+	if err := r.db.First(&d, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (r *repository) GetDeliveries(webhookID uint, filter DeliveryFilter) ([]Delivery, int64, error) {
+	scope := func(db *gorm.DB) *gorm.DB {
+		db = db.Where("webhook_id = ?", webhookID)
+		if filter.Status != "" {
+			db = db.Where("status = ?", filter.Status)
+		}
+		return db
+	}
+
+	var total int64
+	if err := r.db.Model(&Delivery{}).Scopes(scope).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var list []Delivery
+	err := r.db.Scopes(scope).
+		Order("created_at DESC, id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&list).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
+
+func (r *repository) ClaimDue(now time.Time, limit int) ([]Delivery, error) {
+	var list []Delivery
+	err := r.db.
+		Where("status = ? AND next_attempt_at <= ?", DeliveryPending, now).
+		Order("next_attempt_at, id").
+		Limit(limit).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *repository) Postpone(ids []uint, until time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Model(&Delivery{}).Where("id IN ?", ids).UpdateColumn("next_attempt_at", until).Error
+}
+
+// SaveDelivery updates an existing delivery; one deleted in the meantime
+// is not recreated.
+func (r *repository) SaveDelivery(d *Delivery) error {
+	return r.db.Model(d).Select("*").Omit("created_at").Updates(d).Error
+}
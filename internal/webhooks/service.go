@@ -0,0 +1,479 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"flowboard-backend-go/internal/pages"
+	"flowboard-backend-go/internal/workspaces"
+	"flowboard-backend-go/pkg/logger"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+	ErrForbidden        = errors.New("insufficient permissions")
+	ErrInvalidURL       = errors.New("webhook url must be https")
+	ErrUnresolvableURL  = errors.New("webhook url host does not resolve")
+	ErrInternalURL      = errors.New("webhook url must point to a public address")
+	ErrDeliveryPending  = errors.New("delivery is still queued")
+)
+
+const (
+	defaultLimit = 20
+	maxLimit     = 100
+
+	// maxAttempts is how often a delivery is tried before it is dead.
+	maxAttempts = 8
+	// baseBackoff is the wait after the first failure; it doubles with
+	// every further failure.
+	baseBackoff = 30 * time.Second
+	// deliveryBatch bounds how many deliveries one worker pass claims.
+	deliveryBatch = 20
+	// deliveryLease postpones claimed deliveries while they are attempted,
+	// so other workers skip them. It must exceed deliveryBatch requests of
+	// requestTimeout each; a worker that dies leaves them to be retried
+	// once it runs out.
+	deliveryLease  = 5 * time.Minute
+	requestTimeout = 10 * time.Second
+	// responseLimit bounds how much of a response body is read.
+	responseLimit = 64 << 10
+	maxErrorLen   = 1000
+)
+
+// WorkspaceAccess reports a user's role in a workspace ("" for
+// non-members); satisfied by workspaces.Service.
+type WorkspaceAccess interface {
+	MemberRole(workspaceID, userID uint) (workspaces.Role, error)
+}
+
+type Service interface {
+	pages.EventHandler
+
+	GetWebhooks(userID uint, workspaceID *uint) ([]Webhook, error)
+	// CreateWebhook stores a webhook with a new signing secret, which is
+	// only ever returned here.
+	CreateWebhook(input WebhookInput, userID uint) (*Webhook, error)
+	UpdateWebhook(id uint, input WebhookUpdateInput, userID uint) (*Webhook, error)
+	DeleteWebhook(id, userID uint) error
+
+	GetDeliveries(webhookID, userID uint, filter DeliveryFilter) ([]Delivery, int64, error)
+	// SendTest sends a ping event right away and returns its delivery.
+	// A failed ping is retried like any other delivery.
+	SendTest(ctx context.Context, webhookID, userID uint) (*Delivery, error)
+	// Redeliver queues a finished delivery again with a fresh set of
+	// attempts.
+	Redeliver(webhookID, deliveryID, userID uint) (*Delivery, error)
+	// DeliverDue attempts every delivery that has come due. Several
+	// replicas may run it at once; each attempt is made by one of them.
+	DeliverDue(ctx context.Context) (int, error)
+}
+
+type service struct {
+	repo       Repository
+	workspaces WorkspaceAccess
+	client     *http.Client
+	allowLocal bool
+	now        func() time.Time
+}
+
+// Option configures optional behaviour of the webhook service.
+type Option func(*service)
+
+// WithLocalTargets accepts plain http receivers on loopback and private
+// addresses. It is meant for development; by default webhooks may only
+// reach public https endpoints.
+func WithLocalTargets() Option {
+	return func(s *service) { s.allowLocal = true }
+}
+
+func NewService(repo Repository, workspaces WorkspaceAccess, opts ...Option) Service {
+	s := &service{repo: repo, workspaces: workspaces, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.client = newClient(s.allowLocal)
+	return s
+}
+
+func (s *service) GetWebhooks(userID uint, workspaceID *uint) ([]Webhook, error) {
+	if workspaceID != nil {
+		if err := s.requireWorkspaceAdmin(*workspaceID, userID); err != nil {
+			return nil, err
+		}
+		return s.repo.GetWorkspaceWebhooks(*workspaceID)
+	}
+	return s.repo.GetPersonalWebhooks(userID)
+}
+
+func (s *service) CreateWebhook(input WebhookInput, userID uint) (*Webhook, error) {
+	if err := s.checkURL(input.URL); err != nil {
+		return nil, err
+	}
+	if input.WorkspaceID != nil {
+		if err := s.requireWorkspaceAdmin(*input.WorkspaceID, userID); err != nil {
+			return nil, err
+		}
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	hook := &Webhook{
+		UserID:      userID,
+		WorkspaceID: input.WorkspaceID,
+		URL:         input.URL,
+		Events:      input.Events,
+		Secret:      secret,
+		Active:      true,
+	}
+	if err := s.repo.CreateWebhook(hook); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+func (s *service) UpdateWebhook(id uint, input WebhookUpdateInput, userID uint) (*Webhook, error) {
+	hook, err := s.loadWebhook(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if input.URL != nil {
+		if err := s.checkURL(*input.URL); err != nil {
+			return nil, err
+		}
+		hook.URL = *input.URL
+	}
+	if input.Events != nil {
+		hook.Events = input.Events
+	}
+	if input.Active != nil {
+		hook.Active = *input.Active
+	}
+	if err := s.repo.UpdateWebhook(hook); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+func (s *service) DeleteWebhook(id, userID uint) error {
+	if _, err := s.loadWebhook(id, userID); err != nil {
+		return err
+	}
+	return s.repo.DeleteWebhook(id)
+}
+
+func (s *service) GetDeliveries(webhookID, userID uint, filter DeliveryFilter) ([]Delivery, int64, error) {
+	if _, err := s.loadWebhook(webhookID, userID); err != nil {
+		return nil, 0, err
+	}
+	switch {
+	case filter.Limit <= 0:
+		filter.Limit = defaultLimit
+	case filter.Limit > maxLimit:
+		filter.Limit = maxLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.repo.GetDeliveries(webhookID, filter)
+}
+
+func (s *service) SendTest(ctx context.Context, webhookID, userID uint) (*Delivery, error) {
+	hook, err := s.loadWebhook(webhookID, userID)
+	if err != nil {
+		return nil, err
+	}
+	eventID, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.newDelivery(hook, eventID, EventPing, map[string]any{"webhookId": hook.ID, "message": "Test event from FlowBoard"})
+	if err != nil {
+		return nil, err
+	}
+	// Leased to this request so the worker leaves it alone.
+	lease := s.now().Add(deliveryLease)
+	d.NextAttemptAt = &lease
+	list := []Delivery{*d}
+	if err := s.repo.CreateDeliveries(list); err != nil {
+		return nil, err
+	}
+	d = &list[0]
+	if err := s.attempt(ctx, hook, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *service) Redeliver(webhookID, deliveryID, userID uint) (*Delivery, error) {
+	if _, err := s.loadWebhook(webhookID, userID); err != nil {
+		return nil, err
+	}
+	d, err := s.repo.GetDeliveryByID(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil || d.WebhookID != webhookID {
+		return nil, ErrDeliveryNotFound
+	}
+	if d.Status == DeliveryPending {
+		return nil, ErrDeliveryPending
+	}
+	now := s.now()
+	d.Status = DeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = &now
+	if err := s.repo.SaveDelivery(d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// HandlePageEvent queues the event for every active webhook subscribed to
+// it: the workspace's webhooks, or the owner's personal ones. The event ID
+// derives from the outbox message, so a redelivered message is not queued
+// twice.
+func (s *service) HandlePageEvent(ctx context.Context, eventID uint64, e pages.PageEvent) error {
+	var hooks []Webhook
+	var err error
+	if e.WorkspaceID != nil {
+		hooks, err = s.repo.GetWorkspaceWebhooks(*e.WorkspaceID)
+	} else {
+		hooks, err = s.repo.GetPersonalWebhooks(e.OwnerID)
+	}
+	if err != nil {
+		return err
+	}
+
+	var list []Delivery
+	for i := range hooks {
+		if !hooks[i].Active || !hooks[i].Subscribes(string(e.Type)) {
+			continue
+		}
+		d, err := s.newDelivery(&hooks[i], fmt.Sprintf("evt_%d", eventID), string(e.Type), e)
+		if err != nil {
+			return err
+		}
+		list = append(list, *d)
+	}
+	return s.repo.CreateDeliveries(list)
+}
+
+func (s *service) DeliverDue(ctx context.Context) (int, error) {
+	attempted := 0
+	for {
+		var due []Delivery
+		err := s.repo.Transaction(func(repo Repository) error {
+			var err error
+			if due, err = repo.ClaimDue(s.now(), deliveryBatch); err != nil {
+				return err
+			}
+			ids := make([]uint, len(due))
+			for i := range due {
+				ids[i] = due[i].ID
+			}
+			return repo.Postpone(ids, s.now().Add(deliveryLease))
+		})
+		if err != nil {
+			return attempted, err
+		}
+
+		hooks := map[uint]*Webhook{}
+		for i := range due {
+			d := &due[i]
+			hook, ok := hooks[d.WebhookID]
+			if !ok {
+				if hook, err = s.repo.GetWebhookByID(d.WebhookID); err != nil {
+					return attempted, err
+				}
+				hooks[d.WebhookID] = hook
+			}
+			// Deleted along with its deliveries in the meantime.
+			if hook == nil {
+				continue
+			}
+			if !hook.Active {
+				d.Status = DeliveryDead
+				d.NextAttemptAt = nil
+				d.LastError = "webhook is disabled"
+				err = s.repo.SaveDelivery(d)
+			} else {
+				err = s.attempt(ctx, hook, d)
+			}
+			if err != nil {
+				return attempted, err
+			}
+			attempted++
+		}
+		if len(due) < deliveryBatch || ctx.Err() != nil {
+			return attempted, nil
+		}
+	}
+}
+
+// attempt posts the delivery once and records the outcome: success, a
+// retry after the next backoff, or the dead-letter state once attempts run
+// out. Only failures to save the outcome are returned.
+func (s *service) attempt(ctx context.Context, hook *Webhook, d *Delivery) error {
+	status, err := s.post(ctx, hook, d)
+	now := s.now()
+	d.Attempts++
+	d.ResponseStatus = status
+	switch {
+	case err == nil:
+		d.Status = DeliverySucceeded
+		d.NextAttemptAt = nil
+		d.LastError = ""
+		d.DeliveredAt = &now
+	case d.Attempts >= maxAttempts:
+		d.Status = DeliveryDead
+		d.NextAttemptAt = nil
+		d.LastError = truncate(err.Error())
+	default:
+		next := now.Add(backoff(d.Attempts))
+		d.NextAttemptAt = &next
+		d.LastError = truncate(err.Error())
+	}
+	return s.repo.SaveDelivery(d)
+}
+
+// post sends the signed payload and returns the response status. Any
+// status outside 2xx is an error.
+func (s *service) post(ctx context.Context, hook *Webhook, d *Delivery) (int, error) {
+	body := []byte(d.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "FlowBoard-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, d.EventType)
+	req.Header.Set(HeaderDelivery, d.EventID)
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, s.now(), body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, deliveryError(err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, responseLimit))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// newDelivery wraps data in the event envelope receivers get.
+func (s *service) newDelivery(hook *Webhook, eventID, eventType string, data any) (*Delivery, error) {
+	now := s.now()
+	payload, err := json.Marshal(struct {
+		ID        string    `json:"id"`
+		Type      string    `json:"type"`
+		CreatedAt time.Time `json:"createdAt"`
+		Data      any       `json:"data"`
+	}{eventID, eventType, now, data})
+	if err != nil {
+		return nil, err
+	}
+	return &Delivery{
+		WebhookID:     hook.ID,
+		EventID:       eventID,
+		EventType:     eventType,
+		Payload:       string(payload),
+		Status:        DeliveryPending,
+		NextAttemptAt: &now,
+	}, nil
+}
+
+// loadWebhook returns a webhook the user may manage: their own personal
+// one, or one of a workspace they administer.
+func (s *service) loadWebhook(id, userID uint) (*Webhook, error) {
+	hook, err := s.repo.GetWebhookByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if hook == nil {
+		return nil, ErrWebhookNotFound
+	}
+	if hook.WorkspaceID == nil {
+		if hook.UserID != userID {
+			return nil, ErrWebhookNotFound
+		}
+		return hook, nil
+	}
+	if err := s.requireWorkspaceAdmin(*hook.WorkspaceID, userID); err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// requireWorkspaceAdmin checks the user administers the workspace.
+// Non-members get ErrWebhookNotFound so the workspace's existence is not
+// revealed.
+func (s *service) requireWorkspaceAdmin(workspaceID, userID uint) error {
+	role, err := s.workspaces.MemberRole(workspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return ErrWebhookNotFound
+	}
+	if !role.AtLeast(workspaces.RoleAdmin) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// backoff is the wait after the given number of failed attempts.
+func backoff(attempts int) time.Duration {
+	return baseBackoff << (attempts - 1)
+}
+
+func newSecret() (string, error) {
+	s, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+	return "whsec_" + s, nil
+}
+
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func truncate(s string) string {
+	if len(s) > maxErrorLen {
+		return s[:maxErrorLen]
+	}
+	return s
+}
+
+// RunWorker attempts due deliveries every interval until ctx is cancelled.
+func RunWorker(ctx context.Context, s Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.DeliverDue(ctx); err != nil {
+				logger.Log.Errorw("Delivering webhooks failed", "error", err)
+			}
+		}
+	}
+}
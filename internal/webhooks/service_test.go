@@ -0,0 +1,419 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"flowboard-backend-go/internal/pages"
+	"flowboard-backend-go/internal/workspaces"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memRepo is an in-memory Repository for service tests.
+type memRepo struct {
+	mu         sync.Mutex
+	hooks      map[uint]*Webhook
+	deliveries map[uint]*Delivery
+	nextID     uint
+}
+
+func newMemRepo() *memRepo {
+	return &memRepo{hooks: map[uint]*Webhook{}, deliveries: map[uint]*Delivery{}}
+}
+
+func (r *memRepo) Transaction(fn func(repo Repository) error) error {
+	return fn(r)
+}
+
+func (r *memRepo) CreateWebhook(w *Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	w.ID = r.nextID
+	cp := *w
+	r.hooks[w.ID] = &cp
+	return nil
+}
+
+func (r *memRepo) GetWebhookByID(id uint) (*Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if w, ok := r.hooks[id]; ok {
+		cp := *w
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (r *memRepo) list(keep func(*Webhook) bool) []Webhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var list []Webhook
+	for _, w := range r.hooks {
+		if keep(w) {
+			list = append(list, *w)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+func (r *memRepo) GetPersonalWebhooks(userID uint) ([]Webhook, error) {
+	return r.list(func(w *Webhook) bool { return w.UserID == userID && w.WorkspaceID == nil }), nil
+}
+
+func (r *memRepo) GetWorkspaceWebhooks(workspaceID uint) ([]Webhook, error) {
+	return r.list(func(w *Webhook) bool { return w.WorkspaceID != nil && *w.WorkspaceID == workspaceID }), nil
+}
+
+func (r *memRepo) UpdateWebhook(w *Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cp := *w
+	r.hooks[w.ID] = &cp
+	return nil
+}
+
+func (r *memRepo) DeleteWebhook(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.hooks, id)
+	for did, d := range r.deliveries {
+		if d.WebhookID == id {
+			delete(r.deliveries, did)
+		}
+	}
+	return nil
+}
+
+func (r *memRepo) CreateDeliveries(list []Delivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+next:
+	for i := range list {
+		for _, d := range r.deliveries {
+			if d.WebhookID == list[i].WebhookID && d.EventID == list[i].EventID {
+				continue next
+			}
+		}
+		r.nextID++
+		list[i].ID = r.nextID
+		cp := list[i]
+		r.deliveries[cp.ID] = &cp
+	}
+	return nil
+}
+
+func (r *memRepo) GetDeliveryByID(id uint) (*Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if d, ok := r.deliveries[id]; ok {
+		cp := *d
+		return &cp, nil
+	}
+	return nil, nil
+}
+
+func (r *memRepo) GetDeliveries(webhookID uint, filter DeliveryFilter) ([]Delivery, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var list []Delivery
+	for _, d := range r.deliveries {
+		if d.WebhookID == webhookID && (filter.Status == "" || d.Status == filter.Status) {
+			list = append(list, *d)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID > list[j].ID })
+	total := int64(len(list))
+	if filter.Offset < len(list) {
+		list = list[filter.Offset:]
+	} else {
+		list = nil
+	}
+	if len(list) > filter.Limit {
+		list = list[:filter.Limit]
+	}
+	return list, total, nil
+}
+
+func (r *memRepo) ClaimDue(now time.Time, limit int) ([]Delivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var list []Delivery
+	for _, d := range r.deliveries {
+		if d.Status == DeliveryPending && d.NextAttemptAt != nil && !d.NextAttemptAt.After(now) {
+			list = append(list, *d)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	if len(list) > limit {
+		list = list[:limit]
+	}
+	return list, nil
+}
+
+func (r *memRepo) Postpone(ids []uint, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range ids {
+		at := until
+		r.deliveries[id].NextAttemptAt = &at
+	}
+	return nil
+}
+
+func (r *memRepo) SaveDelivery(d *Delivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.deliveries[d.ID]; ok {
+		cp := *d
+		r.deliveries[d.ID] = &cp
+	}
+	return nil
+}
+
+type stubWorkspaces map[[2]uint]workspaces.Role
+
+func (s stubWorkspaces) MemberRole(workspaceID, userID uint) (workspaces.Role, error) {
+	return s[[2]uint{workspaceID, userID}], nil
+}
+
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver is a local webhook endpoint that answers with status.
+type receiver struct {
+	mu     sync.Mutex
+	status int
+	got    []received
+}
+
+func newReceiver(t *testing.T) (*receiver, *httptest.Server) {
+	rc := &receiver{status: http.StatusOK}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rc.mu.Lock()
+		defer rc.mu.Unlock()
+		rc.got = append(rc.got, received{r.Header.Clone(), body})
+		w.WriteHeader(rc.status)
+	}))
+	t.Cleanup(srv.Close)
+	return rc, srv
+}
+
+func (rc *receiver) setStatus(status int) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.status = status
+}
+
+func (rc *receiver) requests() []received {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]received(nil), rc.got...)
+}
+
+func newTestService(repo Repository, access stubWorkspaces) (*service, *time.Time) {
+	s := NewService(repo, access, WithLocalTargets()).(*service)
+	clock := time.Now()
+	s.now = func() time.Time { return clock }
+	return s, &clock
+}
+
+func TestPageEventsAreSignedAndDelivered(t *testing.T) {
+	rc, srv := newReceiver(t)
+	ws := uint(7)
+	s, _ := newTestService(newMemRepo(), stubWorkspaces{{ws, 1}: workspaces.RoleAdmin})
+
+	hook, err := s.CreateWebhook(WebhookInput{WorkspaceID: &ws, URL: srv.URL, Events: []string{"page.updated"}}, 1)
+	require.NoError(t, err)
+	assert.Contains(t, hook.Secret, "whsec_")
+	_, err = s.CreateWebhook(WebhookInput{URL: srv.URL, Events: []string{"page.updated"}}, 1)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, s.HandlePageEvent(ctx, 1, pages.PageEvent{Type: pages.PageUpdated, PageID: 3, WorkspaceID: &ws, OwnerID: 2, Title: "Plan"}))
+	require.NoError(t, s.HandlePageEvent(ctx, 2, pages.PageEvent{Type: pages.PageCreated, PageID: 4, WorkspaceID: &ws, OwnerID: 2}))
+	require.NoError(t, s.HandlePageEvent(ctx, 3, pages.PageEvent{Type: pages.PageUpdated, PageID: 5, OwnerID: 2}))
+
+	n, err := s.DeliverDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n, "only the subscribed workspace webhook gets an event")
+
+	reqs := rc.requests()
+	require.Len(t, reqs, 1)
+	got := reqs[0]
+	assert.Equal(t, "page.updated", got.header.Get(HeaderEvent))
+	assert.NoError(t, Verify(hook.Secret, got.header.Get(HeaderSignature), got.body, 5*time.Minute, time.Now()))
+	assert.ErrorIs(t, Verify("whsec_other", got.header.Get(HeaderSignature), got.body, 5*time.Minute, time.Now()), ErrBadSignature)
+
+	var payload struct {
+		ID   string          `json:"id"`
+		Type string          `json:"type"`
+		Data pages.PageEvent `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(got.body, &payload))
+	assert.Equal(t, got.header.Get(HeaderDelivery), payload.ID)
+	assert.Equal(t, "evt_1", payload.ID)
+	assert.Equal(t, "Plan", payload.Data.Title)
+
+	list, total, err := s.GetDeliveries(hook.ID, 1, DeliveryFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, DeliverySucceeded, list[0].Status)
+	assert.Equal(t, http.StatusOK, list[0].ResponseStatus)
+}
+
+func TestRedeliveredPageEventIsQueuedOnce(t *testing.T) {
+	_, srv := newReceiver(t)
+	s, _ := newTestService(newMemRepo(), nil)
+	hook, err := s.CreateWebhook(WebhookInput{URL: srv.URL, Events: []string{"page.created"}}, 1)
+	require.NoError(t, err)
+
+	e := pages.PageEvent{Type: pages.PageCreated, PageID: 3, OwnerID: 1}
+	require.NoError(t, s.HandlePageEvent(context.Background(), 4, e))
+	require.NoError(t, s.HandlePageEvent(context.Background(), 4, e))
+
+	list, total, err := s.GetDeliveries(hook.ID, 1, DeliveryFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, "evt_4", list[0].EventID)
+}
+
+func TestFailedDeliveriesBackOffThenDie(t *testing.T) {
+	rc, srv := newReceiver(t)
+	rc.setStatus(http.StatusInternalServerError)
+	s, clock := newTestService(newMemRepo(), nil)
+
+	hook, err := s.CreateWebhook(WebhookInput{URL: srv.URL, Events: []string{"page.deleted"}}, 1)
+	require.NoError(t, err)
+	require.NoError(t, s.HandlePageEvent(context.Background(), 1, pages.PageEvent{Type: pages.PageDeleted, PageID: 3, OwnerID: 1}))
+
+	wait := baseBackoff
+	for attempt := 1; attempt < maxAttempts; attempt++ {
+		n, err := s.DeliverDue(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, 1, n, "attempt %d", attempt)
+
+		// Not retried before the backoff has passed.
+		*clock = clock.Add(wait - time.Second)
+		n, err = s.DeliverDue(context.Background())
+		require.NoError(t, err)
+		require.Zero(t, n)
+		*clock = clock.Add(time.Second)
+		wait *= 2
+	}
+	_, err = s.DeliverDue(context.Background())
+	require.NoError(t, err)
+
+	list, _, err := s.GetDeliveries(hook.ID, 1, DeliveryFilter{})
+	require.NoError(t, err)
+	d := list[0]
+	assert.Equal(t, DeliveryDead, d.Status)
+	assert.Equal(t, maxAttempts, d.Attempts)
+	assert.Equal(t, http.StatusInternalServerError, d.ResponseStatus)
+	assert.Nil(t, d.NextAttemptAt)
+	assert.Len(t, rc.requests(), maxAttempts)
+
+	// Every attempt carries the same event ID for deduplication.
+	reqs := rc.requests()
+	assert.Equal(t, reqs[0].header.Get(HeaderDelivery), reqs[len(reqs)-1].header.Get(HeaderDelivery))
+
+	// A dead delivery can be queued again by hand.
+	rc.setStatus(http.StatusNoContent)
+	_, err = s.Redeliver(hook.ID, d.ID, 1)
+	require.NoError(t, err)
+	_, err = s.Redeliver(hook.ID, d.ID, 1)
+	assert.ErrorIs(t, err, ErrDeliveryPending)
+	n, err := s.DeliverDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	d2, _ := s.repo.GetDeliveryByID(d.ID)
+	assert.Equal(t, DeliverySucceeded, d2.Status)
+	assert.Equal(t, 1, d2.Attempts)
+}
+
+func TestSendTestAndAccess(t *testing.T) {
+	rc, srv := newReceiver(t)
+	ws := uint(7)
+	s, _ := newTestService(newMemRepo(), stubWorkspaces{
+		{ws, 1}: workspaces.RoleAdmin,
+		{ws, 2}: workspaces.RoleEditor,
+	})
+
+	hook, err := s.CreateWebhook(WebhookInput{WorkspaceID: &ws, URL: srv.URL, Events: []string{"page.created"}}, 1)
+	require.NoError(t, err)
+
+	d, err := s.SendTest(context.Background(), hook.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, DeliverySucceeded, d.Status)
+	assert.Equal(t, EventPing, d.EventType)
+	require.Len(t, rc.requests(), 1)
+	assert.Equal(t, EventPing, rc.requests()[0].header.Get(HeaderEvent))
+
+	// The ping is not delivered a second time by the worker.
+	n, err := s.DeliverDue(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	_, err = s.SendTest(context.Background(), hook.ID, 2)
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = s.SendTest(context.Background(), hook.ID, 3)
+	assert.ErrorIs(t, err, ErrWebhookNotFound)
+	_, err = s.CreateWebhook(WebhookInput{WorkspaceID: &ws, URL: srv.URL, Events: []string{"page.created"}}, 2)
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = s.CreateWebhook(WebhookInput{URL: "ftp://example.com/hook", Events: []string{"page.created"}}, 1)
+	assert.ErrorIs(t, err, ErrInvalidURL)
+}
+
+func TestWebhooksCannotReachInternalAddresses(t *testing.T) {
+	rc, srv := newReceiver(t)
+	repo := newMemRepo()
+	s := NewService(repo, nil).(*service)
+
+	for _, raw := range []string{
+		"http://example.com/hook",
+		"https://127.0.0.1/hook",
+		"https://localhost:8080/hook",
+		"https://10.1.2.3/hook",
+		"https://169.254.169.254/latest/meta-data",
+		"https://[::1]/hook",
+		"https://[::ffff:192.168.0.1]/hook",
+		"https://0.0.0.0/hook",
+	} {
+		_, err := s.CreateWebhook(WebhookInput{URL: raw, Events: []string{"page.created"}}, 1)
+		assert.Error(t, err, raw)
+	}
+
+	// A host that resolved to a public address when it was saved may
+	// point elsewhere by the time it is delivered to.
+	hook := &Webhook{UserID: 1, URL: srv.URL, Secret: "whsec_a", Events: []string{"page.created"}, Active: true}
+	require.NoError(t, repo.CreateWebhook(hook))
+	d, err := s.SendTest(context.Background(), hook.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, DeliveryPending, d.Status)
+	assert.Equal(t, errBlockedAddress.Error(), d.LastError)
+	assert.Empty(t, rc.requests())
+}
+
+func TestVerifyRejectsStaleAndTampered(t *testing.T) {
+	body := []byte(`{"id":"x"}`)
+	at := time.Unix(1700000000, 0)
+	header := Sign("whsec_a", at, body)
+
+	assert.NoError(t, Verify("whsec_a", header, body, time.Minute, at.Add(30*time.Second)))
+	assert.ErrorIs(t, Verify("whsec_a", header, body, time.Minute, at.Add(2*time.Minute)), ErrStale)
+	assert.ErrorIs(t, Verify("whsec_a", header, []byte(`{"id":"y"}`), time.Minute, at), ErrBadSignature)
+	assert.ErrorIs(t, Verify("whsec_a", "garbage", body, time.Minute, at), ErrBadSignature)
+}
@@ -0,0 +1,97 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+)
+
+// errBlockedAddress is returned when a delivery would connect to an address
+// webhooks may not reach.
+var errBlockedAddress = errors.New("receiver address is not allowed")
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which
+// netip does not count as private.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// blockedAddr reports whether addr is internal to the server's network:
+// loopback, private, link-local (including cloud metadata endpoints),
+// multicast or unspecified.
+func blockedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() ||
+		sharedAddressSpace.Contains(addr)
+}
+
+// checkURL validates a receiver URL. Unless local targets are allowed it
+// must be https and every address its host resolves to must be public.
+func (s *service) checkURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrInvalidURL
+	}
+	if s.allowLocal {
+		return nil
+	}
+	if u.Scheme != "https" {
+		return ErrInvalidURL
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return ErrUnresolvableURL
+	}
+	for _, addr := range addrs {
+		if blockedAddr(addr) {
+			return ErrInternalURL
+		}
+	}
+	return nil
+}
+
+// newClient returns the HTTP client deliveries are posted with. Addresses
+// are checked again on every connection, after DNS resolution, so a host
+// that passed checkURL cannot later point a delivery at an internal one.
+func newClient(allowLocal bool) *http.Client {
+	dialer := &net.Dialer{Timeout: requestTimeout}
+	if !allowLocal {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			addr, err := netip.ParseAddrPort(address)
+			if err != nil || blockedAddr(addr.Addr()) {
+				return errBlockedAddress
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would make the connection checked above the proxy's.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Transport: transport,
+		Timeout:   requestTimeout,
+		// A redirect is reported as the response it is.
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
+
+// deliveryError describes a failed request without the transport details
+// that would tell the webhook's owner about the server's network.
+func deliveryError(err error) error {
+	var timeout interface{ Timeout() bool }
+	switch {
+	case errors.Is(err, errBlockedAddress):
+		return errBlockedAddress
+	case errors.As(err, &timeout) && timeout.Timeout():
+		return errors.New("receiver timed out")
+	default:
+		return errors.New("could not connect to receiver")
+	}
+}
@@ -0,0 +1,22 @@
+package workspaces
+
+// WorkspaceInput for creating a workspace
+type WorkspaceInput struct {
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+// InvitationInput for inviting someone by email
+type InvitationInput struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  Role   `json:"role" binding:"required,oneof=admin editor viewer"`
+}
+
+// MemberRoleInput changes a member's role
+type MemberRoleInput struct {
+	Role Role `json:"role" binding:"required,oneof=admin editor viewer"`
+}
+
+// InvitationTokenInput carries the emailed token when accepting or declining
+type InvitationTokenInput struct {
+	Token string `json:"token" binding:"required"`
+}
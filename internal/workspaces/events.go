@@ -0,0 +1,53 @@
+package workspaces
+
+import "time"
+
+type ShareEventType string
+
+const (
+	ShareInvited  ShareEventType = "share.invited"
+	ShareJoined   ShareEventType = "share.joined"
+	ShareDeclined ShareEventType = "share.declined"
+	ShareRevoked  ShareEventType = "share.revoked"
+)
+
+// ShareEvent tells one user that workspace access involving them changed.
+// An accepted invitation produces one event for the new member and one
+// for the inviter.
+type ShareEvent struct {
+	Type         ShareEventType `json:"type"`
+	UserID       uint           `json:"userId"`
+	WorkspaceID  uint           `json:"workspaceId"`
+	InvitationID uint           `json:"invitationId"`
+	MemberID     *uint          `json:"memberId,omitempty"`
+	ActorID      uint           `json:"actorId"`
+	Role         Role           `json:"role"`
+	At           time.Time      `json:"at"`
+}
+
+// EventPublisher receives share events after the change is stored.
+// PublishShareEvent must not block.
+type EventPublisher interface {
+	PublishShareEvent(e ShareEvent)
+}
+
+// WithEventPublisher announces invitations and membership changes.
+func WithEventPublisher(p EventPublisher) Option {
+	return func(s *service) { s.events = p }
+}
+
+func (s *service) emit(t ShareEventType, inv *Invitation, recipient, actorID uint, memberID *uint) {
+	if s.events == nil {
+		return
+	}
+	s.events.PublishShareEvent(ShareEvent{
+		Type:         t,
+		UserID:       recipient,
+		WorkspaceID:  inv.WorkspaceID,
+		InvitationID: inv.ID,
+		MemberID:     memberID,
+		ActorID:      actorID,
+		Role:         inv.Role,
+		At:           time.Now(),
+	})
+}
@@ -0,0 +1,296 @@
+package workspaces
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"flowboard-backend-go/internal/audit"
+	"flowboard-backend-go/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+	audit   audit.Recorder
+}
+
+func NewHandler(service Service, auditLog audit.Recorder) *Handler {
+	return &Handler{
+		service: service,
+		audit:   auditLog,
+	}
+}
+
+// getUserID safely retrieves user ID from context
+func getUserID(c *gin.Context) (uint, error) {
+	uidVal, exists := c.Get(middleware.ContextUserIDKey)
+	if !exists {
+		return 0, fmt.Errorf("unauthorized")
+	}
+
+	uid, ok := uidVal.(uint)
+	if !ok {
+		return 0, fmt.Errorf("invalid user ID type")
+	}
+
+	return uid, nil
+}
+
+// parseID reads a numeric path parameter
+func parseID(c *gin.Context, name string) (uint, bool) {
+	id64, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name})
+		return 0, false
+	}
+	return uint(id64), true
+}
+
+// respondError maps service errors to HTTP statuses
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrWorkspaceNotFound), errors.Is(err, ErrInvitationNotFound), errors.Is(err, ErrMemberNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrForbidden), errors.Is(err, ErrEmailMismatch), errors.Is(err, ErrOwnerMember):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvitationExpired), errors.Is(err, ErrInvitationUsed):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrAlreadyMember):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (h *Handler) CreateWorkspace(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input WorkspaceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ws, err := h.service.CreateWorkspace(input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": ws})
+}
+
+func (h *Handler) GetWorkspaces(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := h.service.GetWorkspacesByUser(userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": list})
+}
+
+func (h *Handler) CreateInvitation(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	workspaceID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input InvitationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	inv, err := h.service.CreateInvitation(workspaceID, input, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	h.audit.Record(audit.FromRequest(c), audit.ShareInvited, audit.Target{Type: audit.TargetInvitation, ID: inv.ID},
+		map[string]any{"workspaceId": workspaceID, "email": inv.Email, "role": inv.Role})
+
+	c.JSON(http.StatusCreated, gin.H{"success": true, "data": inv})
+}
+
+func (h *Handler) GetInvitations(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	workspaceID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	list, err := h.service.GetInvitations(workspaceID, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": list})
+}
+
+func (h *Handler) RevokeInvitation(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	workspaceID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	invitationID, ok := parseID(c, "invitationId")
+	if !ok {
+		return
+	}
+
+	if err := h.service.RevokeInvitation(workspaceID, invitationID, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+	h.audit.Record(audit.FromRequest(c), audit.ShareRevoked, audit.Target{Type: audit.TargetInvitation, ID: invitationID},
+		map[string]any{"workspaceId": workspaceID})
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+func (h *Handler) UpdateMemberRole(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	workspaceID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	memberID, ok := parseID(c, "userId")
+	if !ok {
+		return
+	}
+
+	var input MemberRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := h.service.UpdateMemberRole(workspaceID, memberID, input.Role, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	h.audit.Record(audit.FromRequest(c), audit.MemberRoleChanged, audit.Target{Type: audit.TargetWorkspace, ID: workspaceID},
+		map[string]any{"userId": memberID, "role": member.Role})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": member})
+}
+
+func (h *Handler) RemoveMember(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	workspaceID, ok := parseID(c, "id")
+	if !ok {
+		return
+	}
+	memberID, ok := parseID(c, "userId")
+	if !ok {
+		return
+	}
+
+	member, err := h.service.RemoveMember(workspaceID, memberID, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	h.audit.Record(audit.FromRequest(c), audit.MemberRemoved, audit.Target{Type: audit.TargetWorkspace, ID: workspaceID},
+		map[string]any{"userId": memberID, "role": member.Role})
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+func (h *Handler) GetMyInvitations(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := h.service.GetPendingInvitations(userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": list})
+}
+
+func (h *Handler) AcceptInvitation(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input InvitationTokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	member, err := h.service.AcceptInvitation(input.Token, userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	h.audit.Record(audit.FromRequest(c), audit.MemberAdded, audit.Target{Type: audit.TargetWorkspace, ID: member.WorkspaceID},
+		map[string]any{"userId": member.UserID, "role": member.Role})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": member})
+}
+
+func (h *Handler) DeclineInvitation(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input InvitationTokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.DeclineInvitation(input.Token, userID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
@@ -0,0 +1,77 @@
+package workspaces
+
+import "time"
+
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleAdmin  Role = "admin"
+	RoleEditor Role = "editor"
+	RoleViewer Role = "viewer"
+)
+
+// rank orders roles so permission checks can use "at least" comparisons.
+func (r Role) rank() int {
+	switch r {
+	case RoleOwner:
+		return 4
+	case RoleAdmin:
+		return 3
+	case RoleEditor:
+		return 2
+	case RoleViewer:
+		return 1
+	}
+	return 0
+}
+
+// AtLeast reports whether r grants at least the permissions of min.
+func (r Role) AtLeast(min Role) bool {
+	return r.rank() >= min.rank()
+}
+
+type Workspace struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"size:255;not null" json:"name"`
+	OwnerID   uint      `gorm:"not null;index" json:"ownerId"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type Member struct {
+	WorkspaceID uint      `gorm:"primaryKey" json:"workspaceId"`
+	UserID      uint      `gorm:"primaryKey;index" json:"userId"`
+	Role        Role      `gorm:"size:20;not null" json:"role"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type InvitationStatus string
+
+const (
+	InvitationPending  InvitationStatus = "pending"
+	InvitationAccepted InvitationStatus = "accepted"
+	InvitationDeclined InvitationStatus = "declined"
+	InvitationRevoked  InvitationStatus = "revoked"
+)
+
+// Invitation is a single-use offer to join a workspace. Only a hash of the
+// token is stored; the plain token is emailed to the invitee.
+type Invitation struct {
+	ID          uint             `gorm:"primaryKey" json:"id"`
+	WorkspaceID uint             `gorm:"not null;index" json:"workspaceId"`
+	InviterID   uint             `gorm:"not null" json:"inviterId"`
+	Email       string           `gorm:"size:255;not null;index" json:"email"`
+	Role        Role             `gorm:"size:20;not null" json:"role"`
+	TokenHash   string           `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	InviteeID   *uint            `gorm:"index" json:"inviteeId,omitempty"`
+	Status      InvitationStatus `gorm:"size:20;not null;index" json:"status"`
+	ExpiresAt   time.Time        `gorm:"not null" json:"expiresAt"`
+	RespondedAt *time.Time       `json:"respondedAt,omitempty"`
+	CreatedAt   time.Time        `json:"createdAt"`
+	UpdatedAt   time.Time        `json:"updatedAt"`
+}
+
+func (i *Invitation) Expired(now time.Time) bool {
+	return now.After(i.ExpiresAt)
+}
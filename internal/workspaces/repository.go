@@ -0,0 +1,182 @@
+package workspaces
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository interface {
+	CreateWorkspace(ws *Workspace) error
+	GetWorkspaceByID(id uint) (*Workspace, error)
+	GetWorkspacesByUser(userID uint) ([]Workspace, error)
+	GetMember(workspaceID, userID uint) (*Member, error)
+	GetMemberIDs(workspaceID uint) ([]uint, error)
+	UpdateMemberRole(workspaceID, userID uint, role Role) error
+	DeleteMember(workspaceID, userID uint) error
+
+	CreateInvitation(inv *Invitation) error
+	GetInvitationByID(id uint) (*Invitation, error)
+	GetInvitationByTokenHash(hash string) (*Invitation, error)
+	GetInvitationsByWorkspace(workspaceID uint) ([]Invitation, error)
+	GetPendingInvitationsByInvitee(userID uint) ([]Invitation, error)
+	RevokePendingInvitations(workspaceID uint, email string) error
+	LinkInvitations(email string, userID uint) error
+	// RespondToInvitation moves a pending invitation to status. It returns
+	// false if the invitation was no longer pending, which makes tokens
+	// single-use even under concurrent requests. When member is non-nil it
+	// is created in the same transaction.
+	RespondToInvitation(id uint, status InvitationStatus, member *Member) (bool, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// CreateWorkspace stores the workspace and makes its owner the first member.
+func (r *repository) CreateWorkspace(ws *Workspace) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(ws).Error; err != nil {
+			return err
+		}
+		return tx.Create(&Member{WorkspaceID: ws.ID, UserID: ws.OwnerID, Role: RoleOwner}).Error
+	})
+}
+
+func (r *repository) GetWorkspaceByID(id uint) (*Workspace, error) {
+	var ws Workspace
+	if err := r.db.First(&ws, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &ws, nil
+}
+
+func (r *repository) GetWorkspacesByUser(userID uint) ([]Workspace, error) {
+	var list []Workspace
+	err := r.db.
+		Joins("JOIN members ON members.workspace_id = workspaces.id").
+		Where("members.user_id = ?", userID).
+		Order("workspaces.name").
+		Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *repository) GetMember(workspaceID, userID uint) (*Member, error) {
+	var m Member
+	err := r.db.Where("workspace_id = ? AND user_id = ?", workspaceID, userID).First(&m).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *repository) GetMemberIDs(workspaceID uint) ([]uint, error) {
+	var ids []uint
+	if err := r.db.Model(&Member{}).Where("workspace_id = ?", workspaceID).Order("user_id").Pluck("user_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *repository) UpdateMemberRole(workspaceID, userID uint, role Role) error {
+	return r.db.Model(&Member{}).
+		Where("workspace_id = ? AND user_id = ?", workspaceID, userID).
+		Update("role", role).Error
+}
+
+func (r *repository) DeleteMember(workspaceID, userID uint) error {
+	return r.db.Where("workspace_id = ? AND user_id = ?", workspaceID, userID).Delete(&Member{}).Error
+}
+
+func (r *repository) CreateInvitation(inv *Invitation) error {
+	return r.db.Create(inv).Error
+}
+
+func (r *repository) GetInvitationByID(id uint) (*Invitation, error) {
+	var inv Invitation
+	if err := r.db.First(&inv, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func (r *repository) GetInvitationByTokenHash(hash string) (*Invitation, error) {
+	var inv Invitation
+	if err := r.db.Where("token_hash = ?", hash).First(&inv).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func (r *repository) GetInvitationsByWorkspace(workspaceID uint) ([]Invitation, error) {
+	var list []Invitation
+	if err := r.db.Where("workspace_id = ?", workspaceID).Order("created_at DESC").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *repository) GetPendingInvitationsByInvitee(userID uint) ([]Invitation, error) {
+	var list []Invitation
+	err := r.db.
+		Where("invitee_id = ? AND status = ? AND expires_at > ?", userID, InvitationPending, time.Now()).
+		Order("created_at DESC").
+		Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r *repository) RevokePendingInvitations(workspaceID uint, email string) error {
+	return r.db.Model(&Invitation{}).
+		Where("workspace_id = ? AND email = ? AND status = ?", workspaceID, email, InvitationPending).
+		Updates(map[string]interface{}{"status": InvitationRevoked, "responded_at": time.Now()}).Error
+}
+
+func (r *repository) LinkInvitations(email string, userID uint) error {
+	return r.db.Model(&Invitation{}).
+		Where("email = ? AND status = ? AND invitee_id IS NULL", email, InvitationPending).
+		Update("invitee_id", userID).Error
+}
+
+func (r *repository) RespondToInvitation(id uint, status InvitationStatus, member *Member) (bool, error) {
+	updated := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&Invitation{}).
+			Where("id = ? AND status = ?", id, InvitationPending).
+			Updates(map[string]interface{}{"status": status, "responded_at": time.Now()})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		updated = true
+		if member != nil {
+			return tx.Create(member).Error
+		}
+		return nil
+	})
+	return updated, err
+}
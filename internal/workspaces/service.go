@@ -0,0 +1,399 @@
+package workspaces
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"flowboard-backend-go/internal/notifications"
+	"flowboard-backend-go/internal/users"
+	"flowboard-backend-go/pkg/logger"
+	"flowboard-backend-go/pkg/mailer"
+)
+
+var (
+	ErrWorkspaceNotFound  = errors.New("workspace not found")
+	ErrForbidden          = errors.New("insufficient workspace permissions")
+	ErrInvitationNotFound = errors.New("invitation not found")
+	ErrInvitationExpired  = errors.New("invitation has expired")
+	ErrInvitationUsed     = errors.New("invitation is no longer valid")
+	ErrEmailMismatch      = errors.New("invitation was sent to a different email")
+	ErrAlreadyMember      = errors.New("user is already a member of this workspace")
+	ErrMemberNotFound     = errors.New("member not found")
+	ErrOwnerMember        = errors.New("the workspace owner cannot be changed or removed")
+)
+
+const invitationTTL = 7 * 24 * time.Hour
+
+// UserDirectory looks up accounts; satisfied by users.Service.
+type UserDirectory interface {
+	GetByID(id uint) (*users.User, error)
+	GetByEmail(email string) (*users.User, error)
+}
+
+type Service interface {
+	// HandleUserRegistered links invitations to new accounts.
+	users.EventHandler
+
+	CreateWorkspace(input WorkspaceInput, userID uint) (*Workspace, error)
+	GetWorkspacesByUser(userID uint) ([]Workspace, error)
+	// MemberRole returns the user's role in the workspace, or "" if the
+	// user is not a member.
+	MemberRole(workspaceID, userID uint) (Role, error)
+	// MemberIDs lists the IDs of every member of the workspace.
+	MemberIDs(workspaceID uint) ([]uint, error)
+
+	CreateInvitation(workspaceID uint, input InvitationInput, inviterID uint) (*Invitation, error)
+	// UpdateMemberRole changes another member's role and RemoveMember takes
+	// them out of the workspace. Both need admin; only the owner may
+	// manage admins or grant admin, and the owner's membership is fixed.
+	UpdateMemberRole(workspaceID, memberID uint, role Role, userID uint) (*Member, error)
+	RemoveMember(workspaceID, memberID, userID uint) (*Member, error)
+
+	GetInvitations(workspaceID, userID uint) ([]Invitation, error)
+	RevokeInvitation(workspaceID, invitationID, userID uint) error
+	GetPendingInvitations(userID uint) ([]Invitation, error)
+	AcceptInvitation(token string, userID uint) (*Member, error)
+	DeclineInvitation(token string, userID uint) error
+	// LinkInvitations attaches pending invitations sent to email to a newly
+	// registered account so they show up in its pending list.
+	LinkInvitations(userID uint, email string) error
+}
+
+type service struct {
+	repo     Repository
+	users    UserDirectory
+	mailer   mailer.Mailer
+	appURL   string
+	notifier notifications.Notifier
+	events   EventPublisher
+}
+
+// Option configures optional collaborators of the workspace service.
+type Option func(*service)
+
+// WithNotifier posts an in-app notification when an invitation is sent to
+// someone who already has an account.
+func WithNotifier(n notifications.Notifier) Option {
+	return func(s *service) { s.notifier = n }
+}
+
+func NewService(repo Repository, users UserDirectory, m mailer.Mailer, appURL string, opts ...Option) Service {
+	s := &service{repo: repo, users: users, mailer: m, appURL: appURL}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *service) CreateWorkspace(input WorkspaceInput, userID uint) (*Workspace, error) {
+	ws := &Workspace{Name: strings.TrimSpace(input.Name), OwnerID: userID}
+	if err := s.repo.CreateWorkspace(ws); err != nil {
+		return nil, err
+	}
+	return ws, nil
+}
+
+func (s *service) GetWorkspacesByUser(userID uint) ([]Workspace, error) {
+	return s.repo.GetWorkspacesByUser(userID)
+}
+
+func (s *service) MemberRole(workspaceID, userID uint) (Role, error) {
+	m, err := s.repo.GetMember(workspaceID, userID)
+	if err != nil || m == nil {
+		return "", err
+	}
+	return m.Role, nil
+}
+
+func (s *service) MemberIDs(workspaceID uint) ([]uint, error) {
+	return s.repo.GetMemberIDs(workspaceID)
+}
+
+// requireRole loads the workspace and checks the user's role in it.
+// Non-members get ErrWorkspaceNotFound so workspace IDs are not leaked.
+func (s *service) requireRole(workspaceID, userID uint, min Role) (*Workspace, error) {
+	ws, err := s.repo.GetWorkspaceByID(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if ws == nil {
+		return nil, ErrWorkspaceNotFound
+	}
+	role, err := s.MemberRole(workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		return nil, ErrWorkspaceNotFound
+	}
+	if !role.AtLeast(min) {
+		return nil, ErrForbidden
+	}
+	return ws, nil
+}
+
+func (s *service) CreateInvitation(workspaceID uint, input InvitationInput, inviterID uint) (*Invitation, error) {
+	ws, err := s.requireRole(workspaceID, inviterID, RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	email := normalizeEmail(input.Email)
+	token, hash, err := newInvitationToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// Re-inviting the same address replaces any older pending invitation.
+	if err := s.repo.RevokePendingInvitations(workspaceID, email); err != nil {
+		return nil, err
+	}
+
+	inv := &Invitation{
+		WorkspaceID: workspaceID,
+		InviterID:   inviterID,
+		Email:       email,
+		Role:        input.Role,
+		TokenHash:   hash,
+		Status:      InvitationPending,
+		ExpiresAt:   time.Now().Add(invitationTTL),
+	}
+	invitee, err := s.users.GetByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if invitee != nil {
+		inv.InviteeID = &invitee.ID
+	}
+	if err := s.repo.CreateInvitation(inv); err != nil {
+		return nil, err
+	}
+
+	if err := s.sendInvitation(ws, inv, inviterID, token); err != nil {
+		// The invitation stays valid; re-inviting resends the email.
+		logger.Log.Errorw("Invitation email failed", "invitationID", inv.ID, "error", err)
+	}
+	if invitee != nil {
+		s.emit(ShareInvited, inv, invitee.ID, inviterID, nil)
+	}
+	if invitee != nil && s.notifier != nil {
+		err := s.notifier.Notify(context.Background(), &notifications.Notification{
+			UserID:  invitee.ID,
+			Type:    notifications.TypeInvitation,
+			ActorID: &inviterID,
+			Title:   fmt.Sprintf("You're invited to %s", ws.Name),
+			Body:    fmt.Sprintf("You were invited to join the %q workspace as %s.", ws.Name, inv.Role),
+			Link:    "/invitations",
+		})
+		if err != nil {
+			logger.Log.Errorw("Invitation notification failed", "invitationID", inv.ID, "error", err)
+		}
+	}
+	return inv, nil
+}
+
+func (s *service) sendInvitation(ws *Workspace, inv *Invitation, inviterID uint, token string) error {
+	inviterName := "A teammate"
+	if inviter, err := s.users.GetByID(inviterID); err == nil && inviter != nil {
+		inviterName = inviter.Name
+	}
+	link := fmt.Sprintf("%s/invitations/accept?token=%s", s.appURL, token)
+	body := fmt.Sprintf(
+		"%s invited you to join the %q workspace on FlowBoard as %s.\n\n"+
+			"Accept the invitation: %s\n\nThis link expires on %s.\n",
+		inviterName, ws.Name, inv.Role, link, inv.ExpiresAt.Format("January 2, 2006"),
+	)
+	return s.mailer.Send(context.Background(), mailer.Message{
+		To:      []string{inv.Email},
+		Subject: fmt.Sprintf("You're invited to %s on FlowBoard", ws.Name),
+		Text:    body,
+	})
+}
+
+func (s *service) UpdateMemberRole(workspaceID, memberID uint, role Role, userID uint) (*Member, error) {
+	member, err := s.manageableMember(workspaceID, memberID, role, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateMemberRole(workspaceID, memberID, role); err != nil {
+		return nil, err
+	}
+	member.Role = role
+	return member, nil
+}
+
+func (s *service) RemoveMember(workspaceID, memberID, userID uint) (*Member, error) {
+	member, err := s.manageableMember(workspaceID, memberID, "", userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.DeleteMember(workspaceID, memberID); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// manageableMember loads a member userID may change to role, or remove
+// when role is empty.
+func (s *service) manageableMember(workspaceID, memberID uint, role Role, userID uint) (*Member, error) {
+	ws, err := s.requireRole(workspaceID, userID, RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	member, err := s.repo.GetMember(workspaceID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrMemberNotFound
+	}
+	if memberID == ws.OwnerID {
+		return nil, ErrOwnerMember
+	}
+	if userID != ws.OwnerID && (member.Role.AtLeast(RoleAdmin) || role.AtLeast(RoleAdmin)) {
+		return nil, ErrForbidden
+	}
+	return member, nil
+}
+
+func (s *service) GetInvitations(workspaceID, userID uint) ([]Invitation, error) {
+	if _, err := s.requireRole(workspaceID, userID, RoleAdmin); err != nil {
+		return nil, err
+	}
+	return s.repo.GetInvitationsByWorkspace(workspaceID)
+}
+
+func (s *service) RevokeInvitation(workspaceID, invitationID, userID uint) error {
+	if _, err := s.requireRole(workspaceID, userID, RoleAdmin); err != nil {
+		return err
+	}
+	inv, err := s.repo.GetInvitationByID(invitationID)
+	if err != nil {
+		return err
+	}
+	if inv == nil || inv.WorkspaceID != workspaceID {
+		return ErrInvitationNotFound
+	}
+	ok, err := s.repo.RespondToInvitation(inv.ID, InvitationRevoked, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvitationUsed
+	}
+	if inv.InviteeID != nil {
+		s.emit(ShareRevoked, inv, *inv.InviteeID, userID, nil)
+	}
+	return nil
+}
+
+func (s *service) GetPendingInvitations(userID uint) ([]Invitation, error) {
+	return s.repo.GetPendingInvitationsByInvitee(userID)
+}
+
+func (s *service) AcceptInvitation(token string, userID uint) (*Member, error) {
+	inv, err := s.invitationForUser(token, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.GetMember(inv.WorkspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if _, err := s.repo.RespondToInvitation(inv.ID, InvitationAccepted, nil); err != nil {
+			return nil, err
+		}
+		return nil, ErrAlreadyMember
+	}
+
+	member := &Member{WorkspaceID: inv.WorkspaceID, UserID: userID, Role: inv.Role}
+	ok, err := s.repo.RespondToInvitation(inv.ID, InvitationAccepted, member)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvitationUsed
+	}
+	s.emit(ShareJoined, inv, userID, userID, &userID)
+	s.emit(ShareJoined, inv, inv.InviterID, userID, &userID)
+	return member, nil
+}
+
+func (s *service) DeclineInvitation(token string, userID uint) error {
+	inv, err := s.invitationForUser(token, userID)
+	if err != nil {
+		return err
+	}
+	ok, err := s.repo.RespondToInvitation(inv.ID, InvitationDeclined, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvitationUsed
+	}
+	s.emit(ShareDeclined, inv, inv.InviterID, userID, nil)
+	return nil
+}
+
+// invitationForUser resolves a token to a pending, unexpired invitation
+// addressed to the given user's email.
+func (s *service) invitationForUser(token string, userID uint) (*Invitation, error) {
+	inv, err := s.repo.GetInvitationByTokenHash(hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if inv == nil {
+		return nil, ErrInvitationNotFound
+	}
+	if inv.Status != InvitationPending {
+		return nil, ErrInvitationUsed
+	}
+	if inv.Expired(time.Now()) {
+		return nil, ErrInvitationExpired
+	}
+
+	user, err := s.users.GetByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || normalizeEmail(user.Email) != inv.Email {
+		return nil, ErrEmailMismatch
+	}
+	return inv, nil
+}
+
+func (s *service) LinkInvitations(userID uint, email string) error {
+	return s.repo.LinkInvitations(normalizeEmail(email), userID)
+}
+
+func (s *service) HandleUserRegistered(_ context.Context, _ uint64, e users.UserEvent) error {
+	return s.LinkInvitations(e.UserID, e.Email)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// newInvitationToken returns a random URL-safe token and its storage hash.
+func newInvitationToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,343 @@
+package workspaces
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"flowboard-backend-go/internal/notifications"
+	"flowboard-backend-go/internal/users"
+	"flowboard-backend-go/pkg/mailer"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// Mocked Repository
+type MockRepo struct {
+	mock.Mock
+}
+
+func (m *MockRepo) CreateWorkspace(ws *Workspace) error {
+	return m.Called(ws).Error(0)
+}
+
+func (m *MockRepo) GetWorkspaceByID(id uint) (*Workspace, error) {
+	args := m.Called(id)
+	ws, _ := args.Get(0).(*Workspace)
+	return ws, args.Error(1)
+}
+
+func (m *MockRepo) GetWorkspacesByUser(userID uint) ([]Workspace, error) {
+	args := m.Called(userID)
+	list, _ := args.Get(0).([]Workspace)
+	return list, args.Error(1)
+}
+
+func (m *MockRepo) GetMember(workspaceID, userID uint) (*Member, error) {
+	args := m.Called(workspaceID, userID)
+	member, _ := args.Get(0).(*Member)
+	return member, args.Error(1)
+}
+
+func (m *MockRepo) GetMemberIDs(workspaceID uint) ([]uint, error) {
+	args := m.Called(workspaceID)
+	ids, _ := args.Get(0).([]uint)
+	return ids, args.Error(1)
+}
+
+func (m *MockRepo) UpdateMemberRole(workspaceID, userID uint, role Role) error {
+	return m.Called(workspaceID, userID, role).Error(0)
+}
+
+func (m *MockRepo) DeleteMember(workspaceID, userID uint) error {
+	return m.Called(workspaceID, userID).Error(0)
+}
+
+func (m *MockRepo) CreateInvitation(inv *Invitation) error {
+	return m.Called(inv).Error(0)
+}
+
+func (m *MockRepo) GetInvitationByID(id uint) (*Invitation, error) {
+	args := m.Called(id)
+	inv, _ := args.Get(0).(*Invitation)
+	return inv, args.Error(1)
+}
+
+func (m *MockRepo) GetInvitationByTokenHash(hash string) (*Invitation, error) {
+	args := m.Called(hash)
+	inv, _ := args.Get(0).(*Invitation)
+	return inv, args.Error(1)
+}
+
+func (m *MockRepo) GetInvitationsByWorkspace(workspaceID uint) ([]Invitation, error) {
+	args := m.Called(workspaceID)
+	list, _ := args.Get(0).([]Invitation)
+	return list, args.Error(1)
+}
+
+func (m *MockRepo) GetPendingInvitationsByInvitee(userID uint) ([]Invitation, error) {
+	args := m.Called(userID)
+	list, _ := args.Get(0).([]Invitation)
+	return list, args.Error(1)
+}
+
+func (m *MockRepo) RevokePendingInvitations(workspaceID uint, email string) error {
+	return m.Called(workspaceID, email).Error(0)
+}
+
+func (m *MockRepo) LinkInvitations(email string, userID uint) error {
+	return m.Called(email, userID).Error(0)
+}
+
+func (m *MockRepo) RespondToInvitation(id uint, status InvitationStatus, member *Member) (bool, error) {
+	args := m.Called(id, status, member)
+	return args.Bool(0), args.Error(1)
+}
+
+type stubUsers map[uint]*users.User
+
+func (s stubUsers) GetByID(id uint) (*users.User, error) {
+	return s[id], nil
+}
+
+func (s stubUsers) GetByEmail(email string) (*users.User, error) {
+	for _, u := range s {
+		if strings.EqualFold(u.Email, email) {
+			return u, nil
+		}
+	}
+	return nil, nil
+}
+
+type recordingMailer struct {
+	sent []mailer.Message
+}
+
+func (r *recordingMailer) Send(_ context.Context, msg mailer.Message) error {
+	r.sent = append(r.sent, msg)
+	return nil
+}
+
+func TestCreateInvitation_SendsEmailWithToken(t *testing.T) {
+	mockRepo := new(MockRepo)
+	mail := &recordingMailer{}
+	s := NewService(mockRepo, stubUsers{1: {ID: 1, Name: "Alex"}}, mail, "https://app.test")
+
+	mockRepo.On("GetWorkspaceByID", uint(10)).Return(&Workspace{ID: 10, Name: "Team"}, nil)
+	mockRepo.On("GetMember", uint(10), uint(1)).Return(&Member{Role: RoleOwner}, nil)
+	mockRepo.On("RevokePendingInvitations", uint(10), "bob@example.com").Return(nil)
+	mockRepo.On("CreateInvitation", mock.AnythingOfType("*workspaces.Invitation")).Return(nil)
+
+	inv, err := s.CreateInvitation(10, InvitationInput{Email: " Bob@Example.com ", Role: RoleEditor}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "bob@example.com", inv.Email)
+	assert.Equal(t, InvitationPending, inv.Status)
+	assert.Len(t, inv.TokenHash, 64)
+
+	assert.Len(t, mail.sent, 1)
+	assert.Equal(t, []string{"bob@example.com"}, mail.sent[0].To)
+	assert.Contains(t, mail.sent[0].Text, "https://app.test/invitations/accept?token=")
+	mockRepo.AssertExpectations(t)
+}
+
+type recordingNotifier struct {
+	sent []*notifications.Notification
+}
+
+func (r *recordingNotifier) Notify(_ context.Context, n *notifications.Notification) error {
+	r.sent = append(r.sent, n)
+	return nil
+}
+
+func TestCreateInvitation_NotifiesExistingUser(t *testing.T) {
+	mockRepo := new(MockRepo)
+	notifier := &recordingNotifier{}
+	s := NewService(mockRepo, stubUsers{1: {ID: 1, Name: "Alex"}, 5: {ID: 5, Email: "bob@example.com"}},
+		&recordingMailer{}, "", WithNotifier(notifier))
+
+	mockRepo.On("GetWorkspaceByID", uint(10)).Return(&Workspace{ID: 10, Name: "Team"}, nil)
+	mockRepo.On("GetMember", uint(10), uint(1)).Return(&Member{Role: RoleOwner}, nil)
+	mockRepo.On("RevokePendingInvitations", uint(10), "bob@example.com").Return(nil)
+	mockRepo.On("CreateInvitation", mock.AnythingOfType("*workspaces.Invitation")).Return(nil)
+
+	inv, err := s.CreateInvitation(10, InvitationInput{Email: "bob@example.com", Role: RoleViewer}, 1)
+	assert.NoError(t, err)
+	if assert.NotNil(t, inv.InviteeID) {
+		assert.Equal(t, uint(5), *inv.InviteeID)
+	}
+	if assert.Len(t, notifier.sent, 1) {
+		assert.Equal(t, uint(5), notifier.sent[0].UserID)
+		assert.Equal(t, notifications.TypeInvitation, notifier.sent[0].Type)
+	}
+
+	notifier.sent = nil
+	mockRepo.On("RevokePendingInvitations", uint(10), "new@example.com").Return(nil)
+	inv, err = s.CreateInvitation(10, InvitationInput{Email: "new@example.com", Role: RoleViewer}, 1)
+	assert.NoError(t, err)
+	assert.Nil(t, inv.InviteeID)
+	assert.Empty(t, notifier.sent)
+}
+
+func TestCreateInvitation_RequiresAdmin(t *testing.T) {
+	mockRepo := new(MockRepo)
+	s := NewService(mockRepo, stubUsers{}, &recordingMailer{}, "")
+
+	mockRepo.On("GetWorkspaceByID", uint(10)).Return(&Workspace{ID: 10}, nil)
+	mockRepo.On("GetMember", uint(10), uint(2)).Return(&Member{Role: RoleEditor}, nil)
+
+	inv, err := s.CreateInvitation(10, InvitationInput{Email: "x@example.com", Role: RoleViewer}, 2)
+	assert.Nil(t, inv)
+	assert.Equal(t, ErrForbidden, err)
+}
+
+func TestMemberManagement(t *testing.T) {
+	mockRepo := new(MockRepo)
+	s := NewService(mockRepo, stubUsers{}, &recordingMailer{}, "")
+
+	// 1 owns the workspace, 2 is an admin and 3 an editor.
+	mockRepo.On("GetWorkspaceByID", uint(10)).Return(&Workspace{ID: 10, OwnerID: 1}, nil)
+	mockRepo.On("GetMember", uint(10), uint(1)).Return(&Member{WorkspaceID: 10, UserID: 1, Role: RoleOwner}, nil)
+	mockRepo.On("GetMember", uint(10), uint(2)).Return(&Member{WorkspaceID: 10, UserID: 2, Role: RoleAdmin}, nil)
+	mockRepo.On("GetMember", uint(10), uint(3)).Return(&Member{WorkspaceID: 10, UserID: 3, Role: RoleEditor}, nil)
+	mockRepo.On("GetMember", uint(10), uint(4)).Return(nil, nil)
+	mockRepo.On("UpdateMemberRole", uint(10), uint(3), RoleViewer).Return(nil)
+	mockRepo.On("DeleteMember", uint(10), uint(3)).Return(nil)
+
+	member, err := s.UpdateMemberRole(10, 3, RoleViewer, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, RoleViewer, member.Role)
+
+	_, err = s.UpdateMemberRole(10, 3, RoleAdmin, 2)
+	assert.ErrorIs(t, err, ErrForbidden, "only the owner grants admin")
+	_, err = s.RemoveMember(10, 2, 2)
+	assert.ErrorIs(t, err, ErrForbidden, "only the owner removes admins")
+	_, err = s.RemoveMember(10, 1, 2)
+	assert.ErrorIs(t, err, ErrOwnerMember)
+	_, err = s.RemoveMember(10, 4, 2)
+	assert.ErrorIs(t, err, ErrMemberNotFound)
+	_, err = s.RemoveMember(10, 2, 3)
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	removed, err := s.RemoveMember(10, 3, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3), removed.UserID)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAcceptInvitation_Success(t *testing.T) {
+	mockRepo := new(MockRepo)
+	s := NewService(mockRepo, stubUsers{5: {ID: 5, Email: "Bob@example.com"}}, &recordingMailer{}, "")
+
+	inv := &Invitation{
+		ID: 3, WorkspaceID: 10, Email: "bob@example.com", Role: RoleEditor,
+		Status: InvitationPending, ExpiresAt: time.Now().Add(time.Hour),
+	}
+	mockRepo.On("GetInvitationByTokenHash", hashToken("tok")).Return(inv, nil)
+	mockRepo.On("GetMember", uint(10), uint(5)).Return(nil, nil)
+	mockRepo.On("RespondToInvitation", uint(3), InvitationAccepted, mock.AnythingOfType("*workspaces.Member")).Return(true, nil)
+
+	member, err := s.AcceptInvitation("tok", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, RoleEditor, member.Role)
+	assert.Equal(t, uint(10), member.WorkspaceID)
+}
+
+func TestHandleUserRegistered_LinksInvitations(t *testing.T) {
+	mockRepo := new(MockRepo)
+	s := NewService(mockRepo, stubUsers{}, &recordingMailer{}, "")
+	mockRepo.On("LinkInvitations", "bob@example.com", uint(5)).Return(nil)
+
+	err := s.HandleUserRegistered(context.Background(), 1, users.UserEvent{Type: users.UserRegistered, UserID: 5, Email: " Bob@Example.com"})
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAcceptInvitation_Rejections(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	cases := map[string]struct {
+		inv  *Invitation
+		want error
+	}{
+		"unknown token": {nil, ErrInvitationNotFound},
+		"expired": {&Invitation{Email: "bob@example.com", Status: InvitationPending,
+			ExpiresAt: time.Now().Add(-time.Hour)}, ErrInvitationExpired},
+		"already used": {&Invitation{Email: "bob@example.com", Status: InvitationAccepted,
+			ExpiresAt: future}, ErrInvitationUsed},
+		"other email": {&Invitation{Email: "eve@example.com", Status: InvitationPending,
+			ExpiresAt: future}, ErrEmailMismatch},
+	}
+	for name, tc := range cases {
+		mockRepo := new(MockRepo)
+		s := NewService(mockRepo, stubUsers{5: {ID: 5, Email: "bob@example.com"}}, &recordingMailer{}, "")
+		mockRepo.On("GetInvitationByTokenHash", hashToken("tok")).Return(tc.inv, nil)
+
+		member, err := s.AcceptInvitation("tok", 5)
+		assert.Nil(t, member, name)
+		assert.Equal(t, tc.want, err, name)
+	}
+}
+
+func TestAcceptInvitation_LostRace(t *testing.T) {
+	mockRepo := new(MockRepo)
+	s := NewService(mockRepo, stubUsers{5: {ID: 5, Email: "bob@example.com"}}, &recordingMailer{}, "")
+
+	inv := &Invitation{ID: 3, WorkspaceID: 10, Email: "bob@example.com", Role: RoleViewer,
+		Status: InvitationPending, ExpiresAt: time.Now().Add(time.Hour)}
+	mockRepo.On("GetInvitationByTokenHash", hashToken("tok")).Return(inv, nil)
+	mockRepo.On("GetMember", uint(10), uint(5)).Return(nil, nil)
+	mockRepo.On("RespondToInvitation", uint(3), InvitationAccepted, mock.Anything).Return(false, nil)
+
+	member, err := s.AcceptInvitation("tok", 5)
+	assert.Nil(t, member)
+	assert.Equal(t, ErrInvitationUsed, err)
+}
+
+func TestCreateInvitation_LinksExistingUser(t *testing.T) {
+	mockRepo := new(MockRepo)
+	s := NewService(mockRepo, stubUsers{1: {ID: 1, Name: "Alex"}, 5: {ID: 5, Email: "bob@example.com"}},
+		&recordingMailer{}, "")
+
+	mockRepo.On("GetWorkspaceByID", uint(10)).Return(&Workspace{ID: 10, Name: "Team"}, nil)
+	mockRepo.On("GetMember", uint(10), uint(1)).Return(&Member{Role: RoleOwner}, nil)
+	mockRepo.On("RevokePendingInvitations", uint(10), "bob@example.com").Return(nil)
+	mockRepo.On("CreateInvitation", mock.AnythingOfType("*workspaces.Invitation")).Return(nil)
+
+	inv, err := s.CreateInvitation(10, InvitationInput{Email: "Bob@Example.com", Role: RoleViewer}, 1)
+	assert.NoError(t, err)
+	if assert.NotNil(t, inv.InviteeID, "an existing account sees the invitation without signing up again") {
+		assert.Equal(t, uint(5), *inv.InviteeID)
+	}
+}
+
+type recordingEvents struct {
+	events []ShareEvent
+}
+
+func (r *recordingEvents) PublishShareEvent(e ShareEvent) {
+	r.events = append(r.events, e)
+}
+
+func TestAcceptInvitation_PublishesShareEvents(t *testing.T) {
+	mockRepo := new(MockRepo)
+	events := &recordingEvents{}
+	s := NewService(mockRepo, stubUsers{5: {ID: 5, Email: "bob@example.com"}}, &recordingMailer{}, "",
+		WithEventPublisher(events))
+
+	inv := &Invitation{ID: 3, WorkspaceID: 10, InviterID: 1, Email: "bob@example.com", Role: RoleEditor,
+		Status: InvitationPending, ExpiresAt: time.Now().Add(time.Hour)}
+	mockRepo.On("GetInvitationByTokenHash", hashToken("tok")).Return(inv, nil)
+	mockRepo.On("GetMember", uint(10), uint(5)).Return(nil, nil)
+	mockRepo.On("RespondToInvitation", uint(3), InvitationAccepted, mock.Anything).Return(true, nil)
+
+	_, err := s.AcceptInvitation("tok", 5)
+	assert.NoError(t, err)
+	if assert.Len(t, events.events, 2) {
+		assert.Equal(t, ShareJoined, events.events[0].Type)
+		assert.Equal(t, uint(5), events.events[0].UserID)
+		assert.Equal(t, uint(1), events.events[1].UserID, "the inviter hears about it too")
+		assert.Equal(t, uint(5), *events.events[1].MemberID)
+		assert.Equal(t, RoleEditor, events.events[1].Role)
+	}
+}
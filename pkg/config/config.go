@@ -1,6 +1,10 @@
 package config
 
 import (
+	"fmt"
+	"slices"
+	"strings"
+
 	"github.com/spf13/viper"
 )
 
@@ -13,10 +17,56 @@ type Config struct {
 	DBName    string
 	JWTSecret string
 	Mode      string
+	AppURL    string // public frontend URL used in emailed links
+	PubSub    string // "local" (default) or "postgres" to fan out across replicas
+	CORS      CORSConfig
+	Mail      MailConfig
+	// AdminEmails lists the accounts allowed to use the /api/admin routes.
+	AdminEmails []string
+	// TrustedProxies lists the proxy addresses or CIDRs whose
+	// X-Forwarded-For headers are believed when resolving client IPs.
+	// None are trusted by default.
+	TrustedProxies []string
+	// WebhooksAllowLocal lets webhooks reach plain http and private
+	// addresses, for development against a local receiver.
+	WebhooksAllowLocal bool
+}
+
+// CORSConfig describes which cross-origin requests the API accepts.
+// AllowedOrigins entries are either exact origins ("https://app.example.com")
+// or wildcard-subdomain patterns ("https://*.example.com").
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           int // seconds, 0 disables the header
+}
+
+// MailConfig selects and configures the outgoing mail driver.
+type MailConfig struct {
+	Driver   string // "file" (default) or "smtp"
+	From     string
+	Dir      string // output directory for the file driver
+	SMTPHost string
+	SMTPPort string
+	SMTPUser string
+	SMTPPass string
 }
 
 func LoadConfig() (*Config, error) {
 	viper.SetConfigFile(".env")
+	viper.SetDefault("CORS_ALLOWED_METHODS", "GET,POST,PUT,PATCH,DELETE,OPTIONS")
+	viper.SetDefault("CORS_ALLOWED_HEADERS", "Authorization,Content-Type")
+	viper.SetDefault("CORS_ALLOW_CREDENTIALS", true)
+	viper.SetDefault("CORS_MAX_AGE", 600)
+	viper.SetDefault("APP_URL", "http://localhost:3000")
+	viper.SetDefault("MAIL_DRIVER", "file")
+	viper.SetDefault("MAIL_FROM", "FlowBoard <no-reply@localhost>")
+	viper.SetDefault("MAIL_DIR", "tmp/mail")
+	viper.SetDefault("SMTP_PORT", "587")
+	viper.SetDefault("PUBSUB_DRIVER", "local")
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, err
 	}
@@ -29,6 +79,51 @@ func LoadConfig() (*Config, error) {
 		DBName:    viper.GetString("DB_NAME"),
 		JWTSecret: viper.GetString("JWT_SECRET"),
 		Mode:      viper.GetString("GIN_MODE"),
+		AppURL:    strings.TrimSuffix(viper.GetString("APP_URL"), "/"),
+		PubSub:    viper.GetString("PUBSUB_DRIVER"),
+		CORS: CORSConfig{
+			AllowedOrigins:   splitList(viper.GetString("CORS_ALLOWED_ORIGINS")),
+			AllowedMethods:   splitList(viper.GetString("CORS_ALLOWED_METHODS")),
+			AllowedHeaders:   splitList(viper.GetString("CORS_ALLOWED_HEADERS")),
+			ExposedHeaders:   splitList(viper.GetString("CORS_EXPOSED_HEADERS")),
+			AllowCredentials: viper.GetBool("CORS_ALLOW_CREDENTIALS"),
+			MaxAge:           viper.GetInt("CORS_MAX_AGE"),
+		},
+		Mail: MailConfig{
+			Driver:   viper.GetString("MAIL_DRIVER"),
+			From:     viper.GetString("MAIL_FROM"),
+			Dir:      viper.GetString("MAIL_DIR"),
+			SMTPHost: viper.GetString("SMTP_HOST"),
+			SMTPPort: viper.GetString("SMTP_PORT"),
+			SMTPUser: viper.GetString("SMTP_USER"),
+			SMTPPass: viper.GetString("SMTP_PASS"),
+		},
+		AdminEmails:        splitList(strings.ToLower(viper.GetString("ADMIN_EMAILS"))),
+		TrustedProxies:     splitList(viper.GetString("TRUSTED_PROXIES")),
+		WebhooksAllowLocal: viper.GetBool("WEBHOOKS_ALLOW_LOCAL"),
+	}
+	if cfg.CORS.AllowCredentials && slices.Contains(cfg.CORS.AllowedOrigins, "*") {
+		return nil, fmt.Errorf("CORS_ALLOWED_ORIGINS=* cannot be combined with CORS_ALLOW_CREDENTIALS")
 	}
 	return cfg, nil
 }
+
+// DatabaseDSN returns the Postgres connection string for the configured
+// database.
+func (c *Config) DatabaseDSN() string {
+	return fmt.Sprintf(
+		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
+		c.DBHost, c.DBUser, c.DBPass, c.DBName, c.DBPort,
+	)
+}
+
+// splitList parses a comma-separated env value, dropping empty entries.
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
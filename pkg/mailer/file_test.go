@@ -0,0 +1,27 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildMessage_HeadersCannotBeInjected(t *testing.T) {
+	raw := string(buildMessage("FlowBoard <no-reply@localhost>", Message{
+		To:      []string{"bob@example.com"},
+		Subject: "Roadmap\r\nBcc: victim@example.com\r\n\r\nfake body",
+		Text:    "hello",
+	}))
+
+	headers, body, _ := strings.Cut(raw, "\r\n\r\n")
+	assert.Contains(t, headers, "Subject: Roadmap Bcc: victim@example.com fake body\r\n")
+	assert.NotContains(t, headers, "\r\nBcc:")
+	assert.Equal(t, "hello", body)
+}
+
+func TestBuildMessage_EncodesNonASCIISubject(t *testing.T) {
+	raw := string(buildMessage("no-reply@localhost", Message{To: []string{"a@example.com"}, Subject: "Café plans"}))
+
+	assert.Contains(t, raw, "Subject: =?UTF-8?q?Caf=C3=A9_plans?=\r\n")
+}
@@ -0,0 +1,38 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"flowboard-backend-go/pkg/config"
+)
+
+// Message is a plain email. HTML is optional; Text is always sent.
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Mailer delivers outgoing email. Implementations must be safe for
+// concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New builds the mailer selected by cfg.Driver.
+func New(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case "", "file":
+		dir := cfg.Dir
+		if dir == "" {
+			dir = "tmp/mail"
+		}
+		return NewFileMailer(dir, cfg.From), nil
+	case "smtp":
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPass, cfg.From)
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Driver)
+	}
+}
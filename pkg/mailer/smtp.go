@@ -0,0 +1,35 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+)
+
+// SMTPMailer sends messages through an SMTP relay using PLAIN auth when
+// credentials are configured.
+type SMTPMailer struct {
+	addr     string
+	host     string
+	auth     smtp.Auth
+	from     string // header form, possibly with a display name
+	envelope string // bare address for MAIL FROM
+}
+
+func NewSMTPMailer(host, port, user, pass, from string) (*SMTPMailer, error) {
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mail sender %q: %w", from, err)
+	}
+	m := &SMTPMailer{addr: net.JoinHostPort(host, port), host: host, from: from, envelope: addr.Address}
+	if user != "" {
+		m.auth = smtp.PlainAuth("", user, pass, host)
+	}
+	return m, nil
+}
+
+func (m *SMTPMailer) Send(_ context.Context, msg Message) error {
+	return smtp.SendMail(m.addr, m.auth, m.envelope, msg.To, buildMessage(m.from, msg))
+}
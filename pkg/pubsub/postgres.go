@@ -0,0 +1,126 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"flowboard-backend-go/pkg/logger"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// channel is the single NOTIFY channel all topics share; the topic travels
+// in the payload.
+const channel = "flowboard_pubsub"
+
+// maxPayload is Postgres' limit on a NOTIFY payload, in bytes.
+const maxPayload = 8000
+
+var ErrPayloadTooLarge = errors.New("pubsub: message exceeds the NOTIFY payload limit")
+
+// envelope is what goes over the wire.
+type envelope struct {
+	Topic   string          `json:"t"`
+	Payload json.RawMessage `json:"p"`
+}
+
+// Postgres relays messages through LISTEN/NOTIFY. Publish sends a NOTIFY;
+// a dedicated listening connection receives every notification, including
+// this replica's own, and hands it to local subscribers. Payloads must be
+// JSON.
+type Postgres struct {
+	local  *Local
+	pool   *pgxpool.Pool
+	dsn    string
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewPostgres connects to the database at dsn and starts listening.
+func NewPostgres(ctx context.Context, dsn string) (*Postgres, error) {
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	listenCtx, cancel := context.WithCancel(context.Background())
+	p := &Postgres{local: NewLocal(), pool: pool, dsn: dsn, cancel: cancel, done: make(chan struct{})}
+	go p.listen(listenCtx)
+	return p, nil
+}
+
+func (p *Postgres) Publish(ctx context.Context, topic string, payload []byte) error {
+	msg, err := json.Marshal(envelope{Topic: topic, Payload: payload})
+	if err != nil {
+		return err
+	}
+	if len(msg) > maxPayload {
+		return ErrPayloadTooLarge
+	}
+	_, err = p.pool.Exec(ctx, "SELECT pg_notify($1, $2)", channel, string(msg))
+	return err
+}
+
+func (p *Postgres) Subscribe(topic string, fn Handler) func() {
+	return p.local.Subscribe(topic, fn)
+}
+
+func (p *Postgres) Close() error {
+	p.cancel()
+	<-p.done
+	p.pool.Close()
+	return nil
+}
+
+// listen keeps a LISTEN connection open until ctx is cancelled,
+// reconnecting with backoff. Messages sent while reconnecting are lost.
+func (p *Postgres) listen(ctx context.Context) {
+	defer close(p.done)
+	backoff := time.Second
+	for ctx.Err() == nil {
+		err := p.listenOnce(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Log.Errorw("Pub/sub listener disconnected", "error", err, "retryIn", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+func (p *Postgres) listenOnce(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, p.dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+		return err
+	}
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var env envelope
+		if err := json.Unmarshal([]byte(n.Payload), &env); err != nil {
+			logger.Log.Errorw("Dropping malformed pub/sub message", "error", err)
+			continue
+		}
+		p.local.deliver(env.Topic, env.Payload)
+	}
+}
@@ -0,0 +1,94 @@
+// Package pubsub fans messages out to subscribers by topic. The local
+// implementation works within one process; the Postgres one relays every
+// message through LISTEN/NOTIFY so subscribers on all replicas see it.
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Handler receives a message published on a subscribed topic. Handlers
+// run on the publisher's or the listener's goroutine and must not block.
+type Handler func(payload []byte)
+
+// PubSub publishes messages to topics and delivers them to subscribers.
+// Implementations must be safe for concurrent use.
+type PubSub interface {
+	Publish(ctx context.Context, topic string, payload []byte) error
+	// Subscribe registers fn for topic and returns a function that removes
+	// it again.
+	Subscribe(topic string, fn Handler) (unsubscribe func())
+	Close() error
+}
+
+// Local delivers messages to subscribers in the same process.
+type Local struct {
+	mu     sync.RWMutex
+	subs   map[string]map[uint64]Handler
+	nextID uint64
+}
+
+func NewLocal() *Local {
+	return &Local{subs: map[string]map[uint64]Handler{}}
+}
+
+func (l *Local) Publish(_ context.Context, topic string, payload []byte) error {
+	l.deliver(topic, payload)
+	return nil
+}
+
+func (l *Local) Subscribe(topic string, fn Handler) func() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.nextID++
+	id := l.nextID
+	if l.subs[topic] == nil {
+		l.subs[topic] = map[uint64]Handler{}
+	}
+	l.subs[topic][id] = fn
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			delete(l.subs[topic], id)
+			if len(l.subs[topic]) == 0 {
+				delete(l.subs, topic)
+			}
+		})
+	}
+}
+
+func (l *Local) Close() error {
+	return nil
+}
+
+// deliver calls every handler subscribed to topic.
+func (l *Local) deliver(topic string, payload []byte) {
+	l.mu.RLock()
+	handlers := make([]Handler, 0, len(l.subs[topic]))
+	for _, fn := range l.subs[topic] {
+		handlers = append(handlers, fn)
+	}
+	l.mu.RUnlock()
+
+	for _, fn := range handlers {
+		fn(payload)
+	}
+}
+
+// New builds the backend selected by driver: "local" or "postgres", which
+// connects to dsn.
+func New(ctx context.Context, driver, dsn string) (PubSub, error) {
+	switch driver {
+	case "", "local":
+		return NewLocal(), nil
+	case "postgres":
+		return NewPostgres(ctx, dsn)
+	default:
+		return nil, fmt.Errorf("pubsub: unknown driver %q", driver)
+	}
+}
@@ -0,0 +1,34 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalDeliversByTopic(t *testing.T) {
+	ps := NewLocal()
+	var a, b []string
+	unsubA := ps.Subscribe("pages", func(p []byte) { a = append(a, string(p)) })
+	ps.Subscribe("pages", func(p []byte) { b = append(b, string(p)) })
+	ps.Subscribe("other", func(p []byte) { t.Fatalf("unexpected delivery of %s", p) })
+
+	require.NoError(t, ps.Publish(context.Background(), "pages", []byte("1")))
+	unsubA()
+	unsubA()
+	require.NoError(t, ps.Publish(context.Background(), "pages", []byte("2")))
+
+	assert.Equal(t, []string{"1"}, a)
+	assert.Equal(t, []string{"1", "2"}, b)
+}
+
+func TestNewRejectsUnknownDriver(t *testing.T) {
+	ps, err := New(context.Background(), "", "")
+	require.NoError(t, err)
+	assert.IsType(t, &Local{}, ps)
+
+	_, err = New(context.Background(), "redis", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,152 @@
+// Package rank generates lexicographically ordered string keys for
+// user-controlled ordering. A key is the fractional part of a base-62
+// number, so a new key can always be placed between two existing ones and
+// moving an item only ever rewrites that item's key.
+package rank
+
+import (
+	"errors"
+	"strings"
+)
+
+const (
+	digits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	base   = len(digits)
+	// maxSpreadLen bounds Spread's key length so values fit in a uint64.
+	maxSpreadLen = 10
+)
+
+var (
+	ErrInvalidKey   = errors.New("invalid rank key")
+	ErrInvalidRange = errors.New("rank keys out of order")
+)
+
+// Between returns a key sorting strictly after a and strictly before b.
+// An empty a means "before everything", an empty b "after everything".
+func Between(a, b string) (string, error) {
+	if err := validate(a); err != nil {
+		return "", err
+	}
+	if err := validate(b); err != nil {
+		return "", err
+	}
+	if b != "" && a >= b {
+		return "", ErrInvalidRange
+	}
+	return midpoint(a, b), nil
+}
+
+// Spread returns n evenly spaced, increasing keys of the shortest length
+// that still leaves gaps between neighbours. It is used to rebalance a
+// set of keys that have grown long.
+func Spread(n int) []string {
+	if n <= 0 {
+		return nil
+	}
+	length, span := 1, uint64(base)
+	for span < uint64(2*(n+1)) && length < maxSpreadLen {
+		length++
+		span *= uint64(base)
+	}
+
+	keys := make([]string, n)
+	step := span / uint64(n+1)
+	for i := range keys {
+		keys[i] = encode(step*uint64(i+1), length)
+	}
+	return keys
+}
+
+// Insert returns a key that sorts at index at of the ordered keys. When
+// the neighbours leave no room, or were never ranked (empty), the whole
+// list is respread: respread then holds replacement keys for keys, in the
+// same order, which the caller must persist along with key.
+func Insert(keys []string, at int) (key string, respread []string) {
+	lo, hi := "", ""
+	if at > 0 {
+		lo = keys[at-1]
+	}
+	if at < len(keys) {
+		hi = keys[at]
+	}
+	if (at == 0 || lo != "") && (at == len(keys) || hi != "") {
+		if k, err := Between(lo, hi); err == nil {
+			return k, nil
+		}
+	}
+
+	spread := Spread(len(keys) + 1)
+	respread = make([]string, 0, len(keys))
+	respread = append(respread, spread[:at]...)
+	respread = append(respread, spread[at+1:]...)
+	return spread[at], respread
+}
+
+// midpoint finds a key between a and b, treating b == "" as 1. Keys never
+// end in '0', which guarantees there is always room below any key.
+func midpoint(a, b string) string {
+	if b != "" {
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(a) {
+				rest = a[n:]
+			}
+			return b[:n] + midpoint(rest, b[n:])
+		}
+	}
+
+	lo := 0
+	if a != "" {
+		lo = strings.IndexByte(digits, a[0])
+	}
+	hi := base
+	if b != "" {
+		hi = strings.IndexByte(digits, b[0])
+	}
+	if hi-lo > 1 {
+		return string(digits[(lo+hi+1)/2])
+	}
+	if len(b) > 1 {
+		return b[:1]
+	}
+	rest := ""
+	if a != "" {
+		rest = a[1:]
+	}
+	return string(digits[lo]) + midpoint(rest, "")
+}
+
+func digitAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return digits[0]
+}
+
+func encode(v uint64, length int) string {
+	buf := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		buf[i] = digits[v%uint64(base)]
+		v /= uint64(base)
+	}
+	return strings.TrimRight(string(buf), digits[:1])
+}
+
+func validate(key string) error {
+	if key == "" {
+		return nil
+	}
+	if key[len(key)-1] == digits[0] {
+		return ErrInvalidKey
+	}
+	for i := 0; i < len(key); i++ {
+		if strings.IndexByte(digits, key[i]) < 0 {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
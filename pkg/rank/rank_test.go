@@ -0,0 +1,104 @@
+package rank
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBetween(t *testing.T) {
+	cases := []struct{ a, b, want string }{
+		{"", "", "V"},
+		{"V", "", "l"},
+		{"", "V", "G"},
+		{"z", "", "zV"},
+		{"", "1", "0V"},
+		{"a", "b", "aV"},
+		{"a", "aV", "aG"},
+		{"1z", "2", "1zV"},
+		{"1", "3", "2"},
+	}
+	for _, tc := range cases {
+		got, err := Between(tc.a, tc.b)
+		require.NoError(t, err, "%q..%q", tc.a, tc.b)
+		assert.Equal(t, tc.want, got, "%q..%q", tc.a, tc.b)
+	}
+}
+
+func TestBetween_Errors(t *testing.T) {
+	_, err := Between("b", "a")
+	assert.Equal(t, ErrInvalidRange, err)
+	_, err = Between("a", "a")
+	assert.Equal(t, ErrInvalidRange, err)
+	_, err = Between("a0", "")
+	assert.Equal(t, ErrInvalidKey, err)
+	_, err = Between("", "a-b")
+	assert.Equal(t, ErrInvalidKey, err)
+}
+
+// Random inserts must keep every key strictly ordered and valid.
+func TestBetween_RandomInsertsStayOrdered(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	keys := []string{}
+	for i := 0; i < 2000; i++ {
+		at := rng.Intn(len(keys) + 1)
+		lo, hi := "", ""
+		if at > 0 {
+			lo = keys[at-1]
+		}
+		if at < len(keys) {
+			hi = keys[at]
+		}
+		k, err := Between(lo, hi)
+		require.NoError(t, err)
+		require.NoError(t, validate(k))
+		keys = append(keys[:at], append([]string{k}, keys[at:]...)...)
+	}
+	assert.True(t, sort.StringsAreSorted(keys))
+	for i := 1; i < len(keys); i++ {
+		require.NotEqual(t, keys[i-1], keys[i])
+	}
+}
+
+func TestSpread(t *testing.T) {
+	assert.Nil(t, Spread(0))
+	assert.Equal(t, []string{"V"}, Spread(1))
+
+	for _, n := range []int{3, 30, 31, 500, 10000} {
+		keys := Spread(n)
+		require.Len(t, keys, n)
+		assert.True(t, sort.StringsAreSorted(keys), "n=%d", n)
+		for i, k := range keys {
+			require.NoError(t, validate(k))
+			if i > 0 {
+				// Neighbours must leave room for an insert.
+				_, err := Between(keys[i-1], k)
+				require.NoError(t, err)
+				require.NotEqual(t, keys[i-1], k)
+			}
+		}
+	}
+	assert.LessOrEqual(t, len(Spread(10000)[0]), 3)
+}
+
+func TestInsert(t *testing.T) {
+	key, respread := Insert([]string{"V", "l"}, 1)
+	assert.Equal(t, "d", key)
+	assert.Nil(t, respread)
+
+	key, respread = Insert(nil, 0)
+	assert.Equal(t, "V", key)
+	assert.Nil(t, respread)
+
+	// Unranked neighbours force a respread that keeps the existing order.
+	key, respread = Insert([]string{"", "", ""}, 1)
+	require.Len(t, respread, 3)
+	all := []string{respread[0], key, respread[1], respread[2]}
+	assert.True(t, sort.StringsAreSorted(all))
+
+	_, respread = Insert([]string{"b", "a"}, 1)
+	assert.Len(t, respread, 2)
+}